@@ -27,16 +27,24 @@ func main() {
 	cli := clif.New("reconciliation-service", "1.0.0", "")
 	cmdServer := console.ServerConsole{}
 	cmdMigrate := console.NewMigrateConsole(conf.Database.Master)
-	//cmdWorker := console.WorkerConsole{}
+	cmdFXRate := console.FXRateConsole{}
+	cmdWorker := console.WorkerConsole{}
+	cmdIngestion := console.IngestionConsole{}
 	cli.Add(cmdServer.StartServer())
 	cli.Add(cmdMigrate.MigrateCreate())
 	cli.Add(cmdMigrate.MigrateRun(ctx))
 	cli.Add(cmdMigrate.MigrateRollback())
-	//cli.Add(cmdWorker.StartWorker())
+	cli.Add(cmdFXRate.LoadRates())
+	cli.Add(cmdWorker.StartWorker())
+	cli.Add(cmdIngestion.RequeueJob())
 	cli.Run()
 }
 
 func init() {
 	parser2.RegisterParser(enum_parser.BANK_STATEMENT, &parser2.BankStatementParser{})
 	parser2.RegisterParser(enum_parser.SYSTEM_TRX, &parser2.SystemTxParser{})
+	parser2.RegisterFileParser(enum_parser.MT940, &parser2.MT940Parser{})
+	parser2.RegisterFileParser(enum_parser.CAMT, &parser2.CamtParser{})
+	parser2.RegisterFileParser(enum_parser.BAI2, &parser2.BAI2Parser{})
+	parser2.RegisterFileParser(enum_parser.OFX, &parser2.OFXParser{})
 }
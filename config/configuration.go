@@ -10,13 +10,18 @@ import (
 )
 
 type Configuration struct {
-	App       AppConfiguration      `mapstructure:"app"`
-	Server    ServerConfiguration   `mapstructure:"server"`
-	Worker    WorkerConfiguration   `mapstructure:"worker"`
-	Database  DatabaseConfiguration `mapstructure:"database"`
-	Log       LogConfig             `mapstructure:"log"`
-	BasicAuth []BasicAuthConfig     `mapstructure:"basic_auth"`
-	Storage   StorageConfiguration  `mapstructure:"storage"`
+	App       AppConfiguration       `mapstructure:"app"`
+	Server    ServerConfiguration    `mapstructure:"server"`
+	Worker    WorkerConfiguration    `mapstructure:"worker"`
+	Database  DatabaseConfiguration  `mapstructure:"database"`
+	Log       LogConfig              `mapstructure:"log"`
+	BasicAuth []BasicAuthConfig      `mapstructure:"basic_auth"`
+	Storage   StorageConfiguration   `mapstructure:"storage"`
+	Reconcile ReconcileConfiguration `mapstructure:"reconcile"`
+	Cases     CasesConfiguration     `mapstructure:"cases"`
+	Approval  ApprovalConfiguration  `mapstructure:"approval"`
+	Journal   JournalConfiguration   `mapstructure:"journal"`
+	Parser    ParserConfiguration    `mapstructure:"parser"`
 }
 
 type AppConfiguration struct {
@@ -34,7 +39,13 @@ type ServerConfiguration struct {
 }
 
 type WorkerConfiguration struct {
-	MaxWorkers string `mapstructure:"max_workers"`
+	MaxWorkers       string `mapstructure:"max_workers"`
+	WorkerID         string `mapstructure:"worker_id"`          // defaults to host name and process id
+	LeaseSeconds     int    `mapstructure:"lease_seconds"`      // claimed jobs return to PENDING this long after the last heartbeat
+	HeartbeatSeconds int    `mapstructure:"heartbeat_seconds"`  // defaults to a third of the lease
+	MaxAttempts      int    `mapstructure:"max_attempts"`       // failed ingestion jobs are dead-lettered after this many attempts
+	RetryBaseSeconds int    `mapstructure:"retry_base_seconds"` // wait before the second attempt, doubled for every later one
+	RetryMaxSeconds  int    `mapstructure:"retry_max_seconds"`
 }
 
 type DatabaseConfiguration struct {
@@ -57,6 +68,97 @@ type StorageConfiguration struct {
 	Bucket       string `mapstructure:"bucket"`
 }
 
+type ReconcileConfiguration struct {
+	AmountTolerance        float64              `mapstructure:"amount_tolerance"`
+	AmountTolerancePercent float64              `mapstructure:"amount_tolerance_percent"`
+	DateWindowDays         int                  `mapstructure:"date_window_days"`
+	GroupMatching          bool                 `mapstructure:"group_matching"`
+	MaxGroupSize           int                  `mapstructure:"max_group_size"`
+	AssignmentMode         string               `mapstructure:"assignment_mode"`
+	Currency               string               `mapstructure:"currency"`
+	ConvertCurrency        bool                 `mapstructure:"convert_currency"`
+	IncludeHistorical      bool                 `mapstructure:"include_historical"`
+	RuleSets               []MatchRuleSetConfig `mapstructure:"rule_sets"`
+}
+
+// CasesConfiguration holds the thresholds that raise the priority of an exception case, zero values use the defaults
+type CasesConfiguration struct {
+	HighPriorityAmount    float64 `mapstructure:"high_priority_amount"`
+	MediumPriorityAmount  float64 `mapstructure:"medium_priority_amount"`
+	HighPriorityAgeDays   int     `mapstructure:"high_priority_age_days"`
+	MediumPriorityAgeDays int     `mapstructure:"medium_priority_age_days"`
+}
+
+// ApprovalConfiguration holds the absolute amount above which a manual match discrepancy or a write-off
+// needs the approval of a second client, zero applies every change right away
+type ApprovalConfiguration struct {
+	Threshold float64 `mapstructure:"threshold"`
+}
+
+// JournalConfiguration maps the bank code and reason of an adjusting journal entry to GL accounts
+type JournalConfiguration struct {
+	Accounts []GLAccountConfig `mapstructure:"accounts"`
+}
+
+// ParserConfiguration holds the column mappings of the CSV layouts ingested without a parser of their own,
+// more profiles can be stored in the parser_profiles table
+type ParserConfiguration struct {
+	Profiles []ParserProfileConfig `mapstructure:"profiles"`
+}
+
+// ParserProfileConfig declares the layout of a CSV or XLSX file, a workflow selects it by name per file
+type ParserProfileConfig struct {
+	Name              string                  `mapstructure:"name"`
+	Delimiter         string                  `mapstructure:"delimiter"`
+	Sheet             string                  `mapstructure:"sheet"`
+	SkipRows          int                     `mapstructure:"skip_rows"`
+	SkipFooterRows    int                     `mapstructure:"skip_footer_rows"`
+	NoHeader          bool                    `mapstructure:"no_header"`
+	Columns           map[string]ColumnConfig `mapstructure:"columns"`
+	DateFormats       []string                `mapstructure:"date_formats"`
+	TimeFormat        string                  `mapstructure:"time_format"`
+	Timezone          string                  `mapstructure:"timezone"`
+	DecimalSeparator  string                  `mapstructure:"decimal_separator"`
+	ThousandSeparator string                  `mapstructure:"thousand_separator"`
+	SignConvention    string                  `mapstructure:"sign_convention"`
+	DebitIndicators   []string                `mapstructure:"debit_indicators"`
+	CreditIndicators  []string                `mapstructure:"credit_indicators"`
+	DefaultBankCode   string                  `mapstructure:"default_bank_code"`
+	DefaultCurrency   string                  `mapstructure:"default_currency"`
+}
+
+// ColumnConfig references a column by its header name or by its zero-based index
+type ColumnConfig struct {
+	Name  string `mapstructure:"name"`
+	Index *int   `mapstructure:"index"`
+}
+
+// GLAccountConfig sets the accounts of the entries of a bank code and reason, an empty bank code or reason matches any
+type GLAccountConfig struct {
+	BankCode          string `mapstructure:"bank_code"`
+	Reason            string `mapstructure:"reason"`
+	CashAccount       string `mapstructure:"cash_account"`
+	AdjustmentAccount string `mapstructure:"adjustment_account"`
+}
+
+type MatchRuleSetConfig struct {
+	Name     string            `mapstructure:"name"`
+	BankCode string            `mapstructure:"bank_code"`
+	Passes   []MatchPassConfig `mapstructure:"passes"`
+}
+
+type MatchPassConfig struct {
+	Name  string            `mapstructure:"name"`
+	Rules []MatchRuleConfig `mapstructure:"rules"`
+}
+
+type MatchRuleConfig struct {
+	Type                   string   `mapstructure:"type"`
+	AmountTolerance        *float64 `mapstructure:"amount_tolerance"`
+	AmountTolerancePercent *float64 `mapstructure:"amount_tolerance_percent"`
+	DateWindowDays         *int     `mapstructure:"date_window_days"`
+}
+
 var (
 	configuration *Configuration
 )
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+// Types of a balance reported by the bank
+const (
+	BalanceOpening = "OPENING"
+	BalanceClosing = "CLOSING"
+)
+
+// Outcomes of the balance proof of an account
+const (
+	RollforwardBalanced       = "BALANCED"
+	RollforwardUnexplained    = "UNEXPLAINED_DIFFERENCE"
+	RollforwardMissingBalance = "MISSING_BALANCE"
+)
+
+// StatementBalance is an opening or closing balance of an account reported in a bank statement file
+type StatementBalance struct {
+	BankCode       string
+	AccountNumber  string
+	BalanceType    string // BalanceOpening or BalanceClosing
+	BalanceDate    time.Time
+	Amount         money.Money
+	IngestionJobID string
+	WorkflowID     string
+}
+
+// AccountActivity totals the statement lines of one bank account in the period of a job by how the job settled them,
+// with the balances the bank reported for the period. The balances are nil when no file reported them.
+type AccountActivity struct {
+	BankCode           string
+	AccountNumber      string
+	Currency           string
+	StatementMovements money.Money // every line of the account in the period
+	MatchedBank        money.Money // lines matched to system transactions
+	SystemMovements    money.Money // system transactions matched to those lines, as expected on the bank
+	UnmatchedBank      money.Money // lines left unmatched
+	WrittenOff         money.Money // lines written off
+	OpeningBalance     *money.Money
+	ClosingBalance     *money.Money
+}
+
+// BalanceRollforward proves the closing balance reported for an account from its opening balance:
+// opening + system movements + match differences + unmatched and written-off bank lines = expected closing.
+// Any difference to the reported closing balance is unexplained by the reconciliation.
+type BalanceRollforward struct {
+	BankCode               string       `json:"bank_code"`
+	AccountNumber          string       `json:"account_number,omitempty"`
+	Currency               string       `json:"currency"`
+	OpeningBalance         *money.Money `json:"opening_balance"`
+	SystemMovements        money.Money  `json:"system_movements"`
+	MatchDifferences       money.Money  `json:"match_differences"`
+	UnmatchedBankMovements money.Money  `json:"unmatched_bank_movements"`
+	WrittenOff             money.Money  `json:"written_off"`
+	StatementMovements     money.Money  `json:"statement_movements"`
+	ExpectedClosing        *money.Money `json:"expected_closing_balance"`
+	ReportedClosing        *money.Money `json:"reported_closing_balance"`
+	UnexplainedDifference  *money.Money `json:"unexplained_difference"`
+	Status                 string       `json:"status"`
+}
@@ -3,4 +3,8 @@ package enum_parser
 const (
 	BANK_STATEMENT = "DEFAULT_BANK_STATEMENT"
 	SYSTEM_TRX     = "DEFAULT_SYSTEM_TRX"
+	MT940          = "SWIFT_MT940"   // SWIFT MT940 and MT942 bank statements
+	CAMT           = "ISO20022_CAMT" // ISO 20022 camt.053 statements and camt.054 notifications
+	BAI2           = "BAI2"          // BAI2 cash management balance reports
+	OFX            = "OFX"           // OFX 1.x and 2.x bank and credit card statements
 )
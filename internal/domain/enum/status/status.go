@@ -10,6 +10,10 @@ const (
 	IN_PROGRESS
 	COMPLETED
 	FAILED
+	INGESTING
+	RECONCILING
+	DEAD_LETTER
+	CANCELLED
 )
 
 func (t Type) String() string {
@@ -19,6 +23,10 @@ func (t Type) String() string {
 		"IN_PROGRESS",
 		"COMPLETED",
 		"FAILED",
+		"INGESTING",
+		"RECONCILING",
+		"DEAD_LETTER",
+		"CANCELLED",
 	}[t]
 }
 
@@ -29,6 +37,10 @@ func FromString(status string) Type {
 		"IN_PROGRESS": IN_PROGRESS,
 		"COMPLETED":   COMPLETED,
 		"FAILED":      FAILED,
+		"INGESTING":   INGESTING,
+		"RECONCILING": RECONCILING,
+		"DEAD_LETTER": DEAD_LETTER,
+		"CANCELLED":   CANCELLED,
 	}[status]
 }
 
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+// Sort keys of the exception lists, not every list supports every key
+const (
+	SortByDate        = "date"
+	SortByAmount      = "amount"
+	SortByReference   = "reference"
+	SortByBankCode    = "bank_code"
+	SortByDiscrepancy = "discrepancy"
+)
+
+// ExceptionQuery selects one page of the unmatched items or matches of a job, zero fields do not filter
+type ExceptionQuery struct {
+	BankCode  string
+	Type      string // transaction type of the system side
+	MinAmount *float64
+	MaxAmount *float64
+	From      time.Time // transaction or statement date
+	To        time.Time
+	SortBy    string
+	Desc      bool
+	After     *ExceptionCursor
+	Limit     int
+}
+
+// ExceptionCursor is the position of the last row of a page in the order of SortBy
+type ExceptionCursor struct {
+	SortBy string
+	Value  string // sort key of the row as text
+	ID     int
+}
+
+// MatchedPair is a 1-to-1 match with the records on both sides
+type MatchedPair struct {
+	MatchedRecord
+	TrxID           string
+	TrxType         string
+	TransactionTime time.Time
+	UniqueID        string
+	BankCode        string
+	StatementTime   time.Time
+}
+
+// BankExceptionCount totals the unmatched statements of one bank
+type BankExceptionCount struct {
+	BankCode string      `json:"bank_code"`
+	Count    int         `json:"count"`
+	Amount   money.Money `json:"amount"`
+}
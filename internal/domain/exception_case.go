@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+// Statuses of an exception case. OPEN and INVESTIGATING are set by hand,
+// a case is RESOLVED when its record gets matched and WRITTEN_OFF when it is written off.
+const (
+	CaseOpen          = "OPEN"
+	CaseInvestigating = "INVESTIGATING"
+	CaseResolved      = "RESOLVED"
+	CaseWrittenOff    = "WRITTEN_OFF"
+)
+
+// CaseStatuses lists the statuses a case can be in
+var CaseStatuses = []string{CaseOpen, CaseInvestigating, CaseResolved, CaseWrittenOff}
+
+const (
+	PriorityHigh   = "HIGH"
+	PriorityMedium = "MEDIUM"
+	PriorityLow    = "LOW"
+)
+
+// CasePriorityPolicy derives the priority of a case from its absolute amount and the days since the item date,
+// whichever of the two reaches the higher level wins
+type CasePriorityPolicy struct {
+	HighAmount    float64
+	MediumAmount  float64
+	HighAgeDays   int
+	MediumAgeDays int
+}
+
+// ExceptionCase tracks the investigation of one unmatched record of a job
+type ExceptionCase struct {
+	ID          int
+	JobID       string
+	ItemType    string // OpenItemSystemTx or OpenItemBankStatement
+	RecordID    int    // system transaction or bank statement id, 0 when it cannot be traced back
+	ExceptionID int    // id of the unmatched row while the record is still an exception of the job, 0 afterwards
+	Reference   string
+	BankCode    string
+	Amount      money.Money
+	ItemDate    time.Time
+	Status      string
+	Assignee    string
+	Priority    string
+	AgeDays     int
+	ClosedAt    *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// CaseFilter selects the cases of a job, newest first. Cases with an id below AfterID are returned when it is set.
+type CaseFilter struct {
+	Statuses []string
+	Assignee string
+	Priority string
+	ItemType string
+	AfterID  int
+	Limit    int
+	Policy   CasePriorityPolicy
+}
+
+// CasePage is one page of listed cases, NextAfterID is 0 on the last page
+type CasePage struct {
+	Items       []ExceptionCase
+	NextAfterID int
+}
+
+// CaseUpdate holds the fields of a case to change, nil fields are kept. An empty assignee unassigns the case.
+type CaseUpdate struct {
+	Status   *string
+	Assignee *string
+}
+
+type CaseComment struct {
+	ID        int
+	CaseID    int
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// CaseAttachment describes a file attached to a case, the content is stored in the bucket under ObjectName
+type CaseAttachment struct {
+	ID          int
+	CaseID      int
+	FileName    string
+	ObjectName  string
+	ContentType string
+	Size        int64
+	UploadedBy  string
+	CreatedAt   time.Time
+}
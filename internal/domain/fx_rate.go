@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+// FXRate is the daily rate at which one unit of BaseCurrency converts into QuoteCurrency
+type FXRate struct {
+	RateDate      time.Time
+	BaseCurrency  string
+	QuoteCurrency string
+	Rate          money.Rate
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -5,10 +5,23 @@ import "time"
 // IngestionJob tracks a single CSV ingestion into DB (system or bank).
 type IngestionJob struct {
 	JobID               string
+	WorkflowID          string // workflow the file was uploaded for
 	FileType            string // "SYSTEM_TX" or "BANK_STMT"
 	FileName            string
+	ParserProfile       string // profile the file is parsed with, the built-in parser of the file type when empty
 	TotalLinesProcessed int64
-	Status              string // "IN_PROGRESS", "COMPLETED", "FAILED"
+	Status              string // "PENDING", "IN_PROGRESS", "COMPLETED", "DEAD_LETTER"
+	WorkerID            string // worker holding the job while IN_PROGRESS
+	LeaseExpiresAt      *time.Time
+	Attempts            int        // claims so far, including the running one
+	NextAttemptAt       *time.Time // a PENDING job is not claimed before this time
+	LastError           string
 	CreatedAt           time.Time
 	UpdatedAt           time.Time
 }
+
+// DataScope restricts the ingested rows a reconciliation looks at
+type DataScope struct {
+	WorkflowID        string // rows uploaded for this workflow, every row when empty
+	IncludeHistorical bool   // also rows ingested before the workflow was created
+}
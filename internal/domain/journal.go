@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+// What a journal entry books
+const (
+	JournalSourceMatch      = "MATCH"
+	JournalSourceMatchGroup = "MATCH_GROUP"
+	JournalSourceWriteOff   = "WRITE_OFF"
+)
+
+// JournalReasonDiscrepancy is the reason of the entries booking the discrepancy of a match,
+// entries of a write-off carry its reason code
+const JournalReasonDiscrepancy = "DISCREPANCY"
+
+// JournalSource is a discrepancy or write-off of a job to book. CashEffect is what it changes on the bank account
+// compared to the books: positive when the bank holds more, negative when it holds less.
+type JournalSource struct {
+	SourceType string
+	SourceID   int
+	BankCode   string
+	Reason     string
+	Reference  string
+	EntryDate  time.Time
+	CashEffect money.Money
+}
+
+// GLAccountRule maps the bank code and reason of an entry to the GL accounts it is booked on,
+// an empty bank code or reason matches any. A rule may set only one of the accounts.
+type GLAccountRule struct {
+	BankCode          string
+	Reason            string
+	CashAccount       string // account of the bank
+	AdjustmentAccount string // account absorbing the difference
+}
+
+// JournalEntry is a balanced set of debit and credit lines adjusting the books for one discrepancy or write-off of a job
+type JournalEntry struct {
+	ID          int
+	JobID       string
+	SourceType  string
+	SourceID    int
+	BankCode    string
+	Reason      string
+	Reference   string
+	Description string
+	EntryDate   time.Time
+	Currency    string
+	Lines       []JournalLine
+	CreatedAt   time.Time
+}
+
+// JournalLine is one side of a journal entry, either Debit or Credit is zero
+type JournalLine struct {
+	LineNo  int
+	Account string
+	Debit   money.Money
+	Credit  money.Money
+}
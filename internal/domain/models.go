@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
 	"time"
 )
 
@@ -14,44 +15,123 @@ const (
 	Credit = "CREDIT"
 )
 
+const (
+	OneToMany = "ONE_TO_MANY" // one system transaction split over several bank lines
+	ManyToOne = "MANY_TO_ONE" // several system transactions settled as one bank line
+)
+
+const (
+	AssignmentGreedy  = "GREEDY"  // each transaction takes its best free candidate in turn
+	AssignmentOptimal = "OPTIMAL" // weighted bipartite assignment over each pass
+)
+
 // Transaction represents the system transaction data.
 type Transaction struct {
 	ID              int
 	TrxID           string
-	Amount          float64
+	Amount          money.Money
 	Type            string
 	TransactionTime time.Time
+	IngestionJobID  string // ingestion job and workflow that loaded the row
+	WorkflowID      string
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 }
 
 // BankStatement represents the external bank statement.
 type BankStatement struct {
-	ID            int
-	UniqueID      string
-	Amount        float64 // Negative for debits, positive for credits
-	StatementTime time.Time
-	BankCode      string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	HashCode      string
+	ID             int
+	UniqueID       string
+	Amount         money.Money // Negative for debits, positive for credits
+	StatementTime  time.Time
+	BankCode       string
+	AccountNumber  string // empty when the file does not name the account
+	Description    string // narrative of the bank, e.g. the :86: field of an MT940 line
+	TypeCode       string // transaction type of the file format, e.g. the BAI2 type code or the OFX TRNTYPE
+	BankReference  string // reference the bank gave the line, next to the one the unique id was taken from
+	IngestionJobID string // ingestion job and workflow that loaded the row
+	WorkflowID     string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	HashCode       string
 }
 
 func (b *BankStatement) GenerateHashCode() string {
 	dateStr := b.StatementTime.Format("2006-01-02") // Extract YYYY-MM-DD
-	hashInput := fmt.Sprintf("%s|%s|%.2f|%s", b.UniqueID, b.BankCode, b.Amount, dateStr)
+	hashInput := fmt.Sprintf("%s|%s|%s|%s", b.UniqueID, b.BankCode, b.Amount, dateStr)
+	// The default currency is left out so statements ingested before currencies existed keep their hash
+	if b.Amount.Currency != "" && b.Amount.Currency != money.DefaultCurrency {
+		hashInput += "|" + b.Amount.Currency
+	}
+	// Likewise the account is only part of the hash when the file names it
+	if b.AccountNumber != "" {
+		hashInput += "|" + b.AccountNumber
+	}
 
 	hash := sha256.Sum256([]byte(hashInput))
 	return hex.EncodeToString(hash[:])
 }
 
+// MatchOptions tunes how system transactions are paired with bank statements
+type MatchOptions struct {
+	AmountTolerance        float64 `json:"amount_tolerance"`         // absolute amount difference accepted
+	AmountTolerancePercent float64 `json:"amount_tolerance_percent"` // percentage of the expected amount accepted
+	DateWindowDays         int     `json:"date_window_days"`         // +/- business days around the transaction date
+	GroupMatching          bool    `json:"group_matching"`           // look for split/aggregate settlements among leftovers
+	MaxGroupSize           int     `json:"max_group_size"`           // upper bound of members on the many side of a group
+	AssignmentMode         string  `json:"assignment_mode"`          // GREEDY (default) or OPTIMAL
+	Currency               string  `json:"currency"`                 // reconciliation currency, money.DefaultCurrency when empty
+	ConvertCurrency        bool    `json:"convert_currency"`         // convert other currencies with the FX rate table instead of leaving them unmatched
+	IncludeHistorical      bool    `json:"include_historical"`       // also reconcile rows ingested before the workflow, not only its own files
+}
+
+// ReconciliationCurrency returns the currency amounts are compared in
+func (o MatchOptions) ReconciliationCurrency() string {
+	if o.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return o.Currency
+}
+
+// AllowedDiscrepancy returns the largest amount difference accepted for the expected amount,
+// taking the wider of the absolute and percentage tolerance.
+func (o MatchOptions) AllowedDiscrepancy(expectedAmount money.Money) money.Money {
+	allowed := money.FromFloat(o.AmountTolerance, expectedAmount.Currency)
+	if pct := expectedAmount.Abs().Percent(o.AmountTolerancePercent); pct.Cmp(allowed) > 0 {
+		allowed = pct
+	}
+	return allowed
+}
+
+// MatchRuleSet is the ordered list of matching passes applied to the statements of a bank
+type MatchRuleSet struct {
+	Name     string
+	BankCode string // empty for the rule set used by banks without their own
+	Passes   []MatchPassDefinition
+}
+
+// MatchPassDefinition pairs records only when all of its rules accept them
+type MatchPassDefinition struct {
+	Name  string
+	Rules []MatchRuleDefinition
+}
+
+// MatchRuleDefinition selects a rule by type, optional parameters override the workflow match options
+type MatchRuleDefinition struct {
+	Type                   string
+	AmountTolerance        *float64
+	AmountTolerancePercent *float64
+	DateWindowDays         *int
+}
+
 // ReconciliationJob for auditing
 type ReconciliationJob struct {
-	JobID     string
-	StartDate time.Time
-	EndDate   time.Time
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	JobID      string
+	WorkflowID string
+	StartDate  time.Time
+	EndDate    time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 // ReconciliationResult holds summary stats for a job
@@ -63,27 +143,53 @@ type ReconciliationResult struct {
 	MatchedCount         int
 	UnmatchedSystemCount int
 	UnmatchedBankCount   int
-	TotalDiscrepancies   float64
+	MatchedGroupCount    int
+	TotalDiscrepancies   money.Money
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
 }
 
-// MatchedRecord links 1 systemTx to 1 bankStatement for a job
+// MatchedRecord links 1 systemTx to 1 bankStatement for a job.
+// Both sides keep their original amount and the rate used to bring it into the reconciliation currency,
+// the discrepancy is expressed in that currency.
 type MatchedRecord struct {
-	ID              int
-	JobID           string
-	SystemTxID      int
-	BankStatementID int
-	Discrepancy     float64
-	MatchedAt       time.Time
+	ID                    int
+	JobID                 string
+	SystemTxID            int
+	BankStatementID       int
+	Discrepancy           money.Money
+	RuleSet               string // rule set and pass that produced the match
+	MatchPass             string
+	PassNumber            int
+	SystemAmount          money.Money
+	SystemFXRate          money.Rate // 1 when already in the reconciliation currency
+	SystemConvertedAmount money.Money
+	BankAmount            money.Money
+	BankFXRate            money.Rate
+	BankConvertedAmount   money.Money
+	MatchedBy             string // client id of the caller for a manual match, empty for automatic ones
+	MatchedAt             time.Time
+}
+
+// MatchGroup links several system transactions and bank statements settled together for a job
+type MatchGroup struct {
+	ID               int
+	JobID            string
+	GroupType        string // ONE_TO_MANY or MANY_TO_ONE
+	SystemTxIDs      []int
+	BankStatementIDs []int
+	Discrepancy      money.Money
+	MatchedBy        string // client id of the caller for a manual match, empty for automatic ones
+	MatchedAt        time.Time
 }
 
 // UnmatchedSystemTx and UnmatchedBankTx store unmatched items
 type UnmatchedSystemTx struct {
 	ID              int
 	JobID           string
+	SystemTxID      int // the system transaction left unmatched, 0 when it could not be traced back
 	TrxID           string
-	Amount          float64
+	Amount          money.Money
 	Type            string
 	TransactionTime time.Time
 	CreatedAt       time.Time
@@ -91,21 +197,62 @@ type UnmatchedSystemTx struct {
 }
 
 type UnmatchedBankTx struct {
-	ID            int
-	JobID         string
-	UniqueID      string
-	Amount        float64
-	StatementDate time.Time
-	BankCode      string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID              int
+	JobID           string
+	BankStatementID int // the bank statement left unmatched, 0 when it could not be traced back
+	UniqueID        string
+	Amount          money.Money
+	StatementDate   time.Time
+	BankCode        string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
+// ReconciliationSummary holds the counts and totals of a job, the rows behind them are listed page by page
 type ReconciliationSummary struct {
-	TotalTransactionsProcessed int                          `json:"total_transactions_processed"`
-	TotalMatchedTransactions   int                          `json:"total_matched_transactions"`
-	TotalUnmatchedTransactions int                          `json:"total_unmatched_transactions"`
-	UnmatchedSystemTx          []UnmatchedSystemTx          `json:"unmatched_system_transactions"`
-	UnmatchedBankTxByBank      map[string][]UnmatchedBankTx `json:"unmatched_bank_transactions_by_bank"`
-	TotalDiscrepancies         float64                      `json:"total_discrepancies"`
+	TotalTransactionsProcessed     int                  `json:"total_transactions_processed"`
+	TotalBankTransactions          int                  `json:"total_bank_transactions"`
+	TotalMatchedTransactions       int                  `json:"total_matched_transactions"`
+	TotalUnmatchedTransactions     int                  `json:"total_unmatched_transactions"`
+	TotalUnmatchedBankTransactions int                  `json:"total_unmatched_bank_transactions"`
+	UnmatchedBankByBank            []BankExceptionCount `json:"unmatched_bank_by_bank"`
+	TotalMatchedGroups             int                  `json:"total_matched_groups"`
+	TotalDiscrepancies             money.Money          `json:"total_discrepancies"`
+	ResolvedOpenItems              int                  `json:"resolved_open_items"` // items of earlier jobs matched by this one
+	TotalOpenItems                 int                  `json:"total_open_items"`    // items still outstanding up to the end of the period
+	OpenItemsAgeing                []AgeingBucket       `json:"open_items_ageing"`
+	BalanceRollforward             []BalanceRollforward `json:"balance_rollforward"`
+}
+
+const (
+	OpenItemSystemTx      = "SYSTEM_TX"
+	OpenItemBankStatement = "BANK_STATEMENT"
+
+	OpenItemOpen     = "OPEN"
+	OpenItemResolved = "RESOLVED"
+)
+
+// OpenItem is an unmatched record carried forward into later jobs until one of them matches it
+type OpenItem struct {
+	ID              int
+	ItemType        string // SYSTEM_TX or BANK_STATEMENT
+	SystemTxID      *int
+	BankStatementID *int
+	Reference       string // trx id or bank unique id
+	BankCode        string
+	Amount          money.Money
+	ItemDate        time.Time
+	Status          string // OPEN or RESOLVED
+	OriginJobID     string // job that first left the record unmatched
+	ResolvedJobID   *string
+	ResolvedAt      *time.Time
+	DaysOutstanding int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// AgeingBucket counts the open items outstanding for a range of days
+type AgeingBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
 }
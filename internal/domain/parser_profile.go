@@ -0,0 +1,50 @@
+package domain
+
+// Columns a parser profile maps
+const (
+	ColumnReference     = "reference" // trx_id of a system transaction, unique_id of a statement line
+	ColumnAmount        = "amount"
+	ColumnDebitAmount   = "debit_amount"
+	ColumnCreditAmount  = "credit_amount"
+	ColumnIndicator     = "indicator" // debit/credit indicator, the type of a system transaction
+	ColumnDate          = "date"
+	ColumnTime          = "time" // optional, for files with the time of day in a column of its own
+	ColumnCurrency      = "currency"
+	ColumnBankCode      = "bank_code"
+	ColumnAccountNumber = "account_number"
+)
+
+// How the amounts of a file are signed, credits are positive and debits negative once parsed
+const (
+	SignSigned      = "SIGNED"       // the amount column carries the sign
+	SignInverted    = "INVERTED"     // the amount column is signed the other way round
+	SignDebitCredit = "DEBIT_CREDIT" // debits and credits are in separate columns
+	SignIndicator   = "INDICATOR"    // the indicator column tells debits from credits
+)
+
+// ParserProfile declares the layout of a CSV or XLSX file so it can be ingested without a parser of its own
+type ParserProfile struct {
+	Name              string               `json:"name"`
+	Delimiter         string               `json:"delimiter,omitempty"`        // a comma by default
+	Sheet             string               `json:"sheet,omitempty"`            // sheet of XLSX files, the first one by default
+	SkipRows          int                  `json:"skip_rows,omitempty"`        // preamble rows above the header
+	SkipFooterRows    int                  `json:"skip_footer_rows,omitempty"` // rows below the data, such as totals
+	NoHeader          bool                 `json:"no_header,omitempty"`        // the first row is data, columns are then referenced by index
+	Columns           map[string]ColumnRef `json:"columns"`
+	DateFormats       []string             `json:"date_formats,omitempty"` // Go layouts tried in order
+	TimeFormat        string               `json:"time_format,omitempty"`  // layout of the time column
+	Timezone          string               `json:"timezone,omitempty"`     // IANA name of the zone of the dates, UTC by default
+	DecimalSeparator  string               `json:"decimal_separator,omitempty"`
+	ThousandSeparator string               `json:"thousand_separator,omitempty"`
+	SignConvention    string               `json:"sign_convention,omitempty"`
+	DebitIndicators   []string             `json:"debit_indicators,omitempty"`
+	CreditIndicators  []string             `json:"credit_indicators,omitempty"`
+	DefaultBankCode   string               `json:"default_bank_code,omitempty"` // for files without a bank_code column
+	DefaultCurrency   string               `json:"default_currency,omitempty"`  // for files without a currency column
+}
+
+// ColumnRef finds a column by the name in the header row, or by its zero-based index
+type ColumnRef struct {
+	Name  string `json:"name,omitempty"`
+	Index *int   `json:"index,omitempty"`
+}
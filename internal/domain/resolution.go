@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+const (
+	AuditManualMatch = "MANUAL_MATCH"
+	AuditUnmatch     = "UNMATCH"
+	AuditWriteOff    = "WRITE_OFF"
+	AuditPropose     = "PROPOSE"
+	AuditReject      = "REJECT"
+)
+
+// Statuses of a resolution proposal
+const (
+	ProposalPending  = "PENDING"
+	ProposalApproved = "APPROVED"
+	ProposalRejected = "REJECTED"
+)
+
+// ManualRuleSet is the rule set recorded on matches made by hand
+const ManualRuleSet = "MANUAL"
+
+// Reason codes accepted for a write-off
+const (
+	WriteOffBankFee          = "BANK_FEE"
+	WriteOffRounding         = "ROUNDING"
+	WriteOffTimingDifference = "TIMING_DIFFERENCE"
+	WriteOffDuplicate        = "DUPLICATE"
+	WriteOffImmaterial       = "IMMATERIAL"
+	WriteOffOther            = "OTHER"
+)
+
+// WriteOffReasonCodes lists the reason codes a write-off can be given
+var WriteOffReasonCodes = []string{
+	WriteOffBankFee, WriteOffRounding, WriteOffTimingDifference, WriteOffDuplicate, WriteOffImmaterial, WriteOffOther,
+}
+
+// ManualMatch links one unmatched system transaction of a job to one or more of its unmatched bank lines.
+// A single bank line is stored as a matched record, several as a ONE_TO_MANY match group.
+type ManualMatch struct {
+	JobID       string
+	SystemItem  UnmatchedSystemTx
+	BankItems   []UnmatchedBankTx
+	Discrepancy money.Money
+	Actor       string
+	Reason      string
+	Approval    *ProposalDecision // set when the match applies an approved proposal
+}
+
+// Unmatch breaks either a matched record (MatchID) or a match group (GroupID) of a job,
+// its records become exceptions again
+type Unmatch struct {
+	JobID   string
+	MatchID int
+	GroupID int
+	Actor   string
+	Reason  string
+}
+
+// WriteOff closes an unmatched item of a job without a counterpart
+type WriteOff struct {
+	ID              int
+	JobID           string
+	ItemType        string // OpenItemSystemTx or OpenItemBankStatement
+	ExceptionID     int    // id of the unmatched row
+	SystemTxID      *int
+	BankStatementID *int
+	Reference       string // trx id or bank unique id
+	Amount          money.Money
+	ItemDate        time.Time
+	ReasonCode      string
+	Note            string
+	WrittenOffBy    string
+	CreatedAt       time.Time
+	Approval        *ProposalDecision // set when the write-off applies an approved proposal
+}
+
+// ApprovalPolicy holds the absolute amount above which a manual match discrepancy or a write-off
+// has to be approved by a second client before it is applied, zero applies every change right away
+type ApprovalPolicy struct {
+	Threshold float64
+}
+
+// Proposal is a manual match or write-off waiting for, or given, the decision of a client other than the one proposing it
+type Proposal struct {
+	ID                int
+	JobID             string
+	Action            string // AuditManualMatch or AuditWriteOff
+	SystemExceptionID int    // manual match
+	BankExceptionIDs  []int  // manual match
+	ItemType          string // write-off
+	ExceptionID       int    // write-off
+	ReasonCode        string // write-off
+	Reason            string // reason of a manual match or note of a write-off
+	Amount            money.Money
+	Status            string
+	ProposedBy        string
+	DecidedBy         string
+	DecisionNote      string
+	CreatedAt         time.Time
+	DecidedAt         *time.Time
+}
+
+// ProposalDecision is the approval or rejection of a pending proposal
+type ProposalDecision struct {
+	ProposalID int
+	JobID      string
+	DecidedBy  string
+	Note       string
+}
+
+// ManualResolution is the outcome of a manual change: the audit entry recording it, the match or write-off it made
+// and the counts of the job recomputed afterwards. A change waiting for approval only has its Proposal set.
+type ManualResolution struct {
+	AuditID    int64
+	MatchID    int
+	GroupID    int
+	WriteOffID int
+	Result     ReconciliationResult
+	Proposal   *Proposal
+}
+
+// AuditEntry is one manual change to the match state of a job, entries are never changed once written
+type AuditEntry struct {
+	ID        int64
+	JobID     string
+	Action    string // MANUAL_MATCH, UNMATCH, WRITE_OFF, PROPOSE or REJECT
+	Actor     string
+	Reason    string
+	Details   map[string]any
+	CreatedAt time.Time
+}
@@ -2,14 +2,63 @@ package domain
 
 import "time"
 
+// Workflow moves PENDING -> INGESTING -> RECONCILING -> COMPLETED, or to FAILED or CANCELLED from any of them.
+// A finished workflow goes back to RECONCILING when it is re-run. Every status change is recorded as a WorkflowTransition.
 type Workflow struct {
 	WorkflowID           string
 	SystemIngestionJobID *string
-	BankIngestionJobID   *string
+	BankIngestionJobID   *string // first bank file, every ingestion job of the workflow carries its workflow id
 	ReconciliationJobID  *string
-	Status               string // e.g. "IN_PROGRESS", "COMPLETED", "FAILED"
+	Status               string // e.g. "PENDING", "INGESTING", "RECONCILING", "COMPLETED", "FAILED", "CANCELLED"
+	SystemFile           string // object keys of the uploaded files
+	BankFiles            []string
+	FileProfiles         map[string]string // parser profile of a file by its object key, files left out use the built-in parser
+	ErrorMessage         string
 	StartDate            time.Time
 	EndDate              time.Time
+	MatchOptions         MatchOptions
+	SourceWorkflowID     string // set on a clone, the workflow it was copied from
+	CreatedBy            string // client id of the caller that started the workflow
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
 }
+
+// WorkflowTransition is one status change of a workflow
+type WorkflowTransition struct {
+	ID         int64     `json:"-"`
+	WorkflowID string    `json:"-"`
+	FromStatus string    `json:"from_status,omitempty"` // empty when the workflow is created
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WorkflowFilter selects the workflows to list, zero fields do not filter
+type WorkflowFilter struct {
+	Statuses  []string
+	From      time.Time // workflows whose period overlaps From - To
+	To        time.Time
+	CreatedBy string
+	BankCode  string // workflows with statements of this bank
+	FileName  string // part of the system or a bank file name
+	After     *WorkflowCursor
+	Limit     int
+}
+
+// WorkflowCursor is the position of the last workflow of a page, workflows are listed newest first
+type WorkflowCursor struct {
+	CreatedAt  time.Time
+	WorkflowID string
+}
+
+// WorkflowListItem is a workflow with the counts of its latest reconciliation, Result is nil until one completed
+type WorkflowListItem struct {
+	Workflow
+	Result *ReconciliationResult
+}
+
+// WorkflowPage is one page of listed workflows, Next is nil on the last page
+type WorkflowPage struct {
+	Items []WorkflowListItem
+	Next  *WorkflowCursor
+}
@@ -6,6 +6,7 @@ package mock_infrastructure
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -49,3 +50,33 @@ func (mr *MockIMinioClientMockRecorder) GetObject(ctx, objectName, opts interfac
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObject", reflect.TypeOf((*MockIMinioClient)(nil).GetObject), ctx, objectName, opts)
 }
+
+// PutObject mocks base method.
+func (m *MockIMinioClient) PutObject(ctx context.Context, objectName string, reader io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutObject", ctx, objectName, reader, size, opts)
+	ret0, _ := ret[0].(minio.UploadInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutObject indicates an expected call of PutObject.
+func (mr *MockIMinioClientMockRecorder) PutObject(ctx, objectName, reader, size, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutObject", reflect.TypeOf((*MockIMinioClient)(nil).PutObject), ctx, objectName, reader, size, opts)
+}
+
+// StatObject mocks base method.
+func (m *MockIMinioClient) StatObject(ctx context.Context, objectName string) (*minio.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StatObject", ctx, objectName)
+	ret0, _ := ret[0].(*minio.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StatObject indicates an expected call of StatObject.
+func (mr *MockIMinioClientMockRecorder) StatObject(ctx, objectName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StatObject", reflect.TypeOf((*MockIMinioClient)(nil).StatObject), ctx, objectName)
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
 )
 
 //go:generate mockgen -source=minio.go -destination=_mock/minio.go
 type IMinioClient interface {
 	GetObject(ctx context.Context, objectName string, opts minio.GetObjectOptions) (*minio.Object, error)
 	StatObject(ctx context.Context, objectName string) (*minio.ObjectInfo, error)
+	PutObject(ctx context.Context, objectName string, reader io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error)
 }
 
 type MinioClient struct {
@@ -41,3 +43,12 @@ func (m *MinioClient) StatObject(ctx context.Context, objectName string) (*minio
 	}
 	return &objInfo, nil
 }
+
+// PutObject uploads reader into the bucket, a size of -1 uploads a stream of unknown length in parts
+func (m *MinioClient) PutObject(ctx context.Context, objectName string, reader io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	info, err := m.Client.PutObject(ctx, m.Bucket, objectName, reader, size, opts)
+	if err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("failed to put object: %w", err)
+	}
+	return info, nil
+}
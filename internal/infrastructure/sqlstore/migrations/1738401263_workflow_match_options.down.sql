@@ -0,0 +1 @@
+ALTER TABLE reconciliation_workflows DROP COLUMN IF EXISTS match_options;
@@ -0,0 +1,3 @@
+-- matching tolerances requested for the workflow, e.g. {"amount_tolerance": 0.5, "date_window_days": 1}
+ALTER TABLE reconciliation_workflows
+    ADD COLUMN IF NOT EXISTS match_options JSONB NOT NULL DEFAULT '{}'::jsonb;
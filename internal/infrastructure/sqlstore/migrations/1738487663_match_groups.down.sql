@@ -0,0 +1,3 @@
+ALTER TABLE reconciliation_results DROP COLUMN IF EXISTS matched_group_count;
+DROP TABLE IF EXISTS reconciliation_match_group_members;
+DROP TABLE IF EXISTS reconciliation_match_groups;
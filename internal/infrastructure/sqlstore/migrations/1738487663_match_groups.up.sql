@@ -0,0 +1,26 @@
+-- reconciliation_match_groups: split/aggregate settlements (one-to-many and many-to-one) for each job
+CREATE TABLE IF NOT EXISTS reconciliation_match_groups (
+    id SERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES reconciliation_jobs(job_id),
+    group_type TEXT NOT NULL,                     -- "ONE_TO_MANY" or "MANY_TO_ONE"
+    discrepancy DECIMAL(18, 2) NOT NULL,          -- absolute difference between both sides of the group
+    matched_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- reconciliation_match_group_members: one row per system transaction or bank statement in a group
+CREATE TABLE IF NOT EXISTS reconciliation_match_group_members (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES reconciliation_match_groups(id),
+    job_id UUID NOT NULL REFERENCES reconciliation_jobs(job_id),
+    system_tx_id INT REFERENCES system_transactions(id),
+    bank_statement_id INT REFERENCES bank_statements(id),
+
+    CONSTRAINT one_side_per_member CHECK ((system_tx_id IS NULL) <> (bank_statement_id IS NULL)),
+    CONSTRAINT unique_group_system_tx_per_job UNIQUE (job_id, system_tx_id),
+    CONSTRAINT unique_group_statement_per_job UNIQUE (job_id, bank_statement_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_group_members_group_id ON reconciliation_match_group_members (group_id);
+
+ALTER TABLE reconciliation_results
+    ADD COLUMN IF NOT EXISTS matched_group_count INT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE reconciliation_matched_records
+    DROP COLUMN IF EXISTS rule_set,
+    DROP COLUMN IF EXISTS match_pass,
+    DROP COLUMN IF EXISTS pass_number;
@@ -0,0 +1,5 @@
+-- which rule set and pass produced each match, for the audit trail
+ALTER TABLE reconciliation_matched_records
+    ADD COLUMN IF NOT EXISTS rule_set TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS match_pass TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS pass_number INT NOT NULL DEFAULT 0;
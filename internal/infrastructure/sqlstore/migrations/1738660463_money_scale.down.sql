@@ -0,0 +1,7 @@
+ALTER TABLE system_transactions ALTER COLUMN amount TYPE DECIMAL(18, 2);
+ALTER TABLE bank_statements ALTER COLUMN amount TYPE DECIMAL(18, 2);
+ALTER TABLE reconciliation_results ALTER COLUMN total_discrepancies TYPE DECIMAL(18, 2);
+ALTER TABLE reconciliation_matched_records ALTER COLUMN discrepancy TYPE DECIMAL(18, 2);
+ALTER TABLE reconciliation_match_groups ALTER COLUMN discrepancy TYPE DECIMAL(18, 2);
+ALTER TABLE reconciliation_unmatched_system_tx ALTER COLUMN amount TYPE DECIMAL(18, 2);
+ALTER TABLE reconciliation_unmatched_bank_tx ALTER COLUMN amount TYPE DECIMAL(18, 2);
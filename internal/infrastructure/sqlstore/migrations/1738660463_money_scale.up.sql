@@ -0,0 +1,8 @@
+-- amounts are stored exactly in the minor unit of their currency, up to 3 decimals (e.g. KWD, BHD)
+ALTER TABLE system_transactions ALTER COLUMN amount TYPE DECIMAL(20, 3);
+ALTER TABLE bank_statements ALTER COLUMN amount TYPE DECIMAL(20, 3);
+ALTER TABLE reconciliation_results ALTER COLUMN total_discrepancies TYPE DECIMAL(20, 3);
+ALTER TABLE reconciliation_matched_records ALTER COLUMN discrepancy TYPE DECIMAL(20, 3);
+ALTER TABLE reconciliation_match_groups ALTER COLUMN discrepancy TYPE DECIMAL(20, 3);
+ALTER TABLE reconciliation_unmatched_system_tx ALTER COLUMN amount TYPE DECIMAL(20, 3);
+ALTER TABLE reconciliation_unmatched_bank_tx ALTER COLUMN amount TYPE DECIMAL(20, 3);
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS fx_rates;
+
+ALTER TABLE reconciliation_matched_records
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS system_amount,
+    DROP COLUMN IF EXISTS system_currency,
+    DROP COLUMN IF EXISTS system_fx_rate,
+    DROP COLUMN IF EXISTS system_converted_amount,
+    DROP COLUMN IF EXISTS bank_amount,
+    DROP COLUMN IF EXISTS bank_currency,
+    DROP COLUMN IF EXISTS bank_fx_rate,
+    DROP COLUMN IF EXISTS bank_converted_amount;
+
+ALTER TABLE reconciliation_match_groups DROP COLUMN IF EXISTS currency;
+ALTER TABLE reconciliation_results DROP COLUMN IF EXISTS currency;
+ALTER TABLE reconciliation_unmatched_bank_tx DROP COLUMN IF EXISTS currency;
+ALTER TABLE reconciliation_unmatched_system_tx DROP COLUMN IF EXISTS currency;
+ALTER TABLE bank_statements DROP COLUMN IF EXISTS currency;
+ALTER TABLE system_transactions DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,31 @@
+-- ISO 4217 currency of every amount, rows loaded before currencies existed are IDR
+ALTER TABLE system_transactions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE bank_statements ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE reconciliation_unmatched_system_tx ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE reconciliation_unmatched_bank_tx ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+-- discrepancies of a job are expressed in its reconciliation currency
+ALTER TABLE reconciliation_results ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE reconciliation_match_groups ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- original and converted amounts of both sides of a match, with the rate applied to each
+ALTER TABLE reconciliation_matched_records
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    ADD COLUMN IF NOT EXISTS system_amount DECIMAL(20, 3),
+    ADD COLUMN IF NOT EXISTS system_currency CHAR(3),
+    ADD COLUMN IF NOT EXISTS system_fx_rate DECIMAL(30, 10),
+    ADD COLUMN IF NOT EXISTS system_converted_amount DECIMAL(20, 3),
+    ADD COLUMN IF NOT EXISTS bank_amount DECIMAL(20, 3),
+    ADD COLUMN IF NOT EXISTS bank_currency CHAR(3),
+    ADD COLUMN IF NOT EXISTS bank_fx_rate DECIMAL(30, 10),
+    ADD COLUMN IF NOT EXISTS bank_converted_amount DECIMAL(20, 3);
+
+-- fx_rates: daily rates, one unit of base_currency buys rate units of quote_currency
+CREATE TABLE IF NOT EXISTS fx_rates (
+    rate_date DATE NOT NULL,
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate DECIMAL(30, 10) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (rate_date, base_currency, quote_currency)
+);
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS workflow_bank_statement_links;
+DROP TABLE IF EXISTS workflow_system_tx_links;
+
+DROP INDEX IF EXISTS idx_bank_statements_workflow;
+DROP INDEX IF EXISTS idx_system_transactions_workflow;
+
+ALTER TABLE bank_statements
+    DROP COLUMN IF EXISTS workflow_id,
+    DROP COLUMN IF EXISTS ingestion_job_id;
+ALTER TABLE system_transactions
+    DROP COLUMN IF EXISTS workflow_id,
+    DROP COLUMN IF EXISTS ingestion_job_id;
+
+ALTER TABLE reconciliation_jobs DROP COLUMN IF EXISTS workflow_id;
+ALTER TABLE ingestion_jobs DROP COLUMN IF EXISTS workflow_id;
@@ -0,0 +1,32 @@
+-- every ingestion job, ingested row and reconciliation job belongs to the workflow that produced it,
+-- rows ingested before workflows were tracked keep NULL
+ALTER TABLE ingestion_jobs ADD COLUMN IF NOT EXISTS workflow_id UUID REFERENCES reconciliation_workflows(workflow_id);
+ALTER TABLE reconciliation_jobs ADD COLUMN IF NOT EXISTS workflow_id UUID REFERENCES reconciliation_workflows(workflow_id);
+
+ALTER TABLE system_transactions
+    ADD COLUMN IF NOT EXISTS ingestion_job_id UUID REFERENCES ingestion_jobs(job_id),
+    ADD COLUMN IF NOT EXISTS workflow_id UUID REFERENCES reconciliation_workflows(workflow_id);
+ALTER TABLE bank_statements
+    ADD COLUMN IF NOT EXISTS ingestion_job_id UUID REFERENCES ingestion_jobs(job_id),
+    ADD COLUMN IF NOT EXISTS workflow_id UUID REFERENCES reconciliation_workflows(workflow_id);
+
+CREATE INDEX IF NOT EXISTS idx_system_transactions_workflow ON system_transactions (workflow_id, transaction_time);
+CREATE INDEX IF NOT EXISTS idx_bank_statements_workflow ON bank_statements (workflow_id, statement_time);
+
+-- a row deduplicated on ingestion keeps the workflow that first loaded it,
+-- the later workflows that uploaded it again are linked here
+CREATE TABLE IF NOT EXISTS workflow_system_tx_links (
+    workflow_id UUID NOT NULL REFERENCES reconciliation_workflows(workflow_id),
+    ingestion_job_id UUID NOT NULL REFERENCES ingestion_jobs(job_id),
+    system_tx_id INT NOT NULL REFERENCES system_transactions(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workflow_id, system_tx_id)
+);
+
+CREATE TABLE IF NOT EXISTS workflow_bank_statement_links (
+    workflow_id UUID NOT NULL REFERENCES reconciliation_workflows(workflow_id),
+    ingestion_job_id UUID NOT NULL REFERENCES ingestion_jobs(job_id),
+    bank_statement_id INT NOT NULL REFERENCES bank_statements(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workflow_id, bank_statement_id)
+);
@@ -0,0 +1,23 @@
+-- reconciliation_open_items: unmatched records carried forward into later jobs until one of them matches the record
+CREATE TABLE IF NOT EXISTS reconciliation_open_items (
+    id SERIAL PRIMARY KEY,
+    item_type TEXT NOT NULL,                      -- "SYSTEM_TX" or "BANK_STATEMENT"
+    system_tx_id INT REFERENCES system_transactions(id),
+    bank_statement_id INT REFERENCES bank_statements(id),
+    amount DECIMAL(20, 3) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    item_date TIMESTAMP NOT NULL,                 -- transaction or statement date, ageing starts here
+    status TEXT NOT NULL DEFAULT 'OPEN',          -- "OPEN" or "RESOLVED"
+    origin_job_id UUID NOT NULL REFERENCES reconciliation_jobs(job_id),
+    resolved_job_id UUID REFERENCES reconciliation_jobs(job_id),
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT open_item_one_record CHECK ((system_tx_id IS NULL) <> (bank_statement_id IS NULL))
+);
+
+-- a record is outstanding at most once
+CREATE UNIQUE INDEX IF NOT EXISTS uq_open_items_system_tx ON reconciliation_open_items (system_tx_id) WHERE status = 'OPEN';
+CREATE UNIQUE INDEX IF NOT EXISTS uq_open_items_bank_statement ON reconciliation_open_items (bank_statement_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_open_items_status_date ON reconciliation_open_items (status, item_date);
+CREATE INDEX IF NOT EXISTS idx_open_items_resolved_job ON reconciliation_open_items (resolved_job_id);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS workflow_transitions;
+
+DROP INDEX IF EXISTS idx_reconciliation_workflows_status;
+
+ALTER TABLE reconciliation_workflows
+    DROP COLUMN IF EXISTS error_message,
+    DROP COLUMN IF EXISTS bank_files,
+    DROP COLUMN IF EXISTS system_file;
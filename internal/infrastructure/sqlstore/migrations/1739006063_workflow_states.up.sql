@@ -0,0 +1,24 @@
+-- workflows are advanced by the worker, the uploaded files are kept so an interrupted workflow can be resumed
+ALTER TABLE reconciliation_workflows
+    ADD COLUMN IF NOT EXISTS system_file TEXT,
+    ADD COLUMN IF NOT EXISTS bank_files TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS error_message TEXT;
+
+-- workflows started in-process before this migration cannot be resumed
+UPDATE reconciliation_workflows
+SET status = 'FAILED', error_message = 'interrupted before workflows were resumable', updated_at = NOW()
+WHERE status = 'IN_PROGRESS';
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_workflows_status ON reconciliation_workflows (status, created_at);
+
+-- every status change of a workflow, e.g. PENDING -> INGESTING -> RECONCILING -> COMPLETED
+CREATE TABLE IF NOT EXISTS workflow_transitions (
+    id BIGSERIAL PRIMARY KEY,
+    workflow_id UUID NOT NULL REFERENCES reconciliation_workflows(workflow_id),
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_transitions_workflow ON workflow_transitions (workflow_id, id);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_ingestion_jobs_lease;
+DROP INDEX IF EXISTS idx_ingestion_jobs_status;
+
+ALTER TABLE reconciliation_workflows
+    DROP COLUMN IF EXISTS lease_expires_at,
+    DROP COLUMN IF EXISTS worker_id;
+
+ALTER TABLE ingestion_jobs
+    DROP COLUMN IF EXISTS heartbeat_at,
+    DROP COLUMN IF EXISTS lease_expires_at,
+    DROP COLUMN IF EXISTS worker_id;
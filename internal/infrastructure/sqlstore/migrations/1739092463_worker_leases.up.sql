@@ -0,0 +1,16 @@
+-- a worker claims a job or workflow for a lease it keeps extending with heartbeats,
+-- an expired lease means the worker is gone and another one may take over
+ALTER TABLE ingestion_jobs
+    ADD COLUMN IF NOT EXISTS worker_id TEXT,
+    ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP;
+
+ALTER TABLE reconciliation_workflows
+    ADD COLUMN IF NOT EXISTS worker_id TEXT,
+    ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP;
+
+-- jobs claimed before leases existed are handed to the reaper straight away
+UPDATE ingestion_jobs SET lease_expires_at = NOW() WHERE status = 'IN_PROGRESS';
+
+CREATE INDEX IF NOT EXISTS idx_ingestion_jobs_status ON ingestion_jobs (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_ingestion_jobs_lease ON ingestion_jobs (lease_expires_at) WHERE status = 'IN_PROGRESS';
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_ingestion_jobs_dead_letter;
+DROP INDEX IF EXISTS idx_ingestion_jobs_pending;
+CREATE INDEX IF NOT EXISTS idx_ingestion_jobs_status ON ingestion_jobs (status, created_at);
+
+ALTER TABLE ingestion_jobs
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS next_attempt_at,
+    DROP COLUMN IF EXISTS attempts;
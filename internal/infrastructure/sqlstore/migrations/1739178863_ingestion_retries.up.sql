@@ -0,0 +1,10 @@
+-- a failed ingestion job is retried with exponential backoff until it runs out of attempts,
+-- then it is parked as DEAD_LETTER with its last error until it is requeued by hand
+ALTER TABLE ingestion_jobs
+    ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+DROP INDEX IF EXISTS idx_ingestion_jobs_status;
+CREATE INDEX IF NOT EXISTS idx_ingestion_jobs_pending ON ingestion_jobs (next_attempt_at, created_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_ingestion_jobs_dead_letter ON ingestion_jobs (updated_at) WHERE status = 'DEAD_LETTER';
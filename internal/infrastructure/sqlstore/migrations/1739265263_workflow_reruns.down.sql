@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_reconciliation_jobs_workflow;
+
+ALTER TABLE reconciliation_workflows
+    DROP COLUMN IF EXISTS source_workflow_id;
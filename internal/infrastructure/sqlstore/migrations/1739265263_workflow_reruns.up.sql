@@ -0,0 +1,6 @@
+-- a cloned workflow remembers the workflow it was copied from
+ALTER TABLE reconciliation_workflows
+    ADD COLUMN IF NOT EXISTS source_workflow_id UUID REFERENCES reconciliation_workflows(workflow_id);
+
+-- every run of a workflow keeps its reconciliation job, the latest one is the workflow's reconciliation_job_id
+CREATE INDEX IF NOT EXISTS idx_reconciliation_jobs_workflow ON reconciliation_jobs (workflow_id, created_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_bank_statements_workflow_bank;
+DROP INDEX IF EXISTS idx_reconciliation_workflows_created_by;
+DROP INDEX IF EXISTS idx_reconciliation_workflows_listing;
+
+ALTER TABLE reconciliation_workflows
+    DROP COLUMN IF EXISTS created_by;
@@ -0,0 +1,8 @@
+-- the client that started the workflow, taken from its basic auth credentials
+ALTER TABLE reconciliation_workflows
+    ADD COLUMN IF NOT EXISTS created_by TEXT;
+
+-- workflows are listed newest first, the cursor is (created_at, workflow_id)
+CREATE INDEX IF NOT EXISTS idx_reconciliation_workflows_listing ON reconciliation_workflows (created_at DESC, workflow_id DESC);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_workflows_created_by ON reconciliation_workflows (created_by, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_bank_statements_workflow_bank ON bank_statements (workflow_id, bank_code);
@@ -0,0 +1,13 @@
+DROP TRIGGER IF EXISTS reconciliation_audit_log_append_only ON reconciliation_audit_log;
+DROP FUNCTION IF EXISTS reject_audit_log_change();
+DROP TABLE IF EXISTS reconciliation_audit_log;
+DROP TABLE IF EXISTS reconciliation_write_offs;
+
+ALTER TABLE reconciliation_match_groups DROP COLUMN IF EXISTS matched_by;
+ALTER TABLE reconciliation_matched_records DROP COLUMN IF EXISTS matched_by;
+
+DROP INDEX IF EXISTS idx_unmatched_bank_tx_job;
+DROP INDEX IF EXISTS idx_unmatched_system_tx_job;
+
+ALTER TABLE reconciliation_unmatched_bank_tx DROP COLUMN IF EXISTS bank_statement_id;
+ALTER TABLE reconciliation_unmatched_system_tx DROP COLUMN IF EXISTS system_tx_id;
@@ -0,0 +1,70 @@
+-- unmatched rows keep the record they stand for, so they can be matched or written off by hand
+ALTER TABLE reconciliation_unmatched_system_tx
+    ADD COLUMN IF NOT EXISTS system_tx_id INT REFERENCES system_transactions(id);
+ALTER TABLE reconciliation_unmatched_bank_tx
+    ADD COLUMN IF NOT EXISTS bank_statement_id INT REFERENCES bank_statements(id);
+
+UPDATE reconciliation_unmatched_system_tx u
+SET system_tx_id = t.id
+FROM system_transactions t
+WHERE u.system_tx_id IS NULL AND t.trx_id = u.trx_id;
+
+UPDATE reconciliation_unmatched_bank_tx u
+SET bank_statement_id = (
+    SELECT MIN(b.id) FROM bank_statements b
+    WHERE b.unique_id = u.unique_id AND b.bank_code = u.bank_code AND b.currency = u.currency
+      AND b.amount = u.amount AND b.statement_time = u.statement_time
+)
+WHERE u.bank_statement_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_unmatched_system_tx_job ON reconciliation_unmatched_system_tx (job_id, system_tx_id);
+CREATE INDEX IF NOT EXISTS idx_unmatched_bank_tx_job ON reconciliation_unmatched_bank_tx (job_id, bank_statement_id);
+
+-- client id of the caller that matched the records by hand, NULL for automatic matches
+ALTER TABLE reconciliation_matched_records ADD COLUMN IF NOT EXISTS matched_by TEXT;
+ALTER TABLE reconciliation_match_groups ADD COLUMN IF NOT EXISTS matched_by TEXT;
+
+-- unmatched records closed without a counterpart
+CREATE TABLE IF NOT EXISTS reconciliation_write_offs (
+    id SERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES reconciliation_jobs(job_id),
+    item_type TEXT NOT NULL,                      -- "SYSTEM_TX" or "BANK_STATEMENT"
+    system_tx_id INT REFERENCES system_transactions(id),
+    bank_statement_id INT REFERENCES bank_statements(id),
+    reference TEXT NOT NULL,                      -- trx id or bank unique id
+    amount DECIMAL(20, 3) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    item_date TIMESTAMP NOT NULL,
+    reason_code TEXT NOT NULL,
+    note TEXT,
+    written_off_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT write_off_one_record CHECK ((system_tx_id IS NULL) <> (bank_statement_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_write_offs_job ON reconciliation_write_offs (job_id, id);
+
+-- every manual change to the match state of a job: who did what and why
+CREATE TABLE IF NOT EXISTS reconciliation_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES reconciliation_jobs(job_id),
+    action TEXT NOT NULL,                         -- "MANUAL_MATCH", "UNMATCH" or "WRITE_OFF"
+    actor TEXT NOT NULL,                          -- client id of the caller
+    reason TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}'::jsonb,   -- records and amounts the action touched
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_audit_log_job ON reconciliation_audit_log (job_id, id);
+
+-- the audit log is append-only
+CREATE OR REPLACE FUNCTION reject_audit_log_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'reconciliation_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS reconciliation_audit_log_append_only ON reconciliation_audit_log;
+CREATE TRIGGER reconciliation_audit_log_append_only
+    BEFORE UPDATE OR DELETE ON reconciliation_audit_log
+    FOR EACH ROW EXECUTE FUNCTION reject_audit_log_change();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS exception_case_attachments;
+DROP TABLE IF EXISTS exception_case_comments;
+DROP TABLE IF EXISTS exception_cases;
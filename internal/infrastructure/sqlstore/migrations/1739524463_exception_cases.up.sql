@@ -0,0 +1,55 @@
+-- every unmatched record of a job is a case tracked until it is matched or written off
+CREATE TABLE IF NOT EXISTS exception_cases (
+    id SERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES reconciliation_jobs(job_id),
+    item_type TEXT NOT NULL,                      -- "SYSTEM_TX" or "BANK_STATEMENT"
+    record_id INT,                                -- system_transactions.id or bank_statements.id, NULL when it cannot be traced back
+    reference TEXT NOT NULL,                      -- trx id or bank unique id
+    bank_code TEXT,
+    amount DECIMAL(20, 3) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    item_date TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'OPEN',          -- "OPEN", "INVESTIGATING", "RESOLVED" or "WRITTEN_OFF"
+    assignee TEXT,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exception_cases_record ON exception_cases (job_id, item_type, record_id);
+CREATE INDEX IF NOT EXISTS idx_exception_cases_open_record ON exception_cases (item_type, record_id) WHERE status IN ('OPEN', 'INVESTIGATING');
+
+CREATE TABLE IF NOT EXISTS exception_case_comments (
+    id SERIAL PRIMARY KEY,
+    case_id INT NOT NULL REFERENCES exception_cases(id),
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_exception_case_comments_case ON exception_case_comments (case_id, id);
+
+-- the files themselves are kept in the storage bucket
+CREATE TABLE IF NOT EXISTS exception_case_attachments (
+    id SERIAL PRIMARY KEY,
+    case_id INT NOT NULL REFERENCES exception_cases(id),
+    file_name TEXT NOT NULL,
+    object_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    uploaded_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_exception_case_attachments_case ON exception_case_attachments (case_id, id);
+
+-- open a case for every exception stored before cases existed
+INSERT INTO exception_cases (job_id, item_type, record_id, reference, amount, currency, item_date, status, created_at, updated_at)
+SELECT job_id, 'SYSTEM_TX', system_tx_id, trx_id, amount, currency, transaction_time, 'OPEN', created_at, NOW()
+FROM reconciliation_unmatched_system_tx
+ON CONFLICT DO NOTHING;
+
+INSERT INTO exception_cases (job_id, item_type, record_id, reference, bank_code, amount, currency, item_date, status, created_at, updated_at)
+SELECT job_id, 'BANK_STATEMENT', bank_statement_id, unique_id, bank_code, amount, currency, statement_time, 'OPEN', created_at, NOW()
+FROM reconciliation_unmatched_bank_tx
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_resolution_proposals_pending;
+DROP INDEX IF EXISTS idx_resolution_proposals_job;
+DROP TABLE IF EXISTS resolution_proposals;
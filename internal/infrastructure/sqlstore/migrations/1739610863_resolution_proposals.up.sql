@@ -0,0 +1,24 @@
+-- manual matches and write-offs over the approval threshold wait here until a second client approves or rejects them
+CREATE TABLE IF NOT EXISTS resolution_proposals (
+    id SERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES reconciliation_jobs(job_id),
+    action TEXT NOT NULL,                         -- "MANUAL_MATCH" or "WRITE_OFF"
+    system_exception_id INT,                      -- manual match: unmatched system row
+    bank_exception_ids INT[],                     -- manual match: unmatched bank rows
+    item_type TEXT,                               -- write-off: "SYSTEM_TX" or "BANK_STATEMENT"
+    exception_id INT,                             -- write-off: unmatched row
+    reason_code TEXT,                             -- write-off reason code
+    reason TEXT NOT NULL,                         -- manual match reason or write-off note
+    amount DECIMAL(20, 3) NOT NULL,               -- discrepancy of the match or amount written off
+    currency CHAR(3) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING',       -- "PENDING", "APPROVED" or "REJECTED"
+    proposed_by TEXT NOT NULL,
+    decided_by TEXT,
+    decision_note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    decided_at TIMESTAMP,
+    CONSTRAINT proposal_decided_by_another CHECK (decided_by IS NULL OR decided_by <> proposed_by)
+);
+
+CREATE INDEX IF NOT EXISTS idx_resolution_proposals_job ON resolution_proposals (job_id, id);
+CREATE INDEX IF NOT EXISTS idx_resolution_proposals_pending ON resolution_proposals (job_id) WHERE status = 'PENDING';
@@ -0,0 +1,27 @@
+-- adjusting journal entries booking the discrepancies and write-offs of a job, regenerated as a whole
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id SERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES reconciliation_jobs(job_id),
+    source_type TEXT NOT NULL,                    -- "MATCH", "MATCH_GROUP" or "WRITE_OFF"
+    source_id INT NOT NULL,                       -- matched record, match group or write-off id
+    bank_code TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,                         -- "DISCREPANCY" or the write-off reason code
+    reference TEXT NOT NULL,
+    description TEXT NOT NULL,
+    entry_date DATE NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_journal_entry_source UNIQUE (job_id, source_type, source_id)
+);
+
+-- one debit or credit line of an entry, the lines of an entry balance
+CREATE TABLE IF NOT EXISTS journal_entry_lines (
+    id SERIAL PRIMARY KEY,
+    entry_id INT NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    line_no INT NOT NULL,
+    account TEXT NOT NULL,
+    debit DECIMAL(20, 3) NOT NULL DEFAULT 0,
+    credit DECIMAL(20, 3) NOT NULL DEFAULT 0,
+    CONSTRAINT journal_line_one_side CHECK ((debit = 0) <> (credit = 0)),
+    CONSTRAINT unique_journal_line UNIQUE (entry_id, line_no)
+);
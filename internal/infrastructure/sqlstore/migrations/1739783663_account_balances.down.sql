@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bank_account_balances;
+ALTER TABLE bank_statements DROP COLUMN IF EXISTS account_number;
@@ -0,0 +1,18 @@
+-- account of the bank a statement line was booked on, empty for files that do not name it
+ALTER TABLE bank_statements ADD COLUMN IF NOT EXISTS account_number TEXT NOT NULL DEFAULT '';
+
+-- opening and closing balances reported by the bank for an account, the latest file reporting a balance wins
+CREATE TABLE IF NOT EXISTS bank_account_balances (
+    id SERIAL PRIMARY KEY,
+    bank_code TEXT NOT NULL,
+    account_number TEXT NOT NULL DEFAULT '',
+    currency CHAR(3) NOT NULL,
+    balance_type TEXT NOT NULL,                   -- "OPENING" or "CLOSING"
+    balance_date DATE NOT NULL,
+    amount DECIMAL(20, 3) NOT NULL,
+    ingestion_job_id UUID REFERENCES ingestion_jobs(job_id),
+    workflow_id UUID REFERENCES reconciliation_workflows(workflow_id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_account_balance UNIQUE (bank_code, account_number, currency, balance_type, balance_date)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE ingestion_jobs DROP COLUMN IF EXISTS parser_profile;
+ALTER TABLE reconciliation_workflows DROP COLUMN IF EXISTS file_profiles;
+DROP TABLE IF EXISTS parser_profiles;
@@ -0,0 +1,11 @@
+-- column mappings of the CSV layouts not covered by the built-in parsers, profiles set in the configuration take precedence
+CREATE TABLE IF NOT EXISTS parser_profiles (
+    name TEXT PRIMARY KEY,
+    definition JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- parser profile of every file of a workflow keyed by its object key, files left out use the built-in parser
+ALTER TABLE reconciliation_workflows ADD COLUMN IF NOT EXISTS file_profiles JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE ingestion_jobs ADD COLUMN IF NOT EXISTS parser_profile TEXT;
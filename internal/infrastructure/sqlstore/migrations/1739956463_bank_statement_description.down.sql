@@ -0,0 +1 @@
+ALTER TABLE bank_statements DROP COLUMN IF EXISTS description;
@@ -0,0 +1,2 @@
+-- narrative the bank gives a statement line, e.g. the :86: field of MT940 files
+ALTER TABLE bank_statements ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
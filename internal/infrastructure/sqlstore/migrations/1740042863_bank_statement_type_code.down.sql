@@ -0,0 +1,2 @@
+ALTER TABLE bank_statements DROP COLUMN IF EXISTS bank_reference;
+ALTER TABLE bank_statements DROP COLUMN IF EXISTS type_code;
@@ -0,0 +1,3 @@
+-- transaction type code and bank reference the statement file gave a line
+ALTER TABLE bank_statements ADD COLUMN IF NOT EXISTS type_code TEXT NOT NULL DEFAULT '';
+ALTER TABLE bank_statements ADD COLUMN IF NOT EXISTS bank_reference TEXT NOT NULL DEFAULT '';
@@ -65,8 +65,16 @@ func NewSQLStore(ctx context.Context, dbConfigMaster db.Config) (Store, error) {
 	}, nil
 }
 
+// BeginTx starts a transaction, within the transaction of ctx it starts a nested one on a savepoint
+// so the work joins the outer transaction and is only kept once that one commits
 func (s *SQLStore) BeginTx(ctx context.Context) context.Context {
-	tx, err := s.Master.Begin(ctx)
+	var tx pgx.Tx
+	var err error
+	if outer, ok := ctx.Value(Tx).(pgx.Tx); ok {
+		tx, err = outer.Begin(ctx)
+	} else {
+		tx, err = s.Master.Begin(ctx)
+	}
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to begin transaction", logger.ErrAttr(err))
 	}
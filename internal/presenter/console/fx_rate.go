@@ -0,0 +1,39 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/config"
+	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/fxrate"
+	"gopkg.in/ukautz/clif.v1"
+	"os"
+)
+
+type FXRateConsole struct{}
+
+func (c *FXRateConsole) LoadRates() *clif.Command {
+	return clif.NewCommand("fxrate:load", "Load daily FX rates from a CSV file (rate_date,base_currency,quote_currency,rate).", func(o *clif.Command, in clif.Input, out clif.Output) error {
+		ctx := context.Background()
+		file, err := os.Open(o.Argument("file").String())
+		if err != nil {
+			return fmt.Errorf("failed to open rate file: %w", err)
+		}
+		defer file.Close()
+
+		infra, err := infrastructure.NewInfra(ctx, *config.Get())
+		if err != nil {
+			return err
+		}
+		fxRateUC := fxrate.NewFXRateUseCase(repository.NewFXRateRepo(infra.SQLStore()))
+
+		loaded, err := fxRateUC.LoadRatesCSV(ctx, file)
+		if err != nil {
+			return err
+		}
+		out.Printf("Loaded %d fx rates.\n", loaded)
+
+		return nil
+	}).NewArgument("file", "Path of the CSV file", "", true, false)
+}
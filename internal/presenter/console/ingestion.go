@@ -0,0 +1,36 @@
+package console
+
+import (
+	"context"
+	"github.com/ardianferdianto/reconciliation-service/config"
+	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/ingestion"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/workflow"
+	"gopkg.in/ukautz/clif.v1"
+)
+
+type IngestionConsole struct{}
+
+func (c *IngestionConsole) RequeueJob() *clif.Command {
+	return clif.NewCommand("ingestion:requeue", "Requeue a dead-lettered ingestion job with a fresh set of attempts.", func(o *clif.Command, in clif.Input, out clif.Output) error {
+		ctx := context.Background()
+		conf := config.Get()
+		infra, err := infrastructure.NewInfra(ctx, *conf)
+		if err != nil {
+			return err
+		}
+		// requeueing does not parse files, the job keeps the profile it was created with
+		ingestionUC := ingestion.NewIngestionUseCase(repository.NewIngestionRepo(infra.SQLStore()), repository.NewDataRepo(infra.SQLStore()), infra.Minio(), newRetryPolicy(conf.Worker), nil)
+		// the reconciliation is run by the worker, requeueing only needs the workflow and ingestion jobs
+		workflowUC := workflow.NewWorkflowUseCase(repository.NewWorkflowRepo(infra.SQLStore()), ingestionUC, nil)
+
+		job, err := workflowUC.RequeueIngestionJob(ctx, o.Argument("job_id").String())
+		if err != nil {
+			return err
+		}
+		out.Printf("Requeued ingestion job %s of workflow %s.\n", job.JobID, job.WorkflowID)
+
+		return nil
+	}).NewArgument("job_id", "ID of the dead-lettered ingestion job", "", true, false)
+}
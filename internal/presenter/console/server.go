@@ -5,14 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/config"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
 	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure"
 	"github.com/ardianferdianto/reconciliation-service/internal/presenter/rest"
 	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/exceptioncase"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/export"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/fxrate"
 	"github.com/ardianferdianto/reconciliation-service/internal/usecase/ingestion"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/journal"
 	"github.com/ardianferdianto/reconciliation-service/internal/usecase/reconcile"
 	"github.com/ardianferdianto/reconciliation-service/internal/usecase/workflow"
 	"github.com/ardianferdianto/reconciliation-service/pkg/logger"
 	"github.com/ardianferdianto/reconciliation-service/pkg/middleware"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
 	"gopkg.in/ukautz/clif.v1"
 	"log/slog"
 	"net/http"
@@ -37,7 +43,10 @@ func (c *ServerConsole) StartServer() *clif.Command {
 			return err
 		}
 
-		routes := SetupRoute(infra)
+		routes, err := SetupRoute(infra)
+		if err != nil {
+			return err
+		}
 		server := http.Server{
 			Handler: routes,
 			Addr:    fmt.Sprintf(":%d", conf.Server.Port),
@@ -65,16 +74,41 @@ func (c *ServerConsole) StartServer() *clif.Command {
 	})
 }
 
-func SetupRoute(infra infrastructure.Infrastructure) *mux.Router {
+func SetupRoute(infra infrastructure.Infrastructure) (*mux.Router, error) {
 	conf := config.Get()
 	wfRepo := repository.NewWorkflowRepo(infra.SQLStore())
 	dtRepo := repository.NewDataRepo(infra.SQLStore())
 	ingRepo := repository.NewIngestionRepo(infra.SQLStore())
 	recRepo := repository.NewReconciliationRepo(infra.SQLStore())
+	fxRepo := repository.NewFXRateRepo(infra.SQLStore())
+	openItemRepo := repository.NewOpenItemRepo(infra.SQLStore())
+	caseRepo := repository.NewExceptionCaseRepo(infra.SQLStore())
+	journalRepo := repository.NewJournalRepo(infra.SQLStore())
 
-	ingestionUC := ingestion.NewIngestionUseCase(ingRepo, dtRepo, infra.Minio())
-	reconcileUC := reconcile.NewReconciliationUseCase(recRepo, dtRepo)
+	profiles, err := newParserProfiles(conf.Parser, repository.NewParserProfileRepo(infra.SQLStore()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid parser profiles: %w", err)
+	}
+	ingestionUC := ingestion.NewIngestionUseCase(ingRepo, dtRepo, infra.Minio(), newRetryPolicy(conf.Worker), profiles)
+	ruleSets, err := newRuleSets(conf.Reconcile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid matching rule sets: %w", err)
+	}
+	reconcileUC := reconcile.NewReconciliationUseCase(recRepo, dtRepo, fxRepo, openItemRepo, ruleSets, domain.ApprovalPolicy{Threshold: conf.Approval.Threshold})
+	fxRateUC := fxrate.NewFXRateUseCase(fxRepo)
 	workflowUC := workflow.NewWorkflowUseCase(wfRepo, ingestionUC, reconcileUC)
+	exportUC := export.NewExportUseCase(wfRepo, recRepo, infra.Minio())
+	caseUC := exceptioncase.NewExceptionCaseUseCase(caseRepo, infra.Minio(), domain.CasePriorityPolicy{
+		HighAmount:    conf.Cases.HighPriorityAmount,
+		MediumAmount:  conf.Cases.MediumPriorityAmount,
+		HighAgeDays:   conf.Cases.HighPriorityAgeDays,
+		MediumAgeDays: conf.Cases.MediumPriorityAgeDays,
+	})
+	accounts, err := newAccountMap(conf.Journal)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GL account mapping: %w", err)
+	}
+	journalUC := journal.NewJournalUseCase(journalRepo, accounts)
 
 	baseRouter := mux.NewRouter()
 	baseRouter.NotFoundHandler = http.HandlerFunc(rest.NotFoundHandler)
@@ -84,9 +118,103 @@ func SetupRoute(infra infrastructure.Infrastructure) *mux.Router {
 	apiRouter.Use(middleware.BasicAuthMiddleware(config.GetCredentials()))
 	apiRouter.Use(middleware.RecoveryHandler())
 
-	workflowHandler := rest.NewWorkflowHandler(workflowUC, reconcileUC)
+	defaultMatchOptions := domain.MatchOptions{
+		AmountTolerance:        conf.Reconcile.AmountTolerance,
+		AmountTolerancePercent: conf.Reconcile.AmountTolerancePercent,
+		DateWindowDays:         conf.Reconcile.DateWindowDays,
+		GroupMatching:          conf.Reconcile.GroupMatching,
+		MaxGroupSize:           conf.Reconcile.MaxGroupSize,
+		AssignmentMode:         conf.Reconcile.AssignmentMode,
+		Currency:               conf.Reconcile.Currency,
+		ConvertCurrency:        conf.Reconcile.ConvertCurrency,
+		IncludeHistorical:      conf.Reconcile.IncludeHistorical,
+	}
+	if defaultMatchOptions.Currency != "" {
+		if defaultMatchOptions.Currency, err = money.ParseCurrency(defaultMatchOptions.Currency); err != nil {
+			return nil, fmt.Errorf("invalid reconciliation currency: %w", err)
+		}
+	}
+	workflowHandler := rest.NewWorkflowHandler(workflowUC, reconcileUC, defaultMatchOptions)
 	apiRouter.HandleFunc("/workflow", workflowHandler.StartWorkflowHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow", workflowHandler.ListWorkflowsHandler).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/workflow/{workflowID}", workflowHandler.GetWorkflowSummary).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/workflow/{workflowID}/cancel", workflowHandler.CancelWorkflowHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/rerun", workflowHandler.RerunWorkflowHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/clone", workflowHandler.CloneWorkflowHandler).Methods(http.MethodPost)
+
+	exceptionHandler := rest.NewExceptionHandler(workflowUC, reconcileUC)
+	apiRouter.HandleFunc("/workflow/{workflowID}/exceptions/system", exceptionHandler.UnmatchedSystemHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/workflow/{workflowID}/exceptions/bank", exceptionHandler.UnmatchedBankHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/workflow/{workflowID}/matches", exceptionHandler.MatchedPairsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/workflow/{workflowID}/match-groups", exceptionHandler.MatchGroupsHandler).Methods(http.MethodGet)
+
+	resolutionHandler := rest.NewResolutionHandler(workflowUC, reconcileUC)
+	apiRouter.HandleFunc("/workflow/{workflowID}/manual-matches", resolutionHandler.ManualMatchHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/matches/{matchID}/unmatch", resolutionHandler.UnmatchHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/match-groups/{groupID}/unmatch", resolutionHandler.UnmatchGroupHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/write-offs", resolutionHandler.WriteOffHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/proposals", resolutionHandler.ProposalsHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/workflow/{workflowID}/proposals/{proposalID}/approve", resolutionHandler.ApproveProposalHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/proposals/{proposalID}/reject", resolutionHandler.RejectProposalHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/audit-log", resolutionHandler.AuditLogHandler).Methods(http.MethodGet)
+
+	caseHandler := rest.NewExceptionCaseHandler(workflowUC, caseUC)
+	apiRouter.HandleFunc("/workflow/{workflowID}/cases", caseHandler.ListCasesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/workflow/{workflowID}/cases/{caseID}", caseHandler.GetCaseHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/workflow/{workflowID}/cases/{caseID}", caseHandler.UpdateCaseHandler).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/workflow/{workflowID}/cases/{caseID}/comments", caseHandler.AddCommentHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/cases/{caseID}/attachments", caseHandler.AddAttachmentHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/cases/{caseID}/attachments/{attachmentID}", caseHandler.DownloadAttachmentHandler).Methods(http.MethodGet)
+
+	journalHandler := rest.NewJournalHandler(workflowUC, journalUC)
+	apiRouter.HandleFunc("/workflow/{workflowID}/journal-entries", journalHandler.GenerateEntriesHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/workflow/{workflowID}/journal-entries", journalHandler.ListEntriesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/workflow/{workflowID}/journal-entries/export", journalHandler.ExportEntriesHandler).Methods(http.MethodGet)
+
+	exportHandler := rest.NewExportHandler(exportUC)
+	apiRouter.HandleFunc("/workflow/{workflowID}/export", exportHandler.ExportWorkflowHandler).Methods(http.MethodGet)
+
+	ingestionHandler := rest.NewIngestionHandler(workflowUC, ingestionUC)
+	apiRouter.HandleFunc("/ingestion-jobs/{jobID}/requeue", ingestionHandler.RequeueJobHandler).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/parser-profiles", ingestionHandler.ListParserProfilesHandler).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/parser-profiles/{name}", ingestionHandler.SaveParserProfileHandler).Methods(http.MethodPut)
+
+	fxRateHandler := rest.NewFXRateHandler(fxRateUC)
+	apiRouter.HandleFunc("/fx-rates", fxRateHandler.LoadRatesHandler).Methods(http.MethodPost)
+
+	return baseRouter, nil
+}
+
+func newRuleSets(conf config.ReconcileConfiguration) (*reconcile.RuleSets, error) {
+	var defs []domain.MatchRuleSet
+	for _, setConf := range conf.RuleSets {
+		def := domain.MatchRuleSet{Name: setConf.Name, BankCode: setConf.BankCode}
+		for _, passConf := range setConf.Passes {
+			pass := domain.MatchPassDefinition{Name: passConf.Name}
+			for _, ruleConf := range passConf.Rules {
+				pass.Rules = append(pass.Rules, domain.MatchRuleDefinition{
+					Type:                   ruleConf.Type,
+					AmountTolerance:        ruleConf.AmountTolerance,
+					AmountTolerancePercent: ruleConf.AmountTolerancePercent,
+					DateWindowDays:         ruleConf.DateWindowDays,
+				})
+			}
+			def.Passes = append(def.Passes, pass)
+		}
+		defs = append(defs, def)
+	}
+	return reconcile.NewRuleSets(defs)
+}
 
-	return baseRouter
+func newAccountMap(conf config.JournalConfiguration) (*journal.AccountMap, error) {
+	rules := make([]domain.GLAccountRule, 0, len(conf.Accounts))
+	for _, accountConf := range conf.Accounts {
+		rules = append(rules, domain.GLAccountRule{
+			BankCode:          accountConf.BankCode,
+			Reason:            accountConf.Reason,
+			CashAccount:       accountConf.CashAccount,
+			AdjustmentAccount: accountConf.AdjustmentAccount,
+		})
+	}
+	return journal.NewAccountMap(rules)
 }
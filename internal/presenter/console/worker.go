@@ -2,10 +2,16 @@ package console
 
 import (
 	"context"
+	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/config"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
 	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure"
 	"github.com/ardianferdianto/reconciliation-service/internal/repository"
 	"github.com/ardianferdianto/reconciliation-service/internal/usecase/ingestion"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/lease"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/parser"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/reconcile"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/workflow"
 	"gopkg.in/ukautz/clif.v1"
 	"log"
 	"log/slog"
@@ -20,7 +26,7 @@ import (
 type WorkerConsole struct{}
 
 func (c *WorkerConsole) StartWorker() *clif.Command {
-	return clif.NewCommand("start", "starting workers.", func(o *clif.Command, in clif.Input, out clif.Output) error {
+	return clif.NewCommand("worker:start", "starting workers.", func(o *clif.Command, in clif.Input, out clif.Output) error {
 		ctx := context.Background()
 		slog.InfoContext(ctx, "Runtime go version "+runtime.Version())
 		conf := config.Get()
@@ -37,21 +43,44 @@ func (c *WorkerConsole) StartWorker() *clif.Command {
 		}
 		jobRepo := repository.NewIngestionRepo(infra.SQLStore())
 		dataRepo := repository.NewDataRepo(infra.SQLStore())
+		wfRepo := repository.NewWorkflowRepo(infra.SQLStore())
+		recRepo := repository.NewReconciliationRepo(infra.SQLStore())
+		fxRepo := repository.NewFXRateRepo(infra.SQLStore())
+		openItemRepo := repository.NewOpenItemRepo(infra.SQLStore())
 
-		ingestionUC := ingestion.NewIngestionUseCase(jobRepo, dataRepo, infra.Minio())
+		ruleSets, err := newRuleSets(conf.Reconcile)
+		if err != nil {
+			return fmt.Errorf("invalid matching rule sets: %w", err)
+		}
+		profiles, err := newParserProfiles(conf.Parser, repository.NewParserProfileRepo(infra.SQLStore()))
+		if err != nil {
+			return fmt.Errorf("invalid parser profiles: %w", err)
+		}
+		ingestionUC := ingestion.NewIngestionUseCase(jobRepo, dataRepo, infra.Minio(), newRetryPolicy(conf.Worker), profiles)
+		reconcileUC := reconcile.NewReconciliationUseCase(recRepo, dataRepo, fxRepo, openItemRepo, ruleSets, domain.ApprovalPolicy{Threshold: conf.Approval.Threshold})
+		workflowUC := workflow.NewWorkflowUseCase(wfRepo, ingestionUC, reconcileUC)
 
 		log.Printf("Starting worker with concurrency = %d\n", workerConcurrency)
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		go ingestion.WorkerIngestionLoop(ctx, ingestionUC, jobRepo, workerConcurrency)
+		leaseOpts := lease.Options{
+			WorkerID:          conf.Worker.WorkerID,
+			Duration:          time.Duration(conf.Worker.LeaseSeconds) * time.Second,
+			HeartbeatInterval: time.Duration(conf.Worker.HeartbeatSeconds) * time.Second,
+		}.WithDefaults()
+		log.Printf("Worker id = %s, lease = %s\n", leaseOpts.WorkerID, leaseOpts.Duration)
+
+		go ingestion.WorkerIngestionLoop(ctx, ingestionUC, jobRepo, workerConcurrency, leaseOpts)
+		go workflow.WorkerWorkflowLoop(ctx, workflowUC, 5*time.Second, leaseOpts)
 
 		// wait for signal
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 		<-sigCh
 		log.Println("Shutting down worker gracefully...")
+		cancel()
 		slog.InfoContext(ctx, "shutting down")
 		time.Sleep(5 * time.Second)
 
@@ -59,6 +88,44 @@ func (c *WorkerConsole) StartWorker() *clif.Command {
 	})
 }
 
+func newRetryPolicy(conf config.WorkerConfiguration) ingestion.RetryPolicy {
+	return ingestion.RetryPolicy{
+		MaxAttempts: conf.MaxAttempts,
+		BaseDelay:   time.Duration(conf.RetryBaseSeconds) * time.Second,
+		MaxDelay:    time.Duration(conf.RetryMaxSeconds) * time.Second,
+	}.WithDefaults()
+}
+
+func newParserProfiles(conf config.ParserConfiguration, repo repository.ParserProfileRepository) (*parser.Profiles, error) {
+	profiles := make([]domain.ParserProfile, 0, len(conf.Profiles))
+	for _, profileConf := range conf.Profiles {
+		columns := make(map[string]domain.ColumnRef, len(profileConf.Columns))
+		for column, columnConf := range profileConf.Columns {
+			columns[column] = domain.ColumnRef{Name: columnConf.Name, Index: columnConf.Index}
+		}
+		profiles = append(profiles, domain.ParserProfile{
+			Name:              profileConf.Name,
+			Delimiter:         profileConf.Delimiter,
+			Sheet:             profileConf.Sheet,
+			SkipRows:          profileConf.SkipRows,
+			SkipFooterRows:    profileConf.SkipFooterRows,
+			NoHeader:          profileConf.NoHeader,
+			Columns:           columns,
+			DateFormats:       profileConf.DateFormats,
+			TimeFormat:        profileConf.TimeFormat,
+			Timezone:          profileConf.Timezone,
+			DecimalSeparator:  profileConf.DecimalSeparator,
+			ThousandSeparator: profileConf.ThousandSeparator,
+			SignConvention:    profileConf.SignConvention,
+			DebitIndicators:   profileConf.DebitIndicators,
+			CreditIndicators:  profileConf.CreditIndicators,
+			DefaultBankCode:   profileConf.DefaultBankCode,
+			DefaultCurrency:   profileConf.DefaultCurrency,
+		})
+	}
+	return parser.NewProfiles(profiles, repo)
+}
+
 func init() {
 
 }
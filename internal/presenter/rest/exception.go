@@ -0,0 +1,261 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/reconcile"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/workflow"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contract"
+	"github.com/ardianferdianto/reconciliation-service/pkg/response"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExceptionHandler lists the unmatched items and the matches of the latest reconciliation of a workflow page by page
+type ExceptionHandler struct {
+	workflowUC  workflow.IUseCase
+	reconcileUC reconcile.IUseCase
+}
+
+func NewExceptionHandler(workflowUC workflow.IUseCase, reconcileUC reconcile.IUseCase) *ExceptionHandler {
+	return &ExceptionHandler{workflowUC: workflowUC, reconcileUC: reconcileUC}
+}
+
+// UnmatchedSystemHandler lists the unmatched system transactions, filtered by type, min_amount, max_amount,
+// start_date and end_date and sorted by date, amount or reference
+func (h *ExceptionHandler) UnmatchedSystemHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, q, ok := h.exceptionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	items, next, err := h.reconcileUC.ListUnmatchedSystemTx(r.Context(), jobID, q)
+	if !listOK(w, err) {
+		return
+	}
+
+	resp := contract.UnmatchedSystemPage{Items: make([]contract.UnmatchedSystemItem, 0, len(items)), NextCursor: nextCursor(next)}
+	for _, tx := range items {
+		resp.Items = append(resp.Items, contract.UnmatchedSystemItem{
+			ID:              tx.ID,
+			TrxID:           tx.TrxID,
+			Type:            tx.Type,
+			Amount:          tx.Amount,
+			Currency:        tx.Amount.Currency,
+			TransactionTime: tx.TransactionTime,
+		})
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, resp)
+}
+
+// UnmatchedBankHandler lists the unmatched bank statements, filtered by bank_code, min_amount, max_amount,
+// start_date and end_date and sorted by date, amount, reference or bank_code
+func (h *ExceptionHandler) UnmatchedBankHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, q, ok := h.exceptionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	items, next, err := h.reconcileUC.ListUnmatchedBankTx(r.Context(), jobID, q)
+	if !listOK(w, err) {
+		return
+	}
+
+	resp := contract.UnmatchedBankPage{Items: make([]contract.UnmatchedBankItem, 0, len(items)), NextCursor: nextCursor(next)}
+	for _, stmt := range items {
+		resp.Items = append(resp.Items, contract.UnmatchedBankItem{
+			ID:            stmt.ID,
+			UniqueID:      stmt.UniqueID,
+			BankCode:      stmt.BankCode,
+			Amount:        stmt.Amount,
+			Currency:      stmt.Amount.Currency,
+			StatementTime: stmt.StatementDate,
+		})
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, resp)
+}
+
+// MatchedPairsHandler lists the 1-to-1 matches, filtered on the system side by type, min_amount, max_amount,
+// start_date and end_date and on the bank side by bank_code, sorted by date, amount, reference, bank_code or discrepancy
+func (h *ExceptionHandler) MatchedPairsHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, q, ok := h.exceptionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	items, next, err := h.reconcileUC.ListMatchedPairs(r.Context(), jobID, q)
+	if !listOK(w, err) {
+		return
+	}
+
+	resp := contract.MatchedPairPage{Items: make([]contract.MatchedPairItem, 0, len(items)), NextCursor: nextCursor(next)}
+	for _, pair := range items {
+		resp.Items = append(resp.Items, contract.MatchedPairItem{
+			ID:              pair.ID,
+			TrxID:           pair.TrxID,
+			TrxType:         pair.TrxType,
+			TransactionTime: pair.TransactionTime,
+			SystemAmount:    pair.SystemAmount,
+			SystemCurrency:  pair.SystemAmount.Currency,
+			SystemFXRate:    pair.SystemFXRate,
+			UniqueID:        pair.UniqueID,
+			BankCode:        pair.BankCode,
+			StatementTime:   pair.StatementTime,
+			BankAmount:      pair.BankAmount,
+			BankCurrency:    pair.BankAmount.Currency,
+			BankFXRate:      pair.BankFXRate,
+			Discrepancy:     pair.Discrepancy,
+			Currency:        pair.Discrepancy.Currency,
+			RuleSet:         pair.RuleSet,
+			MatchPass:       pair.MatchPass,
+			MatchedBy:       pair.MatchedBy,
+			MatchedAt:       pair.MatchedAt,
+		})
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, resp)
+}
+
+// MatchGroupsHandler lists the split and aggregate matches, filtered by start_date and end_date and sorted by date or discrepancy
+func (h *ExceptionHandler) MatchGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, q, ok := h.exceptionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	items, next, err := h.reconcileUC.ListMatchGroups(r.Context(), jobID, q)
+	if !listOK(w, err) {
+		return
+	}
+
+	resp := contract.MatchGroupPage{Items: make([]contract.MatchGroupItem, 0, len(items)), NextCursor: nextCursor(next)}
+	for _, group := range items {
+		resp.Items = append(resp.Items, contract.MatchGroupItem{
+			ID:               group.ID,
+			GroupType:        group.GroupType,
+			SystemTxIDs:      group.SystemTxIDs,
+			BankStatementIDs: group.BankStatementIDs,
+			Discrepancy:      group.Discrepancy,
+			Currency:         group.Discrepancy.Currency,
+			MatchedBy:        group.MatchedBy,
+			MatchedAt:        group.MatchedAt,
+		})
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, resp)
+}
+
+// exceptionRequest resolves the reconciliation job of the workflow and reads the query parameters,
+// it writes the error response itself and returns false when the request cannot be served
+func (h *ExceptionHandler) exceptionRequest(w http.ResponseWriter, r *http.Request) (string, domain.ExceptionQuery, bool) {
+	workflowID := mux.Vars(r)["workflowID"]
+
+	q, err := exceptionQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", domain.ExceptionQuery{}, false
+	}
+
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, workflowID)
+	if !ok {
+		return "", domain.ExceptionQuery{}, false
+	}
+	return jobID, q, true
+}
+
+// reconciliationJobID returns the latest reconciliation job of a workflow,
+// it writes the error response itself and returns false when the workflow has none
+func reconciliationJobID(w http.ResponseWriter, r *http.Request, workflowUC workflow.IUseCase, workflowID string) (string, bool) {
+	wf, err := workflowUC.GetWorkflowSummary(r.Context(), workflowID)
+	if errors.Is(err, repository.ErrWorkflowNotFound) {
+		http.Error(w, fmt.Sprintf("Workflow %s not found", workflowID), http.StatusNotFound)
+		return "", false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve workflow: %v", err), http.StatusInternalServerError)
+		return "", false
+	}
+	if wf.ReconciliationJobID == nil {
+		http.Error(w, fmt.Sprintf("Workflow %s has no completed reconciliation yet", workflowID), http.StatusConflict)
+		return "", false
+	}
+	return *wf.ReconciliationJobID, true
+}
+
+// exceptionQuery reads the filters, the sort and the page of an exception list.
+// sort takes a key such as "amount", prefixed with "-" for descending order.
+func exceptionQuery(query url.Values) (domain.ExceptionQuery, error) {
+	q := domain.ExceptionQuery{
+		BankCode: query.Get("bank_code"),
+		Type:     query.Get("type"),
+		SortBy:   strings.TrimPrefix(query.Get("sort"), "-"),
+		Desc:     strings.HasPrefix(query.Get("sort"), "-"),
+	}
+
+	var err error
+	if q.MinAmount, err = queryAmount(query.Get("min_amount")); err != nil {
+		return q, fmt.Errorf("Invalid min_amount: %v", err)
+	}
+	if q.MaxAmount, err = queryAmount(query.Get("max_amount")); err != nil {
+		return q, fmt.Errorf("Invalid max_amount: %v", err)
+	}
+	if q.From, err = queryDate(query.Get("start_date")); err != nil {
+		return q, fmt.Errorf("Invalid start_date: %v", err)
+	}
+	end := query.Get("end_date")
+	if q.To, err = queryDate(end); err != nil {
+		return q, fmt.Errorf("Invalid end_date: %v", err)
+	}
+	// a date without a time includes the whole day
+	if _, dateOnly := time.Parse(time.DateOnly, end); dateOnly == nil {
+		q.To = q.To.AddDate(0, 0, 1)
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit <= 0 {
+			return q, fmt.Errorf("limit must be a positive number")
+		}
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := reconcile.DecodeExceptionCursor(cursor, q.SortBy)
+		if err != nil {
+			return q, err
+		}
+		q.After = &after
+	}
+	return q, nil
+}
+
+func queryAmount(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &amount, nil
+}
+
+// listOK writes the error response of a failed list, an unknown sort key is the caller's mistake
+func listOK(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, repository.ErrUnknownSort) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func nextCursor(next *domain.ExceptionCursor) string {
+	if next == nil {
+		return ""
+	}
+	return reconcile.EncodeExceptionCursor(*next)
+}
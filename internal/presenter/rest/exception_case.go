@@ -0,0 +1,281 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/exceptioncase"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/workflow"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contextprop"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contract"
+	"github.com/ardianferdianto/reconciliation-service/pkg/logger"
+	"github.com/ardianferdianto/reconciliation-service/pkg/request"
+	"github.com/ardianferdianto/reconciliation-service/pkg/response"
+	"github.com/gorilla/mux"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxAttachmentSize bounds the size of a file uploaded for a case
+const maxAttachmentSize = 20 << 20
+
+// ExceptionCaseHandler tracks the investigation of the exceptions of the latest reconciliation of a workflow
+type ExceptionCaseHandler struct {
+	workflowUC workflow.IUseCase
+	caseUC     exceptioncase.IUseCase
+}
+
+func NewExceptionCaseHandler(workflowUC workflow.IUseCase, caseUC exceptioncase.IUseCase) *ExceptionCaseHandler {
+	return &ExceptionCaseHandler{workflowUC: workflowUC, caseUC: caseUC}
+}
+
+// ListCasesHandler lists the cases newest first, filtered by status (comma separated), assignee ("me" for the caller),
+// priority and item_type. A page continues from the next_cursor of the previous one.
+func (h *ExceptionCaseHandler) ListCasesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.CaseFilter{
+		Assignee: query.Get("assignee"),
+		Priority: strings.ToUpper(query.Get("priority")),
+		ItemType: strings.ToUpper(query.Get("item_type")),
+	}
+	if filter.Assignee == "me" {
+		filter.Assignee = contextprop.GetValue(r.Context(), contextprop.ClientIDKey)
+	}
+	for _, status := range strings.Split(query.Get("status"), ",") {
+		if status = strings.ToUpper(strings.TrimSpace(status)); status != "" {
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	var err error
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		if filter.AfterID, err = strconv.Atoi(cursor); err != nil || filter.AfterID <= 0 {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	if !ok {
+		return
+	}
+
+	page, err := h.caseUC.ListCases(r.Context(), jobID, filter)
+	if err != nil {
+		caseError(w, err)
+		return
+	}
+
+	resp := contract.ExceptionCasePage{Items: make([]contract.ExceptionCaseItem, 0, len(page.Items))}
+	for _, c := range page.Items {
+		resp.Items = append(resp.Items, caseItem(c))
+	}
+	if page.NextAfterID != 0 {
+		resp.NextCursor = strconv.Itoa(page.NextAfterID)
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, resp)
+}
+
+// GetCaseHandler returns a case with its comments and attachments
+func (h *ExceptionCaseHandler) GetCaseHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, caseID, ok := h.caseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	c, err := h.caseUC.GetCase(ctx, jobID, caseID)
+	if err != nil {
+		caseError(w, err)
+		return
+	}
+	comments, err := h.caseUC.ListComments(ctx, jobID, caseID)
+	if err != nil {
+		caseError(w, err)
+		return
+	}
+	attachments, err := h.caseUC.ListAttachments(ctx, jobID, caseID)
+	if err != nil {
+		caseError(w, err)
+		return
+	}
+
+	resp := contract.ExceptionCaseDetail{
+		ExceptionCaseItem: caseItem(c),
+		Comments:          make([]contract.CaseComment, 0, len(comments)),
+		Attachments:       make([]contract.CaseAttachment, 0, len(attachments)),
+	}
+	for _, comment := range comments {
+		resp.Comments = append(resp.Comments, caseComment(comment))
+	}
+	for _, attachment := range attachments {
+		resp.Attachments = append(resp.Attachments, caseAttachment(attachment))
+	}
+	response.WriteJSON(ctx, w, http.StatusOK, resp)
+}
+
+// UpdateCaseHandler changes the status or the assignee of an open case
+func (h *ExceptionCaseHandler) UpdateCaseHandler(w http.ResponseWriter, r *http.Request) {
+	var req contract.UpdateCaseRequest
+	if err := request.ReadJSON(r, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	jobID, caseID, ok := h.caseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := h.caseUC.UpdateCase(r.Context(), jobID, caseID, domain.CaseUpdate{Status: req.Status, Assignee: req.Assignee})
+	if err != nil {
+		caseError(w, err)
+		return
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, caseItem(c))
+}
+
+func (h *ExceptionCaseHandler) AddCommentHandler(w http.ResponseWriter, r *http.Request) {
+	var req contract.AddCommentRequest
+	if err := request.ReadJSON(r, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	jobID, caseID, ok := h.caseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	comment, err := h.caseUC.AddComment(r.Context(), jobID, caseID, req.Body)
+	if err != nil {
+		caseError(w, err)
+		return
+	}
+	response.WriteJSON(r.Context(), w, http.StatusCreated, caseComment(comment))
+}
+
+// AddAttachmentHandler stores the "file" field of a multipart form in the bucket and attaches it to the case
+func (h *ExceptionCaseHandler) AddAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, caseID, ok := h.caseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid attachment: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	if header.Size > maxAttachmentSize {
+		http.Error(w, fmt.Sprintf("Attachments are limited to %d MB", maxAttachmentSize>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	attachment, err := h.caseUC.AddAttachment(r.Context(), jobID, caseID, header.Filename, header.Header.Get("Content-Type"), header.Size, file)
+	if err != nil {
+		caseError(w, err)
+		return
+	}
+	response.WriteJSON(r.Context(), w, http.StatusCreated, caseAttachment(attachment))
+}
+
+// DownloadAttachmentHandler streams a file attached to a case
+func (h *ExceptionCaseHandler) DownloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, caseID, ok := h.caseRequest(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, err := strconv.Atoi(mux.Vars(r)["attachmentID"])
+	if err != nil {
+		http.Error(w, "Invalid attachmentID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, content, err := h.caseUC.GetAttachment(r.Context(), jobID, caseID, attachmentID)
+	if err != nil {
+		caseError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.FileName))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		slog.ErrorContext(r.Context(), "attachment download interrupted", slog.Int("attachment_id", attachmentID), logger.ErrAttr(err))
+	}
+}
+
+// caseRequest resolves the reconciliation job of the workflow and reads the case id,
+// it writes the error response itself and returns false when the request cannot be served
+func (h *ExceptionCaseHandler) caseRequest(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	caseID, err := strconv.Atoi(mux.Vars(r)["caseID"])
+	if err != nil {
+		http.Error(w, "Invalid caseID", http.StatusBadRequest)
+		return "", 0, false
+	}
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	if !ok {
+		return "", 0, false
+	}
+	return jobID, caseID, true
+}
+
+func caseError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, exceptioncase.ErrNoActor):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, exceptioncase.ErrInvalidCase):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrCaseNotFound), errors.Is(err, repository.ErrAttachmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrCaseClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to process case: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func caseItem(c domain.ExceptionCase) contract.ExceptionCaseItem {
+	return contract.ExceptionCaseItem{
+		ID:          c.ID,
+		ItemType:    c.ItemType,
+		ExceptionID: c.ExceptionID,
+		Reference:   c.Reference,
+		BankCode:    c.BankCode,
+		Amount:      c.Amount,
+		Currency:    c.Amount.Currency,
+		ItemDate:    c.ItemDate,
+		Status:      c.Status,
+		Assignee:    c.Assignee,
+		Priority:    c.Priority,
+		AgeDays:     c.AgeDays,
+		ClosedAt:    c.ClosedAt,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
+
+func caseComment(c domain.CaseComment) contract.CaseComment {
+	return contract.CaseComment{ID: c.ID, Author: c.Author, Body: c.Body, CreatedAt: c.CreatedAt}
+}
+
+func caseAttachment(a domain.CaseAttachment) contract.CaseAttachment {
+	return contract.CaseAttachment{
+		ID:          a.ID,
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		UploadedBy:  a.UploadedBy,
+		CreatedAt:   a.CreatedAt,
+	}
+}
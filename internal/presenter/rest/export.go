@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/export"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contract"
+	"github.com/ardianferdianto/reconciliation-service/pkg/logger"
+	"github.com/ardianferdianto/reconciliation-service/pkg/response"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+)
+
+type ExportHandler struct {
+	exportUC export.IUseCase
+}
+
+func NewExportHandler(exportUC export.IUseCase) *ExportHandler {
+	return &ExportHandler{exportUC: exportUC}
+}
+
+// ExportWorkflowHandler streams the matched pairs and unmatched items of a workflow as csv (default), xlsx or ndjson.
+// With destination=storage the file is written into the bucket instead and its object name is returned.
+func (h *ExportHandler) ExportWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	workflowID := mux.Vars(r)["workflowID"]
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	contentType, err := export.ContentType(format)
+	if err != nil {
+		http.Error(w, "format must be one of csv, xlsx or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Query().Get("destination") {
+	case "", "download":
+	case "storage":
+		objectName, err := h.exportUC.StoreWorkflowExport(r.Context(), workflowID, format)
+		if err != nil {
+			exportError(w, workflowID, err)
+			return
+		}
+		response.WriteJSON(r.Context(), w, http.StatusCreated, contract.StoreExportResponse{
+			WorkflowID: workflowID,
+			Format:     format,
+			ObjectName: objectName,
+		})
+		return
+	default:
+		http.Error(w, "destination must be download or storage", http.StatusBadRequest)
+		return
+	}
+
+	out := &exportResponse{
+		ResponseWriter: w,
+		contentType:    contentType,
+		fileName:       fmt.Sprintf("reconciliation-%s.%s", workflowID, format),
+	}
+	err = h.exportUC.ExportWorkflow(r.Context(), workflowID, format, out)
+	if err != nil && !out.started {
+		exportError(w, workflowID, err)
+		return
+	}
+	if err != nil {
+		// the status is already sent, the client is left with a truncated file
+		slog.ErrorContext(r.Context(), "export interrupted", slog.String("workflow_id", workflowID), logger.ErrAttr(err))
+	}
+}
+
+// exportResponse sends the download headers with the first bytes of the file,
+// so an export failing before writing anything can still answer with an error status
+type exportResponse struct {
+	http.ResponseWriter
+	contentType string
+	fileName    string
+	started     bool
+}
+
+func (e *exportResponse) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.Header().Set("Content-Type", e.contentType)
+		e.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.fileName))
+		e.WriteHeader(http.StatusOK)
+	}
+	return e.ResponseWriter.Write(p)
+}
+
+func exportError(w http.ResponseWriter, workflowID string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrWorkflowNotFound):
+		http.Error(w, fmt.Sprintf("Workflow %s not found", workflowID), http.StatusNotFound)
+	case errors.Is(err, export.ErrNoReconciliation):
+		http.Error(w, fmt.Sprintf("Workflow %s has no completed reconciliation yet", workflowID), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to export workflow: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/fxrate"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contract"
+	"github.com/ardianferdianto/reconciliation-service/pkg/response"
+	"net/http"
+)
+
+// maxFXRateFileSize bounds the CSV body accepted by LoadRatesHandler
+const maxFXRateFileSize = 10 << 20
+
+type FXRateHandler struct {
+	fxRateUC fxrate.IUseCase
+}
+
+func NewFXRateHandler(fxRateUC fxrate.IUseCase) *FXRateHandler {
+	return &FXRateHandler{fxRateUC: fxRateUC}
+}
+
+// LoadRatesHandler loads daily FX rates from a CSV request body (rate_date,base_currency,quote_currency,rate)
+func (h *FXRateHandler) LoadRatesHandler(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, maxFXRateFileSize)
+	defer body.Close()
+
+	loaded, err := h.fxRateUC.LoadRatesCSV(r.Context(), body)
+	if errors.Is(err, fxrate.ErrInvalidFile) {
+		http.Error(w, fmt.Sprintf("Invalid fx rate file: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load fx rates: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(r.Context(), w, http.StatusOK, contract.LoadFXRatesResponse{Loaded: loaded})
+}
@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/ingestion"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/parser"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/workflow"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contract"
+	"github.com/ardianferdianto/reconciliation-service/pkg/request"
+	"github.com/ardianferdianto/reconciliation-service/pkg/response"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type IngestionHandler struct {
+	workflowUC  workflow.IUseCase
+	ingestionUC ingestion.IUseCase
+}
+
+func NewIngestionHandler(workflowUC workflow.IUseCase, ingestionUC ingestion.IUseCase) *IngestionHandler {
+	return &IngestionHandler{workflowUC: workflowUC, ingestionUC: ingestionUC}
+}
+
+// RequeueJobHandler puts a dead-lettered ingestion job back in the queue with a fresh set of attempts
+func (h *IngestionHandler) RequeueJobHandler(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["jobID"]
+
+	job, err := h.workflowUC.RequeueIngestionJob(r.Context(), jobID)
+	if errors.Is(err, ingestion.ErrNotDeadLettered) {
+		http.Error(w, fmt.Sprintf("Ingestion job %s not found or not dead-lettered", jobID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to requeue ingestion job: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(r.Context(), w, http.StatusOK, contract.RequeueIngestionJobResponse{
+		JobID:      job.JobID,
+		WorkflowID: job.WorkflowID,
+		Status:     job.Status,
+	})
+}
+
+// ListParserProfilesHandler lists the configured and stored parser profiles a workflow can select per file
+func (h *IngestionHandler) ListParserProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	profiles, err := h.ingestionUC.ListParserProfiles(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list parser profiles: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if profiles == nil {
+		profiles = []domain.ParserProfile{}
+	}
+
+	response.WriteJSON(r.Context(), w, http.StatusOK, contract.ListParserProfilesResponse{Profiles: profiles})
+}
+
+// SaveParserProfileHandler stores the parser profile of the name in the request path, replacing a stored one
+func (h *IngestionHandler) SaveParserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var profile domain.ParserProfile
+	if err := request.ReadJSON(r, &profile); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	profile.Name = mux.Vars(r)["name"]
+
+	err := h.ingestionUC.SaveParserProfile(r.Context(), profile)
+	if errors.Is(err, parser.ErrInvalidProfile) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save parser profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(r.Context(), w, http.StatusOK, profile)
+}
@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/journal"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/workflow"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contract"
+	"github.com/ardianferdianto/reconciliation-service/pkg/logger"
+	"github.com/ardianferdianto/reconciliation-service/pkg/response"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// JournalHandler generates and exports the adjusting journal entries of the latest reconciliation of a workflow
+type JournalHandler struct {
+	workflowUC workflow.IUseCase
+	journalUC  journal.IUseCase
+}
+
+func NewJournalHandler(workflowUC workflow.IUseCase, journalUC journal.IUseCase) *JournalHandler {
+	return &JournalHandler{workflowUC: workflowUC, journalUC: journalUC}
+}
+
+// GenerateEntriesHandler books the discrepancies and write-offs of a workflow, replacing the entries generated before
+func (h *JournalHandler) GenerateEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	if !ok {
+		return
+	}
+
+	entries, err := h.journalUC.GenerateEntries(r.Context(), jobID)
+	if errors.Is(err, journal.ErrUnmappedAccount) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate journal entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	response.WriteJSON(r.Context(), w, http.StatusCreated, toJournalContract(entries))
+}
+
+// ListEntriesHandler lists the journal entries generated for a workflow
+func (h *JournalHandler) ListEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	if !ok {
+		return
+	}
+
+	entries, err := h.journalUC.ListEntries(r.Context(), jobID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list journal entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, toJournalContract(entries))
+}
+
+// ExportEntriesHandler downloads the journal entries generated for a workflow as the CSV the ERP imports
+func (h *JournalHandler) ExportEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	workflowID := mux.Vars(r)["workflowID"]
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, workflowID)
+	if !ok {
+		return
+	}
+
+	out := &exportResponse{
+		ResponseWriter: w,
+		contentType:    "text/csv",
+		fileName:       fmt.Sprintf("journal-%s.csv", workflowID),
+	}
+	err := h.journalUC.ExportEntries(r.Context(), jobID, out)
+	if err != nil && !out.started {
+		http.Error(w, fmt.Sprintf("Failed to export journal entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "journal export interrupted", slog.String("workflow_id", workflowID), logger.ErrAttr(err))
+	}
+}
+
+func toJournalContract(entries []domain.JournalEntry) contract.JournalEntriesResponse {
+	resp := contract.JournalEntriesResponse{Entries: make([]contract.JournalEntry, 0, len(entries))}
+	for _, e := range entries {
+		entry := contract.JournalEntry{
+			ID:          e.ID,
+			SourceType:  e.SourceType,
+			SourceID:    e.SourceID,
+			BankCode:    e.BankCode,
+			Reason:      e.Reason,
+			Reference:   e.Reference,
+			Description: e.Description,
+			EntryDate:   e.EntryDate.Format(time.DateOnly),
+			Currency:    e.Currency,
+			Lines:       make([]contract.JournalLine, 0, len(e.Lines)),
+			CreatedAt:   e.CreatedAt,
+		}
+		for _, line := range e.Lines {
+			entry.Lines = append(entry.Lines, contract.JournalLine{
+				LineNo:  line.LineNo,
+				Account: line.Account,
+				Debit:   line.Debit,
+				Credit:  line.Credit,
+			})
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	return resp
+}
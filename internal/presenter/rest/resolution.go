@@ -0,0 +1,252 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/reconcile"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/workflow"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contract"
+	"github.com/ardianferdianto/reconciliation-service/pkg/request"
+	"github.com/ardianferdianto/reconciliation-service/pkg/response"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+// ResolutionHandler applies the manual matches, unmatches and write-offs of an operator
+// to the latest reconciliation of a workflow, each of them is kept in the audit log.
+// Matches and write-offs over the approval threshold are proposed and wait for another client to approve them.
+type ResolutionHandler struct {
+	workflowUC  workflow.IUseCase
+	reconcileUC reconcile.IUseCase
+}
+
+func NewResolutionHandler(workflowUC workflow.IUseCase, reconcileUC reconcile.IUseCase) *ResolutionHandler {
+	return &ResolutionHandler{workflowUC: workflowUC, reconcileUC: reconcileUC}
+}
+
+// ManualMatchHandler matches an unmatched system transaction with one or more unmatched bank lines
+func (h *ResolutionHandler) ManualMatchHandler(w http.ResponseWriter, r *http.Request) {
+	var req contract.ManualMatchRequest
+	if err := request.ReadJSON(r, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	if !ok {
+		return
+	}
+
+	resolution, err := h.reconcileUC.ManualMatch(r.Context(), jobID, req.SystemExceptionID, req.BankExceptionIDs, req.Reason)
+	writeResolution(w, r, http.StatusCreated, resolution, err)
+}
+
+// UnmatchHandler breaks a 1-to-1 match, both records are listed as exceptions again
+func (h *ResolutionHandler) UnmatchHandler(w http.ResponseWriter, r *http.Request) {
+	h.unmatch(w, r, "matchID", h.reconcileUC.Unmatch)
+}
+
+// UnmatchGroupHandler breaks a split or aggregate match, all of its records are listed as exceptions again
+func (h *ResolutionHandler) UnmatchGroupHandler(w http.ResponseWriter, r *http.Request) {
+	h.unmatch(w, r, "groupID", h.reconcileUC.UnmatchGroup)
+}
+
+func (h *ResolutionHandler) unmatch(w http.ResponseWriter, r *http.Request, idVar string,
+	apply func(ctx context.Context, jobID string, id int, reason string) (domain.ManualResolution, error)) {
+	id, err := strconv.Atoi(mux.Vars(r)[idVar])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid %s", idVar), http.StatusBadRequest)
+		return
+	}
+	var req contract.UnmatchRequest
+	if err := request.ReadJSON(r, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	if !ok {
+		return
+	}
+
+	resolution, err := apply(r.Context(), jobID, id, req.Reason)
+	writeResolution(w, r, http.StatusOK, resolution, err)
+}
+
+// WriteOffHandler closes an unmatched item without a counterpart under a reason code
+func (h *ResolutionHandler) WriteOffHandler(w http.ResponseWriter, r *http.Request) {
+	var req contract.WriteOffRequest
+	if err := request.ReadJSON(r, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	if !ok {
+		return
+	}
+
+	resolution, err := h.reconcileUC.WriteOff(r.Context(), jobID, req.ItemType, req.ExceptionID, req.ReasonCode, req.Note)
+	writeResolution(w, r, http.StatusCreated, resolution, err)
+}
+
+// ProposalsHandler lists the proposals of the latest reconciliation of a workflow, oldest first, ?status= filters them
+func (h *ResolutionHandler) ProposalsHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	if !ok {
+		return
+	}
+
+	proposals, err := h.reconcileUC.ListProposals(r.Context(), jobID, r.URL.Query().Get("status"))
+	if errors.Is(err, reconcile.ErrInvalidResolution) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list proposals: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := contract.ProposalListResponse{Proposals: make([]contract.Proposal, 0, len(proposals))}
+	for _, p := range proposals {
+		resp.Proposals = append(resp.Proposals, toProposalContract(p))
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, resp)
+}
+
+// ApproveProposalHandler applies a pending proposal, the caller has to be another client than the one that proposed it
+func (h *ResolutionHandler) ApproveProposalHandler(w http.ResponseWriter, r *http.Request) {
+	proposalID, req, jobID, ok := h.decision(w, r)
+	if !ok {
+		return
+	}
+
+	resolution, err := h.reconcileUC.ApproveProposal(r.Context(), jobID, proposalID, req.Note)
+	writeResolution(w, r, http.StatusOK, resolution, err)
+}
+
+// RejectProposalHandler closes a pending proposal without applying it, the caller has to be another client than the one that proposed it
+func (h *ResolutionHandler) RejectProposalHandler(w http.ResponseWriter, r *http.Request) {
+	proposalID, req, jobID, ok := h.decision(w, r)
+	if !ok {
+		return
+	}
+
+	proposal, err := h.reconcileUC.RejectProposal(r.Context(), jobID, proposalID, req.Note)
+	if err != nil {
+		writeResolutionError(w, err)
+		return
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, toProposalContract(proposal))
+}
+
+func (h *ResolutionHandler) decision(w http.ResponseWriter, r *http.Request) (int, contract.DecisionRequest, string, bool) {
+	var req contract.DecisionRequest
+	proposalID, err := strconv.Atoi(mux.Vars(r)["proposalID"])
+	if err != nil {
+		http.Error(w, "Invalid proposalID", http.StatusBadRequest)
+		return 0, req, "", false
+	}
+	if err := request.ReadJSON(r, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return 0, req, "", false
+	}
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	return proposalID, req, jobID, ok
+}
+
+// AuditLogHandler lists the manual changes made to the latest reconciliation of a workflow, oldest first
+func (h *ResolutionHandler) AuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := reconciliationJobID(w, r, h.workflowUC, mux.Vars(r)["workflowID"])
+	if !ok {
+		return
+	}
+
+	entries, err := h.reconcileUC.GetAuditLog(r.Context(), jobID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get audit log: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := contract.AuditLogResponse{Entries: make([]contract.AuditLogEntry, 0, len(entries))}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, contract.AuditLogEntry{
+			ID:        entry.ID,
+			JobID:     entry.JobID,
+			Action:    entry.Action,
+			Actor:     entry.Actor,
+			Reason:    entry.Reason,
+			Details:   entry.Details,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+	response.WriteJSON(r.Context(), w, http.StatusOK, resp)
+}
+
+// writeResolution writes the outcome of a manual change, a change waiting for approval is answered with 202 and its proposal
+func writeResolution(w http.ResponseWriter, r *http.Request, status int, resolution domain.ManualResolution, err error) {
+	if err != nil {
+		writeResolutionError(w, err)
+		return
+	}
+	if resolution.Proposal != nil {
+		response.WriteJSON(r.Context(), w, http.StatusAccepted, toProposalContract(*resolution.Proposal))
+		return
+	}
+
+	result := resolution.Result
+	response.WriteJSON(r.Context(), w, status, contract.ManualResolutionResponse{
+		AuditID:    resolution.AuditID,
+		MatchID:    resolution.MatchID,
+		GroupID:    resolution.GroupID,
+		WriteOffID: resolution.WriteOffID,
+		Counts: contract.WorkflowCounts{
+			TotalSystemTransactions: result.TotalSystemTxCount,
+			TotalBankTransactions:   result.TotalBankTxCount,
+			Matched:                 result.MatchedCount,
+			MatchedGroups:           result.MatchedGroupCount,
+			UnmatchedSystem:         result.UnmatchedSystemCount,
+			UnmatchedBank:           result.UnmatchedBankCount,
+			TotalDiscrepancies:      result.TotalDiscrepancies,
+		},
+	})
+}
+
+func writeResolutionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, reconcile.ErrNoActor):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, reconcile.ErrSelfApproval):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, reconcile.ErrInvalidResolution):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, repository.ErrExceptionNotFound), errors.Is(err, repository.ErrMatchNotFound), errors.Is(err, repository.ErrProposalNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrProposalNotPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to apply manual change: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func toProposalContract(p domain.Proposal) contract.Proposal {
+	return contract.Proposal{
+		ID:                p.ID,
+		Action:            p.Action,
+		SystemExceptionID: p.SystemExceptionID,
+		BankExceptionIDs:  p.BankExceptionIDs,
+		ItemType:          p.ItemType,
+		ExceptionID:       p.ExceptionID,
+		ReasonCode:        p.ReasonCode,
+		Reason:            p.Reason,
+		Amount:            p.Amount,
+		Currency:          p.Amount.Currency,
+		Status:            p.Status,
+		ProposedBy:        p.ProposedBy,
+		DecidedBy:         p.DecidedBy,
+		DecisionNote:      p.DecisionNote,
+		CreatedAt:         p.CreatedAt,
+		DecidedAt:         p.DecidedAt,
+	}
+}
@@ -1,25 +1,35 @@
 package rest
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	enum_status "github.com/ardianferdianto/reconciliation-service/internal/domain/enum/status"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/parser"
 	"github.com/ardianferdianto/reconciliation-service/internal/usecase/reconcile"
 	"github.com/ardianferdianto/reconciliation-service/internal/usecase/workflow"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contextprop"
 	"github.com/ardianferdianto/reconciliation-service/pkg/contract"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
 	"github.com/ardianferdianto/reconciliation-service/pkg/request"
 	"github.com/ardianferdianto/reconciliation-service/pkg/response"
 	"github.com/gorilla/mux"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type WorkflowHandler struct {
-	workflowUC  workflow.IUseCase
-	reconcileUC reconcile.IUseCase
+	workflowUC          workflow.IUseCase
+	reconcileUC         reconcile.IUseCase
+	defaultMatchOptions domain.MatchOptions
 }
 
-func NewWorkflowHandler(workflowUC workflow.IUseCase, reconcileUC reconcile.IUseCase) *WorkflowHandler {
-	return &WorkflowHandler{workflowUC: workflowUC, reconcileUC: reconcileUC}
+func NewWorkflowHandler(workflowUC workflow.IUseCase, reconcileUC reconcile.IUseCase, defaultMatchOptions domain.MatchOptions) *WorkflowHandler {
+	return &WorkflowHandler{workflowUC: workflowUC, reconcileUC: reconcileUC, defaultMatchOptions: defaultMatchOptions}
 }
 
 func (h *WorkflowHandler) StartWorkflowHandler(w http.ResponseWriter, r *http.Request) {
@@ -38,22 +48,37 @@ func (h *WorkflowHandler) StartWorkflowHandler(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Start date must be before end date", http.StatusBadRequest)
 		return
 	}
+	matchOptions, err := validMatchOptions(req.MatchOptions(h.defaultMatchOptions))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validFileProfiles(req.FileProfiles, req.SystemTransactionFilePath, req.BankStatementFilePaths); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	workflowID, err := h.workflowUC.StartWorkflow(
-		context.Background(),
+		r.Context(),
 		req.SystemTransactionFilePath, // Bucket name for system transactions
 		req.BankStatementFilePaths,    // Assuming same bucket for bank statements
+		req.FileProfiles,
 		req.StartDate,
 		req.EndDate,
+		matchOptions,
 	)
 
+	if errors.Is(err, parser.ErrUnknownProfile) || errors.Is(err, parser.ErrInvalidProfile) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to start workflow: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	statusCode := http.StatusOK
-	responseBody := map[string]string{"workflow_id": workflowID}
+	statusCode := http.StatusAccepted
+	responseBody := contract.StartWorkflowResponse{WorkflowID: workflowID, Status: enum_status.PENDING.String()}
 	response.WriteJSON(r.Context(), w, statusCode, responseBody)
 }
 
@@ -63,6 +88,20 @@ func (h *WorkflowHandler) GetWorkflowSummary(w http.ResponseWriter, r *http.Requ
 
 	ctx := r.Context()
 	wf, err := h.workflowUC.GetWorkflowSummary(ctx, workflowID)
+	if errors.Is(err, repository.ErrWorkflowNotFound) {
+		http.Error(w, fmt.Sprintf("Workflow %s not found", workflowID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to retrieve workflow summary: %v", err), http.StatusInternalServerError)
+		return
+	}
+	transitions, err := h.workflowUC.GetWorkflowTransitions(ctx, workflowID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to retrieve workflow summary: %v", err), http.StatusInternalServerError)
+		return
+	}
+	runs, err := h.reconcileUC.GetWorkflowRuns(ctx, workflowID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to retrieve workflow summary: %v", err), http.StatusInternalServerError)
 		return
@@ -77,6 +116,10 @@ func (h *WorkflowHandler) GetWorkflowSummary(w http.ResponseWriter, r *http.Requ
 		Status:           wf.Status,
 		StartDate:        wf.StartDate,
 		EndDate:          wf.EndDate,
+		ErrorMessage:     wf.ErrorMessage,
+		SourceWorkflowID: wf.SourceWorkflowID,
+		Transitions:      transitions,
+		Runs:             reconciliationRuns(runs),
 		ReconcileSummary: &rec,
 	}
 
@@ -84,3 +127,293 @@ func (h *WorkflowHandler) GetWorkflowSummary(w http.ResponseWriter, r *http.Requ
 	statusCode := http.StatusOK
 	response.WriteJSON(r.Context(), w, statusCode, resp)
 }
+
+// ListWorkflowsHandler lists the workflows newest first. The filters are query parameters:
+// status (comma separated), start_date and end_date (period overlap), created_by ("me" for the caller),
+// bank_code and file_name, a page continues from the next_cursor of the previous one.
+func (h *WorkflowHandler) ListWorkflowsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	ctx := r.Context()
+
+	filter := domain.WorkflowFilter{
+		CreatedBy: query.Get("created_by"),
+		BankCode:  query.Get("bank_code"),
+		FileName:  query.Get("file_name"),
+	}
+	if filter.CreatedBy == "me" {
+		filter.CreatedBy = contextprop.GetValue(ctx, contextprop.ClientIDKey)
+	}
+	for _, status := range strings.Split(query.Get("status"), ",") {
+		status = strings.ToUpper(strings.TrimSpace(status))
+		if status == "" {
+			continue
+		}
+		if enum_status.FromString(status) == 0 {
+			http.Error(w, fmt.Sprintf("Unknown workflow status %q", status), http.StatusBadRequest)
+			return
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	var err error
+	if filter.From, err = queryDate(query.Get("start_date")); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid start_date: %v", err), http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = queryDate(query.Get("end_date")); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid end_date: %v", err), http.StatusBadRequest)
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := workflow.DecodeCursor(cursor)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.After = &after
+	}
+
+	page, err := h.workflowUC.ListWorkflows(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list workflows: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := contract.ListWorkflowsResponse{Workflows: make([]contract.WorkflowListEntry, 0, len(page.Items))}
+	for _, item := range page.Items {
+		resp.Workflows = append(resp.Workflows, workflowListEntry(item))
+	}
+	if page.Next != nil {
+		resp.NextCursor = workflow.EncodeCursor(*page.Next)
+	}
+	response.WriteJSON(ctx, w, http.StatusOK, resp)
+}
+
+// CancelWorkflowHandler stops a workflow that is still being ingested or reconciled
+func (h *WorkflowHandler) CancelWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	workflowID := mux.Vars(r)["workflowID"]
+
+	wf, err := h.workflowUC.CancelWorkflow(r.Context(), workflowID)
+	if errors.Is(err, repository.ErrWorkflowNotFound) {
+		http.Error(w, fmt.Sprintf("Workflow %s not found", workflowID), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, workflow.ErrWorkflowNotActive) {
+		http.Error(w, fmt.Sprintf("Workflow %s is %s and cannot be cancelled", workflowID, wf.Status), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to cancel workflow: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(r.Context(), w, http.StatusOK, contract.WorkflowStatusResponse{WorkflowID: wf.WorkflowID, Status: wf.Status})
+}
+
+// RerunWorkflowHandler reconciles the ingested files of a finished workflow again, optionally with other tolerances
+func (h *WorkflowHandler) RerunWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	workflowID := mux.Vars(r)["workflowID"]
+
+	var req contract.RerunWorkflowRequest
+	if r.ContentLength != 0 {
+		if err := request.ReadJSON(r, &req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	wf, err := h.workflowUC.GetWorkflowSummary(ctx, workflowID)
+	if errors.Is(err, repository.ErrWorkflowNotFound) {
+		http.Error(w, fmt.Sprintf("Workflow %s not found", workflowID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to re-run workflow: %v", err), http.StatusInternalServerError)
+		return
+	}
+	matchOptions, err := validMatchOptions(req.MatchOptions(wf.MatchOptions))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rerun, err := h.workflowUC.RerunWorkflow(ctx, workflowID, matchOptions)
+	if errors.Is(err, workflow.ErrWorkflowNotRerunnable) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to re-run workflow: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(ctx, w, http.StatusAccepted, contract.WorkflowStatusResponse{WorkflowID: rerun.WorkflowID, Status: rerun.Status})
+}
+
+// CloneWorkflowHandler starts a new workflow with the files, period and tolerances of an existing one,
+// any of them can be overridden in the request
+func (h *WorkflowHandler) CloneWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	workflowID := mux.Vars(r)["workflowID"]
+
+	var req contract.CloneWorkflowRequest
+	if r.ContentLength != 0 {
+		if err := request.ReadJSON(r, &req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	source, err := h.workflowUC.GetWorkflowSummary(ctx, workflowID)
+	if errors.Is(err, repository.ErrWorkflowNotFound) {
+		http.Error(w, fmt.Sprintf("Workflow %s not found", workflowID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to clone workflow: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	sysFile, bankFiles, startDate, endDate := source.SystemFile, source.BankFiles, source.StartDate, source.EndDate
+	if req.SystemTransactionFilePath != "" {
+		sysFile = req.SystemTransactionFilePath
+	}
+	if len(req.BankStatementFilePaths) > 0 {
+		bankFiles = req.BankStatementFilePaths
+	}
+	if req.StartDate != nil {
+		startDate = *req.StartDate
+	}
+	if req.EndDate != nil {
+		endDate = *req.EndDate
+	}
+	if sysFile == "" || len(bankFiles) == 0 {
+		http.Error(w, "Missing required file paths", http.StatusBadRequest)
+		return
+	}
+	if startDate.After(endDate) {
+		http.Error(w, "Start date must be before end date", http.StatusBadRequest)
+		return
+	}
+	matchOptions, err := validMatchOptions(req.MatchOptions(source.MatchOptions))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fileProfiles := req.FileProfiles
+	if fileProfiles == nil {
+		// the files kept from the source keep their profile
+		fileProfiles = make(map[string]string, len(source.FileProfiles))
+		for file, profile := range source.FileProfiles {
+			if file == sysFile || slices.Contains(bankFiles, file) {
+				fileProfiles[file] = profile
+			}
+		}
+	}
+	if err := validFileProfiles(fileProfiles, sysFile, bankFiles); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cloneID, err := h.workflowUC.CloneWorkflow(ctx, source.WorkflowID, sysFile, bankFiles, fileProfiles, startDate, endDate, matchOptions)
+	if errors.Is(err, parser.ErrUnknownProfile) || errors.Is(err, parser.ErrInvalidProfile) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to clone workflow: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteJSON(ctx, w, http.StatusAccepted, contract.CloneWorkflowResponse{
+		WorkflowID:       cloneID,
+		SourceWorkflowID: source.WorkflowID,
+		Status:           enum_status.PENDING.String(),
+	})
+}
+
+// validFileProfiles makes sure every file a profile is selected for is one of the workflow's files
+func validFileProfiles(fileProfiles map[string]string, sysFile string, bankFiles []string) error {
+	for file := range fileProfiles {
+		if file != sysFile && !slices.Contains(bankFiles, file) {
+			return fmt.Errorf("Parser profile selected for %s, which is not a file of the workflow", file)
+		}
+	}
+	return nil
+}
+
+// validMatchOptions rejects tolerances the matcher cannot work with and normalises the currency
+func validMatchOptions(opts domain.MatchOptions) (domain.MatchOptions, error) {
+	if opts.AmountTolerance < 0 || opts.AmountTolerancePercent < 0 || opts.DateWindowDays < 0 || opts.MaxGroupSize < 0 {
+		return opts, fmt.Errorf("Matching tolerances must not be negative")
+	}
+	switch opts.AssignmentMode {
+	case "", domain.AssignmentGreedy, domain.AssignmentOptimal:
+	default:
+		return opts, fmt.Errorf("Unknown assignment mode %q", opts.AssignmentMode)
+	}
+
+	if opts.Currency != "" {
+		currency, err := money.ParseCurrency(opts.Currency)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid reconciliation currency: %v", err)
+		}
+		opts.Currency = currency
+	}
+	return opts, nil
+}
+
+func reconciliationRuns(jobs []domain.ReconciliationJob) []contract.ReconciliationRun {
+	runs := make([]contract.ReconciliationRun, 0, len(jobs))
+	for _, job := range jobs {
+		runs = append(runs, contract.ReconciliationRun{JobID: job.JobID, CreatedAt: job.CreatedAt})
+	}
+	return runs
+}
+
+func workflowListEntry(item domain.WorkflowListItem) contract.WorkflowListEntry {
+	entry := contract.WorkflowListEntry{
+		WorkflowID:          item.WorkflowID,
+		Status:              item.Status,
+		StartDate:           item.StartDate,
+		EndDate:             item.EndDate,
+		SystemFile:          item.SystemFile,
+		BankFiles:           item.BankFiles,
+		CreatedBy:           item.CreatedBy,
+		SourceWorkflowID:    item.SourceWorkflowID,
+		ErrorMessage:        item.ErrorMessage,
+		ReconciliationJobID: item.ReconciliationJobID,
+		CreatedAt:           item.CreatedAt,
+		UpdatedAt:           item.UpdatedAt,
+	}
+	if item.Result != nil {
+		entry.Counts = &contract.WorkflowCounts{
+			TotalSystemTransactions: item.Result.TotalSystemTxCount,
+			TotalBankTransactions:   item.Result.TotalBankTxCount,
+			Matched:                 item.Result.MatchedCount,
+			MatchedGroups:           item.Result.MatchedGroupCount,
+			UnmatchedSystem:         item.Result.UnmatchedSystemCount,
+			UnmatchedBank:           item.Result.UnmatchedBankCount,
+			TotalDiscrepancies:      item.Result.TotalDiscrepancies,
+		}
+	}
+	return entry
+}
+
+// queryDate reads a date query parameter given as 2006-01-02 or RFC 3339, an empty one is the zero time
+func queryDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
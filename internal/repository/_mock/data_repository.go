@@ -65,31 +65,45 @@ func (mr *MockDataRepositoryMockRecorder) BatchInsertSystemTx(ctx, txList interf
 }
 
 // FindBankStmtsByDateRange mocks base method.
-func (m *MockDataRepository) FindBankStmtsByDateRange(ctx context.Context, startDate, endDate time.Time) ([]domain.BankStatement, error) {
+func (m *MockDataRepository) FindBankStmtsByDateRange(ctx context.Context, scope domain.DataScope, startDate, endDate time.Time) ([]domain.BankStatement, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindBankStmtsByDateRange", ctx, startDate, endDate)
+	ret := m.ctrl.Call(m, "FindBankStmtsByDateRange", ctx, scope, startDate, endDate)
 	ret0, _ := ret[0].([]domain.BankStatement)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindBankStmtsByDateRange indicates an expected call of FindBankStmtsByDateRange.
-func (mr *MockDataRepositoryMockRecorder) FindBankStmtsByDateRange(ctx, startDate, endDate interface{}) *gomock.Call {
+func (mr *MockDataRepositoryMockRecorder) FindBankStmtsByDateRange(ctx, scope, startDate, endDate interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBankStmtsByDateRange", reflect.TypeOf((*MockDataRepository)(nil).FindBankStmtsByDateRange), ctx, startDate, endDate)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBankStmtsByDateRange", reflect.TypeOf((*MockDataRepository)(nil).FindBankStmtsByDateRange), ctx, scope, startDate, endDate)
 }
 
 // FindSystemTxByDateRange mocks base method.
-func (m *MockDataRepository) FindSystemTxByDateRange(ctx context.Context, startDate, endDate time.Time) ([]domain.Transaction, error) {
+func (m *MockDataRepository) FindSystemTxByDateRange(ctx context.Context, scope domain.DataScope, startDate, endDate time.Time) ([]domain.Transaction, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindSystemTxByDateRange", ctx, startDate, endDate)
+	ret := m.ctrl.Call(m, "FindSystemTxByDateRange", ctx, scope, startDate, endDate)
 	ret0, _ := ret[0].([]domain.Transaction)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindSystemTxByDateRange indicates an expected call of FindSystemTxByDateRange.
-func (mr *MockDataRepositoryMockRecorder) FindSystemTxByDateRange(ctx, startDate, endDate interface{}) *gomock.Call {
+func (mr *MockDataRepositoryMockRecorder) FindSystemTxByDateRange(ctx, scope, startDate, endDate interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSystemTxByDateRange", reflect.TypeOf((*MockDataRepository)(nil).FindSystemTxByDateRange), ctx, startDate, endDate)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSystemTxByDateRange", reflect.TypeOf((*MockDataRepository)(nil).FindSystemTxByDateRange), ctx, scope, startDate, endDate)
+}
+
+// StoreStatementBalances mocks base method.
+func (m *MockDataRepository) StoreStatementBalances(ctx context.Context, balances []domain.StatementBalance) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreStatementBalances", ctx, balances)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreStatementBalances indicates an expected call of StoreStatementBalances.
+func (mr *MockDataRepositoryMockRecorder) StoreStatementBalances(ctx, balances interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreStatementBalances", reflect.TypeOf((*MockDataRepository)(nil).StoreStatementBalances), ctx, balances)
 }
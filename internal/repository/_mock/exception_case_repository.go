@@ -0,0 +1,155 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: exception_case_repository.go
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/ardianferdianto/reconciliation-service/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockExceptionCaseRepository is a mock of ExceptionCaseRepository interface.
+type MockExceptionCaseRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockExceptionCaseRepositoryMockRecorder
+}
+
+// MockExceptionCaseRepositoryMockRecorder is the mock recorder for MockExceptionCaseRepository.
+type MockExceptionCaseRepositoryMockRecorder struct {
+	mock *MockExceptionCaseRepository
+}
+
+// NewMockExceptionCaseRepository creates a new mock instance.
+func NewMockExceptionCaseRepository(ctrl *gomock.Controller) *MockExceptionCaseRepository {
+	mock := &MockExceptionCaseRepository{ctrl: ctrl}
+	mock.recorder = &MockExceptionCaseRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExceptionCaseRepository) EXPECT() *MockExceptionCaseRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddAttachment mocks base method.
+func (m *MockExceptionCaseRepository) AddAttachment(ctx context.Context, attachment domain.CaseAttachment) (domain.CaseAttachment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAttachment", ctx, attachment)
+	ret0, _ := ret[0].(domain.CaseAttachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddAttachment indicates an expected call of AddAttachment.
+func (mr *MockExceptionCaseRepositoryMockRecorder) AddAttachment(ctx, attachment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAttachment", reflect.TypeOf((*MockExceptionCaseRepository)(nil).AddAttachment), ctx, attachment)
+}
+
+// AddComment mocks base method.
+func (m *MockExceptionCaseRepository) AddComment(ctx context.Context, comment domain.CaseComment) (domain.CaseComment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddComment", ctx, comment)
+	ret0, _ := ret[0].(domain.CaseComment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddComment indicates an expected call of AddComment.
+func (mr *MockExceptionCaseRepositoryMockRecorder) AddComment(ctx, comment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddComment", reflect.TypeOf((*MockExceptionCaseRepository)(nil).AddComment), ctx, comment)
+}
+
+// GetAttachment mocks base method.
+func (m *MockExceptionCaseRepository) GetAttachment(ctx context.Context, caseID, attachmentID int) (domain.CaseAttachment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAttachment", ctx, caseID, attachmentID)
+	ret0, _ := ret[0].(domain.CaseAttachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAttachment indicates an expected call of GetAttachment.
+func (mr *MockExceptionCaseRepositoryMockRecorder) GetAttachment(ctx, caseID, attachmentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAttachment", reflect.TypeOf((*MockExceptionCaseRepository)(nil).GetAttachment), ctx, caseID, attachmentID)
+}
+
+// GetCase mocks base method.
+func (m *MockExceptionCaseRepository) GetCase(ctx context.Context, jobID string, caseID int, policy domain.CasePriorityPolicy) (domain.ExceptionCase, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCase", ctx, jobID, caseID, policy)
+	ret0, _ := ret[0].(domain.ExceptionCase)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCase indicates an expected call of GetCase.
+func (mr *MockExceptionCaseRepositoryMockRecorder) GetCase(ctx, jobID, caseID, policy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCase", reflect.TypeOf((*MockExceptionCaseRepository)(nil).GetCase), ctx, jobID, caseID, policy)
+}
+
+// ListAttachments mocks base method.
+func (m *MockExceptionCaseRepository) ListAttachments(ctx context.Context, caseID int) ([]domain.CaseAttachment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAttachments", ctx, caseID)
+	ret0, _ := ret[0].([]domain.CaseAttachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAttachments indicates an expected call of ListAttachments.
+func (mr *MockExceptionCaseRepositoryMockRecorder) ListAttachments(ctx, caseID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAttachments", reflect.TypeOf((*MockExceptionCaseRepository)(nil).ListAttachments), ctx, caseID)
+}
+
+// ListCases mocks base method.
+func (m *MockExceptionCaseRepository) ListCases(ctx context.Context, jobID string, filter domain.CaseFilter) ([]domain.ExceptionCase, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCases", ctx, jobID, filter)
+	ret0, _ := ret[0].([]domain.ExceptionCase)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCases indicates an expected call of ListCases.
+func (mr *MockExceptionCaseRepositoryMockRecorder) ListCases(ctx, jobID, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCases", reflect.TypeOf((*MockExceptionCaseRepository)(nil).ListCases), ctx, jobID, filter)
+}
+
+// ListComments mocks base method.
+func (m *MockExceptionCaseRepository) ListComments(ctx context.Context, caseID int) ([]domain.CaseComment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListComments", ctx, caseID)
+	ret0, _ := ret[0].([]domain.CaseComment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListComments indicates an expected call of ListComments.
+func (mr *MockExceptionCaseRepositoryMockRecorder) ListComments(ctx, caseID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListComments", reflect.TypeOf((*MockExceptionCaseRepository)(nil).ListComments), ctx, caseID)
+}
+
+// UpdateCase mocks base method.
+func (m *MockExceptionCaseRepository) UpdateCase(ctx context.Context, jobID string, caseID int, update domain.CaseUpdate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCase", ctx, jobID, caseID, update)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCase indicates an expected call of UpdateCase.
+func (mr *MockExceptionCaseRepositoryMockRecorder) UpdateCase(ctx, jobID, caseID, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCase", reflect.TypeOf((*MockExceptionCaseRepository)(nil).UpdateCase), ctx, jobID, caseID, update)
+}
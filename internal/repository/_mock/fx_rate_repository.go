@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: fx_rate_repository.go
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/ardianferdianto/reconciliation-service/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockFXRateRepository is a mock of FXRateRepository interface.
+type MockFXRateRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockFXRateRepositoryMockRecorder
+}
+
+// MockFXRateRepositoryMockRecorder is the mock recorder for MockFXRateRepository.
+type MockFXRateRepositoryMockRecorder struct {
+	mock *MockFXRateRepository
+}
+
+// NewMockFXRateRepository creates a new mock instance.
+func NewMockFXRateRepository(ctrl *gomock.Controller) *MockFXRateRepository {
+	mock := &MockFXRateRepository{ctrl: ctrl}
+	mock.recorder = &MockFXRateRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFXRateRepository) EXPECT() *MockFXRateRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindRatesByDateRange mocks base method.
+func (m *MockFXRateRepository) FindRatesByDateRange(ctx context.Context, startDate, endDate time.Time) ([]domain.FXRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRatesByDateRange", ctx, startDate, endDate)
+	ret0, _ := ret[0].([]domain.FXRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRatesByDateRange indicates an expected call of FindRatesByDateRange.
+func (mr *MockFXRateRepositoryMockRecorder) FindRatesByDateRange(ctx, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRatesByDateRange", reflect.TypeOf((*MockFXRateRepository)(nil).FindRatesByDateRange), ctx, startDate, endDate)
+}
+
+// UpsertRates mocks base method.
+func (m *MockFXRateRepository) UpsertRates(ctx context.Context, rates []domain.FXRate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertRates", ctx, rates)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertRates indicates an expected call of UpsertRates.
+func (mr *MockFXRateRepositoryMockRecorder) UpsertRates(ctx, rates interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertRates", reflect.TypeOf((*MockFXRateRepository)(nil).UpsertRates), ctx, rates)
+}
@@ -7,6 +7,7 @@ package mock_repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/ardianferdianto/reconciliation-service/internal/domain"
 	gomock "github.com/golang/mock/gomock"
@@ -35,6 +36,21 @@ func (m *MockIngestionJobRepository) EXPECT() *MockIngestionJobRepositoryMockRec
 	return m.recorder
 }
 
+// ClaimPendingJobs mocks base method.
+func (m *MockIngestionJobRepository) ClaimPendingJobs(ctx context.Context, workerID string, limit int, lease time.Duration) ([]domain.IngestionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimPendingJobs", ctx, workerID, limit, lease)
+	ret0, _ := ret[0].([]domain.IngestionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimPendingJobs indicates an expected call of ClaimPendingJobs.
+func (mr *MockIngestionJobRepositoryMockRecorder) ClaimPendingJobs(ctx, workerID, limit, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimPendingJobs", reflect.TypeOf((*MockIngestionJobRepository)(nil).ClaimPendingJobs), ctx, workerID, limit, lease)
+}
+
 // CreateJob mocks base method.
 func (m *MockIngestionJobRepository) CreateJob(ctx context.Context, job *domain.IngestionJob) error {
 	m.ctrl.T.Helper()
@@ -49,45 +65,105 @@ func (mr *MockIngestionJobRepositoryMockRecorder) CreateJob(ctx, job interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockIngestionJobRepository)(nil).CreateJob), ctx, job)
 }
 
-// ListPendingJobs mocks base method.
-func (m *MockIngestionJobRepository) ListPendingJobs(ctx context.Context, limit int) ([]domain.IngestionJob, error) {
+// DeadLetterJob mocks base method.
+func (m *MockIngestionJobRepository) DeadLetterJob(ctx context.Context, jobID, workerID, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeadLetterJob", ctx, jobID, workerID, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeadLetterJob indicates an expected call of DeadLetterJob.
+func (mr *MockIngestionJobRepositoryMockRecorder) DeadLetterJob(ctx, jobID, workerID, lastError interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeadLetterJob", reflect.TypeOf((*MockIngestionJobRepository)(nil).DeadLetterJob), ctx, jobID, workerID, lastError)
+}
+
+// HeartbeatJob mocks base method.
+func (m *MockIngestionJobRepository) HeartbeatJob(ctx context.Context, jobID, workerID string, lease time.Duration) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListPendingJobs", ctx, limit)
+	ret := m.ctrl.Call(m, "HeartbeatJob", ctx, jobID, workerID, lease)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HeartbeatJob indicates an expected call of HeartbeatJob.
+func (mr *MockIngestionJobRepositoryMockRecorder) HeartbeatJob(ctx, jobID, workerID, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HeartbeatJob", reflect.TypeOf((*MockIngestionJobRepository)(nil).HeartbeatJob), ctx, jobID, workerID, lease)
+}
+
+// ListJobsByWorkflow mocks base method.
+func (m *MockIngestionJobRepository) ListJobsByWorkflow(ctx context.Context, workflowID string) ([]domain.IngestionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListJobsByWorkflow", ctx, workflowID)
 	ret0, _ := ret[0].([]domain.IngestionJob)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListPendingJobs indicates an expected call of ListPendingJobs.
-func (mr *MockIngestionJobRepositoryMockRecorder) ListPendingJobs(ctx, limit interface{}) *gomock.Call {
+// ListJobsByWorkflow indicates an expected call of ListJobsByWorkflow.
+func (mr *MockIngestionJobRepositoryMockRecorder) ListJobsByWorkflow(ctx, workflowID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJobsByWorkflow", reflect.TypeOf((*MockIngestionJobRepository)(nil).ListJobsByWorkflow), ctx, workflowID)
+}
+
+// ReapExpiredLeases mocks base method.
+func (m *MockIngestionJobRepository) ReapExpiredLeases(ctx context.Context, maxAttempts int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReapExpiredLeases", ctx, maxAttempts)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReapExpiredLeases indicates an expected call of ReapExpiredLeases.
+func (mr *MockIngestionJobRepositoryMockRecorder) ReapExpiredLeases(ctx, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReapExpiredLeases", reflect.TypeOf((*MockIngestionJobRepository)(nil).ReapExpiredLeases), ctx, maxAttempts)
+}
+
+// RequeueDeadLetterJob mocks base method.
+func (m *MockIngestionJobRepository) RequeueDeadLetterJob(ctx context.Context, jobID string) (domain.IngestionJob, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueDeadLetterJob", ctx, jobID)
+	ret0, _ := ret[0].(domain.IngestionJob)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RequeueDeadLetterJob indicates an expected call of RequeueDeadLetterJob.
+func (mr *MockIngestionJobRepositoryMockRecorder) RequeueDeadLetterJob(ctx, jobID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPendingJobs", reflect.TypeOf((*MockIngestionJobRepository)(nil).ListPendingJobs), ctx, limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueDeadLetterJob", reflect.TypeOf((*MockIngestionJobRepository)(nil).RequeueDeadLetterJob), ctx, jobID)
 }
 
-// MarkJobInProgress mocks base method.
-func (m *MockIngestionJobRepository) MarkJobInProgress(ctx context.Context, jobID string) error {
+// RetryJob mocks base method.
+func (m *MockIngestionJobRepository) RetryJob(ctx context.Context, jobID, workerID, lastError string, nextAttemptAt time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MarkJobInProgress", ctx, jobID)
+	ret := m.ctrl.Call(m, "RetryJob", ctx, jobID, workerID, lastError, nextAttemptAt)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// MarkJobInProgress indicates an expected call of MarkJobInProgress.
-func (mr *MockIngestionJobRepositoryMockRecorder) MarkJobInProgress(ctx, jobID interface{}) *gomock.Call {
+// RetryJob indicates an expected call of RetryJob.
+func (mr *MockIngestionJobRepositoryMockRecorder) RetryJob(ctx, jobID, workerID, lastError, nextAttemptAt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkJobInProgress", reflect.TypeOf((*MockIngestionJobRepository)(nil).MarkJobInProgress), ctx, jobID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryJob", reflect.TypeOf((*MockIngestionJobRepository)(nil).RetryJob), ctx, jobID, workerID, lastError, nextAttemptAt)
 }
 
 // UpdateJobProgress mocks base method.
-func (m *MockIngestionJobRepository) UpdateJobProgress(ctx context.Context, jobID string, linesProcessed int64, status string) error {
+func (m *MockIngestionJobRepository) UpdateJobProgress(ctx context.Context, jobID, workerID string, linesProcessed int64, status string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateJobProgress", ctx, jobID, linesProcessed, status)
+	ret := m.ctrl.Call(m, "UpdateJobProgress", ctx, jobID, workerID, linesProcessed, status)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateJobProgress indicates an expected call of UpdateJobProgress.
-func (mr *MockIngestionJobRepositoryMockRecorder) UpdateJobProgress(ctx, jobID, linesProcessed, status interface{}) *gomock.Call {
+func (mr *MockIngestionJobRepositoryMockRecorder) UpdateJobProgress(ctx, jobID, workerID, linesProcessed, status interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJobProgress", reflect.TypeOf((*MockIngestionJobRepository)(nil).UpdateJobProgress), ctx, jobID, linesProcessed, status)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJobProgress", reflect.TypeOf((*MockIngestionJobRepository)(nil).UpdateJobProgress), ctx, jobID, workerID, linesProcessed, status)
 }
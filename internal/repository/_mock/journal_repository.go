@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: journal_repository.go
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/ardianferdianto/reconciliation-service/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockJournalRepository is a mock of JournalRepository interface.
+type MockJournalRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockJournalRepositoryMockRecorder
+}
+
+// MockJournalRepositoryMockRecorder is the mock recorder for MockJournalRepository.
+type MockJournalRepositoryMockRecorder struct {
+	mock *MockJournalRepository
+}
+
+// NewMockJournalRepository creates a new mock instance.
+func NewMockJournalRepository(ctrl *gomock.Controller) *MockJournalRepository {
+	mock := &MockJournalRepository{ctrl: ctrl}
+	mock.recorder = &MockJournalRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJournalRepository) EXPECT() *MockJournalRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ListJournalEntries mocks base method.
+func (m *MockJournalRepository) ListJournalEntries(ctx context.Context, jobID string) ([]domain.JournalEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListJournalEntries", ctx, jobID)
+	ret0, _ := ret[0].([]domain.JournalEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListJournalEntries indicates an expected call of ListJournalEntries.
+func (mr *MockJournalRepositoryMockRecorder) ListJournalEntries(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJournalEntries", reflect.TypeOf((*MockJournalRepository)(nil).ListJournalEntries), ctx, jobID)
+}
+
+// ListJournalSources mocks base method.
+func (m *MockJournalRepository) ListJournalSources(ctx context.Context, jobID string) ([]domain.JournalSource, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListJournalSources", ctx, jobID)
+	ret0, _ := ret[0].([]domain.JournalSource)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListJournalSources indicates an expected call of ListJournalSources.
+func (mr *MockJournalRepositoryMockRecorder) ListJournalSources(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJournalSources", reflect.TypeOf((*MockJournalRepository)(nil).ListJournalSources), ctx, jobID)
+}
+
+// ReplaceJournalEntries mocks base method.
+func (m *MockJournalRepository) ReplaceJournalEntries(ctx context.Context, jobID string, entries []domain.JournalEntry) ([]domain.JournalEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceJournalEntries", ctx, jobID, entries)
+	ret0, _ := ret[0].([]domain.JournalEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplaceJournalEntries indicates an expected call of ReplaceJournalEntries.
+func (mr *MockJournalRepositoryMockRecorder) ReplaceJournalEntries(ctx, jobID, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceJournalEntries", reflect.TypeOf((*MockJournalRepository)(nil).ReplaceJournalEntries), ctx, jobID, entries)
+}
@@ -0,0 +1,140 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: open_item_repository.go
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/ardianferdianto/reconciliation-service/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOpenItemRepository is a mock of OpenItemRepository interface.
+type MockOpenItemRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOpenItemRepositoryMockRecorder
+}
+
+// MockOpenItemRepositoryMockRecorder is the mock recorder for MockOpenItemRepository.
+type MockOpenItemRepositoryMockRecorder struct {
+	mock *MockOpenItemRepository
+}
+
+// NewMockOpenItemRepository creates a new mock instance.
+func NewMockOpenItemRepository(ctrl *gomock.Controller) *MockOpenItemRepository {
+	mock := &MockOpenItemRepository{ctrl: ctrl}
+	mock.recorder = &MockOpenItemRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOpenItemRepository) EXPECT() *MockOpenItemRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CountResolvedOpenItems mocks base method.
+func (m *MockOpenItemRepository) CountResolvedOpenItems(ctx context.Context, jobID string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountResolvedOpenItems", ctx, jobID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountResolvedOpenItems indicates an expected call of CountResolvedOpenItems.
+func (mr *MockOpenItemRepositoryMockRecorder) CountResolvedOpenItems(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountResolvedOpenItems", reflect.TypeOf((*MockOpenItemRepository)(nil).CountResolvedOpenItems), ctx, jobID)
+}
+
+// FindOpenBankStmts mocks base method.
+func (m *MockOpenItemRepository) FindOpenBankStmts(ctx context.Context, scope domain.DataScope, until time.Time) ([]domain.BankStatement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOpenBankStmts", ctx, scope, until)
+	ret0, _ := ret[0].([]domain.BankStatement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOpenBankStmts indicates an expected call of FindOpenBankStmts.
+func (mr *MockOpenItemRepositoryMockRecorder) FindOpenBankStmts(ctx, scope, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOpenBankStmts", reflect.TypeOf((*MockOpenItemRepository)(nil).FindOpenBankStmts), ctx, scope, until)
+}
+
+// FindOpenSystemTx mocks base method.
+func (m *MockOpenItemRepository) FindOpenSystemTx(ctx context.Context, scope domain.DataScope, until time.Time) ([]domain.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOpenSystemTx", ctx, scope, until)
+	ret0, _ := ret[0].([]domain.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOpenSystemTx indicates an expected call of FindOpenSystemTx.
+func (mr *MockOpenItemRepositoryMockRecorder) FindOpenSystemTx(ctx, scope, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOpenSystemTx", reflect.TypeOf((*MockOpenItemRepository)(nil).FindOpenSystemTx), ctx, scope, until)
+}
+
+// GetOpenItems mocks base method.
+func (m *MockOpenItemRepository) GetOpenItems(ctx context.Context, jobID string) ([]domain.OpenItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOpenItems", ctx, jobID)
+	ret0, _ := ret[0].([]domain.OpenItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOpenItems indicates an expected call of GetOpenItems.
+func (mr *MockOpenItemRepositoryMockRecorder) GetOpenItems(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOpenItems", reflect.TypeOf((*MockOpenItemRepository)(nil).GetOpenItems), ctx, jobID)
+}
+
+// ReopenWorkflowItems mocks base method.
+func (m *MockOpenItemRepository) ReopenWorkflowItems(ctx context.Context, workflowID, jobID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReopenWorkflowItems", ctx, workflowID, jobID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReopenWorkflowItems indicates an expected call of ReopenWorkflowItems.
+func (mr *MockOpenItemRepositoryMockRecorder) ReopenWorkflowItems(ctx, workflowID, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReopenWorkflowItems", reflect.TypeOf((*MockOpenItemRepository)(nil).ReopenWorkflowItems), ctx, workflowID, jobID)
+}
+
+// ResolveOpenItems mocks base method.
+func (m *MockOpenItemRepository) ResolveOpenItems(ctx context.Context, jobID string, systemTxIDs, bankStatementIDs []int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveOpenItems", ctx, jobID, systemTxIDs, bankStatementIDs)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveOpenItems indicates an expected call of ResolveOpenItems.
+func (mr *MockOpenItemRepositoryMockRecorder) ResolveOpenItems(ctx, jobID, systemTxIDs, bankStatementIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveOpenItems", reflect.TypeOf((*MockOpenItemRepository)(nil).ResolveOpenItems), ctx, jobID, systemTxIDs, bankStatementIDs)
+}
+
+// StoreOpenItems mocks base method.
+func (m *MockOpenItemRepository) StoreOpenItems(ctx context.Context, items []domain.OpenItem) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreOpenItems", ctx, items)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreOpenItems indicates an expected call of StoreOpenItems.
+func (mr *MockOpenItemRepositoryMockRecorder) StoreOpenItems(ctx, items interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreOpenItems", reflect.TypeOf((*MockOpenItemRepository)(nil).StoreOpenItems), ctx, items)
+}
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: parser_profile_repository.go
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/ardianferdianto/reconciliation-service/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockParserProfileRepository is a mock of ParserProfileRepository interface.
+type MockParserProfileRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockParserProfileRepositoryMockRecorder
+}
+
+// MockParserProfileRepositoryMockRecorder is the mock recorder for MockParserProfileRepository.
+type MockParserProfileRepositoryMockRecorder struct {
+	mock *MockParserProfileRepository
+}
+
+// NewMockParserProfileRepository creates a new mock instance.
+func NewMockParserProfileRepository(ctrl *gomock.Controller) *MockParserProfileRepository {
+	mock := &MockParserProfileRepository{ctrl: ctrl}
+	mock.recorder = &MockParserProfileRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockParserProfileRepository) EXPECT() *MockParserProfileRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetParserProfile mocks base method.
+func (m *MockParserProfileRepository) GetParserProfile(ctx context.Context, name string) (domain.ParserProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetParserProfile", ctx, name)
+	ret0, _ := ret[0].(domain.ParserProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetParserProfile indicates an expected call of GetParserProfile.
+func (mr *MockParserProfileRepositoryMockRecorder) GetParserProfile(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetParserProfile", reflect.TypeOf((*MockParserProfileRepository)(nil).GetParserProfile), ctx, name)
+}
+
+// ListParserProfiles mocks base method.
+func (m *MockParserProfileRepository) ListParserProfiles(ctx context.Context) ([]domain.ParserProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListParserProfiles", ctx)
+	ret0, _ := ret[0].([]domain.ParserProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListParserProfiles indicates an expected call of ListParserProfiles.
+func (mr *MockParserProfileRepositoryMockRecorder) ListParserProfiles(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListParserProfiles", reflect.TypeOf((*MockParserProfileRepository)(nil).ListParserProfiles), ctx)
+}
+
+// SaveParserProfile mocks base method.
+func (m *MockParserProfileRepository) SaveParserProfile(ctx context.Context, profile domain.ParserProfile) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveParserProfile", ctx, profile)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveParserProfile indicates an expected call of SaveParserProfile.
+func (mr *MockParserProfileRepositoryMockRecorder) SaveParserProfile(ctx, profile interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveParserProfile", reflect.TypeOf((*MockParserProfileRepository)(nil).SaveParserProfile), ctx, profile)
+}
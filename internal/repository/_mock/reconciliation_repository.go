@@ -35,6 +35,66 @@ func (m *MockReconciliationRepository) EXPECT() *MockReconciliationRepositoryMoc
 	return m.recorder
 }
 
+// ApplyManualMatch mocks base method.
+func (m_2 *MockReconciliationRepository) ApplyManualMatch(ctx context.Context, m domain.ManualMatch) (domain.ManualResolution, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "ApplyManualMatch", ctx, m)
+	ret0, _ := ret[0].(domain.ManualResolution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyManualMatch indicates an expected call of ApplyManualMatch.
+func (mr *MockReconciliationRepositoryMockRecorder) ApplyManualMatch(ctx, m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyManualMatch", reflect.TypeOf((*MockReconciliationRepository)(nil).ApplyManualMatch), ctx, m)
+}
+
+// ApplyUnmatch mocks base method.
+func (m *MockReconciliationRepository) ApplyUnmatch(ctx context.Context, u domain.Unmatch) (domain.ManualResolution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyUnmatch", ctx, u)
+	ret0, _ := ret[0].(domain.ManualResolution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyUnmatch indicates an expected call of ApplyUnmatch.
+func (mr *MockReconciliationRepositoryMockRecorder) ApplyUnmatch(ctx, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyUnmatch", reflect.TypeOf((*MockReconciliationRepository)(nil).ApplyUnmatch), ctx, u)
+}
+
+// ApplyWriteOff mocks base method.
+func (m *MockReconciliationRepository) ApplyWriteOff(ctx context.Context, w domain.WriteOff) (domain.ManualResolution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyWriteOff", ctx, w)
+	ret0, _ := ret[0].(domain.ManualResolution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyWriteOff indicates an expected call of ApplyWriteOff.
+func (mr *MockReconciliationRepositoryMockRecorder) ApplyWriteOff(ctx, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyWriteOff", reflect.TypeOf((*MockReconciliationRepository)(nil).ApplyWriteOff), ctx, w)
+}
+
+// CountUnmatchedBankByBank mocks base method.
+func (m *MockReconciliationRepository) CountUnmatchedBankByBank(ctx context.Context, jobID string) ([]domain.BankExceptionCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUnmatchedBankByBank", ctx, jobID)
+	ret0, _ := ret[0].([]domain.BankExceptionCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUnmatchedBankByBank indicates an expected call of CountUnmatchedBankByBank.
+func (mr *MockReconciliationRepositoryMockRecorder) CountUnmatchedBankByBank(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUnmatchedBankByBank", reflect.TypeOf((*MockReconciliationRepository)(nil).CountUnmatchedBankByBank), ctx, jobID)
+}
+
 // CreateJob mocks base method.
 func (m *MockReconciliationRepository) CreateJob(ctx context.Context, job domain.ReconciliationJob) error {
 	m.ctrl.T.Helper()
@@ -49,6 +109,221 @@ func (mr *MockReconciliationRepositoryMockRecorder) CreateJob(ctx, job interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockReconciliationRepository)(nil).CreateJob), ctx, job)
 }
 
+// CreateProposal mocks base method.
+func (m *MockReconciliationRepository) CreateProposal(ctx context.Context, p domain.Proposal) (domain.Proposal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProposal", ctx, p)
+	ret0, _ := ret[0].(domain.Proposal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateProposal indicates an expected call of CreateProposal.
+func (mr *MockReconciliationRepositoryMockRecorder) CreateProposal(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProposal", reflect.TypeOf((*MockReconciliationRepository)(nil).CreateProposal), ctx, p)
+}
+
+// GetProposal mocks base method.
+func (m *MockReconciliationRepository) GetProposal(ctx context.Context, jobID string, proposalID int) (domain.Proposal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProposal", ctx, jobID, proposalID)
+	ret0, _ := ret[0].(domain.Proposal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProposal indicates an expected call of GetProposal.
+func (mr *MockReconciliationRepositoryMockRecorder) GetProposal(ctx, jobID, proposalID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProposal", reflect.TypeOf((*MockReconciliationRepository)(nil).GetProposal), ctx, jobID, proposalID)
+}
+
+// GetReconciliationResult mocks base method.
+func (m *MockReconciliationRepository) GetReconciliationResult(ctx context.Context, jobID string) (*domain.ReconciliationResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReconciliationResult", ctx, jobID)
+	ret0, _ := ret[0].(*domain.ReconciliationResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReconciliationResult indicates an expected call of GetReconciliationResult.
+func (mr *MockReconciliationRepositoryMockRecorder) GetReconciliationResult(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReconciliationResult", reflect.TypeOf((*MockReconciliationRepository)(nil).GetReconciliationResult), ctx, jobID)
+}
+
+// GetUnmatchedItems mocks base method.
+func (m *MockReconciliationRepository) GetUnmatchedItems(ctx context.Context, jobID string, systemIDs, bankIDs []int) ([]domain.UnmatchedSystemTx, []domain.UnmatchedBankTx, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUnmatchedItems", ctx, jobID, systemIDs, bankIDs)
+	ret0, _ := ret[0].([]domain.UnmatchedSystemTx)
+	ret1, _ := ret[1].([]domain.UnmatchedBankTx)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetUnmatchedItems indicates an expected call of GetUnmatchedItems.
+func (mr *MockReconciliationRepositoryMockRecorder) GetUnmatchedItems(ctx, jobID, systemIDs, bankIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnmatchedItems", reflect.TypeOf((*MockReconciliationRepository)(nil).GetUnmatchedItems), ctx, jobID, systemIDs, bankIDs)
+}
+
+// ListAccountActivity mocks base method.
+func (m *MockReconciliationRepository) ListAccountActivity(ctx context.Context, jobID string) ([]domain.AccountActivity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountActivity", ctx, jobID)
+	ret0, _ := ret[0].([]domain.AccountActivity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountActivity indicates an expected call of ListAccountActivity.
+func (mr *MockReconciliationRepositoryMockRecorder) ListAccountActivity(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountActivity", reflect.TypeOf((*MockReconciliationRepository)(nil).ListAccountActivity), ctx, jobID)
+}
+
+// ListAuditLog mocks base method.
+func (m *MockReconciliationRepository) ListAuditLog(ctx context.Context, jobID string) ([]domain.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditLog", ctx, jobID)
+	ret0, _ := ret[0].([]domain.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditLog indicates an expected call of ListAuditLog.
+func (mr *MockReconciliationRepositoryMockRecorder) ListAuditLog(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditLog", reflect.TypeOf((*MockReconciliationRepository)(nil).ListAuditLog), ctx, jobID)
+}
+
+// ListJobsByWorkflow mocks base method.
+func (m *MockReconciliationRepository) ListJobsByWorkflow(ctx context.Context, workflowID string) ([]domain.ReconciliationJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListJobsByWorkflow", ctx, workflowID)
+	ret0, _ := ret[0].([]domain.ReconciliationJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListJobsByWorkflow indicates an expected call of ListJobsByWorkflow.
+func (mr *MockReconciliationRepositoryMockRecorder) ListJobsByWorkflow(ctx, workflowID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJobsByWorkflow", reflect.TypeOf((*MockReconciliationRepository)(nil).ListJobsByWorkflow), ctx, workflowID)
+}
+
+// ListMatchGroups mocks base method.
+func (m *MockReconciliationRepository) ListMatchGroups(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchGroup, *domain.ExceptionCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMatchGroups", ctx, jobID, q)
+	ret0, _ := ret[0].([]domain.MatchGroup)
+	ret1, _ := ret[1].(*domain.ExceptionCursor)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListMatchGroups indicates an expected call of ListMatchGroups.
+func (mr *MockReconciliationRepositoryMockRecorder) ListMatchGroups(ctx, jobID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMatchGroups", reflect.TypeOf((*MockReconciliationRepository)(nil).ListMatchGroups), ctx, jobID, q)
+}
+
+// ListMatchedPairs mocks base method.
+func (m *MockReconciliationRepository) ListMatchedPairs(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchedPair, *domain.ExceptionCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMatchedPairs", ctx, jobID, q)
+	ret0, _ := ret[0].([]domain.MatchedPair)
+	ret1, _ := ret[1].(*domain.ExceptionCursor)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListMatchedPairs indicates an expected call of ListMatchedPairs.
+func (mr *MockReconciliationRepositoryMockRecorder) ListMatchedPairs(ctx, jobID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMatchedPairs", reflect.TypeOf((*MockReconciliationRepository)(nil).ListMatchedPairs), ctx, jobID, q)
+}
+
+// ListProposals mocks base method.
+func (m *MockReconciliationRepository) ListProposals(ctx context.Context, jobID, status string) ([]domain.Proposal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListProposals", ctx, jobID, status)
+	ret0, _ := ret[0].([]domain.Proposal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListProposals indicates an expected call of ListProposals.
+func (mr *MockReconciliationRepositoryMockRecorder) ListProposals(ctx, jobID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListProposals", reflect.TypeOf((*MockReconciliationRepository)(nil).ListProposals), ctx, jobID, status)
+}
+
+// ListUnmatchedBankTx mocks base method.
+func (m *MockReconciliationRepository) ListUnmatchedBankTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedBankTx, *domain.ExceptionCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnmatchedBankTx", ctx, jobID, q)
+	ret0, _ := ret[0].([]domain.UnmatchedBankTx)
+	ret1, _ := ret[1].(*domain.ExceptionCursor)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListUnmatchedBankTx indicates an expected call of ListUnmatchedBankTx.
+func (mr *MockReconciliationRepositoryMockRecorder) ListUnmatchedBankTx(ctx, jobID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnmatchedBankTx", reflect.TypeOf((*MockReconciliationRepository)(nil).ListUnmatchedBankTx), ctx, jobID, q)
+}
+
+// ListUnmatchedSystemTx mocks base method.
+func (m *MockReconciliationRepository) ListUnmatchedSystemTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedSystemTx, *domain.ExceptionCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnmatchedSystemTx", ctx, jobID, q)
+	ret0, _ := ret[0].([]domain.UnmatchedSystemTx)
+	ret1, _ := ret[1].(*domain.ExceptionCursor)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListUnmatchedSystemTx indicates an expected call of ListUnmatchedSystemTx.
+func (mr *MockReconciliationRepositoryMockRecorder) ListUnmatchedSystemTx(ctx, jobID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnmatchedSystemTx", reflect.TypeOf((*MockReconciliationRepository)(nil).ListUnmatchedSystemTx), ctx, jobID, q)
+}
+
+// RejectProposal mocks base method.
+func (m *MockReconciliationRepository) RejectProposal(ctx context.Context, d domain.ProposalDecision) (domain.Proposal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectProposal", ctx, d)
+	ret0, _ := ret[0].(domain.Proposal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectProposal indicates an expected call of RejectProposal.
+func (mr *MockReconciliationRepositoryMockRecorder) RejectProposal(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectProposal", reflect.TypeOf((*MockReconciliationRepository)(nil).RejectProposal), ctx, d)
+}
+
+// StoreMatchGroup mocks base method.
+func (m *MockReconciliationRepository) StoreMatchGroup(ctx context.Context, group domain.MatchGroup) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreMatchGroup", ctx, group)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StoreMatchGroup indicates an expected call of StoreMatchGroup.
+func (mr *MockReconciliationRepositoryMockRecorder) StoreMatchGroup(ctx, group interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreMatchGroup", reflect.TypeOf((*MockReconciliationRepository)(nil).StoreMatchGroup), ctx, group)
+}
+
 // StoreMatchedRecord mocks base method.
 func (m *MockReconciliationRepository) StoreMatchedRecord(ctx context.Context, rec domain.MatchedRecord) (int, error) {
 	m.ctrl.T.Helper()
@@ -106,3 +381,17 @@ func (mr *MockReconciliationRepositoryMockRecorder) StoreUnmatchedSystemTx(ctx,
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreUnmatchedSystemTx", reflect.TypeOf((*MockReconciliationRepository)(nil).StoreUnmatchedSystemTx), ctx, txList)
 }
+
+// WithinTx mocks base method.
+func (m *MockReconciliationRepository) WithinTx(ctx context.Context, fn func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithinTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithinTx indicates an expected call of WithinTx.
+func (mr *MockReconciliationRepositoryMockRecorder) WithinTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTx", reflect.TypeOf((*MockReconciliationRepository)(nil).WithinTx), ctx, fn)
+}
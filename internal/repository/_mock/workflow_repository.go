@@ -0,0 +1,185 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: workflow_repository.go
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/ardianferdianto/reconciliation-service/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockWorkflowRepository is a mock of WorkflowRepository interface.
+type MockWorkflowRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkflowRepositoryMockRecorder
+}
+
+// MockWorkflowRepositoryMockRecorder is the mock recorder for MockWorkflowRepository.
+type MockWorkflowRepositoryMockRecorder struct {
+	mock *MockWorkflowRepository
+}
+
+// NewMockWorkflowRepository creates a new mock instance.
+func NewMockWorkflowRepository(ctrl *gomock.Controller) *MockWorkflowRepository {
+	mock := &MockWorkflowRepository{ctrl: ctrl}
+	mock.recorder = &MockWorkflowRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWorkflowRepository) EXPECT() *MockWorkflowRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CancelWorkflow mocks base method.
+func (m *MockWorkflowRepository) CancelWorkflow(ctx context.Context, workflowID, reason string) (domain.Workflow, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelWorkflow", ctx, workflowID, reason)
+	ret0, _ := ret[0].(domain.Workflow)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CancelWorkflow indicates an expected call of CancelWorkflow.
+func (mr *MockWorkflowRepositoryMockRecorder) CancelWorkflow(ctx, workflowID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWorkflow", reflect.TypeOf((*MockWorkflowRepository)(nil).CancelWorkflow), ctx, workflowID, reason)
+}
+
+// ClaimActiveWorkflows mocks base method.
+func (m *MockWorkflowRepository) ClaimActiveWorkflows(ctx context.Context, workerID string, limit int, lease time.Duration) ([]domain.Workflow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimActiveWorkflows", ctx, workerID, limit, lease)
+	ret0, _ := ret[0].([]domain.Workflow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimActiveWorkflows indicates an expected call of ClaimActiveWorkflows.
+func (mr *MockWorkflowRepositoryMockRecorder) ClaimActiveWorkflows(ctx, workerID, limit, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimActiveWorkflows", reflect.TypeOf((*MockWorkflowRepository)(nil).ClaimActiveWorkflows), ctx, workerID, limit, lease)
+}
+
+// CreateWorkflow mocks base method.
+func (m *MockWorkflowRepository) CreateWorkflow(ctx context.Context, wf domain.Workflow) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWorkflow", ctx, wf)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWorkflow indicates an expected call of CreateWorkflow.
+func (mr *MockWorkflowRepositoryMockRecorder) CreateWorkflow(ctx, wf interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWorkflow", reflect.TypeOf((*MockWorkflowRepository)(nil).CreateWorkflow), ctx, wf)
+}
+
+// GetTransitions mocks base method.
+func (m *MockWorkflowRepository) GetTransitions(ctx context.Context, workflowID string) ([]domain.WorkflowTransition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransitions", ctx, workflowID)
+	ret0, _ := ret[0].([]domain.WorkflowTransition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransitions indicates an expected call of GetTransitions.
+func (mr *MockWorkflowRepositoryMockRecorder) GetTransitions(ctx, workflowID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransitions", reflect.TypeOf((*MockWorkflowRepository)(nil).GetTransitions), ctx, workflowID)
+}
+
+// GetWorkflow mocks base method.
+func (m *MockWorkflowRepository) GetWorkflow(ctx context.Context, workflowID string) (domain.Workflow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkflow", ctx, workflowID)
+	ret0, _ := ret[0].(domain.Workflow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorkflow indicates an expected call of GetWorkflow.
+func (mr *MockWorkflowRepositoryMockRecorder) GetWorkflow(ctx, workflowID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkflow", reflect.TypeOf((*MockWorkflowRepository)(nil).GetWorkflow), ctx, workflowID)
+}
+
+// HeartbeatWorkflow mocks base method.
+func (m *MockWorkflowRepository) HeartbeatWorkflow(ctx context.Context, workflowID, workerID string, lease time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HeartbeatWorkflow", ctx, workflowID, workerID, lease)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HeartbeatWorkflow indicates an expected call of HeartbeatWorkflow.
+func (mr *MockWorkflowRepositoryMockRecorder) HeartbeatWorkflow(ctx, workflowID, workerID, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HeartbeatWorkflow", reflect.TypeOf((*MockWorkflowRepository)(nil).HeartbeatWorkflow), ctx, workflowID, workerID, lease)
+}
+
+// ListWorkflows mocks base method.
+func (m *MockWorkflowRepository) ListWorkflows(ctx context.Context, filter domain.WorkflowFilter) ([]domain.WorkflowListItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWorkflows", ctx, filter)
+	ret0, _ := ret[0].([]domain.WorkflowListItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWorkflows indicates an expected call of ListWorkflows.
+func (mr *MockWorkflowRepositoryMockRecorder) ListWorkflows(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWorkflows", reflect.TypeOf((*MockWorkflowRepository)(nil).ListWorkflows), ctx, filter)
+}
+
+// ReleaseWorkflow mocks base method.
+func (m *MockWorkflowRepository) ReleaseWorkflow(ctx context.Context, workflowID, workerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseWorkflow", ctx, workflowID, workerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseWorkflow indicates an expected call of ReleaseWorkflow.
+func (mr *MockWorkflowRepositoryMockRecorder) ReleaseWorkflow(ctx, workflowID, workerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseWorkflow", reflect.TypeOf((*MockWorkflowRepository)(nil).ReleaseWorkflow), ctx, workflowID, workerID)
+}
+
+// TransitionWorkflow mocks base method.
+func (m *MockWorkflowRepository) TransitionWorkflow(ctx context.Context, wf domain.Workflow, fromStatus, reason string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionWorkflow", ctx, wf, fromStatus, reason)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransitionWorkflow indicates an expected call of TransitionWorkflow.
+func (mr *MockWorkflowRepositoryMockRecorder) TransitionWorkflow(ctx, wf, fromStatus, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionWorkflow", reflect.TypeOf((*MockWorkflowRepository)(nil).TransitionWorkflow), ctx, wf, fromStatus, reason)
+}
+
+// UpdateWorkflow mocks base method.
+func (m *MockWorkflowRepository) UpdateWorkflow(ctx context.Context, wf domain.Workflow) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWorkflow", ctx, wf)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWorkflow indicates an expected call of UpdateWorkflow.
+func (mr *MockWorkflowRepositoryMockRecorder) UpdateWorkflow(ctx, wf interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWorkflow", reflect.TypeOf((*MockWorkflowRepository)(nil).UpdateWorkflow), ctx, wf)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
 	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure/sqlstore"
@@ -12,8 +13,9 @@ import (
 type DataRepository interface {
 	BatchInsertSystemTx(ctx context.Context, txList []domain.Transaction) error
 	BatchInsertBankStmts(ctx context.Context, stmts []domain.BankStatement) error
-	FindSystemTxByDateRange(ctx context.Context, startDate, endDate time.Time) ([]domain.Transaction, error)
-	FindBankStmtsByDateRange(ctx context.Context, startDate, endDate time.Time) ([]domain.BankStatement, error)
+	FindSystemTxByDateRange(ctx context.Context, scope domain.DataScope, startDate, endDate time.Time) ([]domain.Transaction, error)
+	FindBankStmtsByDateRange(ctx context.Context, scope domain.DataScope, startDate, endDate time.Time) ([]domain.BankStatement, error)
+	StoreStatementBalances(ctx context.Context, balances []domain.StatementBalance) error
 }
 
 type dataRepo struct {
@@ -28,10 +30,20 @@ func (r *dataRepo) BatchInsertSystemTx(ctx context.Context, txList []domain.Tran
 	ctx = r.db.BeginTx(ctx)
 	defer r.db.RollbackTx(ctx)
 
+	// A transaction already loaded by another workflow is linked to this one instead of inserted again
 	const query = `
-        INSERT INTO system_transactions (trx_id, amount, trx_type, transaction_time, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, NOW(), NOW())
-		ON CONFLICT (trx_id) DO NOTHING
+        WITH inserted AS (
+            INSERT INTO system_transactions (trx_id, amount, currency, trx_type, transaction_time, ingestion_job_id, workflow_id, created_at, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+            ON CONFLICT (trx_id) DO NOTHING
+            RETURNING id
+        )
+        INSERT INTO workflow_system_tx_links (workflow_id, ingestion_job_id, system_tx_id)
+        SELECT $7, $6, t.id
+        FROM system_transactions t
+        WHERE t.trx_id = $1 AND $7::uuid IS NOT NULL AND t.workflow_id IS DISTINCT FROM $7::uuid
+          AND NOT EXISTS (SELECT 1 FROM inserted)
+        ON CONFLICT DO NOTHING
     `
 
 	conn, deferFunc, err := r.db.GetConn(ctx)
@@ -47,7 +59,8 @@ func (r *dataRepo) BatchInsertSystemTx(ctx context.Context, txList []domain.Tran
 	defer conn.Deallocate(ctx, "insertSystemTx")
 
 	for _, tx := range txList {
-		_, err := conn.Exec(ctx, "insertSystemTx", tx.TrxID, tx.Amount, tx.Type, tx.TransactionTime)
+		_, err := conn.Exec(ctx, "insertSystemTx", tx.TrxID, tx.Amount, tx.Amount.Currency, tx.Type, tx.TransactionTime,
+			nullString(tx.IngestionJobID), nullString(tx.WorkflowID))
 		if err != nil {
 			return fmt.Errorf("execute insert error: %w", err)
 		}
@@ -63,10 +76,21 @@ func (r *dataRepo) BatchInsertBankStmts(ctx context.Context, stmtList []domain.B
 	ctx = r.db.BeginTx(ctx)
 	defer r.db.RollbackTx(ctx)
 
+	// A statement already loaded by another workflow is linked to this one instead of inserted again
 	const query = `
-        INSERT INTO bank_statements (unique_id, amount, statement_time, bank_code, hash_code, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
-        ON CONFLICT (hash_code) DO NOTHING
+        WITH inserted AS (
+            INSERT INTO bank_statements (
+                unique_id, amount, currency, statement_time, bank_code, hash_code, ingestion_job_id, workflow_id, account_number, description, type_code, bank_reference, created_at, updated_at
+            ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+            ON CONFLICT (hash_code) DO NOTHING
+            RETURNING id
+        )
+        INSERT INTO workflow_bank_statement_links (workflow_id, ingestion_job_id, bank_statement_id)
+        SELECT $8, $7, b.id
+        FROM bank_statements b
+        WHERE b.hash_code = $6 AND $8::uuid IS NOT NULL AND b.workflow_id IS DISTINCT FROM $8::uuid
+          AND NOT EXISTS (SELECT 1 FROM inserted)
+        ON CONFLICT DO NOTHING
     `
 
 	conn, deferFunc, err := r.db.GetConn(ctx)
@@ -83,7 +107,8 @@ func (r *dataRepo) BatchInsertBankStmts(ctx context.Context, stmtList []domain.B
 
 	for _, stmt := range stmtList {
 		stmt.HashCode = stmt.GenerateHashCode()
-		_, err := conn.Exec(ctx, "insertBankStmt", stmt.UniqueID, stmt.Amount, stmt.StatementTime, stmt.BankCode, stmt.HashCode)
+		_, err := conn.Exec(ctx, "insertBankStmt", stmt.UniqueID, stmt.Amount, stmt.Amount.Currency, stmt.StatementTime, stmt.BankCode, stmt.HashCode,
+			nullString(stmt.IngestionJobID), nullString(stmt.WorkflowID), stmt.AccountNumber, stmt.Description, stmt.TypeCode, stmt.BankReference)
 		if err != nil {
 			return fmt.Errorf("execute insert error: %w", err)
 		}
@@ -95,12 +120,18 @@ func (r *dataRepo) BatchInsertBankStmts(ctx context.Context, stmtList []domain.B
 	return nil
 }
 
-func (r *dataRepo) FindSystemTxByDateRange(ctx context.Context, startDate, endDate time.Time) ([]domain.Transaction, error) {
+// FindSystemTxByDateRange retrieves system transactions within a date range, limited to the rows of the scope's workflow
+func (r *dataRepo) FindSystemTxByDateRange(ctx context.Context, scope domain.DataScope, startDate, endDate time.Time) ([]domain.Transaction, error) {
 	const query = `
-        SELECT id, trx_id, amount, trx_type, transaction_time, created_at, updated_at
-        FROM system_transactions
-        WHERE transaction_time BETWEEN $1 AND $2
-        ORDER BY transaction_time ASC
+        SELECT t.id, t.trx_id, t.currency, t.amount, t.trx_type, t.transaction_time,
+               COALESCE(t.ingestion_job_id::text, ''), COALESCE(t.workflow_id::text, ''), t.created_at, t.updated_at
+        FROM system_transactions t
+        WHERE t.transaction_time BETWEEN $1 AND $2
+          AND ($3::uuid IS NULL
+               OR t.workflow_id = $3
+               OR EXISTS (SELECT 1 FROM workflow_system_tx_links l WHERE l.workflow_id = $3 AND l.system_tx_id = t.id)
+               OR ($4 AND t.created_at < (SELECT w.created_at FROM reconciliation_workflows w WHERE w.workflow_id = $3)))
+        ORDER BY t.transaction_time ASC
     `
 
 	conn, deferFunc, err := r.db.GetConn(ctx)
@@ -109,7 +140,7 @@ func (r *dataRepo) FindSystemTxByDateRange(ctx context.Context, startDate, endDa
 	}
 	defer deferFunc()
 
-	rows, err := conn.Query(ctx, query, startDate, endDate)
+	rows, err := conn.Query(ctx, query, startDate, endDate, nullString(scope.WorkflowID), scope.IncludeHistorical)
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
 	}
@@ -118,7 +149,9 @@ func (r *dataRepo) FindSystemTxByDateRange(ctx context.Context, startDate, endDa
 	var transactions []domain.Transaction
 	for rows.Next() {
 		var t domain.Transaction
-		if err := rows.Scan(&t.ID, &t.TrxID, &t.Amount, &t.Type, &t.TransactionTime, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		// The currency is scanned first so the amount is read at that currency's scale
+		if err := rows.Scan(&t.ID, &t.TrxID, &t.Amount.Currency, &t.Amount, &t.Type, &t.TransactionTime,
+			&t.IngestionJobID, &t.WorkflowID, &t.CreatedAt, &t.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("row scan error: %w", err)
 		}
 		transactions = append(transactions, t)
@@ -127,13 +160,18 @@ func (r *dataRepo) FindSystemTxByDateRange(ctx context.Context, startDate, endDa
 	return transactions, nil
 }
 
-// FindBankStmtsByDateRange retrieves bank statements within a specified date range
-func (r *dataRepo) FindBankStmtsByDateRange(ctx context.Context, startDate, endDate time.Time) ([]domain.BankStatement, error) {
+// FindBankStmtsByDateRange retrieves bank statements within a specified date range, limited to the rows of the scope's workflow
+func (r *dataRepo) FindBankStmtsByDateRange(ctx context.Context, scope domain.DataScope, startDate, endDate time.Time) ([]domain.BankStatement, error) {
 	const query = `
-        SELECT id, unique_id, amount, statement_time, bank_code, created_at, updated_at
-        FROM bank_statements
-        WHERE statement_time BETWEEN $1 AND $2
-        ORDER BY statement_time ASC
+        SELECT b.id, b.unique_id, b.currency, b.amount, b.statement_time, b.bank_code, b.account_number,
+               COALESCE(b.ingestion_job_id::text, ''), COALESCE(b.workflow_id::text, ''), b.created_at, b.updated_at
+        FROM bank_statements b
+        WHERE b.statement_time BETWEEN $1 AND $2
+          AND ($3::uuid IS NULL
+               OR b.workflow_id = $3
+               OR EXISTS (SELECT 1 FROM workflow_bank_statement_links l WHERE l.workflow_id = $3 AND l.bank_statement_id = b.id)
+               OR ($4 AND b.created_at < (SELECT w.created_at FROM reconciliation_workflows w WHERE w.workflow_id = $3)))
+        ORDER BY b.statement_time ASC
     `
 
 	conn, deferFunc, err := r.db.GetConn(ctx)
@@ -142,7 +180,7 @@ func (r *dataRepo) FindBankStmtsByDateRange(ctx context.Context, startDate, endD
 	}
 	defer deferFunc()
 
-	rows, err := conn.Query(ctx, query, startDate, endDate)
+	rows, err := conn.Query(ctx, query, startDate, endDate, nullString(scope.WorkflowID), scope.IncludeHistorical)
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
 	}
@@ -151,7 +189,8 @@ func (r *dataRepo) FindBankStmtsByDateRange(ctx context.Context, startDate, endD
 	var statements []domain.BankStatement
 	for rows.Next() {
 		var b domain.BankStatement
-		if err := rows.Scan(&b.ID, &b.UniqueID, &b.Amount, &b.StatementTime, &b.BankCode, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		if err := rows.Scan(&b.ID, &b.UniqueID, &b.Amount.Currency, &b.Amount, &b.StatementTime, &b.BankCode, &b.AccountNumber,
+			&b.IngestionJobID, &b.WorkflowID, &b.CreatedAt, &b.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("row scan error: %w", err)
 		}
 		statements = append(statements, b)
@@ -159,3 +198,42 @@ func (r *dataRepo) FindBankStmtsByDateRange(ctx context.Context, startDate, endD
 
 	return statements, nil
 }
+
+// StoreStatementBalances stores the balances reported in a bank statement file,
+// a balance reported again for the same account, type and date replaces the earlier one
+func (r *dataRepo) StoreStatementBalances(ctx context.Context, balances []domain.StatementBalance) error {
+	const query = `
+        INSERT INTO bank_account_balances (
+            bank_code, account_number, currency, balance_type, balance_date, amount, ingestion_job_id, workflow_id, created_at, updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+        ON CONFLICT (bank_code, account_number, currency, balance_type, balance_date) DO UPDATE
+        SET amount = EXCLUDED.amount, ingestion_job_id = EXCLUDED.ingestion_job_id, workflow_id = EXCLUDED.workflow_id, updated_at = NOW()
+    `
+
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	for _, b := range balances {
+		_, err := conn.Exec(ctx, query, b.BankCode, b.AccountNumber, b.Amount.Currency, b.BalanceType, b.BalanceDate, b.Amount,
+			nullString(b.IngestionJobID), nullString(b.WorkflowID))
+		if err != nil {
+			return fmt.Errorf("execute insert error: %w", err)
+		}
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
+
+// nullString maps an empty id to NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
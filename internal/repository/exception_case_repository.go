@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure/sqlstore"
+	"github.com/jackc/pgx/v5"
+)
+
+//go:generate mockgen -source=exception_case_repository.go -destination=_mock/exception_case_repository.go
+type ExceptionCaseRepository interface {
+	ListCases(ctx context.Context, jobID string, filter domain.CaseFilter) ([]domain.ExceptionCase, error)
+	GetCase(ctx context.Context, jobID string, caseID int, policy domain.CasePriorityPolicy) (domain.ExceptionCase, error)
+	UpdateCase(ctx context.Context, jobID string, caseID int, update domain.CaseUpdate) error
+	AddComment(ctx context.Context, comment domain.CaseComment) (domain.CaseComment, error)
+	ListComments(ctx context.Context, caseID int) ([]domain.CaseComment, error)
+	AddAttachment(ctx context.Context, attachment domain.CaseAttachment) (domain.CaseAttachment, error)
+	ListAttachments(ctx context.Context, caseID int) ([]domain.CaseAttachment, error)
+	GetAttachment(ctx context.Context, caseID, attachmentID int) (domain.CaseAttachment, error)
+}
+
+var (
+	// ErrCaseNotFound is returned for a case id that is not a case of the job
+	ErrCaseNotFound = errors.New("exception case not found")
+	// ErrCaseClosed is returned when changing a case that was resolved or written off
+	ErrCaseClosed = errors.New("exception case is closed")
+	// ErrAttachmentNotFound is returned for an attachment id that is not attached to the case
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
+
+type exceptionCaseRepo struct {
+	db sqlstore.Store
+}
+
+func NewExceptionCaseRepo(db sqlstore.Store) ExceptionCaseRepository {
+	return &exceptionCaseRepo{db: db}
+}
+
+// caseSelect selects the cases with their current exception id, age and priority as a subquery aliased c.
+// Closed cases age until the day they were closed.
+func caseSelect(b *queryBuilder, policy domain.CasePriorityPolicy) string {
+	const age = `(COALESCE(c.closed_at, NOW())::date - c.item_date::date)`
+	return `
+        SELECT * FROM (
+            SELECT c.id, c.job_id::text AS job_id, c.item_type, COALESCE(c.record_id, 0) AS record_id,
+                   COALESCE(CASE c.item_type
+                       WHEN 'SYSTEM_TX' THEN (SELECT MIN(u.id) FROM reconciliation_unmatched_system_tx u
+                                              WHERE u.job_id = c.job_id AND u.system_tx_id = c.record_id)
+                       ELSE (SELECT MIN(u.id) FROM reconciliation_unmatched_bank_tx u
+                             WHERE u.job_id = c.job_id AND u.bank_statement_id = c.record_id)
+                   END, 0) AS exception_id,
+                   c.reference, COALESCE(c.bank_code, '') AS bank_code, c.currency, c.amount, c.item_date, c.status,
+                   COALESCE(c.assignee, '') AS assignee,
+                   CASE WHEN ABS(c.amount) >= ` + b.arg(policy.HighAmount) + ` OR ` + age + ` >= ` + b.arg(policy.HighAgeDays) + ` THEN 'HIGH'
+                        WHEN ABS(c.amount) >= ` + b.arg(policy.MediumAmount) + ` OR ` + age + ` >= ` + b.arg(policy.MediumAgeDays) + ` THEN 'MEDIUM'
+                        ELSE 'LOW'
+                   END AS priority,
+                   ` + age + ` AS age_days, c.closed_at, c.created_at, c.updated_at
+            FROM exception_cases c
+        ) c`
+}
+
+func scanCase(row pgx.Row) (domain.ExceptionCase, error) {
+	var c domain.ExceptionCase
+	err := row.Scan(&c.ID, &c.JobID, &c.ItemType, &c.RecordID, &c.ExceptionID, &c.Reference, &c.BankCode,
+		&c.Amount.Currency, &c.Amount, &c.ItemDate, &c.Status, &c.Assignee, &c.Priority, &c.AgeDays,
+		&c.ClosedAt, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+// ListCases retrieves up to filter.Limit cases of a job matching the filter, newest first
+func (r *exceptionCaseRepo) ListCases(ctx context.Context, jobID string, filter domain.CaseFilter) ([]domain.ExceptionCase, error) {
+	var b queryBuilder
+	query := caseSelect(&b, filter.Policy)
+	b.where("c.job_id = ?", jobID)
+	if len(filter.Statuses) > 0 {
+		b.where("c.status = ANY(?)", filter.Statuses)
+	}
+	if filter.Assignee != "" {
+		b.where("c.assignee = ?", filter.Assignee)
+	}
+	if filter.Priority != "" {
+		b.where("c.priority = ?", filter.Priority)
+	}
+	if filter.ItemType != "" {
+		b.where("c.item_type = ?", filter.ItemType)
+	}
+	if filter.AfterID > 0 {
+		b.where("c.id < ?", filter.AfterID)
+	}
+	query += b.whereClause() + `
+        ORDER BY c.id DESC
+        LIMIT ` + b.arg(filter.Limit)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var cases []domain.ExceptionCase
+	for rows.Next() {
+		c, err := scanCase(rows)
+		if err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		cases = append(cases, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	return cases, nil
+}
+
+// GetCase retrieves a case of a job, ErrCaseNotFound is returned when there is none with the id
+func (r *exceptionCaseRepo) GetCase(ctx context.Context, jobID string, caseID int, policy domain.CasePriorityPolicy) (domain.ExceptionCase, error) {
+	var b queryBuilder
+	query := caseSelect(&b, policy)
+	b.where("c.job_id = ?", jobID)
+	b.where("c.id = ?", caseID)
+	query += b.whereClause()
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.ExceptionCase{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	c, err := scanCase(conn.QueryRow(ctx, query, b.args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ExceptionCase{}, ErrCaseNotFound
+	}
+	if err != nil {
+		return domain.ExceptionCase{}, fmt.Errorf("query row scan error: %w", err)
+	}
+	return c, nil
+}
+
+// UpdateCase changes the status or assignee of a case that is still open,
+// ErrCaseClosed is returned when it was resolved or written off in the meantime
+func (r *exceptionCaseRepo) UpdateCase(ctx context.Context, jobID string, caseID int, update domain.CaseUpdate) error {
+	const query = `
+        UPDATE exception_cases
+        SET status = COALESCE($3, status),
+            assignee = CASE WHEN $4::text IS NULL THEN assignee ELSE NULLIF($4::text, '') END,
+            updated_at = NOW()
+        WHERE job_id = $1 AND id = $2 AND status IN ('OPEN', 'INVESTIGATING')
+    `
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	tag, err := conn.Exec(ctx, query, jobID, caseID, update.Status, update.Assignee)
+	if err != nil {
+		return fmt.Errorf("execute update error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCaseClosed
+	}
+	return nil
+}
+
+// AddComment stores a comment on a case and returns it with its id and time
+func (r *exceptionCaseRepo) AddComment(ctx context.Context, comment domain.CaseComment) (domain.CaseComment, error) {
+	const query = `
+        INSERT INTO exception_case_comments (case_id, author, body, created_at)
+        VALUES ($1, $2, $3, NOW())
+        RETURNING id, created_at
+    `
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.CaseComment{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	if err := conn.QueryRow(ctx, query, comment.CaseID, comment.Author, comment.Body).Scan(&comment.ID, &comment.CreatedAt); err != nil {
+		return domain.CaseComment{}, fmt.Errorf("query row scan error: %w", err)
+	}
+	return comment, nil
+}
+
+// ListComments retrieves the comments of a case, oldest first
+func (r *exceptionCaseRepo) ListComments(ctx context.Context, caseID int) ([]domain.CaseComment, error) {
+	const query = `
+        SELECT id, case_id, author, body, created_at
+        FROM exception_case_comments
+        WHERE case_id = $1
+        ORDER BY id
+    `
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, caseID)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []domain.CaseComment
+	for rows.Next() {
+		var c domain.CaseComment
+		if err := rows.Scan(&c.ID, &c.CaseID, &c.Author, &c.Body, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	return comments, nil
+}
+
+// AddAttachment stores the description of a file uploaded for a case and returns it with its id and time
+func (r *exceptionCaseRepo) AddAttachment(ctx context.Context, attachment domain.CaseAttachment) (domain.CaseAttachment, error) {
+	const query = `
+        INSERT INTO exception_case_attachments (case_id, file_name, object_name, content_type, size, uploaded_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NOW())
+        RETURNING id, created_at
+    `
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.CaseAttachment{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	err = conn.QueryRow(ctx, query, attachment.CaseID, attachment.FileName, attachment.ObjectName, attachment.ContentType,
+		attachment.Size, attachment.UploadedBy).Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		return domain.CaseAttachment{}, fmt.Errorf("query row scan error: %w", err)
+	}
+	return attachment, nil
+}
+
+const attachmentColumns = `id, case_id, file_name, object_name, content_type, size, uploaded_by, created_at`
+
+func scanAttachment(row pgx.Row) (domain.CaseAttachment, error) {
+	var a domain.CaseAttachment
+	err := row.Scan(&a.ID, &a.CaseID, &a.FileName, &a.ObjectName, &a.ContentType, &a.Size, &a.UploadedBy, &a.CreatedAt)
+	return a, err
+}
+
+// ListAttachments retrieves the files attached to a case, oldest first
+func (r *exceptionCaseRepo) ListAttachments(ctx context.Context, caseID int) ([]domain.CaseAttachment, error) {
+	query := `
+        SELECT ` + attachmentColumns + `
+        FROM exception_case_attachments
+        WHERE case_id = $1
+        ORDER BY id
+    `
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, caseID)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []domain.CaseAttachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	return attachments, nil
+}
+
+// GetAttachment retrieves a file attached to a case, ErrAttachmentNotFound is returned when the case has none with the id
+func (r *exceptionCaseRepo) GetAttachment(ctx context.Context, caseID, attachmentID int) (domain.CaseAttachment, error) {
+	query := `
+        SELECT ` + attachmentColumns + `
+        FROM exception_case_attachments
+        WHERE case_id = $1 AND id = $2
+    `
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.CaseAttachment{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	a, err := scanAttachment(conn.QueryRow(ctx, query, caseID, attachmentID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.CaseAttachment{}, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return domain.CaseAttachment{}, fmt.Errorf("query row scan error: %w", err)
+	}
+	return a, nil
+}
+
+// closeCases closes the open cases of the given records in every job, status is CaseResolved or CaseWrittenOff
+func closeCases(ctx context.Context, conn *pgx.Conn, status string, systemTxIDs, bankStatementIDs []int) error {
+	const query = `
+        UPDATE exception_cases
+        SET status = $1, closed_at = NOW(), updated_at = NOW()
+        WHERE status IN ('OPEN', 'INVESTIGATING')
+          AND ((item_type = 'SYSTEM_TX' AND record_id = ANY($2)) OR (item_type = 'BANK_STATEMENT' AND record_id = ANY($3)))
+    `
+	if _, err := conn.Exec(ctx, query, status, systemTxIDs, bankStatementIDs); err != nil {
+		return fmt.Errorf("execute update error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure/sqlstore"
+	"time"
+)
+
+//go:generate mockgen -source=fx_rate_repository.go -destination=_mock/fx_rate_repository.go
+type FXRateRepository interface {
+	UpsertRates(ctx context.Context, rates []domain.FXRate) error
+	FindRatesByDateRange(ctx context.Context, startDate, endDate time.Time) ([]domain.FXRate, error)
+}
+
+type fxRateRepo struct {
+	db sqlstore.Store
+}
+
+func NewFXRateRepo(db sqlstore.Store) FXRateRepository {
+	return &fxRateRepo{db: db}
+}
+
+// UpsertRates stores daily rates, a rate loaded again for the same day and currency pair replaces the previous one
+func (r *fxRateRepo) UpsertRates(ctx context.Context, rates []domain.FXRate) error {
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	const query = `
+        INSERT INTO fx_rates (rate_date, base_currency, quote_currency, rate, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, NOW(), NOW())
+        ON CONFLICT (rate_date, base_currency, quote_currency)
+        DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW()
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	_, err = conn.Prepare(ctx, "upsertFXRate", query)
+	if err != nil {
+		return fmt.Errorf("prepare statement error: %w", err)
+	}
+	defer conn.Deallocate(ctx, "upsertFXRate")
+
+	for _, rate := range rates {
+		_, err := conn.Exec(ctx, "upsertFXRate", rate.RateDate, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate)
+		if err != nil {
+			return fmt.Errorf("execute upsert error: %w", err)
+		}
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
+
+// FindRatesByDateRange retrieves the rates of every currency pair between both dates, oldest first
+func (r *fxRateRepo) FindRatesByDateRange(ctx context.Context, startDate, endDate time.Time) ([]domain.FXRate, error) {
+	const query = `
+        SELECT rate_date, base_currency, quote_currency, rate, created_at, updated_at
+        FROM fx_rates
+        WHERE rate_date BETWEEN $1 AND $2
+        ORDER BY rate_date ASC, base_currency, quote_currency
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []domain.FXRate
+	for rows.Next() {
+		var rate domain.FXRate
+		if err := rows.Scan(&rate.RateDate, &rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate, &rate.CreatedAt, &rate.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating fx rates: %w", err)
+	}
+
+	return rates, nil
+}
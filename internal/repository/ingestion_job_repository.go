@@ -2,20 +2,36 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
 	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure/sqlstore"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
 )
 
+// ErrJobNotHeld is returned when the worker no longer holds the job it updates, its lease expired or the job was cancelled
+var ErrJobNotHeld = errors.New("ingestion job is not held by the worker")
+
 //go:generate mockgen -source=ingestion_job_repository.go -destination=_mock/ingestion_job_repository.go
 type IngestionJobRepository interface {
 	CreateJob(ctx context.Context, job *domain.IngestionJob) error
-	UpdateJobProgress(ctx context.Context, jobID string, linesProcessed int64, status string) error
-	ListPendingJobs(ctx context.Context, limit int) ([]domain.IngestionJob, error)
-	MarkJobInProgress(ctx context.Context, jobID string) error
+	UpdateJobProgress(ctx context.Context, jobID, workerID string, linesProcessed int64, status string) error
+	ClaimPendingJobs(ctx context.Context, workerID string, limit int, lease time.Duration) ([]domain.IngestionJob, error)
+	HeartbeatJob(ctx context.Context, jobID, workerID string, lease time.Duration) (bool, error)
+	ReapExpiredLeases(ctx context.Context, maxAttempts int) (int64, error)
+	RetryJob(ctx context.Context, jobID, workerID, lastError string, nextAttemptAt time.Time) error
+	DeadLetterJob(ctx context.Context, jobID, workerID, lastError string) error
+	RequeueDeadLetterJob(ctx context.Context, jobID string) (domain.IngestionJob, bool, error)
+	ListJobsByWorkflow(ctx context.Context, workflowID string) ([]domain.IngestionJob, error)
 }
 
+const ingestionJobColumns = `j.job_id, COALESCE(j.workflow_id::text, ''), j.file_type, j.file_name, COALESCE(j.parser_profile, ''),
+            j.total_lines_processed, j.status,
+            COALESCE(j.worker_id, ''), j.lease_expires_at, j.attempts, j.next_attempt_at, COALESCE(j.last_error, ''),
+            j.created_at, j.updated_at`
+
 type ingestionRepo struct {
 	db sqlstore.Store
 }
@@ -36,32 +52,42 @@ func (r *ingestionRepo) CreateJob(ctx context.Context, job *domain.IngestionJob)
 		job.Status = "PENDING"
 	}
 	const q = `
-	INSERT INTO ingestion_jobs (job_id, file_type, file_name, total_lines_processed, status, created_at, updated_at)
-	VALUES ($1, $2, $3, 0, $4, NOW(), NOW())
+	INSERT INTO ingestion_jobs (job_id, workflow_id, file_type, file_name, parser_profile, total_lines_processed, status, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, 0, $6, NOW(), NOW())
 	`
-	_, err = conn.Exec(ctx, q, job.JobID, job.FileType, job.FileName, job.Status)
+	_, err = conn.Exec(ctx, q, job.JobID, nullString(job.WorkflowID), job.FileType, job.FileName, nullString(job.ParserProfile), job.Status)
 	defer deferFunc()
 	return err
 }
 
-func (r *ingestionRepo) UpdateJobProgress(ctx context.Context, jobID string, linesProcessed int64, status string) error {
+// UpdateJobProgress records the progress of a job the worker holds, ErrJobNotHeld is returned once it lost the job
+func (r *ingestionRepo) UpdateJobProgress(ctx context.Context, jobID, workerID string, linesProcessed int64, status string) error {
 	conn, deferFunc, err := r.db.GetConn(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("GetConn error: %w", err)
 	}
+	defer deferFunc()
+
 	const q = `
 	UPDATE ingestion_jobs
 	SET total_lines_processed = $1,
 	    status = $2,
 	    updated_at = NOW()
-	WHERE job_id = $3
+	WHERE job_id = $3 AND worker_id = $4 AND status = 'IN_PROGRESS'
 	`
-	_, err = conn.Exec(ctx, q, linesProcessed, status, jobID)
-	defer deferFunc()
-	return err
+	tag, err := conn.Exec(ctx, q, linesProcessed, status, jobID, workerID)
+	if err != nil {
+		return fmt.Errorf("execute update error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobNotHeld
+	}
+	return nil
 }
 
-func (r *ingestionRepo) ListPendingJobs(ctx context.Context, limit int) ([]domain.IngestionJob, error) {
+// ClaimPendingJobs atomically moves up to limit PENDING jobs that are due to IN_PROGRESS for the worker
+// and counts the attempt, jobs locked by a concurrent claim are skipped so no two workers get the same job
+func (r *ingestionRepo) ClaimPendingJobs(ctx context.Context, workerID string, limit int, lease time.Duration) ([]domain.IngestionJob, error) {
 	conn, deferFunc, err := r.db.GetConn(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("GetConn error: %w", err)
@@ -69,29 +95,100 @@ func (r *ingestionRepo) ListPendingJobs(ctx context.Context, limit int) ([]domai
 	defer deferFunc() // ensure connection is released
 
 	const q = `
-        SELECT job_id, file_type, file_name, total_lines_processed, status, created_at, updated_at
-        FROM ingestion_jobs
-        WHERE status IN ('PENDING')
-        ORDER BY created_at ASC
-        LIMIT $1
+        UPDATE ingestion_jobs j
+        SET status = 'IN_PROGRESS',
+            worker_id = $1,
+            lease_expires_at = NOW() + make_interval(secs => $3),
+            heartbeat_at = NOW(),
+            attempts = j.attempts + 1,
+            updated_at = NOW()
+        FROM (
+            SELECT job_id
+            FROM ingestion_jobs
+            WHERE status = 'PENDING'
+              AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+            ORDER BY created_at ASC
+            LIMIT $2
+            FOR UPDATE SKIP LOCKED
+        ) claimed
+        WHERE j.job_id = claimed.job_id
+        RETURNING ` + ingestionJobColumns + `
     `
 
-	// Use Query(...) instead of Exec(...) to get a row set
-	rows, err := conn.Query(ctx, q, limit)
+	rows, err := conn.Query(ctx, q, workerID, limit, lease.Seconds())
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
 	}
 	defer rows.Close()
 
+	return scanIngestionJobs(rows)
+}
+
+// HeartbeatJob extends the lease of a job the worker still holds, it returns false once the lease was lost
+func (r *ingestionRepo) HeartbeatJob(ctx context.Context, jobID, workerID string, lease time.Duration) (bool, error) {
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	const q = `
+	UPDATE ingestion_jobs
+	SET lease_expires_at = NOW() + make_interval(secs => $3),
+	    heartbeat_at = NOW()
+	WHERE job_id = $1 AND worker_id = $2 AND status = 'IN_PROGRESS'
+	`
+	tag, err := conn.Exec(ctx, q, jobID, workerID, lease.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("execute update error: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReapExpiredLeases puts the jobs of workers that stopped sending heartbeats back to PENDING,
+// ingestion skips rows that are already stored so a job can be processed again from the start.
+// A job that used up its attempts is dead-lettered instead, it may be what keeps killing the workers.
+func (r *ingestionRepo) ReapExpiredLeases(ctx context.Context, maxAttempts int) (int64, error) {
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	const q = `
+	UPDATE ingestion_jobs
+	SET status = CASE WHEN attempts >= $1 THEN 'DEAD_LETTER' ELSE 'PENDING' END,
+	    worker_id = NULL,
+	    lease_expires_at = NULL,
+	    last_error = 'lease expired, worker ' || COALESCE(worker_id, '') || ' stopped sending heartbeats',
+	    total_lines_processed = 0,
+	    updated_at = NOW()
+	WHERE status = 'IN_PROGRESS' AND lease_expires_at < NOW()
+	`
+	tag, err := conn.Exec(ctx, q, maxAttempts)
+	if err != nil {
+		return 0, fmt.Errorf("execute update error: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanIngestionJobs(rows pgx.Rows) ([]domain.IngestionJob, error) {
 	var jobs []domain.IngestionJob
 	for rows.Next() {
 		var job domain.IngestionJob
 		if scanErr := rows.Scan(
 			&job.JobID,
+			&job.WorkflowID,
 			&job.FileType,
 			&job.FileName,
+			&job.ParserProfile,
 			&job.TotalLinesProcessed,
 			&job.Status,
+			&job.WorkerID,
+			&job.LeaseExpiresAt,
+			&job.Attempts,
+			&job.NextAttemptAt,
+			&job.LastError,
 			&job.CreatedAt,
 			&job.UpdatedAt,
 		); scanErr != nil {
@@ -108,19 +205,114 @@ func (r *ingestionRepo) ListPendingJobs(ctx context.Context, limit int) ([]domai
 	return jobs, nil
 }
 
-func (r *ingestionRepo) MarkJobInProgress(ctx context.Context, jobID string) error {
+// ListJobsByWorkflow returns every ingestion job created for the workflow
+func (r *ingestionRepo) ListJobsByWorkflow(ctx context.Context, workflowID string) ([]domain.IngestionJob, error) {
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	const q = `
+        SELECT ` + ingestionJobColumns + `
+        FROM ingestion_jobs j
+        WHERE j.workflow_id = $1
+        ORDER BY j.created_at ASC
+    `
+	rows, err := conn.Query(ctx, q, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIngestionJobs(rows)
+}
+
+// RetryJob returns a failed job the worker holds to PENDING, it is not claimed again before nextAttemptAt
+func (r *ingestionRepo) RetryJob(ctx context.Context, jobID, workerID, lastError string, nextAttemptAt time.Time) error {
 	conn, deferFunc, err := r.db.GetConn(ctx)
 	if err != nil {
 		return fmt.Errorf("GetConn error: %w", err)
 	}
-	defer deferFunc() // ensure connection is released
+	defer deferFunc()
 
 	const q = `
 	UPDATE ingestion_jobs
-	SET status = 'IN_PROGRESS',
+	SET status = 'PENDING',
+	    worker_id = NULL,
+	    lease_expires_at = NULL,
+	    next_attempt_at = $3,
+	    last_error = $4,
+	    total_lines_processed = 0,
 	    updated_at = NOW()
-	WHERE job_id = $1
+	WHERE job_id = $1 AND worker_id = $2 AND status = 'IN_PROGRESS'
 	`
-	_, err = conn.Exec(ctx, q, jobID)
-	return err
+	tag, err := conn.Exec(ctx, q, jobID, workerID, nextAttemptAt, lastError)
+	if err != nil {
+		return fmt.Errorf("execute update error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobNotHeld
+	}
+	return nil
+}
+
+// DeadLetterJob parks a job the worker holds that will not succeed by retrying until it is requeued by hand
+func (r *ingestionRepo) DeadLetterJob(ctx context.Context, jobID, workerID, lastError string) error {
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	const q = `
+	UPDATE ingestion_jobs
+	SET status = 'DEAD_LETTER',
+	    lease_expires_at = NULL,
+	    next_attempt_at = NULL,
+	    last_error = $3,
+	    updated_at = NOW()
+	WHERE job_id = $1 AND worker_id = $2 AND status = 'IN_PROGRESS'
+	`
+	tag, err := conn.Exec(ctx, q, jobID, workerID, lastError)
+	if err != nil {
+		return fmt.Errorf("execute update error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobNotHeld
+	}
+	return nil
+}
+
+// RequeueDeadLetterJob gives a dead-lettered job a fresh set of attempts,
+// it returns false when there is no dead-lettered job with that id
+func (r *ingestionRepo) RequeueDeadLetterJob(ctx context.Context, jobID string) (domain.IngestionJob, bool, error) {
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.IngestionJob{}, false, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	const q = `
+        UPDATE ingestion_jobs j
+        SET status = 'PENDING',
+            worker_id = NULL,
+            attempts = 0,
+            next_attempt_at = NULL,
+            total_lines_processed = 0,
+            updated_at = NOW()
+        WHERE j.job_id = $1 AND j.status = 'DEAD_LETTER'
+        RETURNING ` + ingestionJobColumns + `
+    `
+	rows, err := conn.Query(ctx, q, jobID)
+	if err != nil {
+		return domain.IngestionJob{}, false, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	jobs, err := scanIngestionJobs(rows)
+	if err != nil || len(jobs) == 0 {
+		return domain.IngestionJob{}, false, err
+	}
+	return jobs[0], true, nil
 }
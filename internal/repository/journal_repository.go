@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure/sqlstore"
+)
+
+//go:generate mockgen -source=journal_repository.go -destination=_mock/journal_repository.go
+type JournalRepository interface {
+	ListJournalSources(ctx context.Context, jobID string) ([]domain.JournalSource, error)
+	ReplaceJournalEntries(ctx context.Context, jobID string, entries []domain.JournalEntry) ([]domain.JournalEntry, error)
+	ListJournalEntries(ctx context.Context, jobID string) ([]domain.JournalEntry, error)
+}
+
+type journalRepo struct {
+	db sqlstore.Store
+}
+
+func NewJournalRepo(db sqlstore.Store) JournalRepository {
+	return &journalRepo{db: db}
+}
+
+// ListJournalSources retrieves what a job has to book: the matches and match groups with a discrepancy and the write-offs.
+// The cash effect of a match is the bank amount less the amount the books expected on the bank,
+// writing off a bank line adds it to the books and writing off a system transaction takes back what the books expected.
+func (r *journalRepo) ListJournalSources(ctx context.Context, jobID string) ([]domain.JournalSource, error) {
+	const query = `
+        SELECT 'MATCH', m.id, b.bank_code, 'DISCREPANCY', t.trx_id, b.statement_time, m.currency,
+               COALESCE(m.bank_converted_amount, b.amount)
+             - CASE WHEN t.trx_type = 'DEBIT' THEN -COALESCE(m.system_converted_amount, t.amount)
+                    ELSE COALESCE(m.system_converted_amount, t.amount) END
+        FROM reconciliation_matched_records m
+        JOIN system_transactions t ON t.id = m.system_tx_id
+        JOIN bank_statements b ON b.id = m.bank_statement_id
+        WHERE m.job_id = $1 AND m.discrepancy <> 0
+
+        UNION ALL
+
+        SELECT 'MATCH_GROUP', g.id, COALESCE(MIN(b.bank_code), ''), 'DISCREPANCY',
+               COALESCE(string_agg(t.trx_id, ',' ORDER BY t.trx_id), ''), COALESCE(MAX(b.statement_time), g.matched_at), g.currency,
+               COALESCE(SUM(b.amount), 0) - COALESCE(SUM(CASE WHEN t.trx_type = 'DEBIT' THEN -t.amount ELSE t.amount END), 0)
+        FROM reconciliation_match_groups g
+        JOIN reconciliation_match_group_members mem ON mem.group_id = g.id
+        LEFT JOIN system_transactions t ON t.id = mem.system_tx_id
+        LEFT JOIN bank_statements b ON b.id = mem.bank_statement_id
+        WHERE g.job_id = $1 AND g.discrepancy <> 0
+        GROUP BY g.id
+
+        UNION ALL
+
+        SELECT 'WRITE_OFF', w.id, COALESCE(b.bank_code, ''), w.reason_code, w.reference, w.item_date, w.currency,
+               CASE WHEN w.item_type = 'BANK_STATEMENT' THEN w.amount
+                    WHEN t.trx_type = 'DEBIT' THEN w.amount
+                    ELSE -w.amount END
+        FROM reconciliation_write_offs w
+        LEFT JOIN bank_statements b ON b.id = w.bank_statement_id
+        LEFT JOIN system_transactions t ON t.id = w.system_tx_id
+        WHERE w.job_id = $1
+
+        ORDER BY 1, 2
+    `
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []domain.JournalSource
+	for rows.Next() {
+		var s domain.JournalSource
+		if err := rows.Scan(&s.SourceType, &s.SourceID, &s.BankCode, &s.Reason, &s.Reference, &s.EntryDate,
+			&s.CashEffect.Currency, &s.CashEffect); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		sources = append(sources, s)
+	}
+	return sources, rows.Err()
+}
+
+// ReplaceJournalEntries stores the entries of a job in place of the ones generated before, in one transaction
+func (r *journalRepo) ReplaceJournalEntries(ctx context.Context, jobID string, entries []domain.JournalEntry) ([]domain.JournalEntry, error) {
+	const entryQuery = `
+        INSERT INTO journal_entries (
+            job_id, source_type, source_id, bank_code, reason, reference, description, entry_date, currency, created_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+        RETURNING id, created_at
+    `
+	const lineQuery = `
+        INSERT INTO journal_entry_lines (entry_id, line_no, account, debit, credit)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	if _, err := conn.Exec(ctx, `DELETE FROM journal_entries WHERE job_id = $1`, jobID); err != nil {
+		return nil, fmt.Errorf("execute delete error: %w", err)
+	}
+	for i := range entries {
+		e := &entries[i]
+		e.JobID = jobID
+		err := conn.QueryRow(ctx, entryQuery, jobID, e.SourceType, e.SourceID, e.BankCode, e.Reason, e.Reference, e.Description,
+			e.EntryDate, e.Currency).Scan(&e.ID, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("query row scan error: %w", err)
+		}
+		for _, line := range e.Lines {
+			if _, err := conn.Exec(ctx, lineQuery, e.ID, line.LineNo, line.Account, line.Debit, line.Credit); err != nil {
+				return nil, fmt.Errorf("execute insert error: %w", err)
+			}
+		}
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx error: %w", err)
+	}
+	return entries, nil
+}
+
+// ListJournalEntries retrieves the entries of a job with their lines, in the order they were generated
+func (r *journalRepo) ListJournalEntries(ctx context.Context, jobID string) ([]domain.JournalEntry, error) {
+	const query = `
+        SELECT e.id, e.job_id::text, e.source_type, e.source_id, e.bank_code, e.reason, e.reference, e.description,
+               e.entry_date, e.currency, e.created_at, l.line_no, l.account, e.currency, l.debit, e.currency, l.credit
+        FROM journal_entries e
+        JOIN journal_entry_lines l ON l.entry_id = e.id
+        WHERE e.job_id = $1
+        ORDER BY e.id, l.line_no
+    `
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []domain.JournalEntry
+	for rows.Next() {
+		var (
+			e    domain.JournalEntry
+			line domain.JournalLine
+		)
+		if err := rows.Scan(&e.ID, &e.JobID, &e.SourceType, &e.SourceID, &e.BankCode, &e.Reason, &e.Reference, &e.Description,
+			&e.EntryDate, &e.Currency, &e.CreatedAt, &line.LineNo, &line.Account, &line.Debit.Currency, &line.Debit,
+			&line.Credit.Currency, &line.Credit); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		if n := len(entries); n > 0 && entries[n-1].ID == e.ID {
+			entries[n-1].Lines = append(entries[n-1].Lines, line)
+			continue
+		}
+		e.Lines = []domain.JournalLine{line}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
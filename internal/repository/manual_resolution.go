@@ -0,0 +1,544 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrExceptionNotFound is returned for an unmatched item that is not an exception of the job, or no longer is
+	ErrExceptionNotFound = errors.New("exception not found")
+	// ErrMatchNotFound is returned for a matched record or match group that is not a match of the job
+	ErrMatchNotFound = errors.New("match not found")
+)
+
+// recordSide holds the queries that move one side of a match back into the exceptions of a job
+type recordSide struct {
+	reopen          string
+	insertUnmatched string
+	insertOpenItem  string
+	openCase        string
+}
+
+var (
+	systemSide = recordSide{
+		reopen: `
+            UPDATE reconciliation_open_items o
+            SET status = 'OPEN', resolved_job_id = NULL, resolved_at = NULL, updated_at = NOW()
+            WHERE o.resolved_job_id = $1 AND o.system_tx_id = $2 AND o.status = 'RESOLVED'
+              AND NOT EXISTS (SELECT 1 FROM reconciliation_open_items other WHERE other.status = 'OPEN' AND other.system_tx_id = $2)
+            RETURNING o.origin_job_id::text
+        `,
+		insertUnmatched: `
+            INSERT INTO reconciliation_unmatched_system_tx (
+                job_id, system_tx_id, trx_id, amount, currency, trx_type, transaction_time, created_at, updated_at
+            )
+            SELECT $1, t.id, t.trx_id, t.amount, t.currency, t.trx_type, t.transaction_time, NOW(), NOW()
+            FROM system_transactions t WHERE t.id = $2
+        `,
+		insertOpenItem: `
+            INSERT INTO reconciliation_open_items (
+                item_type, system_tx_id, amount, currency, item_date, status, origin_job_id, created_at, updated_at
+            )
+            SELECT 'SYSTEM_TX', t.id, t.amount, t.currency, t.transaction_time, 'OPEN', $1, NOW(), NOW()
+            FROM system_transactions t WHERE t.id = $2
+            ON CONFLICT DO NOTHING
+        `,
+		openCase: `
+            INSERT INTO exception_cases (job_id, item_type, record_id, reference, amount, currency, item_date, status, created_at, updated_at)
+            SELECT $1, 'SYSTEM_TX', t.id, t.trx_id, t.amount, t.currency, t.transaction_time, 'OPEN', NOW(), NOW()
+            FROM system_transactions t WHERE t.id = $2
+            ON CONFLICT (job_id, item_type, record_id) DO UPDATE SET status = 'OPEN', closed_at = NULL, updated_at = NOW()
+        `,
+	}
+	bankSide = recordSide{
+		reopen: `
+            UPDATE reconciliation_open_items o
+            SET status = 'OPEN', resolved_job_id = NULL, resolved_at = NULL, updated_at = NOW()
+            WHERE o.resolved_job_id = $1 AND o.bank_statement_id = $2 AND o.status = 'RESOLVED'
+              AND NOT EXISTS (SELECT 1 FROM reconciliation_open_items other WHERE other.status = 'OPEN' AND other.bank_statement_id = $2)
+            RETURNING o.origin_job_id::text
+        `,
+		insertUnmatched: `
+            INSERT INTO reconciliation_unmatched_bank_tx (
+                job_id, bank_statement_id, unique_id, amount, currency, statement_time, bank_code, created_at, updated_at
+            )
+            SELECT $1, b.id, b.unique_id, b.amount, b.currency, b.statement_time, b.bank_code, NOW(), NOW()
+            FROM bank_statements b WHERE b.id = $2
+        `,
+		insertOpenItem: `
+            INSERT INTO reconciliation_open_items (
+                item_type, bank_statement_id, amount, currency, item_date, status, origin_job_id, created_at, updated_at
+            )
+            SELECT 'BANK_STATEMENT', b.id, b.amount, b.currency, b.statement_time, 'OPEN', $1, NOW(), NOW()
+            FROM bank_statements b WHERE b.id = $2
+            ON CONFLICT DO NOTHING
+        `,
+		openCase: `
+            INSERT INTO exception_cases (job_id, item_type, record_id, reference, bank_code, amount, currency, item_date, status, created_at, updated_at)
+            SELECT $1, 'BANK_STATEMENT', b.id, b.unique_id, b.bank_code, b.amount, b.currency, b.statement_time, 'OPEN', NOW(), NOW()
+            FROM bank_statements b WHERE b.id = $2
+            ON CONFLICT (job_id, item_type, record_id) DO UPDATE SET status = 'OPEN', closed_at = NULL, updated_at = NOW()
+        `,
+	}
+)
+
+// GetUnmatchedItems retrieves the unmatched rows of a job with the given ids, ids that are not exceptions of the job are left out
+func (r *reconciliationRepo) GetUnmatchedItems(ctx context.Context, jobID string, systemIDs, bankIDs []int) ([]domain.UnmatchedSystemTx, []domain.UnmatchedBankTx, error) {
+	const systemQuery = `
+        SELECT id, COALESCE(system_tx_id, 0), trx_id, currency, amount, trx_type, transaction_time, created_at, updated_at
+        FROM reconciliation_unmatched_system_tx
+        WHERE job_id = $1 AND id = ANY($2)
+        ORDER BY id
+    `
+	const bankQuery = `
+        SELECT id, COALESCE(bank_statement_id, 0), unique_id, currency, amount, statement_time, bank_code, created_at, updated_at
+        FROM reconciliation_unmatched_bank_tx
+        WHERE job_id = $1 AND id = ANY($2)
+        ORDER BY id
+    `
+
+	var systemItems []domain.UnmatchedSystemTx
+	if len(systemIDs) > 0 {
+		err := r.list(ctx, systemQuery, []any{jobID, systemIDs}, func(rows pgx.Rows) error {
+			tx := domain.UnmatchedSystemTx{JobID: jobID}
+			if err := rows.Scan(&tx.ID, &tx.SystemTxID, &tx.TrxID, &tx.Amount.Currency, &tx.Amount, &tx.Type, &tx.TransactionTime,
+				&tx.CreatedAt, &tx.UpdatedAt); err != nil {
+				return err
+			}
+			systemItems = append(systemItems, tx)
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var bankItems []domain.UnmatchedBankTx
+	if len(bankIDs) > 0 {
+		err := r.list(ctx, bankQuery, []any{jobID, bankIDs}, func(rows pgx.Rows) error {
+			stmt := domain.UnmatchedBankTx{JobID: jobID}
+			if err := rows.Scan(&stmt.ID, &stmt.BankStatementID, &stmt.UniqueID, &stmt.Amount.Currency, &stmt.Amount, &stmt.StatementDate,
+				&stmt.BankCode, &stmt.CreatedAt, &stmt.UpdatedAt); err != nil {
+				return err
+			}
+			bankItems = append(bankItems, stmt)
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return systemItems, bankItems, nil
+}
+
+// ApplyManualMatch turns the exceptions of a manual match into a matched record, or a match group for several bank lines,
+// resolves their open items and cases, approves the proposal it applies, records the change in the audit log and recomputes the counts of the job in one transaction.
+// ErrExceptionNotFound is returned when one of the items was resolved in the meantime, ErrProposalNotPending when the proposal was decided.
+func (r *reconciliationRepo) ApplyManualMatch(ctx context.Context, m domain.ManualMatch) (domain.ManualResolution, error) {
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	bankIDs := make([]int, 0, len(m.BankItems))
+	bankStatementIDs := make([]int, 0, len(m.BankItems))
+	for _, item := range m.BankItems {
+		bankIDs = append(bankIDs, item.ID)
+		bankStatementIDs = append(bankStatementIDs, item.BankStatementID)
+	}
+	if err := deleteExceptions(ctx, conn, "reconciliation_unmatched_system_tx", m.JobID, []int{m.SystemItem.ID}); err != nil {
+		return domain.ManualResolution{}, err
+	}
+	if err := deleteExceptions(ctx, conn, "reconciliation_unmatched_bank_tx", m.JobID, bankIDs); err != nil {
+		return domain.ManualResolution{}, err
+	}
+
+	var resolution domain.ManualResolution
+	details := map[string]any{
+		"system_tx_ids":      []int{m.SystemItem.SystemTxID},
+		"bank_statement_ids": bankStatementIDs,
+		"discrepancy":        m.Discrepancy.String(),
+		"currency":           m.Discrepancy.Currency,
+	}
+	if len(m.BankItems) == 1 {
+		bank := m.BankItems[0]
+		resolution.MatchID, err = insertMatchedRecord(ctx, conn, domain.MatchedRecord{
+			JobID:                 m.JobID,
+			SystemTxID:            m.SystemItem.SystemTxID,
+			BankStatementID:       bank.BankStatementID,
+			Discrepancy:           m.Discrepancy,
+			RuleSet:               domain.ManualRuleSet,
+			MatchPass:             "manual",
+			SystemAmount:          m.SystemItem.Amount,
+			SystemFXRate:          money.OneRate(),
+			SystemConvertedAmount: m.SystemItem.Amount,
+			BankAmount:            bank.Amount,
+			BankFXRate:            money.OneRate(),
+			BankConvertedAmount:   bank.Amount,
+			MatchedBy:             m.Actor,
+		})
+		details["match_id"] = resolution.MatchID
+	} else {
+		resolution.GroupID, err = insertMatchGroup(ctx, conn, domain.MatchGroup{
+			JobID:            m.JobID,
+			GroupType:        domain.OneToMany,
+			SystemTxIDs:      []int{m.SystemItem.SystemTxID},
+			BankStatementIDs: bankStatementIDs,
+			Discrepancy:      m.Discrepancy,
+			MatchedBy:        m.Actor,
+		})
+		details["group_id"] = resolution.GroupID
+	}
+	if err != nil {
+		return domain.ManualResolution{}, err
+	}
+
+	if err := resolveOpenItems(ctx, conn, m.JobID, []int{m.SystemItem.SystemTxID}, bankStatementIDs); err != nil {
+		return domain.ManualResolution{}, err
+	}
+	if err := closeCases(ctx, conn, domain.CaseResolved, []int{m.SystemItem.SystemTxID}, bankStatementIDs); err != nil {
+		return domain.ManualResolution{}, err
+	}
+	if err := approve(ctx, conn, m.Approval, details); err != nil {
+		return domain.ManualResolution{}, err
+	}
+	return r.finishResolution(ctx, conn, resolution, domain.AuditEntry{
+		JobID:   m.JobID,
+		Action:  domain.AuditManualMatch,
+		Actor:   m.Actor,
+		Reason:  m.Reason,
+		Details: details,
+	})
+}
+
+// ApplyUnmatch removes a matched record or match group and puts its records back where they were before the match:
+// an open item of an earlier job is outstanding again, any other record becomes an exception of the job.
+// The change is recorded in the audit log and the counts of the job are recomputed in the same transaction.
+func (r *reconciliationRepo) ApplyUnmatch(ctx context.Context, u domain.Unmatch) (domain.ManualResolution, error) {
+	const deleteRecordQuery = `
+        DELETE FROM reconciliation_matched_records
+        WHERE job_id = $1 AND id = $2
+        RETURNING system_tx_id, bank_statement_id, currency, discrepancy
+    `
+	const deleteMembersQuery = `
+        DELETE FROM reconciliation_match_group_members
+        WHERE job_id = $1 AND group_id = $2
+        RETURNING COALESCE(system_tx_id, 0), COALESCE(bank_statement_id, 0)
+    `
+	const deleteGroupQuery = `
+        DELETE FROM reconciliation_match_groups
+        WHERE job_id = $1 AND id = $2
+        RETURNING currency, discrepancy
+    `
+
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	var (
+		systemTxIDs, bankStatementIDs []int
+		discrepancy                   money.Money
+		details                       = map[string]any{}
+	)
+	if u.MatchID != 0 {
+		var systemTxID, bankStatementID int
+		err := conn.QueryRow(ctx, deleteRecordQuery, u.JobID, u.MatchID).Scan(&systemTxID, &bankStatementID,
+			&discrepancy.Currency, &discrepancy)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ManualResolution{}, ErrMatchNotFound
+		}
+		if err != nil {
+			return domain.ManualResolution{}, fmt.Errorf("query row scan error: %w", err)
+		}
+		systemTxIDs, bankStatementIDs = []int{systemTxID}, []int{bankStatementID}
+		details["match_id"] = u.MatchID
+	} else {
+		rows, err := conn.Query(ctx, deleteMembersQuery, u.JobID, u.GroupID)
+		if err != nil {
+			return domain.ManualResolution{}, fmt.Errorf("query error: %w", err)
+		}
+		for rows.Next() {
+			var systemTxID, bankStatementID int
+			if err := rows.Scan(&systemTxID, &bankStatementID); err != nil {
+				rows.Close()
+				return domain.ManualResolution{}, fmt.Errorf("row scan error: %w", err)
+			}
+			if systemTxID != 0 {
+				systemTxIDs = append(systemTxIDs, systemTxID)
+			} else {
+				bankStatementIDs = append(bankStatementIDs, bankStatementID)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return domain.ManualResolution{}, fmt.Errorf("query error: %w", err)
+		}
+
+		err = conn.QueryRow(ctx, deleteGroupQuery, u.JobID, u.GroupID).Scan(&discrepancy.Currency, &discrepancy)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ManualResolution{}, ErrMatchNotFound
+		}
+		if err != nil {
+			return domain.ManualResolution{}, fmt.Errorf("query row scan error: %w", err)
+		}
+		details["group_id"] = u.GroupID
+	}
+
+	for _, id := range systemTxIDs {
+		if err := restoreRecord(ctx, conn, systemSide, u.JobID, id); err != nil {
+			return domain.ManualResolution{}, err
+		}
+	}
+	for _, id := range bankStatementIDs {
+		if err := restoreRecord(ctx, conn, bankSide, u.JobID, id); err != nil {
+			return domain.ManualResolution{}, err
+		}
+	}
+
+	details["system_tx_ids"] = systemTxIDs
+	details["bank_statement_ids"] = bankStatementIDs
+	details["discrepancy"] = discrepancy.String()
+	details["currency"] = discrepancy.Currency
+	return r.finishResolution(ctx, conn, domain.ManualResolution{}, domain.AuditEntry{
+		JobID:   u.JobID,
+		Action:  domain.AuditUnmatch,
+		Actor:   u.Actor,
+		Reason:  u.Reason,
+		Details: details,
+	})
+}
+
+// ApplyWriteOff closes an exception of a job without a counterpart: the unmatched row is replaced by a write-off,
+// its open item is resolved, its cases written off and the proposal it applies approved, the change is recorded in the audit log and the counts of the job are recomputed in one transaction
+func (r *reconciliationRepo) ApplyWriteOff(ctx context.Context, w domain.WriteOff) (domain.ManualResolution, error) {
+	const query = `
+        INSERT INTO reconciliation_write_offs (
+            job_id, item_type, system_tx_id, bank_statement_id, reference, amount, currency, item_date,
+            reason_code, note, written_off_by, created_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+        RETURNING id
+    `
+
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	table := "reconciliation_unmatched_system_tx"
+	var systemTxIDs, bankStatementIDs []int
+	if w.ItemType == domain.OpenItemBankStatement {
+		table = "reconciliation_unmatched_bank_tx"
+		bankStatementIDs = []int{*w.BankStatementID}
+	} else {
+		systemTxIDs = []int{*w.SystemTxID}
+	}
+	if err := deleteExceptions(ctx, conn, table, w.JobID, []int{w.ExceptionID}); err != nil {
+		return domain.ManualResolution{}, err
+	}
+
+	var resolution domain.ManualResolution
+	err = conn.QueryRow(ctx, query, w.JobID, w.ItemType, w.SystemTxID, w.BankStatementID, w.Reference, w.Amount, w.Amount.Currency,
+		w.ItemDate, w.ReasonCode, nullString(w.Note), w.WrittenOffBy).Scan(&resolution.WriteOffID)
+	if err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("query row scan error: %w", err)
+	}
+	if err := resolveOpenItems(ctx, conn, w.JobID, systemTxIDs, bankStatementIDs); err != nil {
+		return domain.ManualResolution{}, err
+	}
+	if err := closeCases(ctx, conn, domain.CaseWrittenOff, systemTxIDs, bankStatementIDs); err != nil {
+		return domain.ManualResolution{}, err
+	}
+	details := map[string]any{
+		"write_off_id":       resolution.WriteOffID,
+		"item_type":          w.ItemType,
+		"system_tx_ids":      systemTxIDs,
+		"bank_statement_ids": bankStatementIDs,
+		"reference":          w.Reference,
+		"amount":             w.Amount.String(),
+		"currency":           w.Amount.Currency,
+		"note":               w.Note,
+	}
+	if err := approve(ctx, conn, w.Approval, details); err != nil {
+		return domain.ManualResolution{}, err
+	}
+
+	return r.finishResolution(ctx, conn, resolution, domain.AuditEntry{
+		JobID:   w.JobID,
+		Action:  domain.AuditWriteOff,
+		Actor:   w.WrittenOffBy,
+		Reason:  w.ReasonCode,
+		Details: details,
+	})
+}
+
+// ListAuditLog retrieves the manual changes made to a job, oldest first
+func (r *reconciliationRepo) ListAuditLog(ctx context.Context, jobID string) ([]domain.AuditEntry, error) {
+	const query = `
+        SELECT id, job_id::text, action, actor, reason, details, created_at
+        FROM reconciliation_audit_log
+        WHERE job_id = $1
+        ORDER BY id
+    `
+
+	var entries []domain.AuditEntry
+	err := r.list(ctx, query, []any{jobID}, func(rows pgx.Rows) error {
+		var (
+			entry   domain.AuditEntry
+			details []byte
+		)
+		if err := rows.Scan(&entry.ID, &entry.JobID, &entry.Action, &entry.Actor, &entry.Reason, &details, &entry.CreatedAt); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(details, &entry.Details); err != nil {
+			return fmt.Errorf("unmarshal details error: %w", err)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// finishResolution writes the audit entry of a manual change, recomputes the counts of its job and commits the transaction
+func (r *reconciliationRepo) finishResolution(ctx context.Context, conn *pgx.Conn, resolution domain.ManualResolution, entry domain.AuditEntry) (domain.ManualResolution, error) {
+	const recountQuery = `
+        UPDATE reconciliation_results res
+        SET matched_count = (SELECT COUNT(*) FROM reconciliation_matched_records WHERE job_id = res.job_id),
+            matched_group_count = (SELECT COUNT(*) FROM reconciliation_match_groups WHERE job_id = res.job_id),
+            unmatched_system_count = (SELECT COUNT(*) FROM reconciliation_unmatched_system_tx WHERE job_id = res.job_id),
+            unmatched_bank_count = (SELECT COUNT(*) FROM reconciliation_unmatched_bank_tx WHERE job_id = res.job_id),
+            total_discrepancies = COALESCE((SELECT SUM(discrepancy) FROM reconciliation_matched_records WHERE job_id = res.job_id), 0)
+                                + COALESCE((SELECT SUM(discrepancy) FROM reconciliation_match_groups WHERE job_id = res.job_id), 0),
+            updated_at = NOW()
+        WHERE res.job_id = $1
+        RETURNING res.job_id::text, res.total_system_tx_count, res.total_bank_tx_count, res.matched_count, res.unmatched_system_count,
+                  res.unmatched_bank_count, res.matched_group_count, res.currency, res.total_discrepancies, res.created_at, res.updated_at
+    `
+
+	var err error
+	if resolution.AuditID, err = insertAuditEntry(ctx, conn, entry); err != nil {
+		return domain.ManualResolution{}, err
+	}
+
+	res := &resolution.Result
+	err = conn.QueryRow(ctx, recountQuery, entry.JobID).Scan(&res.JobID, &res.TotalSystemTxCount, &res.TotalBankTxCount,
+		&res.MatchedCount, &res.UnmatchedSystemCount, &res.UnmatchedBankCount, &res.MatchedGroupCount,
+		&res.TotalDiscrepancies.Currency, &res.TotalDiscrepancies, &res.CreatedAt, &res.UpdatedAt)
+	if err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("query row scan error: %w", err)
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("commit tx error: %w", err)
+	}
+	return resolution, nil
+}
+
+// insertAuditEntry appends an entry to the audit log
+func insertAuditEntry(ctx context.Context, conn *pgx.Conn, entry domain.AuditEntry) (int64, error) {
+	const query = `
+        INSERT INTO reconciliation_audit_log (job_id, action, actor, reason, details, created_at)
+        VALUES ($1, $2, $3, $4, $5, NOW())
+        RETURNING id
+    `
+
+	details, err := json.Marshal(entry.Details)
+	if err != nil {
+		return 0, fmt.Errorf("marshal details error: %w", err)
+	}
+	var id int64
+	if err := conn.QueryRow(ctx, query, entry.JobID, entry.Action, entry.Actor, entry.Reason, details).Scan(&id); err != nil {
+		return 0, fmt.Errorf("query row scan error: %w", err)
+	}
+	return id, nil
+}
+
+// approve marks the proposal a manual change applies as approved and notes the decision in the details of its audit entry
+func approve(ctx context.Context, conn *pgx.Conn, decision *domain.ProposalDecision, details map[string]any) error {
+	if decision == nil {
+		return nil
+	}
+	if _, err := decideProposal(ctx, conn, domain.ProposalApproved, *decision); err != nil {
+		return err
+	}
+	details["proposal_id"] = decision.ProposalID
+	details["approved_by"] = decision.DecidedBy
+	if decision.Note != "" {
+		details["approval_note"] = decision.Note
+	}
+	return nil
+}
+
+// deleteExceptions removes unmatched rows of a job, all of them have to be there
+func deleteExceptions(ctx context.Context, conn *pgx.Conn, table, jobID string, ids []int) error {
+	tag, err := conn.Exec(ctx, "DELETE FROM "+table+" WHERE job_id = $1 AND id = ANY($2)", jobID, ids)
+	if err != nil {
+		return fmt.Errorf("execute delete error: %w", err)
+	}
+	if int(tag.RowsAffected()) != len(ids) {
+		return ErrExceptionNotFound
+	}
+	return nil
+}
+
+// resolveOpenItems closes the outstanding items of records resolved by hand in a job
+func resolveOpenItems(ctx context.Context, conn *pgx.Conn, jobID string, systemTxIDs, bankStatementIDs []int) error {
+	const query = `
+        UPDATE reconciliation_open_items
+        SET status = 'RESOLVED', resolved_job_id = $1, resolved_at = NOW(), updated_at = NOW()
+        WHERE status = 'OPEN'
+          AND (system_tx_id = ANY($2) OR bank_statement_id = ANY($3))
+    `
+	if _, err := conn.Exec(ctx, query, jobID, systemTxIDs, bankStatementIDs); err != nil {
+		return fmt.Errorf("execute update error: %w", err)
+	}
+	return nil
+}
+
+// restoreRecord reopens the open item the job resolved for a record, a record the job itself left unmatched
+// or first saw when matching it becomes an exception of the job again. The case of the record is reopened in the job it is outstanding for.
+func restoreRecord(ctx context.Context, conn *pgx.Conn, side recordSide, jobID string, id int) error {
+	var originJobID string
+	err := conn.QueryRow(ctx, side.reopen, jobID, id).Scan(&originJobID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("query row scan error: %w", err)
+	}
+	reopened := err == nil
+	if reopened && originJobID != jobID {
+		if _, err := conn.Exec(ctx, side.openCase, originJobID, id); err != nil {
+			return fmt.Errorf("execute insert error: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := conn.Exec(ctx, side.insertUnmatched, jobID, id); err != nil {
+		return fmt.Errorf("execute insert error: %w", err)
+	}
+	if _, err := conn.Exec(ctx, side.openCase, jobID, id); err != nil {
+		return fmt.Errorf("execute insert error: %w", err)
+	}
+	if !reopened {
+		if _, err := conn.Exec(ctx, side.insertOpenItem, jobID, id); err != nil {
+			return fmt.Errorf("execute insert error: %w", err)
+		}
+	}
+	return nil
+}
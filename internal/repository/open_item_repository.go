@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure/sqlstore"
+	"time"
+)
+
+// ErrOpenItemResolved is returned when an item to resolve is no longer outstanding, another job resolved it first
+var ErrOpenItemResolved = errors.New("open item already resolved by another job")
+
+//go:generate mockgen -source=open_item_repository.go -destination=_mock/open_item_repository.go
+type OpenItemRepository interface {
+	FindOpenSystemTx(ctx context.Context, scope domain.DataScope, until time.Time) ([]domain.Transaction, error)
+	FindOpenBankStmts(ctx context.Context, scope domain.DataScope, until time.Time) ([]domain.BankStatement, error)
+	StoreOpenItems(ctx context.Context, items []domain.OpenItem) error
+	ResolveOpenItems(ctx context.Context, jobID string, systemTxIDs, bankStatementIDs []int) (int, error)
+	GetOpenItems(ctx context.Context, jobID string) ([]domain.OpenItem, error)
+	CountResolvedOpenItems(ctx context.Context, jobID string) (int, error)
+	ReopenWorkflowItems(ctx context.Context, workflowID, jobID string) error
+}
+
+type openItemRepo struct {
+	db sqlstore.Store
+}
+
+func NewOpenItemRepo(db sqlstore.Store) OpenItemRepository {
+	return &openItemRepo{db: db}
+}
+
+// FindOpenSystemTx retrieves the system transactions still outstanding, dated up to until,
+// limited to the items left by the runs of the scope's workflow and to the rows in its scope
+func (r *openItemRepo) FindOpenSystemTx(ctx context.Context, scope domain.DataScope, until time.Time) ([]domain.Transaction, error) {
+	const query = `
+        SELECT t.id, t.trx_id, t.currency, t.amount, t.trx_type, t.transaction_time,
+               COALESCE(t.ingestion_job_id::text, ''), COALESCE(t.workflow_id::text, ''), t.created_at, t.updated_at
+        FROM reconciliation_open_items o
+        JOIN system_transactions t ON t.id = o.system_tx_id
+        WHERE o.status = 'OPEN' AND o.item_date <= $1
+          AND ($2::uuid IS NULL
+               OR EXISTS (SELECT 1 FROM reconciliation_jobs j WHERE j.job_id = o.origin_job_id AND j.workflow_id = $2)
+               OR t.workflow_id = $2
+               OR EXISTS (SELECT 1 FROM workflow_system_tx_links l WHERE l.workflow_id = $2 AND l.system_tx_id = t.id)
+               OR ($3 AND t.created_at < (SELECT w.created_at FROM reconciliation_workflows w WHERE w.workflow_id = $2)))
+        ORDER BY t.transaction_time ASC
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, until, nullString(scope.WorkflowID), scope.IncludeHistorical)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []domain.Transaction
+	for rows.Next() {
+		var t domain.Transaction
+		if err := rows.Scan(&t.ID, &t.TrxID, &t.Amount.Currency, &t.Amount, &t.Type, &t.TransactionTime,
+			&t.IngestionJobID, &t.WorkflowID, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating open system transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// FindOpenBankStmts retrieves the bank statements still outstanding, dated up to until,
+// limited to the items left by the runs of the scope's workflow and to the rows in its scope
+func (r *openItemRepo) FindOpenBankStmts(ctx context.Context, scope domain.DataScope, until time.Time) ([]domain.BankStatement, error) {
+	const query = `
+        SELECT b.id, b.unique_id, b.currency, b.amount, b.statement_time, b.bank_code,
+               COALESCE(b.ingestion_job_id::text, ''), COALESCE(b.workflow_id::text, ''), b.created_at, b.updated_at
+        FROM reconciliation_open_items o
+        JOIN bank_statements b ON b.id = o.bank_statement_id
+        WHERE o.status = 'OPEN' AND o.item_date <= $1
+          AND ($2::uuid IS NULL
+               OR EXISTS (SELECT 1 FROM reconciliation_jobs j WHERE j.job_id = o.origin_job_id AND j.workflow_id = $2)
+               OR b.workflow_id = $2
+               OR EXISTS (SELECT 1 FROM workflow_bank_statement_links l WHERE l.workflow_id = $2 AND l.bank_statement_id = b.id)
+               OR ($3 AND b.created_at < (SELECT w.created_at FROM reconciliation_workflows w WHERE w.workflow_id = $2)))
+        ORDER BY b.statement_time ASC
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, until, nullString(scope.WorkflowID), scope.IncludeHistorical)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var statements []domain.BankStatement
+	for rows.Next() {
+		var b domain.BankStatement
+		if err := rows.Scan(&b.ID, &b.UniqueID, &b.Amount.Currency, &b.Amount, &b.StatementTime, &b.BankCode,
+			&b.IngestionJobID, &b.WorkflowID, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		statements = append(statements, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating open bank statements: %w", err)
+	}
+
+	return statements, nil
+}
+
+// StoreOpenItems adds records to the ledger, records that are already outstanding are left as they are
+func (r *openItemRepo) StoreOpenItems(ctx context.Context, items []domain.OpenItem) error {
+	const query = `
+        INSERT INTO reconciliation_open_items (
+            item_type, system_tx_id, bank_statement_id, amount, currency, item_date, status, origin_job_id, created_at, updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, 'OPEN', $7, NOW(), NOW())
+        ON CONFLICT DO NOTHING
+    `
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	for _, item := range items {
+		_, err := conn.Exec(ctx, query, item.ItemType, item.SystemTxID, item.BankStatementID, item.Amount, item.Amount.Currency,
+			item.ItemDate, item.OriginJobID)
+		if err != nil {
+			return fmt.Errorf("execute insert error: %w", err)
+		}
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
+
+// ResolveOpenItems closes the outstanding items of the given records with a reference to the job that matched them,
+// their open exception cases are resolved in the same transaction.
+// Nothing is resolved and ErrOpenItemResolved is returned when one of the records is no longer outstanding.
+func (r *openItemRepo) ResolveOpenItems(ctx context.Context, jobID string, systemTxIDs, bankStatementIDs []int) (int, error) {
+	const query = `
+        UPDATE reconciliation_open_items
+        SET status = 'RESOLVED',
+            resolved_job_id = $1,
+            resolved_at = NOW(),
+            updated_at = NOW()
+        WHERE status = 'OPEN'
+          AND (system_tx_id = ANY($2) OR bank_statement_id = ANY($3))
+    `
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	tag, err := conn.Exec(ctx, query, jobID, systemTxIDs, bankStatementIDs)
+	if err != nil {
+		return 0, fmt.Errorf("execute update error: %w", err)
+	}
+	// a record has at most one outstanding item, a concurrent job resolving one first leaves it out of the update
+	if int(tag.RowsAffected()) != len(systemTxIDs)+len(bankStatementIDs) {
+		return 0, ErrOpenItemResolved
+	}
+	if err := closeCases(ctx, conn, domain.CaseResolved, systemTxIDs, bankStatementIDs); err != nil {
+		return 0, err
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return 0, fmt.Errorf("commit tx error: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// GetOpenItems retrieves the items still outstanding up to the end of a job's period, oldest first
+func (r *openItemRepo) GetOpenItems(ctx context.Context, jobID string) ([]domain.OpenItem, error) {
+	const query = `
+        SELECT o.id, o.item_type, o.system_tx_id, o.bank_statement_id,
+               COALESCE(t.trx_id, b.unique_id, ''), COALESCE(b.bank_code, ''),
+               o.currency, o.amount, o.item_date, o.status, o.origin_job_id,
+               CURRENT_DATE - o.item_date::date, o.created_at, o.updated_at
+        FROM reconciliation_open_items o
+        JOIN reconciliation_jobs j ON j.job_id = $1
+        LEFT JOIN system_transactions t ON t.id = o.system_tx_id
+        LEFT JOIN bank_statements b ON b.id = o.bank_statement_id
+        WHERE o.status = 'OPEN' AND o.item_date::date <= j.end_date
+        ORDER BY o.item_date, o.id
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.OpenItem
+	for rows.Next() {
+		var item domain.OpenItem
+		if err := rows.Scan(&item.ID, &item.ItemType, &item.SystemTxID, &item.BankStatementID,
+			&item.Reference, &item.BankCode, &item.Amount.Currency, &item.Amount, &item.ItemDate, &item.Status, &item.OriginJobID,
+			&item.DaysOutstanding, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan open item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating open items: %w", err)
+	}
+
+	return items, nil
+}
+
+// CountResolvedOpenItems counts the items of earlier jobs a job matched
+func (r *openItemRepo) CountResolvedOpenItems(ctx context.Context, jobID string) (int, error) {
+	const query = `
+        SELECT COUNT(*) FROM reconciliation_open_items WHERE resolved_job_id = $1
+    `
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	var count int
+	if err := conn.QueryRow(ctx, query, jobID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("query error: %w", err)
+	}
+	return count, nil
+}
+
+// ReopenWorkflowItems undoes what earlier runs of a workflow did to the ledger before jobID runs it again:
+// the items they left outstanding are removed and the items they resolved are outstanding again
+func (r *openItemRepo) ReopenWorkflowItems(ctx context.Context, workflowID, jobID string) error {
+	const deleteQuery = `
+        DELETE FROM reconciliation_open_items o
+        USING reconciliation_jobs j
+        WHERE o.origin_job_id = j.job_id
+          AND j.workflow_id = $1 AND j.job_id <> $2
+          AND o.status = 'OPEN'
+    `
+	const reopenQuery = `
+        UPDATE reconciliation_open_items o
+        SET status = 'OPEN',
+            resolved_job_id = NULL,
+            resolved_at = NULL,
+            updated_at = NOW()
+        FROM reconciliation_jobs j
+        WHERE o.resolved_job_id = j.job_id
+          AND j.workflow_id = $1 AND j.job_id <> $2
+          AND NOT EXISTS (
+              SELECT 1 FROM reconciliation_open_items other
+              WHERE other.status = 'OPEN'
+                AND (other.system_tx_id = o.system_tx_id OR other.bank_statement_id = o.bank_statement_id)
+          )
+    `
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	if _, err := conn.Exec(ctx, deleteQuery, workflowID, jobID); err != nil {
+		return fmt.Errorf("execute delete error: %w", err)
+	}
+	if _, err := conn.Exec(ctx, reopenQuery, workflowID, jobID); err != nil {
+		return fmt.Errorf("execute update error: %w", err)
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
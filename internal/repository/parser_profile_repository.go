@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure/sqlstore"
+	"github.com/jackc/pgx/v5"
+)
+
+//go:generate mockgen -source=parser_profile_repository.go -destination=_mock/parser_profile_repository.go
+type ParserProfileRepository interface {
+	GetParserProfile(ctx context.Context, name string) (domain.ParserProfile, error)
+	ListParserProfiles(ctx context.Context) ([]domain.ParserProfile, error)
+	SaveParserProfile(ctx context.Context, profile domain.ParserProfile) error
+}
+
+// ErrParserProfileNotFound is returned for a profile name that is not stored
+var ErrParserProfileNotFound = errors.New("parser profile not found")
+
+type parserProfileRepo struct {
+	db sqlstore.Store
+}
+
+func NewParserProfileRepo(db sqlstore.Store) ParserProfileRepository {
+	return &parserProfileRepo{db: db}
+}
+
+func (r *parserProfileRepo) GetParserProfile(ctx context.Context, name string) (domain.ParserProfile, error) {
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.ParserProfile{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	var profile domain.ParserProfile
+	err = conn.QueryRow(ctx, `SELECT definition FROM parser_profiles WHERE name = $1`, name).Scan(&profile)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ParserProfile{}, ErrParserProfileNotFound
+	}
+	if err != nil {
+		return domain.ParserProfile{}, fmt.Errorf("query row scan error: %w", err)
+	}
+	profile.Name = name
+	return profile, nil
+}
+
+func (r *parserProfileRepo) ListParserProfiles(ctx context.Context) ([]domain.ParserProfile, error) {
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, `SELECT name, definition FROM parser_profiles ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []domain.ParserProfile
+	for rows.Next() {
+		var name string
+		var profile domain.ParserProfile
+		if err := rows.Scan(&name, &profile); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		profile.Name = name
+		profiles = append(profiles, profile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return profiles, nil
+}
+
+// SaveParserProfile stores a profile, replacing the definition of a profile of the same name
+func (r *parserProfileRepo) SaveParserProfile(ctx context.Context, profile domain.ParserProfile) error {
+	const query = `
+        INSERT INTO parser_profiles (name, definition, created_at, updated_at)
+        VALUES ($1, $2, NOW(), NOW())
+        ON CONFLICT (name) DO UPDATE SET definition = EXCLUDED.definition, updated_at = NOW()
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	if _, err := conn.Exec(ctx, query, profile.Name, profile); err != nil {
+		return fmt.Errorf("execute upsert error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queryBuilder collects the optional conditions of a list query together with their arguments
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// where adds a condition, every ? in it is replaced by the placeholder of the next value
+func (b *queryBuilder) where(condition string, values ...any) {
+	for _, v := range values {
+		condition = strings.Replace(condition, "?", b.arg(v), 1)
+	}
+	b.conditions = append(b.conditions, condition)
+}
+
+// arg adds a value and returns its placeholder
+func (b *queryBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// whereClause is empty when no condition was added
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return `
+        WHERE ` + strings.Join(b.conditions, `
+          AND `)
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/jackc/pgx/v5"
+)
+
+// ListAccountActivity totals, per bank account, the statement lines of the job's workflow dated in its period by how the job settled them,
+// along with the earliest opening and the latest closing balance reported for the period.
+// Accounts with reported balances but no line in the period are listed too, lines the job did not settle only count in the statement movements.
+func (r *reconciliationRepo) ListAccountActivity(ctx context.Context, jobID string) ([]domain.AccountActivity, error) {
+	const query = `
+        WITH job AS (
+            SELECT j.workflow_id, j.start_date, j.end_date FROM reconciliation_jobs j WHERE j.job_id = $1
+        ),
+        lines AS (
+            SELECT b.id, b.bank_code, b.account_number, b.currency, b.amount
+            FROM bank_statements b, job
+            WHERE b.statement_time::date BETWEEN job.start_date AND job.end_date
+              AND (job.workflow_id IS NULL
+                   OR b.workflow_id = job.workflow_id
+                   OR EXISTS (SELECT 1 FROM workflow_bank_statement_links l WHERE l.workflow_id = job.workflow_id AND l.bank_statement_id = b.id))
+        ),
+        matched_lines AS (
+            SELECT bank_statement_id AS line_id FROM reconciliation_matched_records WHERE job_id = $1
+            UNION
+            SELECT bank_statement_id FROM reconciliation_match_group_members WHERE job_id = $1 AND bank_statement_id IS NOT NULL
+        ),
+        -- the system side of a match as expected on the bank, a match group books it on its first bank line
+        system_side AS (
+            SELECT m.bank_statement_id AS line_id,
+                   CASE WHEN t.currency <> b.currency THEN b.amount
+                        WHEN t.trx_type = 'DEBIT' THEN -t.amount
+                        ELSE t.amount END AS amount
+            FROM reconciliation_matched_records m
+            JOIN system_transactions t ON t.id = m.system_tx_id
+            JOIN bank_statements b ON b.id = m.bank_statement_id
+            WHERE m.job_id = $1
+            UNION ALL
+            SELECT g.line_id, CASE WHEN t.trx_type = 'DEBIT' THEN -t.amount ELSE t.amount END
+            FROM (
+                SELECT group_id, MIN(bank_statement_id) AS line_id
+                FROM reconciliation_match_group_members
+                WHERE job_id = $1 AND bank_statement_id IS NOT NULL
+                GROUP BY group_id
+            ) g
+            JOIN reconciliation_match_group_members mem ON mem.group_id = g.group_id AND mem.system_tx_id IS NOT NULL
+            JOIN system_transactions t ON t.id = mem.system_tx_id
+        ),
+        accounts AS (
+            SELECT l.bank_code, l.account_number, l.currency,
+                   SUM(l.amount) AS statement_movements,
+                   COALESCE(SUM(l.amount) FILTER (WHERE l.id IN (SELECT line_id FROM matched_lines)), 0) AS matched_bank,
+                   COALESCE(SUM((SELECT SUM(s.amount) FROM system_side s WHERE s.line_id = l.id)), 0) AS system_movements,
+                   COALESCE(SUM(l.amount) FILTER (WHERE EXISTS (
+                       SELECT 1 FROM reconciliation_unmatched_bank_tx u WHERE u.job_id = $1 AND u.bank_statement_id = l.id)), 0) AS unmatched_bank,
+                   COALESCE(SUM(l.amount) FILTER (WHERE EXISTS (
+                       SELECT 1 FROM reconciliation_write_offs w WHERE w.job_id = $1 AND w.bank_statement_id = l.id)), 0) AS written_off
+            FROM lines l
+            GROUP BY l.bank_code, l.account_number, l.currency
+        ),
+        balances AS (
+            SELECT bal.bank_code, bal.account_number, bal.currency, bal.balance_type, bal.balance_date, bal.amount
+            FROM bank_account_balances bal, job
+            WHERE bal.balance_date BETWEEN job.start_date AND job.end_date
+        ),
+        all_accounts AS (
+            SELECT bank_code, account_number, currency FROM accounts
+            UNION
+            SELECT bal.bank_code, bal.account_number, bal.currency
+            FROM bank_account_balances bal, job
+            WHERE bal.balance_date BETWEEN job.start_date AND job.end_date
+              AND (job.workflow_id IS NULL OR bal.workflow_id = job.workflow_id)
+        )
+        SELECT k.bank_code, k.account_number, k.currency,
+               COALESCE(a.statement_movements, 0)::text, COALESCE(a.matched_bank, 0)::text, COALESCE(a.system_movements, 0)::text,
+               COALESCE(a.unmatched_bank, 0)::text, COALESCE(a.written_off, 0)::text,
+               (SELECT o.amount::text FROM balances o
+                WHERE o.bank_code = k.bank_code AND o.account_number = k.account_number AND o.currency = k.currency AND o.balance_type = 'OPENING'
+                ORDER BY o.balance_date LIMIT 1),
+               (SELECT c.amount::text FROM balances c
+                WHERE c.bank_code = k.bank_code AND c.account_number = k.account_number AND c.currency = k.currency AND c.balance_type = 'CLOSING'
+                ORDER BY c.balance_date DESC LIMIT 1)
+        FROM all_accounts k
+        LEFT JOIN accounts a ON a.bank_code = k.bank_code AND a.account_number = k.account_number AND a.currency = k.currency
+        ORDER BY k.bank_code, k.account_number, k.currency
+    `
+
+	var activity []domain.AccountActivity
+	err := r.list(ctx, query, []any{jobID}, func(rows pgx.Rows) error {
+		// the amounts are read as text and parsed once the currency of the account is known
+		var (
+			a                                                 domain.AccountActivity
+			statement, matched, system, unmatched, writtenOff string
+			opening, closing                                  *string
+		)
+		if err := rows.Scan(&a.BankCode, &a.AccountNumber, &a.Currency, &statement, &matched, &system, &unmatched, &writtenOff,
+			&opening, &closing); err != nil {
+			return err
+		}
+		for _, amount := range []struct {
+			text string
+			dst  *money.Money
+		}{
+			{statement, &a.StatementMovements},
+			{matched, &a.MatchedBank},
+			{system, &a.SystemMovements},
+			{unmatched, &a.UnmatchedBank},
+			{writtenOff, &a.WrittenOff},
+		} {
+			m, err := money.Parse(amount.text, a.Currency)
+			if err != nil {
+				return err
+			}
+			*amount.dst = m
+		}
+		var err error
+		if a.OpeningBalance, err = parseBalance(opening, a.Currency); err != nil {
+			return err
+		}
+		if a.ClosingBalance, err = parseBalance(closing, a.Currency); err != nil {
+			return err
+		}
+		activity = append(activity, a)
+		return nil
+	})
+	return activity, err
+}
+
+func parseBalance(text *string, currency string) (*money.Money, error) {
+	if text == nil {
+		return nil, nil
+	}
+	m, err := money.Parse(*text, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
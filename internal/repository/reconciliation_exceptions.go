@@ -0,0 +1,338 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnknownSort is returned for a sort key the list does not support
+var ErrUnknownSort = errors.New("unknown sort key")
+
+// sortKey is a column a list can be sorted by, cast turns the text of a cursor back into its type
+type sortKey struct {
+	expr string
+	cast string
+}
+
+// exceptionList describes the columns an exception list is filtered and sorted on, filters without a column are ignored
+type exceptionList struct {
+	id       string
+	jobID    string
+	bankCode string
+	trxType  string
+	amount   string
+	date     string
+	sorts    map[string]sortKey
+}
+
+var (
+	unmatchedSystemList = exceptionList{
+		id:      "u.id",
+		jobID:   "u.job_id",
+		trxType: "u.trx_type",
+		amount:  "u.amount",
+		date:    "u.transaction_time",
+		sorts: map[string]sortKey{
+			domain.SortByDate:      {"u.transaction_time", "timestamp"},
+			domain.SortByAmount:    {"u.amount", "numeric"},
+			domain.SortByReference: {"u.trx_id", "text"},
+		},
+	}
+	unmatchedBankList = exceptionList{
+		id:       "u.id",
+		jobID:    "u.job_id",
+		bankCode: "u.bank_code",
+		amount:   "u.amount",
+		date:     "u.statement_time",
+		sorts: map[string]sortKey{
+			domain.SortByDate:      {"u.statement_time", "timestamp"},
+			domain.SortByAmount:    {"u.amount", "numeric"},
+			domain.SortByReference: {"u.unique_id", "text"},
+			domain.SortByBankCode:  {"u.bank_code", "text"},
+		},
+	}
+	matchedPairList = exceptionList{
+		id:       "m.id",
+		jobID:    "m.job_id",
+		bankCode: "b.bank_code",
+		trxType:  "t.trx_type",
+		amount:   "t.amount",
+		date:     "t.transaction_time",
+		sorts: map[string]sortKey{
+			domain.SortByDate:        {"t.transaction_time", "timestamp"},
+			domain.SortByAmount:      {"t.amount", "numeric"},
+			domain.SortByReference:   {"t.trx_id", "text"},
+			domain.SortByBankCode:    {"b.bank_code", "text"},
+			domain.SortByDiscrepancy: {"m.discrepancy", "numeric"},
+		},
+	}
+	matchGroupList = exceptionList{
+		id:    "g.id",
+		jobID: "g.job_id",
+		date:  "g.matched_at",
+		sorts: map[string]sortKey{
+			domain.SortByDate:        {"g.matched_at", "timestamp"},
+			domain.SortByDiscrepancy: {"g.discrepancy", "numeric"},
+		},
+	}
+)
+
+// build adds the conditions of q to b and returns the sort expression and the ORDER BY / LIMIT tail of the query.
+// Rows are ordered by the sort key and then by id, so a page continues right after the (key, id) of the cursor.
+func (l exceptionList) build(b *queryBuilder, jobID string, q domain.ExceptionQuery) (string, string, error) {
+	sort, ok := l.sorts[q.SortBy]
+	if !ok {
+		return "", "", fmt.Errorf("%w %q", ErrUnknownSort, q.SortBy)
+	}
+
+	b.where(l.jobID+" = ?", jobID)
+	if q.BankCode != "" && l.bankCode != "" {
+		b.where(l.bankCode+" = ?", q.BankCode)
+	}
+	if q.Type != "" && l.trxType != "" {
+		b.where("lower("+l.trxType+") = lower(?)", q.Type)
+	}
+	if q.MinAmount != nil && l.amount != "" {
+		b.where(l.amount+" >= ?", *q.MinAmount)
+	}
+	if q.MaxAmount != nil && l.amount != "" {
+		b.where(l.amount+" <= ?", *q.MaxAmount)
+	}
+	if !q.From.IsZero() && l.date != "" {
+		b.where(l.date+" >= ?", q.From)
+	}
+	if !q.To.IsZero() && l.date != "" {
+		b.where(l.date+" < ?", q.To)
+	}
+
+	direction, compare := "ASC", ">"
+	if q.Desc {
+		direction, compare = "DESC", "<"
+	}
+	if q.After != nil {
+		b.where(fmt.Sprintf("(%s, %s) %s (?::%s, ?)", sort.expr, l.id, compare, sort.cast), q.After.Value, q.After.ID)
+	}
+
+	tail := fmt.Sprintf(`
+        ORDER BY %s %s, %s %s
+        LIMIT %s`, sort.expr, direction, l.id, direction, b.arg(q.Limit+1))
+	return sort.expr + "::text", tail, nil
+}
+
+// page keeps the first limit rows, the extra row read tells whether another page follows
+func page[T any](items []T, keys []string, ids []int, q domain.ExceptionQuery) ([]T, *domain.ExceptionCursor) {
+	if len(items) <= q.Limit {
+		return items, nil
+	}
+	last := q.Limit - 1
+	return items[:q.Limit], &domain.ExceptionCursor{SortBy: q.SortBy, Value: keys[last], ID: ids[last]}
+}
+
+// ListUnmatchedSystemTx retrieves one page of the system transactions a job left unmatched
+func (r *reconciliationRepo) ListUnmatchedSystemTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedSystemTx, *domain.ExceptionCursor, error) {
+	var b queryBuilder
+	sortExpr, tail, err := unmatchedSystemList.build(&b, jobID, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := `
+        SELECT u.id, COALESCE(u.system_tx_id, 0), u.trx_id, u.currency, u.amount, u.trx_type, u.transaction_time, u.created_at, u.updated_at, ` + sortExpr + `
+        FROM reconciliation_unmatched_system_tx u` + b.whereClause() + tail
+
+	var (
+		items []domain.UnmatchedSystemTx
+		keys  []string
+		ids   []int
+	)
+	err = r.list(ctx, query, b.args, func(rows pgx.Rows) error {
+		var (
+			tx  domain.UnmatchedSystemTx
+			key string
+		)
+		if err := rows.Scan(&tx.ID, &tx.SystemTxID, &tx.TrxID, &tx.Amount.Currency, &tx.Amount, &tx.Type, &tx.TransactionTime,
+			&tx.CreatedAt, &tx.UpdatedAt, &key); err != nil {
+			return err
+		}
+		tx.JobID = jobID
+		items, keys, ids = append(items, tx), append(keys, key), append(ids, tx.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	items, next := page(items, keys, ids, q)
+	return items, next, nil
+}
+
+// ListUnmatchedBankTx retrieves one page of the bank statements a job left unmatched
+func (r *reconciliationRepo) ListUnmatchedBankTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedBankTx, *domain.ExceptionCursor, error) {
+	var b queryBuilder
+	sortExpr, tail, err := unmatchedBankList.build(&b, jobID, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := `
+        SELECT u.id, COALESCE(u.bank_statement_id, 0), u.unique_id, u.currency, u.amount, u.statement_time, u.bank_code, u.created_at, u.updated_at, ` + sortExpr + `
+        FROM reconciliation_unmatched_bank_tx u` + b.whereClause() + tail
+
+	var (
+		items []domain.UnmatchedBankTx
+		keys  []string
+		ids   []int
+	)
+	err = r.list(ctx, query, b.args, func(rows pgx.Rows) error {
+		var (
+			stmt domain.UnmatchedBankTx
+			key  string
+		)
+		if err := rows.Scan(&stmt.ID, &stmt.BankStatementID, &stmt.UniqueID, &stmt.Amount.Currency, &stmt.Amount, &stmt.StatementDate, &stmt.BankCode,
+			&stmt.CreatedAt, &stmt.UpdatedAt, &key); err != nil {
+			return err
+		}
+		stmt.JobID = jobID
+		items, keys, ids = append(items, stmt), append(keys, key), append(ids, stmt.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	items, next := page(items, keys, ids, q)
+	return items, next, nil
+}
+
+// ListMatchedPairs retrieves one page of the 1-to-1 matches of a job with the records on both sides
+func (r *reconciliationRepo) ListMatchedPairs(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchedPair, *domain.ExceptionCursor, error) {
+	var b queryBuilder
+	sortExpr, tail, err := matchedPairList.build(&b, jobID, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := `
+        SELECT m.id, m.system_tx_id, m.bank_statement_id, m.currency, m.discrepancy, m.rule_set, m.match_pass, m.pass_number,
+               COALESCE(m.system_currency, t.currency), COALESCE(m.system_amount, t.amount), m.system_fx_rate,
+               m.currency, COALESCE(m.system_converted_amount, t.amount),
+               COALESCE(m.bank_currency, b.currency), COALESCE(m.bank_amount, b.amount), m.bank_fx_rate,
+               m.currency, COALESCE(m.bank_converted_amount, b.amount),
+               COALESCE(m.matched_by, ''), m.matched_at, t.trx_id, t.trx_type, t.transaction_time, b.unique_id, b.bank_code, b.statement_time, ` + sortExpr + `
+        FROM reconciliation_matched_records m
+        JOIN system_transactions t ON t.id = m.system_tx_id
+        JOIN bank_statements b ON b.id = m.bank_statement_id` + b.whereClause() + tail
+
+	var (
+		items []domain.MatchedPair
+		keys  []string
+		ids   []int
+	)
+	err = r.list(ctx, query, b.args, func(rows pgx.Rows) error {
+		var (
+			pair domain.MatchedPair
+			key  string
+		)
+		if err := rows.Scan(&pair.ID, &pair.SystemTxID, &pair.BankStatementID, &pair.Discrepancy.Currency, &pair.Discrepancy,
+			&pair.RuleSet, &pair.MatchPass, &pair.PassNumber,
+			&pair.SystemAmount.Currency, &pair.SystemAmount, &pair.SystemFXRate,
+			&pair.SystemConvertedAmount.Currency, &pair.SystemConvertedAmount,
+			&pair.BankAmount.Currency, &pair.BankAmount, &pair.BankFXRate,
+			&pair.BankConvertedAmount.Currency, &pair.BankConvertedAmount,
+			&pair.MatchedBy, &pair.MatchedAt, &pair.TrxID, &pair.TrxType, &pair.TransactionTime, &pair.UniqueID, &pair.BankCode, &pair.StatementTime,
+			&key); err != nil {
+			return err
+		}
+		pair.JobID = jobID
+		items, keys, ids = append(items, pair), append(keys, key), append(ids, pair.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	items, next := page(items, keys, ids, q)
+	return items, next, nil
+}
+
+// ListMatchGroups retrieves one page of the match groups of a job with their member ids
+func (r *reconciliationRepo) ListMatchGroups(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchGroup, *domain.ExceptionCursor, error) {
+	var b queryBuilder
+	sortExpr, tail, err := matchGroupList.build(&b, jobID, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := `
+        SELECT g.id, g.group_type, g.currency, g.discrepancy, COALESCE(g.matched_by, ''), g.matched_at,
+               COALESCE(array_agg(m.system_tx_id) FILTER (WHERE m.system_tx_id IS NOT NULL), '{}'),
+               COALESCE(array_agg(m.bank_statement_id) FILTER (WHERE m.bank_statement_id IS NOT NULL), '{}'),
+               ` + sortExpr + `
+        FROM reconciliation_match_groups g
+        LEFT JOIN reconciliation_match_group_members m ON m.group_id = g.id` + b.whereClause() + `
+        GROUP BY g.id` + tail
+
+	var (
+		items []domain.MatchGroup
+		keys  []string
+		ids   []int
+	)
+	err = r.list(ctx, query, b.args, func(rows pgx.Rows) error {
+		var (
+			group domain.MatchGroup
+			key   string
+		)
+		if err := rows.Scan(&group.ID, &group.GroupType, &group.Discrepancy.Currency, &group.Discrepancy, &group.MatchedBy, &group.MatchedAt,
+			&group.SystemTxIDs, &group.BankStatementIDs, &key); err != nil {
+			return err
+		}
+		group.JobID = jobID
+		items, keys, ids = append(items, group), append(keys, key), append(ids, group.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	items, next := page(items, keys, ids, q)
+	return items, next, nil
+}
+
+// CountUnmatchedBankByBank totals the unmatched bank statements of a job per bank
+func (r *reconciliationRepo) CountUnmatchedBankByBank(ctx context.Context, jobID string) ([]domain.BankExceptionCount, error) {
+	const query = `
+        SELECT bank_code, MIN(currency), COUNT(*), SUM(amount)
+        FROM reconciliation_unmatched_bank_tx
+        WHERE job_id = $1
+        GROUP BY bank_code
+        ORDER BY bank_code
+    `
+
+	var counts []domain.BankExceptionCount
+	err := r.list(ctx, query, []any{jobID}, func(rows pgx.Rows) error {
+		var count domain.BankExceptionCount
+		if err := rows.Scan(&count.BankCode, &count.Amount.Currency, &count.Count, &count.Amount); err != nil {
+			return err
+		}
+		counts = append(counts, count)
+		return nil
+	})
+	return counts, err
+}
+
+// list runs a query and hands every row to scan
+func (r *reconciliationRepo) list(ctx context.Context, query string, args []any, scan func(pgx.Rows) error) error {
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return fmt.Errorf("row scan error: %w", err)
+		}
+	}
+	return rows.Err()
+}
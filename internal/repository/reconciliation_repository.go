@@ -2,32 +2,66 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
 	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure/sqlstore"
+	"github.com/jackc/pgx/v5"
 )
 
 //go:generate mockgen -source=reconciliation_repository.go -destination=_mock/reconciliation_repository.go
 type ReconciliationRepository interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
 	CreateJob(ctx context.Context, job domain.ReconciliationJob) error
 	StoreResult(ctx context.Context, result domain.ReconciliationResult) (int, error)
 	StoreMatchedRecord(ctx context.Context, rec domain.MatchedRecord) (int, error)
+	StoreMatchGroup(ctx context.Context, group domain.MatchGroup) (int, error)
 	StoreUnmatchedSystemTx(ctx context.Context, txList []domain.UnmatchedSystemTx) error
 	StoreUnmatchedBankTx(ctx context.Context, txList []domain.UnmatchedBankTx) error
 	GetReconciliationResult(ctx context.Context, jobID string) (*domain.ReconciliationResult, error)
-	GetUnmatchedSystemTx(ctx context.Context, jobID string) ([]domain.UnmatchedSystemTx, error)
-	GetUnmatchedBankTxGroupedByBank(ctx context.Context, jobID string) (map[string][]domain.UnmatchedBankTx, error)
+	ListUnmatchedSystemTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedSystemTx, *domain.ExceptionCursor, error)
+	ListUnmatchedBankTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedBankTx, *domain.ExceptionCursor, error)
+	ListMatchedPairs(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchedPair, *domain.ExceptionCursor, error)
+	ListMatchGroups(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchGroup, *domain.ExceptionCursor, error)
+	CountUnmatchedBankByBank(ctx context.Context, jobID string) ([]domain.BankExceptionCount, error)
+	ListAccountActivity(ctx context.Context, jobID string) ([]domain.AccountActivity, error)
+	ListJobsByWorkflow(ctx context.Context, workflowID string) ([]domain.ReconciliationJob, error)
+	GetUnmatchedItems(ctx context.Context, jobID string, systemIDs, bankIDs []int) ([]domain.UnmatchedSystemTx, []domain.UnmatchedBankTx, error)
+	ApplyManualMatch(ctx context.Context, m domain.ManualMatch) (domain.ManualResolution, error)
+	ApplyUnmatch(ctx context.Context, u domain.Unmatch) (domain.ManualResolution, error)
+	ApplyWriteOff(ctx context.Context, w domain.WriteOff) (domain.ManualResolution, error)
+	ListAuditLog(ctx context.Context, jobID string) ([]domain.AuditEntry, error)
+	CreateProposal(ctx context.Context, p domain.Proposal) (domain.Proposal, error)
+	GetProposal(ctx context.Context, jobID string, proposalID int) (domain.Proposal, error)
+	ListProposals(ctx context.Context, jobID, status string) ([]domain.Proposal, error)
+	RejectProposal(ctx context.Context, d domain.ProposalDecision) (domain.Proposal, error)
 }
 
 type reconciliationRepo struct {
 	db sqlstore.Store
 }
 
+// WithinTx runs fn in one transaction, the repositories called with the context fn is given take part in it.
+// The transaction is committed when fn succeeds and rolled back otherwise.
+func (r *reconciliationRepo) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return fmt.Errorf("commit tx error: %w", err)
+	}
+	return nil
+}
+
 // CreateJob creates a new reconciliation job record in the database
 func (r *reconciliationRepo) CreateJob(ctx context.Context, job domain.ReconciliationJob) error {
 	const query = `
-        INSERT INTO reconciliation_jobs (job_id, start_date, end_date, created_at, updated_at)
-        VALUES ($1, $2, $3, NOW(), NOW())
+        INSERT INTO reconciliation_jobs (job_id, workflow_id, start_date, end_date, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, NOW(), NOW())
     `
 	conn, deferFunc, err := r.db.GetConn(ctx)
 	if err != nil {
@@ -35,7 +69,7 @@ func (r *reconciliationRepo) CreateJob(ctx context.Context, job domain.Reconcili
 	}
 	defer deferFunc()
 
-	_, err = conn.Exec(ctx, query, job.JobID, job.StartDate, job.EndDate)
+	_, err = conn.Exec(ctx, query, job.JobID, nullString(job.WorkflowID), job.StartDate, job.EndDate)
 	return err
 }
 
@@ -44,8 +78,8 @@ func (r *reconciliationRepo) StoreResult(ctx context.Context, result domain.Reco
 	const query = `
         INSERT INTO reconciliation_results (
             job_id, total_system_tx_count, total_bank_tx_count, matched_count,
-            unmatched_system_count, unmatched_bank_count, total_discrepancies, created_at, updated_at
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+            unmatched_system_count, unmatched_bank_count, matched_group_count, total_discrepancies, currency, created_at, updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
         RETURNING id
     `
 	conn, deferFunc, err := r.db.GetConn(ctx)
@@ -56,7 +90,7 @@ func (r *reconciliationRepo) StoreResult(ctx context.Context, result domain.Reco
 
 	var id int
 	err = conn.QueryRow(ctx, query, result.JobID, result.TotalSystemTxCount, result.TotalBankTxCount, result.MatchedCount,
-		result.UnmatchedSystemCount, result.UnmatchedBankCount, result.TotalDiscrepancies).Scan(&id)
+		result.UnmatchedSystemCount, result.UnmatchedBankCount, result.MatchedGroupCount, result.TotalDiscrepancies, result.TotalDiscrepancies.Currency).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("query row scan error: %w", err)
 	}
@@ -65,32 +99,98 @@ func (r *reconciliationRepo) StoreResult(ctx context.Context, result domain.Reco
 
 // StoreMatchedRecord stores a record of a match between a system transaction and a bank statement
 func (r *reconciliationRepo) StoreMatchedRecord(ctx context.Context, rec domain.MatchedRecord) (int, error) {
-	const query = `
-        INSERT INTO reconciliation_matched_records (
-            job_id, system_tx_id, bank_statement_id, discrepancy, matched_at
-        ) VALUES ($1, $2, $3, $4, NOW())
-        RETURNING id
-    `
 	conn, deferFunc, err := r.db.GetConn(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("GetConn error: %w", err)
 	}
 	defer deferFunc()
 
+	return insertMatchedRecord(ctx, conn, rec)
+}
+
+// StoreMatchGroup stores a split/aggregate match together with its member links
+func (r *reconciliationRepo) StoreMatchGroup(ctx context.Context, group domain.MatchGroup) (int, error) {
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	id, err := insertMatchGroup(ctx, conn, group)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return 0, fmt.Errorf("commit tx error: %w", err)
+	}
+	return id, nil
+}
+
+func insertMatchedRecord(ctx context.Context, conn *pgx.Conn, rec domain.MatchedRecord) (int, error) {
+	const query = `
+        INSERT INTO reconciliation_matched_records (
+            job_id, system_tx_id, bank_statement_id, discrepancy, currency, rule_set, match_pass, pass_number,
+            system_amount, system_currency, system_fx_rate, system_converted_amount,
+            bank_amount, bank_currency, bank_fx_rate, bank_converted_amount, matched_by, matched_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW())
+        RETURNING id
+    `
 	var id int
-	err = conn.QueryRow(ctx, query, rec.JobID, rec.SystemTxID, rec.BankStatementID, rec.Discrepancy).Scan(&id)
+	err := conn.QueryRow(ctx, query, rec.JobID, rec.SystemTxID, rec.BankStatementID, rec.Discrepancy, rec.Discrepancy.Currency,
+		rec.RuleSet, rec.MatchPass, rec.PassNumber,
+		rec.SystemAmount, rec.SystemAmount.Currency, rec.SystemFXRate, rec.SystemConvertedAmount,
+		rec.BankAmount, rec.BankAmount.Currency, rec.BankFXRate, rec.BankConvertedAmount, nullString(rec.MatchedBy)).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("query row scan error: %w", err)
 	}
 	return id, nil
 }
 
-// StoreUnmatchedSystemTx stores details of unmatched system transactions
+func insertMatchGroup(ctx context.Context, conn *pgx.Conn, group domain.MatchGroup) (int, error) {
+	const groupQuery = `
+        INSERT INTO reconciliation_match_groups (job_id, group_type, discrepancy, currency, matched_by, matched_at)
+        VALUES ($1, $2, $3, $4, $5, NOW())
+        RETURNING id
+    `
+	const memberQuery = `
+        INSERT INTO reconciliation_match_group_members (group_id, job_id, system_tx_id, bank_statement_id)
+        VALUES ($1, $2, $3, $4)
+    `
+
+	var id int
+	if err := conn.QueryRow(ctx, groupQuery, group.JobID, group.GroupType, group.Discrepancy, group.Discrepancy.Currency,
+		nullString(group.MatchedBy)).Scan(&id); err != nil {
+		return 0, fmt.Errorf("query row scan error: %w", err)
+	}
+	for _, systemTxID := range group.SystemTxIDs {
+		if _, err := conn.Exec(ctx, memberQuery, id, group.JobID, systemTxID, nil); err != nil {
+			return 0, fmt.Errorf("execute insert error: %w", err)
+		}
+	}
+	for _, bankStatementID := range group.BankStatementIDs {
+		if _, err := conn.Exec(ctx, memberQuery, id, group.JobID, nil, bankStatementID); err != nil {
+			return 0, fmt.Errorf("execute insert error: %w", err)
+		}
+	}
+	return id, nil
+}
+
+// StoreUnmatchedSystemTx stores details of unmatched system transactions and opens an exception case for each of them
 func (r *reconciliationRepo) StoreUnmatchedSystemTx(ctx context.Context, txList []domain.UnmatchedSystemTx) error {
 	const query = `
-        INSERT INTO reconciliation_unmatched_system_tx (
-            job_id, trx_id, amount, trx_type, transaction_time, created_at, updated_at
-        ) VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+        WITH u AS (
+            INSERT INTO reconciliation_unmatched_system_tx (
+                job_id, system_tx_id, trx_id, amount, currency, trx_type, transaction_time, created_at, updated_at
+            ) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+            RETURNING job_id, system_tx_id, trx_id, amount, currency, transaction_time
+        )
+        INSERT INTO exception_cases (job_id, item_type, record_id, reference, amount, currency, item_date, status, created_at, updated_at)
+        SELECT job_id, 'SYSTEM_TX', system_tx_id, trx_id, amount, currency, transaction_time, 'OPEN', NOW(), NOW() FROM u
+        ON CONFLICT DO NOTHING
     `
 	conn, deferFunc, err := r.db.GetConn(ctx)
 	if err != nil {
@@ -99,7 +199,7 @@ func (r *reconciliationRepo) StoreUnmatchedSystemTx(ctx context.Context, txList
 	defer deferFunc()
 
 	for _, tx := range txList {
-		_, err := conn.Exec(ctx, query, tx.JobID, tx.TrxID, tx.Amount, tx.Type, tx.TransactionTime)
+		_, err := conn.Exec(ctx, query, tx.JobID, nullInt(tx.SystemTxID), tx.TrxID, tx.Amount, tx.Amount.Currency, tx.Type, tx.TransactionTime)
 		if err != nil {
 			return fmt.Errorf("execute insert error: %w", err)
 		}
@@ -107,12 +207,18 @@ func (r *reconciliationRepo) StoreUnmatchedSystemTx(ctx context.Context, txList
 	return nil
 }
 
-// StoreUnmatchedBankTx stores details of unmatched bank transactions
+// StoreUnmatchedBankTx stores details of unmatched bank transactions and opens an exception case for each of them
 func (r *reconciliationRepo) StoreUnmatchedBankTx(ctx context.Context, txList []domain.UnmatchedBankTx) error {
 	const query = `
-        INSERT INTO reconciliation_unmatched_bank_tx (
-            job_id, unique_id, amount, statement_time, bank_code, created_at, updated_at
-        ) VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+        WITH u AS (
+            INSERT INTO reconciliation_unmatched_bank_tx (
+                job_id, bank_statement_id, unique_id, amount, currency, statement_time, bank_code, created_at, updated_at
+            ) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+            RETURNING job_id, bank_statement_id, unique_id, bank_code, amount, currency, statement_time
+        )
+        INSERT INTO exception_cases (job_id, item_type, record_id, reference, bank_code, amount, currency, item_date, status, created_at, updated_at)
+        SELECT job_id, 'BANK_STATEMENT', bank_statement_id, unique_id, bank_code, amount, currency, statement_time, 'OPEN', NOW(), NOW() FROM u
+        ON CONFLICT DO NOTHING
     `
 	conn, deferFunc, err := r.db.GetConn(ctx)
 	if err != nil {
@@ -121,7 +227,7 @@ func (r *reconciliationRepo) StoreUnmatchedBankTx(ctx context.Context, txList []
 	defer deferFunc()
 
 	for _, b := range txList {
-		_, err := conn.Exec(ctx, query, b.JobID, b.UniqueID, b.Amount, b.StatementDate, b.BankCode)
+		_, err := conn.Exec(ctx, query, b.JobID, nullInt(b.BankStatementID), b.UniqueID, b.Amount, b.Amount.Currency, b.StatementDate, b.BankCode)
 		if err != nil {
 			return fmt.Errorf("execute insert error: %w", err)
 		}
@@ -131,7 +237,7 @@ func (r *reconciliationRepo) StoreUnmatchedBankTx(ctx context.Context, txList []
 
 func (r *reconciliationRepo) GetReconciliationResult(ctx context.Context, jobID string) (*domain.ReconciliationResult, error) {
 	const query = `
-        SELECT job_id, total_system_tx_count, total_bank_tx_count, matched_count, unmatched_system_count, unmatched_bank_count, total_discrepancies, created_at, updated_at
+        SELECT job_id, total_system_tx_count, total_bank_tx_count, matched_count, unmatched_system_count, unmatched_bank_count, matched_group_count, currency, total_discrepancies, created_at, updated_at
         FROM reconciliation_results
         WHERE job_id = $1
     `
@@ -149,6 +255,8 @@ func (r *reconciliationRepo) GetReconciliationResult(ctx context.Context, jobID
 		&wf.MatchedCount,
 		&wf.UnmatchedSystemCount,
 		&wf.UnmatchedBankCount,
+		&wf.MatchedGroupCount,
+		&wf.TotalDiscrepancies.Currency,
 		&wf.TotalDiscrepancies,
 		&wf.CreatedAt,
 		&wf.UpdatedAt,
@@ -160,12 +268,13 @@ func (r *reconciliationRepo) GetReconciliationResult(ctx context.Context, jobID
 	return &wf, nil
 }
 
-func (r *reconciliationRepo) GetUnmatchedBankTxGroupedByBank(ctx context.Context, jobID string) (map[string][]domain.UnmatchedBankTx, error) {
+// ListJobsByWorkflow retrieves every reconciliation run of a workflow, oldest first
+func (r *reconciliationRepo) ListJobsByWorkflow(ctx context.Context, workflowID string) ([]domain.ReconciliationJob, error) {
 	const query = `
-        SELECT bank_code, unique_id, amount, statement_time
-        FROM reconciliation_unmatched_bank_tx
-        WHERE job_id = $1
-        ORDER BY bank_code, statement_time
+        SELECT job_id, COALESCE(workflow_id::text, ''), start_date, end_date, created_at, updated_at
+        FROM reconciliation_jobs
+        WHERE workflow_id = $1
+        ORDER BY created_at, job_id
     `
 
 	conn, deferFunc, err := r.db.GetConn(ctx)
@@ -174,66 +283,27 @@ func (r *reconciliationRepo) GetUnmatchedBankTxGroupedByBank(ctx context.Context
 	}
 	defer deferFunc()
 
-	rows, err := conn.Query(ctx, query, jobID)
+	rows, err := conn.Query(ctx, query, workflowID)
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
 	}
 	defer rows.Close()
-	groupedByBank := make(map[string][]domain.UnmatchedBankTx)
 
+	var jobs []domain.ReconciliationJob
 	for rows.Next() {
-		var bankTx domain.UnmatchedBankTx
-		if err := rows.Scan(&bankTx.BankCode, &bankTx.UniqueID, &bankTx.Amount, &bankTx.StatementDate); err != nil {
-			return nil, fmt.Errorf("scan unmatched bank statement: %w", err)
+		var job domain.ReconciliationJob
+		if err := rows.Scan(&job.JobID, &job.WorkflowID, &job.StartDate, &job.EndDate, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
 		}
-
-		groupedByBank[bankTx.BankCode] = append(groupedByBank[bankTx.BankCode], bankTx)
+		jobs = append(jobs, job)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating unmatched bank transactions: %w", err)
-	}
-
-	return groupedByBank, nil
+	return jobs, rows.Err()
 }
 
-func (r *reconciliationRepo) GetUnmatchedSystemTx(ctx context.Context, jobID string) ([]domain.UnmatchedSystemTx, error) {
-	const query = `
-        SELECT trx_id, amount, trx_type, transaction_time
-        FROM reconciliation_unmatched_system_tx
-        WHERE job_id = $1
-        ORDER BY transaction_time
-    `
-
-	conn, deferFunc, err := r.db.GetConn(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("GetConn error: %w", err)
-	}
-	defer deferFunc()
-
-	rows, err := conn.Query(ctx, query, jobID)
-	if err != nil {
-		return nil, fmt.Errorf("query error: %w", err)
-	}
-	defer rows.Close()
-
-	var unmatchedSystemTx []domain.UnmatchedSystemTx
-
-	for rows.Next() {
-		var tx domain.UnmatchedSystemTx
-		if err := rows.Scan(&tx.TrxID, &tx.Amount, &tx.Type, &tx.TransactionTime); err != nil {
-			return nil, fmt.Errorf("scan unmatched system transactions: %w", err)
-		}
-
-		tx.JobID = jobID
-		unmatchedSystemTx = append(unmatchedSystemTx, tx)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating unmatched bank transactions: %w", err)
-	}
-
-	return unmatchedSystemTx, nil
+// nullInt stores a zero id as NULL
+func nullInt(i int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(i), Valid: i != 0}
 }
 
 func NewReconciliationRepo(db sqlstore.Store) ReconciliationRepository {
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrProposalNotFound is returned for a proposal that is not a proposal of the job
+	ErrProposalNotFound = errors.New("proposal not found")
+	// ErrProposalNotPending is returned for a proposal that was already approved or rejected
+	ErrProposalNotPending = errors.New("proposal already decided")
+)
+
+const proposalColumns = `
+    id, job_id::text, action, COALESCE(system_exception_id, 0), COALESCE(bank_exception_ids, '{}'), COALESCE(item_type, ''),
+    COALESCE(exception_id, 0), COALESCE(reason_code, ''), reason, currency, amount, status, proposed_by,
+    COALESCE(decided_by, ''), COALESCE(decision_note, ''), created_at, decided_at
+`
+
+// CreateProposal stores a manual match or write-off waiting for approval and records it in the audit log in one transaction
+func (r *reconciliationRepo) CreateProposal(ctx context.Context, p domain.Proposal) (domain.Proposal, error) {
+	const query = `
+        INSERT INTO resolution_proposals (
+            job_id, action, system_exception_id, bank_exception_ids, item_type, exception_id, reason_code, reason,
+            amount, currency, status, proposed_by, created_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'PENDING', $11, NOW())
+        RETURNING id, created_at
+    `
+
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.Proposal{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	var systemExceptionID, exceptionID *int
+	if p.Action == domain.AuditManualMatch {
+		systemExceptionID = &p.SystemExceptionID
+	} else {
+		exceptionID = &p.ExceptionID
+	}
+	err = conn.QueryRow(ctx, query, p.JobID, p.Action, systemExceptionID, p.BankExceptionIDs, nullString(p.ItemType), exceptionID,
+		nullString(p.ReasonCode), p.Reason, p.Amount, p.Amount.Currency, p.ProposedBy).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return domain.Proposal{}, fmt.Errorf("query row scan error: %w", err)
+	}
+	p.Status = domain.ProposalPending
+
+	if _, err := insertAuditEntry(ctx, conn, domain.AuditEntry{
+		JobID:   p.JobID,
+		Action:  domain.AuditPropose,
+		Actor:   p.ProposedBy,
+		Reason:  p.Reason,
+		Details: proposalDetails(p),
+	}); err != nil {
+		return domain.Proposal{}, err
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return domain.Proposal{}, fmt.Errorf("commit tx error: %w", err)
+	}
+	return p, nil
+}
+
+// GetProposal retrieves a proposal of a job
+func (r *reconciliationRepo) GetProposal(ctx context.Context, jobID string, proposalID int) (domain.Proposal, error) {
+	query := `SELECT ` + proposalColumns + ` FROM resolution_proposals WHERE job_id = $1 AND id = $2`
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.Proposal{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	p, err := scanProposal(conn.QueryRow(ctx, query, jobID, proposalID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Proposal{}, ErrProposalNotFound
+	}
+	if err != nil {
+		return domain.Proposal{}, fmt.Errorf("query row scan error: %w", err)
+	}
+	return p, nil
+}
+
+// ListProposals retrieves the proposals of a job, oldest first, only those in the given status when it is set
+func (r *reconciliationRepo) ListProposals(ctx context.Context, jobID, status string) ([]domain.Proposal, error) {
+	query := `SELECT ` + proposalColumns + ` FROM resolution_proposals WHERE job_id = $1 AND ($2 = '' OR status = $2) ORDER BY id`
+
+	var proposals []domain.Proposal
+	err := r.list(ctx, query, []any{jobID, status}, func(rows pgx.Rows) error {
+		p, err := scanProposal(rows)
+		if err != nil {
+			return err
+		}
+		proposals = append(proposals, p)
+		return nil
+	})
+	return proposals, err
+}
+
+// RejectProposal marks a pending proposal of a job as rejected and records the decision in the audit log in one transaction.
+// ErrProposalNotPending is returned when the proposal was decided in the meantime.
+func (r *reconciliationRepo) RejectProposal(ctx context.Context, d domain.ProposalDecision) (domain.Proposal, error) {
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.Proposal{}, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	p, err := decideProposal(ctx, conn, domain.ProposalRejected, d)
+	if err != nil {
+		return domain.Proposal{}, err
+	}
+	if _, err := insertAuditEntry(ctx, conn, domain.AuditEntry{
+		JobID:   p.JobID,
+		Action:  domain.AuditReject,
+		Actor:   d.DecidedBy,
+		Reason:  d.Note,
+		Details: proposalDetails(p),
+	}); err != nil {
+		return domain.Proposal{}, err
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return domain.Proposal{}, fmt.Errorf("commit tx error: %w", err)
+	}
+	return p, nil
+}
+
+// decideProposal moves a pending proposal of a job to the given status, a proposal can only be decided once
+func decideProposal(ctx context.Context, conn *pgx.Conn, status string, d domain.ProposalDecision) (domain.Proposal, error) {
+	query := `
+        UPDATE resolution_proposals
+        SET status = $3, decided_by = $4, decision_note = $5, decided_at = NOW()
+        WHERE job_id = $1 AND id = $2 AND status = 'PENDING'
+        RETURNING ` + proposalColumns
+
+	p, err := scanProposal(conn.QueryRow(ctx, query, d.JobID, d.ProposalID, status, d.DecidedBy, nullString(d.Note)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Proposal{}, ErrProposalNotPending
+	}
+	if err != nil {
+		return domain.Proposal{}, fmt.Errorf("query row scan error: %w", err)
+	}
+	return p, nil
+}
+
+func scanProposal(row pgx.Row) (domain.Proposal, error) {
+	var p domain.Proposal
+	err := row.Scan(&p.ID, &p.JobID, &p.Action, &p.SystemExceptionID, &p.BankExceptionIDs, &p.ItemType, &p.ExceptionID,
+		&p.ReasonCode, &p.Reason, &p.Amount.Currency, &p.Amount, &p.Status, &p.ProposedBy, &p.DecidedBy, &p.DecisionNote,
+		&p.CreatedAt, &p.DecidedAt)
+	return p, err
+}
+
+// proposalDetails describes a proposal in the audit log
+func proposalDetails(p domain.Proposal) map[string]any {
+	details := map[string]any{
+		"proposal_id": p.ID,
+		"action":      p.Action,
+		"amount":      p.Amount.String(),
+		"currency":    p.Amount.Currency,
+		"proposed_by": p.ProposedBy,
+	}
+	if p.Action == domain.AuditManualMatch {
+		details["system_exception_id"] = p.SystemExceptionID
+		details["bank_exception_ids"] = p.BankExceptionIDs
+	} else {
+		details["item_type"] = p.ItemType
+		details["exception_id"] = p.ExceptionID
+		details["reason_code"] = p.ReasonCode
+	}
+	return details
+}
@@ -3,17 +3,53 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
 	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure/sqlstore"
+	"github.com/jackc/pgx/v5"
+	"slices"
+	"time"
 )
 
+//go:generate mockgen -source=workflow_repository.go -destination=_mock/workflow_repository.go
 type WorkflowRepository interface {
 	CreateWorkflow(ctx context.Context, wf domain.Workflow) error
 	GetWorkflow(ctx context.Context, workflowID string) (domain.Workflow, error)
 	UpdateWorkflow(ctx context.Context, wf domain.Workflow) error
+	TransitionWorkflow(ctx context.Context, wf domain.Workflow, fromStatus, reason string) (bool, error)
+	ClaimActiveWorkflows(ctx context.Context, workerID string, limit int, lease time.Duration) ([]domain.Workflow, error)
+	HeartbeatWorkflow(ctx context.Context, workflowID, workerID string, lease time.Duration) (bool, error)
+	ReleaseWorkflow(ctx context.Context, workflowID, workerID string) error
+	GetTransitions(ctx context.Context, workflowID string) ([]domain.WorkflowTransition, error)
+	CancelWorkflow(ctx context.Context, workflowID, reason string) (domain.Workflow, bool, error)
+	ListWorkflows(ctx context.Context, filter domain.WorkflowFilter) ([]domain.WorkflowListItem, error)
 }
 
+// ErrWorkflowNotFound is returned for a workflow id that is not stored
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// activeStatuses are the workflow statuses the worker still has to advance
+var activeStatuses = []string{"PENDING", "INGESTING", "RECONCILING"}
+
+const workflowColumns = `
+          w.workflow_id,
+          w.system_ingestion_job_id,
+          w.bank_ingestion_job_id,
+          w.reconciliation_job_id,
+          w.status,
+          COALESCE(w.system_file, ''),
+          w.bank_files,
+          w.file_profiles,
+          COALESCE(w.error_message, ''),
+          w.start_date,
+          w.end_date,
+          w.match_options,
+          COALESCE(w.source_workflow_id::text, ''),
+          COALESCE(w.created_by, ''),
+          w.created_at,
+          w.updated_at`
+
 // workflowRepo works with the sqlstore.Store to manage ReconciliationWorkflow records
 type workflowRepo struct {
 	db sqlstore.Store
@@ -33,8 +69,14 @@ func (r *workflowRepo) CreateWorkflow(ctx context.Context, wf domain.Workflow) e
             reconciliation_job_id,
             status,
             start_date,
-            end_date
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7)
+            end_date,
+            match_options,
+            system_file,
+            bank_files,
+            file_profiles,
+            source_workflow_id,
+            created_by
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
     `
 
 	// Begin a new transaction
@@ -56,11 +98,25 @@ func (r *workflowRepo) CreateWorkflow(ctx context.Context, wf domain.Workflow) e
 		wf.Status,
 		wf.StartDate,
 		wf.EndDate,
+		wf.MatchOptions,
+		wf.SystemFile,
+		bankFiles(wf.BankFiles),
+		fileProfiles(wf.FileProfiles),
+		nullString(wf.SourceWorkflowID),
+		nullString(wf.CreatedBy),
 	)
 	if err != nil {
 		return fmt.Errorf("insert workflow error: %w", err)
 	}
 
+	reason := "workflow created"
+	if wf.SourceWorkflowID != "" {
+		reason = "cloned from workflow " + wf.SourceWorkflowID
+	}
+	if err := insertTransition(ctx, conn, wf.WorkflowID, "", wf.Status, reason); err != nil {
+		return err
+	}
+
 	// Commit the transaction
 	if err := r.db.CommitTx(ctx); err != nil {
 		return fmt.Errorf("commit tx error: %w", err)
@@ -72,18 +128,9 @@ func (r *workflowRepo) CreateWorkflow(ctx context.Context, wf domain.Workflow) e
 // GetWorkflow retrieves a workflow by its ID from the reconciliation_workflows table
 func (r *workflowRepo) GetWorkflow(ctx context.Context, id string) (domain.Workflow, error) {
 	const query = `
-        SELECT
-          workflow_id,
-          system_ingestion_job_id,
-          bank_ingestion_job_id,
-          reconciliation_job_id,
-          status,
-          start_date,
-          end_date,
-          created_at,
-          updated_at
-        FROM reconciliation_workflows
-        WHERE workflow_id = $1
+        SELECT` + workflowColumns + `
+        FROM reconciliation_workflows w
+        WHERE w.workflow_id = $1
     `
 
 	conn, deferFunc, err := r.db.GetConn(ctx)
@@ -92,18 +139,10 @@ func (r *workflowRepo) GetWorkflow(ctx context.Context, id string) (domain.Workf
 	}
 	defer deferFunc()
 
-	var wf domain.Workflow
-	err = conn.QueryRow(ctx, query, id).Scan(
-		&wf.WorkflowID,
-		&wf.SystemIngestionJobID,
-		&wf.BankIngestionJobID,
-		&wf.ReconciliationJobID,
-		&wf.Status,
-		&wf.StartDate,
-		&wf.EndDate,
-		&wf.CreatedAt,
-		&wf.UpdatedAt,
-	)
+	wf, err := scanWorkflow(conn.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Workflow{}, ErrWorkflowNotFound
+	}
 	if err != nil {
 		return domain.Workflow{}, fmt.Errorf("query error: %w", err)
 	}
@@ -171,3 +210,397 @@ func (r *workflowRepo) UpdateWorkflow(ctx context.Context, wf domain.Workflow) e
 
 	return nil
 }
+
+// TransitionWorkflow moves the workflow from fromStatus to wf.Status, storing its job ids, error message and match options.
+// It returns false without changing anything when the workflow is no longer in fromStatus.
+func (r *workflowRepo) TransitionWorkflow(ctx context.Context, wf domain.Workflow, fromStatus, reason string) (bool, error) {
+	const query = `
+        UPDATE reconciliation_workflows
+        SET system_ingestion_job_id = $1,
+            bank_ingestion_job_id = $2,
+            reconciliation_job_id = $3,
+            status = $4,
+            error_message = $5,
+            match_options = $8,
+            updated_at = NOW()
+        WHERE workflow_id = $6 AND status = $7
+    `
+
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	tag, err := conn.Exec(ctx, query,
+		nullStringPtr(wf.SystemIngestionJobID),
+		nullStringPtr(wf.BankIngestionJobID),
+		nullStringPtr(wf.ReconciliationJobID),
+		wf.Status,
+		nullString(wf.ErrorMessage),
+		wf.WorkflowID,
+		fromStatus,
+		wf.MatchOptions,
+	)
+	if err != nil {
+		return false, fmt.Errorf("update workflow error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := insertTransition(ctx, conn, wf.WorkflowID, fromStatus, wf.Status, reason); err != nil {
+		return false, err
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return false, fmt.Errorf("commit tx error: %w", err)
+	}
+
+	return true, nil
+}
+
+// ClaimActiveWorkflows leases up to limit workflows that are not COMPLETED or FAILED to the worker,
+// skipping the ones another worker holds an unexpired lease on. The workflows released longest ago come first.
+func (r *workflowRepo) ClaimActiveWorkflows(ctx context.Context, workerID string, limit int, lease time.Duration) ([]domain.Workflow, error) {
+	const query = `
+        UPDATE reconciliation_workflows w
+        SET worker_id = $1,
+            lease_expires_at = NOW() + make_interval(secs => $3)
+        FROM (
+            SELECT workflow_id
+            FROM reconciliation_workflows
+            WHERE status = ANY($4)
+              AND (lease_expires_at IS NULL OR lease_expires_at <= NOW())
+            ORDER BY lease_expires_at ASC NULLS FIRST, created_at ASC
+            LIMIT $2
+            FOR UPDATE SKIP LOCKED
+        ) claimed
+        WHERE w.workflow_id = claimed.workflow_id
+        RETURNING` + workflowColumns + `
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, workerID, limit, lease.Seconds(), activeStatuses)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var workflows []domain.Workflow
+	for rows.Next() {
+		wf, err := scanWorkflow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		workflows = append(workflows, wf)
+	}
+
+	return workflows, rows.Err()
+}
+
+// HeartbeatWorkflow extends the lease the worker holds on a workflow, it returns false once the lease was lost
+// or the workflow was cancelled
+func (r *workflowRepo) HeartbeatWorkflow(ctx context.Context, workflowID, workerID string, lease time.Duration) (bool, error) {
+	const query = `
+        UPDATE reconciliation_workflows
+        SET lease_expires_at = NOW() + make_interval(secs => $3)
+        WHERE workflow_id = $1 AND worker_id = $2 AND status = ANY($4)
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	tag, err := conn.Exec(ctx, query, workflowID, workerID, lease.Seconds(), activeStatuses)
+	if err != nil {
+		return false, fmt.Errorf("update workflow error: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseWorkflow ends the lease of the worker so any worker can advance the workflow again,
+// the lease is expired rather than cleared so the workflow goes behind the ones not looked at yet
+func (r *workflowRepo) ReleaseWorkflow(ctx context.Context, workflowID, workerID string) error {
+	const query = `
+        UPDATE reconciliation_workflows
+        SET lease_expires_at = NOW()
+        WHERE workflow_id = $1 AND worker_id = $2
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	if _, err := conn.Exec(ctx, query, workflowID, workerID); err != nil {
+		return fmt.Errorf("update workflow error: %w", err)
+	}
+	return nil
+}
+
+// GetTransitions returns the status changes of a workflow, oldest first
+func (r *workflowRepo) GetTransitions(ctx context.Context, workflowID string) ([]domain.WorkflowTransition, error) {
+	const query = `
+        SELECT id, workflow_id, COALESCE(from_status, ''), to_status, COALESCE(reason, ''), created_at
+        FROM workflow_transitions
+        WHERE workflow_id = $1
+        ORDER BY id ASC
+    `
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var transitions []domain.WorkflowTransition
+	for rows.Next() {
+		var t domain.WorkflowTransition
+		if err := rows.Scan(&t.ID, &t.WorkflowID, &t.FromStatus, &t.ToStatus, &t.Reason, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		transitions = append(transitions, t)
+	}
+
+	return transitions, rows.Err()
+}
+
+// CancelWorkflow moves a workflow that is still active to CANCELLED together with its unfinished ingestion jobs.
+// The workers running them notice on their next heartbeat and stop. It returns false when the workflow is not active.
+func (r *workflowRepo) CancelWorkflow(ctx context.Context, workflowID, reason string) (domain.Workflow, bool, error) {
+	const selectQuery = `
+        SELECT` + workflowColumns + `
+        FROM reconciliation_workflows w
+        WHERE w.workflow_id = $1
+        FOR UPDATE
+    `
+	const cancelWorkflowQuery = `
+        UPDATE reconciliation_workflows
+        SET status = 'CANCELLED',
+            updated_at = NOW()
+        WHERE workflow_id = $1
+    `
+	const cancelJobsQuery = `
+        UPDATE ingestion_jobs
+        SET status = 'CANCELLED',
+            next_attempt_at = NULL,
+            updated_at = NOW()
+        WHERE workflow_id = $1 AND status IN ('PENDING', 'IN_PROGRESS')
+    `
+
+	ctx = r.db.BeginTx(ctx)
+	defer r.db.RollbackTx(ctx)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return domain.Workflow{}, false, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	wf, err := scanWorkflow(conn.QueryRow(ctx, selectQuery, workflowID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Workflow{}, false, ErrWorkflowNotFound
+	}
+	if err != nil {
+		return domain.Workflow{}, false, fmt.Errorf("query error: %w", err)
+	}
+	if !slices.Contains(activeStatuses, wf.Status) {
+		return wf, false, nil
+	}
+
+	if _, err := conn.Exec(ctx, cancelWorkflowQuery, workflowID); err != nil {
+		return domain.Workflow{}, false, fmt.Errorf("update workflow error: %w", err)
+	}
+	if _, err := conn.Exec(ctx, cancelJobsQuery, workflowID); err != nil {
+		return domain.Workflow{}, false, fmt.Errorf("update ingestion jobs error: %w", err)
+	}
+	if err := insertTransition(ctx, conn, workflowID, wf.Status, "CANCELLED", reason); err != nil {
+		return domain.Workflow{}, false, err
+	}
+
+	if err := r.db.CommitTx(ctx); err != nil {
+		return domain.Workflow{}, false, fmt.Errorf("commit tx error: %w", err)
+	}
+
+	wf.Status = "CANCELLED"
+	return wf, true, nil
+}
+
+// ListWorkflows retrieves up to filter.Limit workflows matching the filter, newest first and after filter.After,
+// each with the counts of the reconciliation job it points at
+func (r *workflowRepo) ListWorkflows(ctx context.Context, filter domain.WorkflowFilter) ([]domain.WorkflowListItem, error) {
+	var b queryBuilder
+	if len(filter.Statuses) > 0 {
+		b.where("w.status = ANY(?)", filter.Statuses)
+	}
+	if !filter.From.IsZero() {
+		b.where("w.end_date >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		b.where("w.start_date <= ?", filter.To)
+	}
+	if filter.CreatedBy != "" {
+		b.where("w.created_by = ?", filter.CreatedBy)
+	}
+	if filter.BankCode != "" {
+		b.where(`(EXISTS (SELECT 1 FROM bank_statements b WHERE b.workflow_id = w.workflow_id AND b.bank_code = ?)
+            OR EXISTS (SELECT 1 FROM workflow_bank_statement_links l JOIN bank_statements b ON b.id = l.bank_statement_id
+                       WHERE l.workflow_id = w.workflow_id AND b.bank_code = ?))`, filter.BankCode, filter.BankCode)
+	}
+	if filter.FileName != "" {
+		b.where(`(strpos(lower(w.system_file), lower(?)) > 0
+            OR EXISTS (SELECT 1 FROM unnest(w.bank_files) f WHERE strpos(lower(f), lower(?)) > 0))`, filter.FileName, filter.FileName)
+	}
+	if filter.After != nil {
+		b.where("(w.created_at, w.workflow_id) < (?, ?::uuid)", filter.After.CreatedAt, filter.After.WorkflowID)
+	}
+
+	query := `
+        SELECT` + workflowColumns + `,
+          r.job_id IS NOT NULL,
+          COALESCE(r.total_system_tx_count, 0),
+          COALESCE(r.total_bank_tx_count, 0),
+          COALESCE(r.matched_count, 0),
+          COALESCE(r.unmatched_system_count, 0),
+          COALESCE(r.unmatched_bank_count, 0),
+          COALESCE(r.matched_group_count, 0),
+          COALESCE(r.currency, ''),
+          COALESCE(r.total_discrepancies, 0)
+        FROM reconciliation_workflows w
+        LEFT JOIN reconciliation_results r ON r.job_id = w.reconciliation_job_id` + b.whereClause() + `
+        ORDER BY w.created_at DESC, w.workflow_id DESC
+        LIMIT ` + b.arg(filter.Limit)
+
+	conn, deferFunc, err := r.db.GetConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetConn error: %w", err)
+	}
+	defer deferFunc()
+
+	rows, err := conn.Query(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.WorkflowListItem
+	for rows.Next() {
+		var (
+			item      domain.WorkflowListItem
+			hasResult bool
+			result    domain.ReconciliationResult
+		)
+		err := rows.Scan(
+			&item.WorkflowID,
+			&item.SystemIngestionJobID,
+			&item.BankIngestionJobID,
+			&item.ReconciliationJobID,
+			&item.Status,
+			&item.SystemFile,
+			&item.BankFiles,
+			&item.FileProfiles,
+			&item.ErrorMessage,
+			&item.StartDate,
+			&item.EndDate,
+			&item.MatchOptions,
+			&item.SourceWorkflowID,
+			&item.CreatedBy,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&hasResult,
+			&result.TotalSystemTxCount,
+			&result.TotalBankTxCount,
+			&result.MatchedCount,
+			&result.UnmatchedSystemCount,
+			&result.UnmatchedBankCount,
+			&result.MatchedGroupCount,
+			&result.TotalDiscrepancies.Currency,
+			&result.TotalDiscrepancies,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		if hasResult {
+			result.JobID = *item.ReconciliationJobID
+			item.Result = &result
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func insertTransition(ctx context.Context, conn *pgx.Conn, workflowID, fromStatus, toStatus, reason string) error {
+	const query = `
+        INSERT INTO workflow_transitions (workflow_id, from_status, to_status, reason)
+        VALUES ($1, $2, $3, $4)
+    `
+	if _, err := conn.Exec(ctx, query, workflowID, nullString(fromStatus), toStatus, nullString(reason)); err != nil {
+		return fmt.Errorf("insert workflow transition error: %w", err)
+	}
+	return nil
+}
+
+func scanWorkflow(row pgx.Row) (domain.Workflow, error) {
+	var wf domain.Workflow
+	err := row.Scan(
+		&wf.WorkflowID,
+		&wf.SystemIngestionJobID,
+		&wf.BankIngestionJobID,
+		&wf.ReconciliationJobID,
+		&wf.Status,
+		&wf.SystemFile,
+		&wf.BankFiles,
+		&wf.FileProfiles,
+		&wf.ErrorMessage,
+		&wf.StartDate,
+		&wf.EndDate,
+		&wf.MatchOptions,
+		&wf.SourceWorkflowID,
+		&wf.CreatedBy,
+		&wf.CreatedAt,
+		&wf.UpdatedAt,
+	)
+	return wf, err
+}
+
+// bankFiles keeps the column NOT NULL for workflows without bank files
+func bankFiles(files []string) []string {
+	if files == nil {
+		return []string{}
+	}
+	return files
+}
+
+// fileProfiles keeps the column NOT NULL for workflows parsing every file with the built-in parsers
+func fileProfiles(profiles map[string]string) map[string]string {
+	if profiles == nil {
+		return map[string]string{}
+	}
+	return profiles
+}
+
+func nullStringPtr(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
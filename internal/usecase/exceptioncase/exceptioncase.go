@@ -0,0 +1,246 @@
+package exceptioncase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contextprop"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	// ErrNoActor is returned for a change to a case without an authenticated client to record as its author
+	ErrNoActor = errors.New("case changes need an authenticated client")
+	// ErrInvalidCase is returned for a case change or filter that cannot be applied as requested
+	ErrInvalidCase = errors.New("invalid exception case request")
+)
+
+// DefaultPriorityPolicy is used for the thresholds left at zero in the configuration
+var DefaultPriorityPolicy = domain.CasePriorityPolicy{
+	HighAmount:    10_000_000,
+	MediumAmount:  1_000_000,
+	HighAgeDays:   30,
+	MediumAgeDays: 7,
+}
+
+type IUseCase interface {
+	ListCases(ctx context.Context, jobID string, filter domain.CaseFilter) (domain.CasePage, error)
+	GetCase(ctx context.Context, jobID string, caseID int) (domain.ExceptionCase, error)
+	UpdateCase(ctx context.Context, jobID string, caseID int, update domain.CaseUpdate) (domain.ExceptionCase, error)
+	AddComment(ctx context.Context, jobID string, caseID int, body string) (domain.CaseComment, error)
+	ListComments(ctx context.Context, jobID string, caseID int) ([]domain.CaseComment, error)
+	AddAttachment(ctx context.Context, jobID string, caseID int, fileName, contentType string, size int64, content io.Reader) (domain.CaseAttachment, error)
+	ListAttachments(ctx context.Context, jobID string, caseID int) ([]domain.CaseAttachment, error)
+	GetAttachment(ctx context.Context, jobID string, caseID, attachmentID int) (domain.CaseAttachment, io.ReadCloser, error)
+}
+
+type useCase struct {
+	caseRepo    repository.ExceptionCaseRepository
+	minioClient infrastructure.IMinioClient
+	policy      domain.CasePriorityPolicy
+}
+
+func NewExceptionCaseUseCase(caseRepo repository.ExceptionCaseRepository, minioClient infrastructure.IMinioClient, policy domain.CasePriorityPolicy) IUseCase {
+	if policy.HighAmount <= 0 {
+		policy.HighAmount = DefaultPriorityPolicy.HighAmount
+	}
+	if policy.MediumAmount <= 0 {
+		policy.MediumAmount = DefaultPriorityPolicy.MediumAmount
+	}
+	if policy.HighAgeDays <= 0 {
+		policy.HighAgeDays = DefaultPriorityPolicy.HighAgeDays
+	}
+	if policy.MediumAgeDays <= 0 {
+		policy.MediumAgeDays = DefaultPriorityPolicy.MediumAgeDays
+	}
+	return &useCase{caseRepo: caseRepo, minioClient: minioClient, policy: policy}
+}
+
+// ListCases returns one page of the cases of a job matching the filter, newest first.
+// One case more than the page size is read to know whether another page follows.
+func (u *useCase) ListCases(ctx context.Context, jobID string, filter domain.CaseFilter) (domain.CasePage, error) {
+	for _, status := range filter.Statuses {
+		if !slices.Contains(domain.CaseStatuses, status) {
+			return domain.CasePage{}, fmt.Errorf("%w: status must be one of %s", ErrInvalidCase, strings.Join(domain.CaseStatuses, ", "))
+		}
+	}
+	if filter.Priority != "" && !slices.Contains([]string{domain.PriorityHigh, domain.PriorityMedium, domain.PriorityLow}, filter.Priority) {
+		return domain.CasePage{}, fmt.Errorf("%w: priority must be %s, %s or %s", ErrInvalidCase, domain.PriorityHigh, domain.PriorityMedium, domain.PriorityLow)
+	}
+	if filter.ItemType != "" && filter.ItemType != domain.OpenItemSystemTx && filter.ItemType != domain.OpenItemBankStatement {
+		return domain.CasePage{}, fmt.Errorf("%w: item type must be %s or %s", ErrInvalidCase, domain.OpenItemSystemTx, domain.OpenItemBankStatement)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	pageSize := filter.Limit
+	filter.Limit++
+	filter.Policy = u.policy
+
+	cases, err := u.caseRepo.ListCases(ctx, jobID, filter)
+	if err != nil {
+		return domain.CasePage{}, fmt.Errorf("failed to list cases: %w", err)
+	}
+
+	page := domain.CasePage{Items: cases}
+	if len(cases) > pageSize {
+		page.Items = cases[:pageSize]
+		page.NextAfterID = page.Items[pageSize-1].ID
+	}
+	return page, nil
+}
+
+func (u *useCase) GetCase(ctx context.Context, jobID string, caseID int) (domain.ExceptionCase, error) {
+	c, err := u.caseRepo.GetCase(ctx, jobID, caseID, u.policy)
+	if err != nil {
+		return domain.ExceptionCase{}, fmt.Errorf("failed to get case: %w", err)
+	}
+	return c, nil
+}
+
+// UpdateCase moves an open case between OPEN and INVESTIGATING or changes its assignee.
+// A case is resolved by matching its record and written off by a write-off, never by hand.
+func (u *useCase) UpdateCase(ctx context.Context, jobID string, caseID int, update domain.CaseUpdate) (domain.ExceptionCase, error) {
+	if contextprop.GetValue(ctx, contextprop.ClientIDKey) == "" {
+		return domain.ExceptionCase{}, ErrNoActor
+	}
+	if update.Status == nil && update.Assignee == nil {
+		return domain.ExceptionCase{}, fmt.Errorf("%w: nothing to update", ErrInvalidCase)
+	}
+	if update.Status != nil {
+		status := strings.ToUpper(strings.TrimSpace(*update.Status))
+		if status != domain.CaseOpen && status != domain.CaseInvestigating {
+			return domain.ExceptionCase{}, fmt.Errorf("%w: status can only be set to %s or %s, cases are resolved by a manual match or a write-off",
+				ErrInvalidCase, domain.CaseOpen, domain.CaseInvestigating)
+		}
+		update.Status = &status
+	}
+	if update.Assignee != nil {
+		assignee := strings.TrimSpace(*update.Assignee)
+		update.Assignee = &assignee
+	}
+
+	c, err := u.GetCase(ctx, jobID, caseID)
+	if err != nil {
+		return domain.ExceptionCase{}, err
+	}
+	if c.Status != domain.CaseOpen && c.Status != domain.CaseInvestigating {
+		return domain.ExceptionCase{}, repository.ErrCaseClosed
+	}
+	if err := u.caseRepo.UpdateCase(ctx, jobID, caseID, update); err != nil {
+		return domain.ExceptionCase{}, fmt.Errorf("failed to update case: %w", err)
+	}
+	return u.GetCase(ctx, jobID, caseID)
+}
+
+// AddComment adds a comment of the authenticated client to a case, closed cases can still be commented on
+func (u *useCase) AddComment(ctx context.Context, jobID string, caseID int, body string) (domain.CaseComment, error) {
+	author := contextprop.GetValue(ctx, contextprop.ClientIDKey)
+	if author == "" {
+		return domain.CaseComment{}, ErrNoActor
+	}
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return domain.CaseComment{}, fmt.Errorf("%w: a comment needs a body", ErrInvalidCase)
+	}
+	if _, err := u.GetCase(ctx, jobID, caseID); err != nil {
+		return domain.CaseComment{}, err
+	}
+
+	comment, err := u.caseRepo.AddComment(ctx, domain.CaseComment{CaseID: caseID, Author: author, Body: body})
+	if err != nil {
+		return domain.CaseComment{}, fmt.Errorf("failed to add comment: %w", err)
+	}
+	return comment, nil
+}
+
+func (u *useCase) ListComments(ctx context.Context, jobID string, caseID int) ([]domain.CaseComment, error) {
+	if _, err := u.GetCase(ctx, jobID, caseID); err != nil {
+		return nil, err
+	}
+	comments, err := u.caseRepo.ListComments(ctx, caseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	return comments, nil
+}
+
+// AddAttachment uploads a file into the bucket as cases/<case_id>/<uuid>-<file name> and attaches it to the case
+func (u *useCase) AddAttachment(ctx context.Context, jobID string, caseID int, fileName, contentType string, size int64, content io.Reader) (domain.CaseAttachment, error) {
+	uploader := contextprop.GetValue(ctx, contextprop.ClientIDKey)
+	if uploader == "" {
+		return domain.CaseAttachment{}, ErrNoActor
+	}
+	fileName = path.Base(strings.ReplaceAll(strings.TrimSpace(fileName), `\`, "/"))
+	if fileName == "" || fileName == "." || fileName == "/" {
+		return domain.CaseAttachment{}, fmt.Errorf("%w: an attachment needs a file name", ErrInvalidCase)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if _, err := u.GetCase(ctx, jobID, caseID); err != nil {
+		return domain.CaseAttachment{}, err
+	}
+
+	objectName := fmt.Sprintf("cases/%d/%s-%s", caseID, uuid.New().String(), fileName)
+	info, err := u.minioClient.PutObject(ctx, objectName, content, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return domain.CaseAttachment{}, fmt.Errorf("failed to upload attachment: %w", err)
+	}
+
+	attachment, err := u.caseRepo.AddAttachment(ctx, domain.CaseAttachment{
+		CaseID:      caseID,
+		FileName:    fileName,
+		ObjectName:  objectName,
+		ContentType: contentType,
+		Size:        info.Size,
+		UploadedBy:  uploader,
+	})
+	if err != nil {
+		return domain.CaseAttachment{}, fmt.Errorf("failed to add attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+func (u *useCase) ListAttachments(ctx context.Context, jobID string, caseID int) ([]domain.CaseAttachment, error) {
+	if _, err := u.GetCase(ctx, jobID, caseID); err != nil {
+		return nil, err
+	}
+	attachments, err := u.caseRepo.ListAttachments(ctx, caseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// GetAttachment returns an attachment of a case with its content, the caller closes the content
+func (u *useCase) GetAttachment(ctx context.Context, jobID string, caseID, attachmentID int) (domain.CaseAttachment, io.ReadCloser, error) {
+	if _, err := u.GetCase(ctx, jobID, caseID); err != nil {
+		return domain.CaseAttachment{}, nil, err
+	}
+	attachment, err := u.caseRepo.GetAttachment(ctx, caseID, attachmentID)
+	if err != nil {
+		return domain.CaseAttachment{}, nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	content, err := u.minioClient.GetObject(ctx, attachment.ObjectName, minio.GetObjectOptions{})
+	if err != nil {
+		return domain.CaseAttachment{}, nil, fmt.Errorf("failed to download attachment: %w", err)
+	}
+	return attachment, content, nil
+}
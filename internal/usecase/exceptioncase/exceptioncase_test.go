@@ -0,0 +1,183 @@
+package exceptioncase
+
+import (
+	"context"
+	"errors"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	mock_infrastructure "github.com/ardianferdianto/reconciliation-service/internal/infrastructure/_mock"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	mock_repository "github.com/ardianferdianto/reconciliation-service/internal/repository/_mock"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contextprop"
+	"github.com/golang/mock/gomock"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"strings"
+	"testing"
+)
+
+type ExceptionCaseUseCaseSuite struct {
+	suite.Suite
+	mockCaseRepo    *mock_repository.MockExceptionCaseRepository
+	mockMinioClient *mock_infrastructure.MockIMinioClient
+	uc              IUseCase
+	controller      *gomock.Controller
+}
+
+func (suite *ExceptionCaseUseCaseSuite) SetupTest() {
+	suite.controller = gomock.NewController(suite.T())
+	suite.mockCaseRepo = mock_repository.NewMockExceptionCaseRepository(suite.controller)
+	suite.mockMinioClient = mock_infrastructure.NewMockIMinioClient(suite.controller)
+	suite.uc = NewExceptionCaseUseCase(suite.mockCaseRepo, suite.mockMinioClient, domain.CasePriorityPolicy{HighAmount: 5000})
+}
+
+func (suite *ExceptionCaseUseCaseSuite) SetupSubTest() {
+	suite.SetupTest()
+}
+
+func (suite *ExceptionCaseUseCaseSuite) TearDownTest() {
+	suite.controller.Finish()
+}
+
+func TestExceptionCaseUseCaseSuite(t *testing.T) {
+	suite.Run(t, new(ExceptionCaseUseCaseSuite))
+}
+
+const jobID = "1fa446d9-51f4-4958-bfb7-21fb5df6934b"
+
+// policy is the configured policy with the defaults filled in
+var policy = domain.CasePriorityPolicy{HighAmount: 5000, MediumAmount: 1_000_000, HighAgeDays: 30, MediumAgeDays: 7}
+
+func (suite *ExceptionCaseUseCaseSuite) TestListCases() {
+	ctx := context.Background()
+
+	suite.Run("Next Page", func() {
+		suite.mockCaseRepo.EXPECT().ListCases(ctx, jobID, domain.CaseFilter{
+			Statuses: []string{domain.CaseOpen},
+			Priority: domain.PriorityHigh,
+			Limit:    3,
+			Policy:   policy,
+		}).Return([]domain.ExceptionCase{{ID: 9}, {ID: 7}, {ID: 4}}, nil)
+
+		page, err := suite.uc.ListCases(ctx, jobID, domain.CaseFilter{Statuses: []string{domain.CaseOpen}, Priority: domain.PriorityHigh, Limit: 2})
+		suite.Require().NoError(err)
+		suite.Len(page.Items, 2)
+		suite.Equal(7, page.NextAfterID)
+	})
+
+	suite.Run("Last Page", func() {
+		suite.mockCaseRepo.EXPECT().ListCases(ctx, jobID, domain.CaseFilter{Limit: DefaultPageSize + 1, Policy: policy}).
+			Return([]domain.ExceptionCase{{ID: 1}}, nil)
+
+		page, err := suite.uc.ListCases(ctx, jobID, domain.CaseFilter{})
+		suite.Require().NoError(err)
+		suite.Zero(page.NextAfterID)
+	})
+
+	suite.Run("Unknown Status", func() {
+		_, err := suite.uc.ListCases(ctx, jobID, domain.CaseFilter{Statuses: []string{"CLOSED"}})
+		suite.ErrorIs(err, ErrInvalidCase)
+	})
+}
+
+func (suite *ExceptionCaseUseCaseSuite) TestUpdateCase() {
+	ctx := contextprop.SetValue(context.Background(), contextprop.ClientIDKey, "ops-user")
+	investigating, assignee := "investigating", " alice "
+
+	suite.Run("Start Investigation", func() {
+		gomock.InOrder(
+			suite.mockCaseRepo.EXPECT().GetCase(ctx, jobID, 3, policy).Return(domain.ExceptionCase{ID: 3, Status: domain.CaseOpen}, nil),
+			suite.mockCaseRepo.EXPECT().UpdateCase(ctx, jobID, 3, gomock.Any()).
+				DoAndReturn(func(ctx context.Context, jobID string, caseID int, update domain.CaseUpdate) error {
+					suite.Equal(domain.CaseInvestigating, *update.Status)
+					suite.Equal("alice", *update.Assignee)
+					return nil
+				}),
+			suite.mockCaseRepo.EXPECT().GetCase(ctx, jobID, 3, policy).
+				Return(domain.ExceptionCase{ID: 3, Status: domain.CaseInvestigating, Assignee: "alice"}, nil),
+		)
+
+		c, err := suite.uc.UpdateCase(ctx, jobID, 3, domain.CaseUpdate{Status: &investigating, Assignee: &assignee})
+		suite.Require().NoError(err)
+		suite.Equal("alice", c.Assignee)
+	})
+
+	suite.Run("Resolved By Hand", func() {
+		resolved := domain.CaseResolved
+		_, err := suite.uc.UpdateCase(ctx, jobID, 3, domain.CaseUpdate{Status: &resolved})
+		suite.ErrorIs(err, ErrInvalidCase)
+	})
+
+	suite.Run("Closed Case", func() {
+		suite.mockCaseRepo.EXPECT().GetCase(ctx, jobID, 3, policy).Return(domain.ExceptionCase{ID: 3, Status: domain.CaseWrittenOff}, nil)
+
+		_, err := suite.uc.UpdateCase(ctx, jobID, 3, domain.CaseUpdate{Assignee: &assignee})
+		suite.ErrorIs(err, repository.ErrCaseClosed)
+	})
+
+	suite.Run("Case Not Found", func() {
+		suite.mockCaseRepo.EXPECT().GetCase(ctx, jobID, 3, policy).Return(domain.ExceptionCase{}, repository.ErrCaseNotFound)
+
+		_, err := suite.uc.UpdateCase(ctx, jobID, 3, domain.CaseUpdate{Assignee: &assignee})
+		suite.ErrorIs(err, repository.ErrCaseNotFound)
+	})
+
+	suite.Run("No Actor", func() {
+		_, err := suite.uc.UpdateCase(context.Background(), jobID, 3, domain.CaseUpdate{Assignee: &assignee})
+		suite.ErrorIs(err, ErrNoActor)
+	})
+}
+
+func (suite *ExceptionCaseUseCaseSuite) TestAddComment() {
+	ctx := contextprop.SetValue(context.Background(), contextprop.ClientIDKey, "ops-user")
+
+	suite.Run("Added", func() {
+		suite.mockCaseRepo.EXPECT().GetCase(ctx, jobID, 3, policy).Return(domain.ExceptionCase{ID: 3, Status: domain.CaseResolved}, nil)
+		suite.mockCaseRepo.EXPECT().AddComment(ctx, domain.CaseComment{CaseID: 3, Author: "ops-user", Body: "bank confirmed the transfer"}).
+			Return(domain.CaseComment{ID: 1, CaseID: 3, Author: "ops-user", Body: "bank confirmed the transfer"}, nil)
+
+		comment, err := suite.uc.AddComment(ctx, jobID, 3, " bank confirmed the transfer\n")
+		suite.Require().NoError(err)
+		suite.Equal(1, comment.ID)
+	})
+
+	suite.Run("Empty Body", func() {
+		_, err := suite.uc.AddComment(ctx, jobID, 3, "  ")
+		suite.ErrorIs(err, ErrInvalidCase)
+	})
+}
+
+func (suite *ExceptionCaseUseCaseSuite) TestAddAttachment() {
+	ctx := contextprop.SetValue(context.Background(), contextprop.ClientIDKey, "ops-user")
+
+	suite.Run("Uploaded", func() {
+		suite.mockCaseRepo.EXPECT().GetCase(ctx, jobID, 3, policy).Return(domain.ExceptionCase{ID: 3, Status: domain.CaseOpen}, nil)
+		suite.mockMinioClient.EXPECT().PutObject(ctx, gomock.Any(), gomock.Any(), int64(11), minio.PutObjectOptions{ContentType: "application/pdf"}).
+			DoAndReturn(func(ctx context.Context, objectName string, reader io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+				suite.True(strings.HasPrefix(objectName, "cases/3/"))
+				suite.True(strings.HasSuffix(objectName, "-advice.pdf"))
+				return minio.UploadInfo{Key: objectName, Size: size}, nil
+			})
+		suite.mockCaseRepo.EXPECT().AddAttachment(ctx, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, attachment domain.CaseAttachment) (domain.CaseAttachment, error) {
+				suite.Equal("advice.pdf", attachment.FileName)
+				suite.Equal(int64(11), attachment.Size)
+				suite.Equal("ops-user", attachment.UploadedBy)
+				attachment.ID = 5
+				return attachment, nil
+			})
+
+		attachment, err := suite.uc.AddAttachment(ctx, jobID, 3, "../../advice.pdf", "application/pdf", 11, strings.NewReader("%PDF-1.7..."))
+		suite.Require().NoError(err)
+		suite.Equal(5, attachment.ID)
+	})
+
+	suite.Run("Upload Failed", func() {
+		suite.mockCaseRepo.EXPECT().GetCase(ctx, jobID, 3, policy).Return(domain.ExceptionCase{ID: 3, Status: domain.CaseOpen}, nil)
+		suite.mockMinioClient.EXPECT().PutObject(ctx, gomock.Any(), gomock.Any(), int64(11), gomock.Any()).
+			Return(minio.UploadInfo{}, errors.New("bucket not found"))
+
+		_, err := suite.uc.AddAttachment(ctx, jobID, 3, "advice.pdf", "", 11, strings.NewReader("%PDF-1.7..."))
+		suite.ErrorContains(err, "bucket not found")
+	})
+}
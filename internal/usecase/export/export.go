@@ -0,0 +1,156 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/minio/minio-go/v7"
+	"io"
+)
+
+// batchSize is the number of rows read per query, an export never holds more than one batch in memory
+const batchSize = 1000
+
+var (
+	// ErrUnknownFormat is returned for an export format other than csv, xlsx or ndjson
+	ErrUnknownFormat = errors.New("unknown export format")
+	// ErrNoReconciliation is returned when the workflow has no completed reconciliation to export
+	ErrNoReconciliation = errors.New("workflow has no completed reconciliation")
+)
+
+type IUseCase interface {
+	ExportWorkflow(ctx context.Context, workflowID, format string, w io.Writer) error
+	StoreWorkflowExport(ctx context.Context, workflowID, format string) (string, error)
+}
+
+type useCase struct {
+	workflowRepo repository.WorkflowRepository
+	recRepo      repository.ReconciliationRepository
+	minioClient  infrastructure.IMinioClient
+}
+
+func NewExportUseCase(
+	wfRepo repository.WorkflowRepository,
+	recRepo repository.ReconciliationRepository,
+	minioClient infrastructure.IMinioClient,
+) IUseCase {
+	return &useCase{
+		workflowRepo: wfRepo,
+		recRepo:      recRepo,
+		minioClient:  minioClient,
+	}
+}
+
+// ExportWorkflow writes the matched pairs, the unmatched system transactions and the unmatched bank statements
+// of the latest reconciliation of a workflow to w. Nothing is written when the workflow cannot be exported.
+func (u *useCase) ExportWorkflow(ctx context.Context, workflowID, format string, w io.Writer) error {
+	if _, err := ContentType(format); err != nil {
+		return err
+	}
+	jobID, err := u.reconciliationJobID(ctx, workflowID)
+	if err != nil {
+		return err
+	}
+	return u.writeReport(ctx, jobID, format, w)
+}
+
+// StoreWorkflowExport writes the export of a workflow into the bucket as exports/<workflow_id>/<job_id>.<format>
+// and returns the object name. The report is uploaded in parts while it is written.
+func (u *useCase) StoreWorkflowExport(ctx context.Context, workflowID, format string) (string, error) {
+	contentType, err := ContentType(format)
+	if err != nil {
+		return "", err
+	}
+	jobID, err := u.reconciliationJobID(ctx, workflowID)
+	if err != nil {
+		return "", err
+	}
+	objectName := fmt.Sprintf("exports/%s/%s.%s", workflowID, jobID, format)
+
+	pr, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := u.writeReport(ctx, jobID, format, pw)
+		pw.CloseWithError(err)
+		written <- err
+	}()
+
+	_, err = u.minioClient.PutObject(ctx, objectName, pr, -1, minio.PutObjectOptions{ContentType: contentType})
+	// unblocks the report when the upload stopped before reading all of it
+	pr.CloseWithError(err)
+	writeErr := <-written
+	if err != nil {
+		return "", fmt.Errorf("failed to upload export: %w", err)
+	}
+	if writeErr != nil {
+		return "", writeErr
+	}
+	return objectName, nil
+}
+
+func (u *useCase) reconciliationJobID(ctx context.Context, workflowID string) (string, error) {
+	wf, err := u.workflowRepo.GetWorkflow(ctx, workflowID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get workflow: %w", err)
+	}
+	if wf.ReconciliationJobID == nil {
+		return "", ErrNoReconciliation
+	}
+	return *wf.ReconciliationJobID, nil
+}
+
+func (u *useCase) writeReport(ctx context.Context, jobID, format string, w io.Writer) error {
+	rw, err := newReportWriter(format, w)
+	if err != nil {
+		return err
+	}
+	defer rw.close()
+
+	if err := rw.section("Matched"); err != nil {
+		return err
+	}
+	if err := stream(ctx, u.recRepo.ListMatchedPairs, jobID, matchedPairRecord, rw); err != nil {
+		return fmt.Errorf("failed to export matched pairs: %w", err)
+	}
+	if err := rw.section("Unmatched System"); err != nil {
+		return err
+	}
+	if err := stream(ctx, u.recRepo.ListUnmatchedSystemTx, jobID, unmatchedSystemRecord, rw); err != nil {
+		return fmt.Errorf("failed to export unmatched system transactions: %w", err)
+	}
+	if err := rw.section("Unmatched Bank"); err != nil {
+		return err
+	}
+	if err := stream(ctx, u.recRepo.ListUnmatchedBankTx, jobID, unmatchedBankRecord, rw); err != nil {
+		return fmt.Errorf("failed to export unmatched bank statements: %w", err)
+	}
+	if err := rw.flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+type listFunc[T any] func(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]T, *domain.ExceptionCursor, error)
+
+// stream pages through a list in date order and writes every row before reading the next batch
+func stream[T any](ctx context.Context, list listFunc[T], jobID string, record func(T) Record, rw reportWriter) error {
+	q := domain.ExceptionQuery{SortBy: domain.SortByDate, Limit: batchSize}
+	for {
+		items, next, err := list(ctx, jobID, q)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := rw.write(record(item)); err != nil {
+				return err
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		q.After = next
+	}
+}
@@ -0,0 +1,222 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	mock_infrastructure "github.com/ardianferdianto/reconciliation-service/internal/infrastructure/_mock"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	mock_repository "github.com/ardianferdianto/reconciliation-service/internal/repository/_mock"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/golang/mock/gomock"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/suite"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ExportUseCaseSuite struct {
+	suite.Suite
+	mockWorkflowRepo *mock_repository.MockWorkflowRepository
+	mockRecRepo      *mock_repository.MockReconciliationRepository
+	mockMinioClient  *mock_infrastructure.MockIMinioClient
+	uc               IUseCase
+	controller       *gomock.Controller
+}
+
+func (suite *ExportUseCaseSuite) SetupTest() {
+	suite.controller = gomock.NewController(suite.T())
+	suite.mockWorkflowRepo = mock_repository.NewMockWorkflowRepository(suite.controller)
+	suite.mockRecRepo = mock_repository.NewMockReconciliationRepository(suite.controller)
+	suite.mockMinioClient = mock_infrastructure.NewMockIMinioClient(suite.controller)
+	suite.uc = NewExportUseCase(suite.mockWorkflowRepo, suite.mockRecRepo, suite.mockMinioClient)
+}
+
+func (suite *ExportUseCaseSuite) SetupSubTest() {
+	suite.SetupTest()
+}
+
+func (suite *ExportUseCaseSuite) TearDownTest() {
+	suite.controller.Finish()
+}
+
+func TestExportUseCaseSuite(t *testing.T) {
+	suite.Run(t, new(ExportUseCaseSuite))
+}
+
+var exportDate = time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+// expectResult serves two batches of matched pairs, one unmatched system transaction and one unmatched bank statement
+func (suite *ExportUseCaseSuite) expectResult(ctx context.Context, jobID string) {
+	workflowID := "wf-1"
+	suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, workflowID).Return(domain.Workflow{WorkflowID: workflowID, ReconciliationJobID: &jobID}, nil)
+
+	pair := func(id int, trxID string) domain.MatchedPair {
+		return domain.MatchedPair{
+			MatchedRecord: domain.MatchedRecord{
+				ID:           id,
+				JobID:        jobID,
+				Discrepancy:  money.MustParse("0.50", "IDR"),
+				RuleSet:      "default",
+				MatchPass:    "exact",
+				SystemAmount: money.MustParse("100.00", "IDR"),
+				SystemFXRate: money.OneRate(),
+				BankAmount:   money.MustParse("99.50", "IDR"),
+				BankFXRate:   money.OneRate(),
+				MatchedAt:    exportDate,
+			},
+			TrxID:           trxID,
+			TrxType:         domain.Credit,
+			TransactionTime: exportDate,
+			UniqueID:        "B-" + trxID,
+			BankCode:        "BCA",
+			StatementTime:   exportDate,
+		}
+	}
+	cursor := &domain.ExceptionCursor{SortBy: domain.SortByDate, Value: "2025-01-02", ID: 1}
+	gomock.InOrder(
+		suite.mockRecRepo.EXPECT().ListMatchedPairs(ctx, jobID, domain.ExceptionQuery{SortBy: domain.SortByDate, Limit: batchSize}).
+			Return([]domain.MatchedPair{pair(1, "T1")}, cursor, nil),
+		suite.mockRecRepo.EXPECT().ListMatchedPairs(ctx, jobID, domain.ExceptionQuery{SortBy: domain.SortByDate, Limit: batchSize, After: cursor}).
+			Return([]domain.MatchedPair{pair(2, "T2")}, nil, nil),
+	)
+	suite.mockRecRepo.EXPECT().ListUnmatchedSystemTx(ctx, jobID, gomock.Any()).Return([]domain.UnmatchedSystemTx{
+		{ID: 3, JobID: jobID, TrxID: "T3", Amount: money.MustParse("25.00", "IDR"), Type: domain.Debit, TransactionTime: exportDate},
+	}, nil, nil)
+	suite.mockRecRepo.EXPECT().ListUnmatchedBankTx(ctx, jobID, gomock.Any()).Return([]domain.UnmatchedBankTx{
+		{ID: 4, JobID: jobID, UniqueID: "B4", Amount: money.MustParse("-10.00", "IDR"), StatementDate: exportDate, BankCode: "BNI"},
+	}, nil, nil)
+}
+
+func (suite *ExportUseCaseSuite) TestExportWorkflow() {
+	ctx := context.Background()
+
+	suite.Run("CSV", func() {
+		suite.expectResult(ctx, "job-1")
+		var out bytes.Buffer
+		suite.Require().NoError(suite.uc.ExportWorkflow(ctx, "wf-1", FormatCSV, &out))
+
+		rows, err := csv.NewReader(&out).ReadAll()
+		suite.Require().NoError(err)
+		suite.Require().Len(rows, 5)
+		suite.Equal(columns, rows[0])
+		suite.Equal([]string{
+			RecordMatched, "1", "T1", domain.Credit, "2025-01-02T00:00:00Z", "100.00", "IDR", "1",
+			"B-T1", "BCA", "2025-01-02T00:00:00Z", "99.50", "IDR", "1", "0.50", "IDR", "default", "exact", "2025-01-02T00:00:00Z",
+		}, rows[1])
+		suite.Equal(RecordMatched, rows[2][0])
+		suite.Equal([]string{
+			RecordUnmatchedSystem, "3", "T3", domain.Debit, "2025-01-02T00:00:00Z", "25.00", "IDR", "",
+			"", "", "", "", "", "", "", "", "", "", "",
+		}, rows[3])
+		suite.Equal(RecordUnmatchedBank, rows[4][0])
+		suite.Equal("-10.00", rows[4][11])
+	})
+
+	suite.Run("NDJSON", func() {
+		suite.expectResult(ctx, "job-1")
+		var out bytes.Buffer
+		suite.Require().NoError(suite.uc.ExportWorkflow(ctx, "wf-1", FormatNDJSON, &out))
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		suite.Require().Len(lines, 4)
+		var rec map[string]any
+		suite.Require().NoError(json.Unmarshal([]byte(lines[3]), &rec))
+		suite.Equal(RecordUnmatchedBank, rec["record_type"])
+		suite.Equal("B4", rec["unique_id"])
+		suite.NotContains(rec, "trx_id")
+	})
+
+	suite.Run("XLSX", func() {
+		suite.expectResult(ctx, "job-1")
+		var out bytes.Buffer
+		suite.Require().NoError(suite.uc.ExportWorkflow(ctx, "wf-1", FormatXLSX, &out))
+
+		file, err := excelize.OpenReader(&out)
+		suite.Require().NoError(err)
+		defer file.Close()
+		suite.Equal([]string{"Matched", "Unmatched System", "Unmatched Bank"}, file.GetSheetList())
+
+		rows, err := file.GetRows("Matched")
+		suite.Require().NoError(err)
+		suite.Require().Len(rows, 3)
+		suite.Equal("T2", rows[2][2])
+		value, err := file.GetCellValue("Unmatched System", "F2")
+		suite.Require().NoError(err)
+		suite.Equal("25", value)
+	})
+
+	suite.Run("Unknown Format", func() {
+		err := suite.uc.ExportWorkflow(ctx, "wf-1", "pdf", io.Discard)
+		suite.ErrorIs(err, ErrUnknownFormat)
+	})
+
+	suite.Run("Not Reconciled", func() {
+		suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, "wf-1").Return(domain.Workflow{WorkflowID: "wf-1", Status: "INGESTING"}, nil)
+		var out bytes.Buffer
+		err := suite.uc.ExportWorkflow(ctx, "wf-1", FormatCSV, &out)
+		suite.ErrorIs(err, ErrNoReconciliation)
+		suite.Zero(out.Len())
+	})
+
+	suite.Run("Workflow Not Found", func() {
+		suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, "wf-1").Return(domain.Workflow{}, repository.ErrWorkflowNotFound)
+		err := suite.uc.ExportWorkflow(ctx, "wf-1", FormatCSV, io.Discard)
+		suite.ErrorIs(err, repository.ErrWorkflowNotFound)
+	})
+}
+
+func (suite *ExportUseCaseSuite) TestStoreWorkflowExport() {
+	ctx := context.Background()
+
+	suite.Run("Uploaded", func() {
+		suite.expectResult(ctx, "job-1")
+		var uploaded []byte
+		suite.mockMinioClient.EXPECT().PutObject(ctx, "exports/wf-1/job-1.ndjson", gomock.Any(), int64(-1), minio.PutObjectOptions{ContentType: "application/x-ndjson"}).
+			DoAndReturn(func(ctx context.Context, objectName string, reader io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+				var err error
+				uploaded, err = io.ReadAll(reader)
+				return minio.UploadInfo{Key: objectName, Size: int64(len(uploaded))}, err
+			})
+
+		objectName, err := suite.uc.StoreWorkflowExport(ctx, "wf-1", FormatNDJSON)
+		suite.Require().NoError(err)
+		suite.Equal("exports/wf-1/job-1.ndjson", objectName)
+		suite.Equal(4, strings.Count(string(uploaded), "\n"))
+	})
+
+	suite.Run("Upload Failed", func() {
+		suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, "wf-1").Return(domain.Workflow{WorkflowID: "wf-1", ReconciliationJobID: strPtr("job-1")}, nil)
+		suite.mockRecRepo.EXPECT().ListMatchedPairs(ctx, "job-1", gomock.Any()).Return(nil, nil, nil).AnyTimes()
+		suite.mockRecRepo.EXPECT().ListUnmatchedSystemTx(ctx, "job-1", gomock.Any()).Return(nil, nil, nil).AnyTimes()
+		suite.mockRecRepo.EXPECT().ListUnmatchedBankTx(ctx, "job-1", gomock.Any()).Return(nil, nil, nil).AnyTimes()
+		suite.mockMinioClient.EXPECT().PutObject(ctx, "exports/wf-1/job-1.csv", gomock.Any(), int64(-1), gomock.Any()).
+			Return(minio.UploadInfo{}, errors.New("bucket not found"))
+
+		_, err := suite.uc.StoreWorkflowExport(ctx, "wf-1", FormatCSV)
+		suite.ErrorContains(err, "bucket not found")
+	})
+
+	suite.Run("Report Failed", func() {
+		suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, "wf-1").Return(domain.Workflow{WorkflowID: "wf-1", ReconciliationJobID: strPtr("job-1")}, nil)
+		suite.mockRecRepo.EXPECT().ListMatchedPairs(ctx, "job-1", gomock.Any()).Return(nil, nil, errors.New("connection reset"))
+		suite.mockMinioClient.EXPECT().PutObject(ctx, "exports/wf-1/job-1.csv", gomock.Any(), int64(-1), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, objectName string, reader io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+				_, err := io.ReadAll(reader)
+				return minio.UploadInfo{}, err
+			})
+
+		_, err := suite.uc.StoreWorkflowExport(ctx, "wf-1", FormatCSV)
+		suite.ErrorContains(err, "connection reset")
+	})
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"strconv"
+	"time"
+)
+
+const (
+	RecordMatched         = "MATCHED"
+	RecordUnmatchedSystem = "UNMATCHED_SYSTEM"
+	RecordUnmatchedBank   = "UNMATCHED_BANK"
+)
+
+// columns is the layout of every export format, numeric columns are written as numbers in a workbook
+var columns = []string{
+	"record_type", "id",
+	"trx_id", "trx_type", "transaction_time", "system_amount", "system_currency", "system_fx_rate",
+	"unique_id", "bank_code", "statement_time", "bank_amount", "bank_currency", "bank_fx_rate",
+	"discrepancy", "currency", "rule_set", "match_pass", "matched_at",
+}
+
+var numericColumns = map[string]bool{
+	"id": true, "system_amount": true, "system_fx_rate": true, "bank_amount": true, "bank_fx_rate": true, "discrepancy": true,
+}
+
+// Record is one exported row, a matched pair fills both sides while an unmatched item only fills its own
+type Record struct {
+	RecordType      string       `json:"record_type"`
+	ID              int          `json:"id"`
+	TrxID           string       `json:"trx_id,omitempty"`
+	TrxType         string       `json:"trx_type,omitempty"`
+	TransactionTime *time.Time   `json:"transaction_time,omitempty"`
+	SystemAmount    *money.Money `json:"system_amount,omitempty"`
+	SystemCurrency  string       `json:"system_currency,omitempty"`
+	SystemFXRate    *money.Rate  `json:"system_fx_rate,omitempty"`
+	UniqueID        string       `json:"unique_id,omitempty"`
+	BankCode        string       `json:"bank_code,omitempty"`
+	StatementTime   *time.Time   `json:"statement_time,omitempty"`
+	BankAmount      *money.Money `json:"bank_amount,omitempty"`
+	BankCurrency    string       `json:"bank_currency,omitempty"`
+	BankFXRate      *money.Rate  `json:"bank_fx_rate,omitempty"`
+	Discrepancy     *money.Money `json:"discrepancy,omitempty"`
+	Currency        string       `json:"currency,omitempty"` // currency of the discrepancy
+	RuleSet         string       `json:"rule_set,omitempty"`
+	MatchPass       string       `json:"match_pass,omitempty"`
+	MatchedAt       *time.Time   `json:"matched_at,omitempty"`
+}
+
+func matchedPairRecord(pair domain.MatchedPair) Record {
+	return Record{
+		RecordType:      RecordMatched,
+		ID:              pair.ID,
+		TrxID:           pair.TrxID,
+		TrxType:         pair.TrxType,
+		TransactionTime: &pair.TransactionTime,
+		SystemAmount:    &pair.SystemAmount,
+		SystemCurrency:  pair.SystemAmount.Currency,
+		SystemFXRate:    ratePtr(pair.SystemFXRate),
+		UniqueID:        pair.UniqueID,
+		BankCode:        pair.BankCode,
+		StatementTime:   &pair.StatementTime,
+		BankAmount:      &pair.BankAmount,
+		BankCurrency:    pair.BankAmount.Currency,
+		BankFXRate:      ratePtr(pair.BankFXRate),
+		Discrepancy:     &pair.Discrepancy,
+		Currency:        pair.Discrepancy.Currency,
+		RuleSet:         pair.RuleSet,
+		MatchPass:       pair.MatchPass,
+		MatchedAt:       &pair.MatchedAt,
+	}
+}
+
+func unmatchedSystemRecord(tx domain.UnmatchedSystemTx) Record {
+	return Record{
+		RecordType:      RecordUnmatchedSystem,
+		ID:              tx.ID,
+		TrxID:           tx.TrxID,
+		TrxType:         tx.Type,
+		TransactionTime: &tx.TransactionTime,
+		SystemAmount:    &tx.Amount,
+		SystemCurrency:  tx.Amount.Currency,
+	}
+}
+
+func unmatchedBankRecord(stmt domain.UnmatchedBankTx) Record {
+	return Record{
+		RecordType:    RecordUnmatchedBank,
+		ID:            stmt.ID,
+		UniqueID:      stmt.UniqueID,
+		BankCode:      stmt.BankCode,
+		StatementTime: &stmt.StatementDate,
+		BankAmount:    &stmt.Amount,
+		BankCurrency:  stmt.Amount.Currency,
+	}
+}
+
+// values renders the record in the order of columns, absent values are empty
+func (r Record) values() []string {
+	return []string{
+		r.RecordType, strconv.Itoa(r.ID),
+		r.TrxID, r.TrxType, formatTime(r.TransactionTime), formatAmount(r.SystemAmount), r.SystemCurrency, formatRate(r.SystemFXRate),
+		r.UniqueID, r.BankCode, formatTime(r.StatementTime), formatAmount(r.BankAmount), r.BankCurrency, formatRate(r.BankFXRate),
+		formatAmount(r.Discrepancy), r.Currency, r.RuleSet, r.MatchPass, formatTime(r.MatchedAt),
+	}
+}
+
+func ratePtr(rate money.Rate) *money.Rate {
+	if rate.IsZero() {
+		return nil
+	}
+	return &rate
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatAmount(m *money.Money) string {
+	if m == nil {
+		return ""
+	}
+	return m.String()
+}
+
+func formatRate(r *money.Rate) string {
+	if r == nil {
+		return ""
+	}
+	return r.String()
+}
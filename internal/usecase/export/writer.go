@@ -0,0 +1,189 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"strconv"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatXLSX   = "xlsx"
+	FormatNDJSON = "ndjson"
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:    "text/csv",
+	FormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatNDJSON: "application/x-ndjson",
+}
+
+// ContentType returns the media type of an export format
+func ContentType(format string) (string, error) {
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+	return contentType, nil
+}
+
+// reportWriter writes the records of an export section by section.
+// flush completes the output once every record is written, close releases the writer whether or not it was flushed.
+type reportWriter interface {
+	section(name string) error
+	write(rec Record) error
+	flush() error
+	close() error
+}
+
+func newReportWriter(format string, w io.Writer) (reportWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatXLSX:
+		return &xlsxWriter{out: w, file: excelize.NewFile()}, nil
+	case FormatNDJSON:
+		buf := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// csvWriter writes every section into one table, the record_type column tells the sections apart
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return nil, fmt.Errorf("write header error: %w", err)
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) section(string) error { return nil }
+
+func (c *csvWriter) write(rec Record) error {
+	return c.w.Write(rec.values())
+}
+
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) close() error { return nil }
+
+// ndjsonWriter writes one JSON object per line
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) section(string) error { return nil }
+
+func (n *ndjsonWriter) write(rec Record) error {
+	return n.enc.Encode(rec)
+}
+
+func (n *ndjsonWriter) flush() error {
+	return n.buf.Flush()
+}
+
+func (n *ndjsonWriter) close() error { return nil }
+
+// xlsxWriter writes a sheet per section. The stream writer spills rows to a temporary file,
+// the workbook is only assembled and written out by flush.
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	sheets int
+	row    int
+}
+
+func (x *xlsxWriter) section(name string) error {
+	if err := x.flushSheet(); err != nil {
+		return err
+	}
+	if _, err := x.file.NewSheet(name); err != nil {
+		return fmt.Errorf("new sheet error: %w", err)
+	}
+	stream, err := x.file.NewStreamWriter(name)
+	if err != nil {
+		return fmt.Errorf("new stream writer error: %w", err)
+	}
+	x.stream, x.row = stream, 0
+	x.sheets++
+
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	return x.setRow(header)
+}
+
+func (x *xlsxWriter) write(rec Record) error {
+	values := rec.values()
+	cells := make([]any, len(values))
+	for i, value := range values {
+		cells[i] = value
+		if value == "" || !numericColumns[columns[i]] {
+			continue
+		}
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			cells[i] = number
+		}
+	}
+	return x.setRow(cells)
+}
+
+func (x *xlsxWriter) setRow(cells []any) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	if err := x.stream.SetRow(cell, cells); err != nil {
+		return fmt.Errorf("write row error: %w", err)
+	}
+	return nil
+}
+
+func (x *xlsxWriter) flushSheet() error {
+	if x.stream == nil {
+		return nil
+	}
+	if err := x.stream.Flush(); err != nil {
+		return fmt.Errorf("flush sheet error: %w", err)
+	}
+	x.stream = nil
+	return nil
+}
+
+func (x *xlsxWriter) flush() error {
+	if err := x.flushSheet(); err != nil {
+		return err
+	}
+	// the default sheet of a new workbook is dropped once a section took its place
+	if x.sheets > 0 {
+		if err := x.file.DeleteSheet("Sheet1"); err != nil {
+			return fmt.Errorf("delete sheet error: %w", err)
+		}
+		x.file.SetActiveSheet(0)
+	}
+	if err := x.file.Write(x.out); err != nil {
+		return fmt.Errorf("write workbook error: %w", err)
+	}
+	return nil
+}
+
+func (x *xlsxWriter) close() error {
+	return x.file.Close()
+}
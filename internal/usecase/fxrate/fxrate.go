@@ -0,0 +1,99 @@
+package fxrate
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"io"
+	"strings"
+	"time"
+)
+
+// csvHeader is the expected layout of a rate file, one rate per day and currency pair
+var csvHeader = []string{"rate_date", "base_currency", "quote_currency", "rate"}
+
+// ErrInvalidFile is returned when a rate file does not pass validation, nothing is stored in that case
+var ErrInvalidFile = errors.New("invalid fx rate file")
+
+type IUseCase interface {
+	LoadRatesCSV(ctx context.Context, r io.Reader) (int, error)
+}
+
+type useCase struct {
+	fxRepo repository.FXRateRepository
+}
+
+func NewFXRateUseCase(fxRepo repository.FXRateRepository) IUseCase {
+	return &useCase{fxRepo: fxRepo}
+}
+
+// LoadRatesCSV validates a whole rate file before storing it, so a bad line never leaves a partial load behind.
+// Rates already stored for the same day and pair are replaced.
+func (u *useCase) LoadRatesCSV(ctx context.Context, r io.Reader) (int, error) {
+	cReader := csv.NewReader(r)
+	cReader.TrimLeadingSpace = true
+
+	header, err := cReader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to read header: %w", ErrInvalidFile, err)
+	}
+	for i, column := range csvHeader {
+		if i >= len(header) || strings.ToLower(strings.TrimSpace(header[i])) != column {
+			return 0, fmt.Errorf("%w: unexpected header, want %s", ErrInvalidFile, strings.Join(csvHeader, ","))
+		}
+	}
+
+	var rates []domain.FXRate
+	for line := 2; ; line++ {
+		record, err := cReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%w: line %d: %w", ErrInvalidFile, line, err)
+		}
+		rate, err := parseRate(record)
+		if err != nil {
+			return 0, fmt.Errorf("%w: line %d: %w", ErrInvalidFile, line, err)
+		}
+		rates = append(rates, rate)
+	}
+	if len(rates) == 0 {
+		return 0, fmt.Errorf("%w: no rates in file", ErrInvalidFile)
+	}
+
+	if err := u.fxRepo.UpsertRates(ctx, rates); err != nil {
+		return 0, fmt.Errorf("failed to store fx rates: %w", err)
+	}
+	return len(rates), nil
+}
+
+func parseRate(record []string) (domain.FXRate, error) {
+	if len(record) < len(csvHeader) {
+		return domain.FXRate{}, fmt.Errorf("not enough columns for fx rate")
+	}
+	rateDate, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+	if err != nil {
+		return domain.FXRate{}, fmt.Errorf("parse date error: %w", err)
+	}
+	base, err := money.ParseCurrency(record[1])
+	if err != nil {
+		return domain.FXRate{}, err
+	}
+	quote, err := money.ParseCurrency(record[2])
+	if err != nil {
+		return domain.FXRate{}, err
+	}
+	if base == quote {
+		return domain.FXRate{}, fmt.Errorf("base and quote currency are both %s", base)
+	}
+	rate, err := money.ParseRate(record[3])
+	if err != nil {
+		return domain.FXRate{}, fmt.Errorf("parse rate error: %w", err)
+	}
+	return domain.FXRate{RateDate: rateDate, BaseCurrency: base, QuoteCurrency: quote, Rate: rate}, nil
+}
@@ -0,0 +1,92 @@
+package fxrate
+
+import (
+	"context"
+	"errors"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	mock_repository "github.com/ardianferdianto/reconciliation-service/internal/repository/_mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type FXRateUseCaseSuite struct {
+	suite.Suite
+	mockFXRepo *mock_repository.MockFXRateRepository
+	uc         IUseCase
+	controller *gomock.Controller
+}
+
+func (suite *FXRateUseCaseSuite) SetupTest() {
+	suite.controller = gomock.NewController(suite.T())
+	suite.mockFXRepo = mock_repository.NewMockFXRateRepository(suite.controller)
+	suite.uc = NewFXRateUseCase(suite.mockFXRepo)
+}
+
+func (suite *FXRateUseCaseSuite) TearDownTest() {
+	suite.controller.Finish()
+}
+
+func (suite *FXRateUseCaseSuite) TestLoadRatesCSV() {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name        string
+		file        string
+		setupMocks  func()
+		expectedErr error
+		loaded      int
+	}{
+		{
+			name: "Valid File",
+			file: "rate_date,base_currency,quote_currency,rate\n2021-01-04,usd,IDR,15850.25\n2021-01-04,SGD,IDR,10600\n",
+			setupMocks: func() {
+				suite.mockFXRepo.EXPECT().UpsertRates(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, rates []domain.FXRate) error {
+					suite.Require().Len(rates, 2)
+					suite.Equal("USD", rates[0].BaseCurrency)
+					suite.Equal("IDR", rates[0].QuoteCurrency)
+					suite.Equal("15850.25", rates[0].Rate.String())
+					suite.Equal("2021-01-04", rates[0].RateDate.Format("2006-01-02"))
+					return nil
+				})
+			},
+			loaded: 2,
+		},
+		{
+			name:        "Wrong Header",
+			file:        "date,from,to,rate\n2021-01-04,USD,IDR,15850.25\n",
+			setupMocks:  func() {},
+			expectedErr: ErrInvalidFile,
+		},
+		{
+			name:        "Bad Line Stores Nothing",
+			file:        "rate_date,base_currency,quote_currency,rate\n2021-01-04,USD,IDR,15850.25\n2021-01-05,USD,IDR,-1\n",
+			setupMocks:  func() {},
+			expectedErr: ErrInvalidFile,
+		},
+		{
+			name:        "Same Currency On Both Sides",
+			file:        "rate_date,base_currency,quote_currency,rate\n2021-01-04,IDR,IDR,1\n",
+			setupMocks:  func() {},
+			expectedErr: ErrInvalidFile,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			tc.setupMocks()
+			loaded, err := suite.uc.LoadRatesCSV(ctx, strings.NewReader(tc.file))
+			if tc.expectedErr != nil {
+				suite.True(errors.Is(err, tc.expectedErr), err)
+				return
+			}
+			suite.NoError(err)
+			suite.Equal(tc.loaded, loaded)
+		})
+	}
+}
+
+func TestFXRateUseCaseSuite(t *testing.T) {
+	suite.Run(t, new(FXRateUseCaseSuite))
+}
@@ -0,0 +1,168 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ingestion.go
+
+// Package mock_ingestion is a generated GoMock package.
+package mock_ingestion
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/ardianferdianto/reconciliation-service/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+	minio "github.com/minio/minio-go/v7"
+)
+
+// MockIUseCase is a mock of IUseCase interface.
+type MockIUseCase struct {
+	ctrl     *gomock.Controller
+	recorder *MockIUseCaseMockRecorder
+}
+
+// MockIUseCaseMockRecorder is the mock recorder for MockIUseCase.
+type MockIUseCaseMockRecorder struct {
+	mock *MockIUseCase
+}
+
+// NewMockIUseCase creates a new mock instance.
+func NewMockIUseCase(ctrl *gomock.Controller) *MockIUseCase {
+	mock := &MockIUseCase{ctrl: ctrl}
+	mock.recorder = &MockIUseCaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIUseCase) EXPECT() *MockIUseCaseMockRecorder {
+	return m.recorder
+}
+
+// CheckParserProfile mocks base method.
+func (m *MockIUseCase) CheckParserProfile(ctx context.Context, profile, fileType string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckParserProfile", ctx, profile, fileType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckParserProfile indicates an expected call of CheckParserProfile.
+func (mr *MockIUseCaseMockRecorder) CheckParserProfile(ctx, profile, fileType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckParserProfile", reflect.TypeOf((*MockIUseCase)(nil).CheckParserProfile), ctx, profile, fileType)
+}
+
+// CreateIngestionJob mocks base method.
+func (m *MockIUseCase) CreateIngestionJob(ctx context.Context, job *domain.IngestionJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIngestionJob", ctx, job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateIngestionJob indicates an expected call of CreateIngestionJob.
+func (mr *MockIUseCaseMockRecorder) CreateIngestionJob(ctx, job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIngestionJob", reflect.TypeOf((*MockIUseCase)(nil).CreateIngestionJob), ctx, job)
+}
+
+// FetchFileMetadata mocks base method.
+func (m *MockIUseCase) FetchFileMetadata(ctx context.Context, objectName string) (*minio.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchFileMetadata", ctx, objectName)
+	ret0, _ := ret[0].(*minio.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchFileMetadata indicates an expected call of FetchFileMetadata.
+func (mr *MockIUseCaseMockRecorder) FetchFileMetadata(ctx, objectName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchFileMetadata", reflect.TypeOf((*MockIUseCase)(nil).FetchFileMetadata), ctx, objectName)
+}
+
+// GetWorkflowJobs mocks base method.
+func (m *MockIUseCase) GetWorkflowJobs(ctx context.Context, workflowID string) ([]domain.IngestionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkflowJobs", ctx, workflowID)
+	ret0, _ := ret[0].([]domain.IngestionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorkflowJobs indicates an expected call of GetWorkflowJobs.
+func (mr *MockIUseCaseMockRecorder) GetWorkflowJobs(ctx, workflowID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkflowJobs", reflect.TypeOf((*MockIUseCase)(nil).GetWorkflowJobs), ctx, workflowID)
+}
+
+// ListParserProfiles mocks base method.
+func (m *MockIUseCase) ListParserProfiles(ctx context.Context) ([]domain.ParserProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListParserProfiles", ctx)
+	ret0, _ := ret[0].([]domain.ParserProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListParserProfiles indicates an expected call of ListParserProfiles.
+func (mr *MockIUseCaseMockRecorder) ListParserProfiles(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListParserProfiles", reflect.TypeOf((*MockIUseCase)(nil).ListParserProfiles), ctx)
+}
+
+// ProcessIngestionJob mocks base method.
+func (m *MockIUseCase) ProcessIngestionJob(ctx context.Context, job *domain.IngestionJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessIngestionJob", ctx, job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProcessIngestionJob indicates an expected call of ProcessIngestionJob.
+func (mr *MockIUseCaseMockRecorder) ProcessIngestionJob(ctx, job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessIngestionJob", reflect.TypeOf((*MockIUseCase)(nil).ProcessIngestionJob), ctx, job)
+}
+
+// ReapExpiredJobs mocks base method.
+func (m *MockIUseCase) ReapExpiredJobs(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReapExpiredJobs", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReapExpiredJobs indicates an expected call of ReapExpiredJobs.
+func (mr *MockIUseCaseMockRecorder) ReapExpiredJobs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReapExpiredJobs", reflect.TypeOf((*MockIUseCase)(nil).ReapExpiredJobs), ctx)
+}
+
+// RequeueDeadLetterJob mocks base method.
+func (m *MockIUseCase) RequeueDeadLetterJob(ctx context.Context, jobID string) (domain.IngestionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueDeadLetterJob", ctx, jobID)
+	ret0, _ := ret[0].(domain.IngestionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequeueDeadLetterJob indicates an expected call of RequeueDeadLetterJob.
+func (mr *MockIUseCaseMockRecorder) RequeueDeadLetterJob(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueDeadLetterJob", reflect.TypeOf((*MockIUseCase)(nil).RequeueDeadLetterJob), ctx, jobID)
+}
+
+// SaveParserProfile mocks base method.
+func (m *MockIUseCase) SaveParserProfile(ctx context.Context, profile domain.ParserProfile) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveParserProfile", ctx, profile)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveParserProfile indicates an expected call of SaveParserProfile.
+func (mr *MockIUseCaseMockRecorder) SaveParserProfile(ctx, profile interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveParserProfile", reflect.TypeOf((*MockIUseCase)(nil).SaveParserProfile), ctx, profile)
+}
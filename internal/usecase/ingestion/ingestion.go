@@ -4,39 +4,60 @@ import (
 	"bufio"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	enum_parser "github.com/ardianferdianto/reconciliation-service/internal/domain/enum/parser"
 	"github.com/ardianferdianto/reconciliation-service/internal/infrastructure"
 	"github.com/ardianferdianto/reconciliation-service/internal/repository"
 	"github.com/ardianferdianto/reconciliation-service/internal/usecase/parser"
 	"github.com/minio/minio-go/v7"
 	"io"
 	"log/slog"
+	"path"
+	"strings"
+	"time"
 )
 
 const defaultBatchSize = 1000
 
+// xlsxExtension marks the files read as XLSX workbooks, any other file is read as CSV
+const xlsxExtension = ".xlsx"
+
+//go:generate mockgen -source=ingestion.go -destination=_mock/ingestion.go
 type IUseCase interface {
 	CreateIngestionJob(ctx context.Context, job *domain.IngestionJob) error
 	ProcessIngestionJob(ctx context.Context, job *domain.IngestionJob) error
 	FetchFileMetadata(ctx context.Context, objectName string) (*minio.ObjectInfo, error)
+	GetWorkflowJobs(ctx context.Context, workflowID string) ([]domain.IngestionJob, error)
+	ReapExpiredJobs(ctx context.Context) (int64, error)
+	RequeueDeadLetterJob(ctx context.Context, jobID string) (domain.IngestionJob, error)
+	CheckParserProfile(ctx context.Context, profile, fileType string) error
+	ListParserProfiles(ctx context.Context) ([]domain.ParserProfile, error)
+	SaveParserProfile(ctx context.Context, profile domain.ParserProfile) error
 }
 
 type useCase struct {
 	jobRepo     repository.IngestionJobRepository
 	dataRepo    repository.DataRepository
 	minioClient infrastructure.IMinioClient
+	retry       RetryPolicy
+	profiles    *parser.Profiles
 }
 
 func NewIngestionUseCase(
 	jobRepo repository.IngestionJobRepository,
 	dataRepo repository.DataRepository,
 	minioClient infrastructure.IMinioClient,
+	retry RetryPolicy,
+	profiles *parser.Profiles,
 ) IUseCase {
 	return &useCase{
 		jobRepo:     jobRepo,
 		dataRepo:    dataRepo,
 		minioClient: minioClient,
+		retry:       retry.WithDefaults(),
+		profiles:    profiles,
 	}
 }
 
@@ -44,94 +65,300 @@ func (u *useCase) CreateIngestionJob(ctx context.Context, job *domain.IngestionJ
 	return u.jobRepo.CreateJob(ctx, job)
 }
 
+func (u *useCase) GetWorkflowJobs(ctx context.Context, workflowID string) ([]domain.IngestionJob, error) {
+	return u.jobRepo.ListJobsByWorkflow(ctx, workflowID)
+}
+
 func (u *useCase) ProcessIngestionJob(ctx context.Context, job *domain.IngestionJob) error {
-	return u.ingestCSVJob(ctx, job)
+	err := u.ingestJob(ctx, job)
+	if err == nil {
+		return nil
+	}
+	// A cancelled job was given up by this worker, the reaper hands it to the next one.
+	// A job the worker lost is left to whoever holds it now.
+	if ctx.Err() != nil || errors.Is(err, repository.ErrJobNotHeld) {
+		return err
+	}
+
+	if u.retry.retryable(job.Attempts, err) {
+		if retryErr := u.jobRepo.RetryJob(ctx, job.JobID, job.WorkerID, err.Error(), time.Now().Add(u.retry.Backoff(job.Attempts))); retryErr != nil {
+			return fmt.Errorf("failed to schedule retry after %v: %w", err, retryErr)
+		}
+		return err
+	}
+	if dlqErr := u.jobRepo.DeadLetterJob(ctx, job.JobID, job.WorkerID, err.Error()); dlqErr != nil {
+		return fmt.Errorf("failed to dead-letter job after %v: %w", err, dlqErr)
+	}
+	return err
+}
+
+// ReapExpiredJobs requeues the jobs of workers that stopped, dead-lettering the ones out of attempts
+func (u *useCase) ReapExpiredJobs(ctx context.Context) (int64, error) {
+	return u.jobRepo.ReapExpiredLeases(ctx, u.retry.MaxAttempts)
 }
 
-func (u *useCase) ingestCSVJob(ctx context.Context, job *domain.IngestionJob) error {
-	prsr := parser.GetParser(job.FileType)
-	if prsr == nil {
-		u.jobRepo.UpdateJobProgress(ctx, job.JobID, job.TotalLinesProcessed, "FAILED")
-		return fmt.Errorf("no parser registered for fileType=%s", job.FileType)
+// RequeueDeadLetterJob gives a dead-lettered job a fresh set of attempts
+func (u *useCase) RequeueDeadLetterJob(ctx context.Context, jobID string) (domain.IngestionJob, error) {
+	job, found, err := u.jobRepo.RequeueDeadLetterJob(ctx, jobID)
+	if err != nil {
+		return domain.IngestionJob{}, fmt.Errorf("failed to requeue ingestion job: %w", err)
+	}
+	if !found {
+		return domain.IngestionJob{}, ErrNotDeadLettered
+	}
+	return job, nil
+}
+
+// CheckParserProfile makes sure a file of the type can be parsed with the named profile
+func (u *useCase) CheckParserProfile(ctx context.Context, profile, fileType string) error {
+	job := &domain.IngestionJob{FileType: fileType, ParserProfile: profile}
+	if fileParser, err := jobFileParser(job); fileParser != nil || err != nil {
+		return err
 	}
+	_, err := u.jobParser(ctx, job)
+	return err
+}
 
+func (u *useCase) ListParserProfiles(ctx context.Context) ([]domain.ParserProfile, error) {
+	if u.profiles == nil {
+		return nil, nil
+	}
+	return u.profiles.List(ctx)
+}
+
+// SaveParserProfile stores a profile in the database, workflows started afterwards can select it
+func (u *useCase) SaveParserProfile(ctx context.Context, profile domain.ParserProfile) error {
+	if u.profiles == nil {
+		return fmt.Errorf("%w %s: profiles are not stored", parser.ErrInvalidProfile, profile.Name)
+	}
+	if parser.GetFileParser(profile.Name) != nil {
+		return fmt.Errorf("%w %s: name of a file format", parser.ErrInvalidProfile, profile.Name)
+	}
+	return u.profiles.Save(ctx, profile)
+}
+
+// jobParser returns the parser of the job's profile, or the parser registered for its file type
+func (u *useCase) jobParser(ctx context.Context, job *domain.IngestionJob) (parser.CSVParser, error) {
+	if job.ParserProfile == "" {
+		prsr := parser.GetParser(job.FileType)
+		if prsr == nil {
+			return nil, fmt.Errorf("%w for fileType=%s", errNoParser, job.FileType)
+		}
+		return prsr, nil
+	}
+	if u.profiles == nil {
+		return nil, fmt.Errorf("%w %s", parser.ErrUnknownProfile, job.ParserProfile)
+	}
+	return u.profiles.Parser(ctx, job.ParserProfile, job.FileType)
+}
+
+// jobFileParser returns the file parser of the format the job's profile names, nil when the file is read as CSV.
+// File formats are bank statement formats, a system transaction file cannot select one.
+func jobFileParser(job *domain.IngestionJob) (parser.FileParser, error) {
+	fileParser := parser.GetFileParser(job.ParserProfile)
+	if fileParser == nil {
+		return nil, nil
+	}
+	if job.FileType != enum_parser.BANK_STATEMENT {
+		return nil, fmt.Errorf("%w %s: not a format of %s files", parser.ErrInvalidProfile, job.ParserProfile, job.FileType)
+	}
+	return fileParser, nil
+}
+
+// ingestJob stores the records of the job's file, fetched from the object storage
+func (u *useCase) ingestJob(ctx context.Context, job *domain.IngestionJob) error {
 	obj, err := u.minioClient.GetObject(ctx, job.FileName, minio.GetObjectOptions{})
 	if err != nil {
-		u.jobRepo.UpdateJobProgress(ctx, job.JobID, job.TotalLinesProcessed, "FAILED")
 		return fmt.Errorf("GetObject error: %w", err)
 	}
 	defer obj.Close()
 
-	cReader := csv.NewReader(bufio.NewReader(obj))
+	return u.ingestFile(ctx, job, obj)
+}
+
+// ingestFile stores the records of the job's file, read by the file parser of its format or line by line as CSV
+func (u *useCase) ingestFile(ctx context.Context, job *domain.IngestionJob, file io.Reader) error {
+	// formats that are not one record per CSV line are read by a parser of the whole file
+	fileParser, err := jobFileParser(job)
+	if err != nil {
+		return err
+	}
+	var lineParser parser.CSVParser
+	if fileParser == nil {
+		if lineParser, err = u.jobParser(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	w := &recordWriter{u: u, job: job}
+	if fileParser != nil {
+		err = fileParser.ParseFile(bufio.NewReader(file), func(record interface{}, parseErr error) error {
+			w.linesProcessed++
+			if parseErr != nil {
+				slog.ErrorContext(ctx, fmt.Sprintf("%s parse entry: %d error: %s", job.FileType, w.linesProcessed, parseErr.Error()))
+				return nil
+			}
+			return w.write(ctx, record)
+		})
+	} else {
+		rows, closeRows, openErr := openRows(job.FileName, file, lineParser)
+		if openErr != nil {
+			return openErr
+		}
+		defer closeRows()
+		err = w.readRows(ctx, rows, lineParser)
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.flush(ctx); err != nil {
+		return err
+	}
 
-	// Skip the header row
-	if _, err := cReader.Read(); err != nil {
-		u.jobRepo.UpdateJobProgress(ctx, job.JobID, job.TotalLinesProcessed, "FAILED")
-		return fmt.Errorf("failed to read header: %w", err)
+	return u.jobRepo.UpdateJobProgress(ctx, job.JobID, job.WorkerID, w.linesProcessed, "COMPLETED")
+}
+
+// recordWriter stores the records parsed from the file of a job in batches
+type recordWriter struct {
+	u              *useCase
+	job            *domain.IngestionJob
+	sysBatch       []domain.Transaction
+	bankBatch      []domain.BankStatement
+	balances       []domain.StatementBalance
+	linesProcessed int64
+}
+
+// openRows reads the file as an XLSX workbook or as CSV by its extension, set up from the layout of the parser
+func openRows(fileName string, r io.Reader, prsr parser.CSVParser) (parser.RowReader, func(), error) {
+	layout, hasLayout := prsr.(parser.LayoutParser)
+	var rows parser.RowReader
+	closeRows := func() {}
+	if strings.EqualFold(path.Ext(fileName), xlsxExtension) {
+		sheet := ""
+		if hasLayout {
+			sheet = layout.Sheet()
+		}
+		workbook, err := parser.NewXLSXReader(r, sheet)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows, closeRows = workbook, func() { _ = workbook.Close() }
+	} else {
+		cReader := csv.NewReader(bufio.NewReader(r))
+		if hasLayout {
+			cReader.Comma = layout.Delimiter()
+		}
+		rows = cReader
 	}
 
-	var sysBatch []domain.Transaction
-	var bankBatch []domain.BankStatement
-	linesProcessed := int64(0)
+	if hasLayout {
+		if preamble, footer := layout.SkipRows(); preamble > 0 || footer > 0 {
+			if cReader, ok := rows.(*csv.Reader); ok {
+				// preamble and footer rows rarely have as many fields as the data
+				cReader.FieldsPerRecord = -1
+			}
+			rows = parser.TrimRows(rows, preamble, footer)
+		}
+	}
+	return rows, closeRows, nil
+}
+
+// readRows parses the file row by row, rows that cannot be parsed are logged and skipped
+func (w *recordWriter) readRows(ctx context.Context, rows parser.RowReader, prsr parser.CSVParser) error {
+	layout, hasLayout := prsr.(parser.LayoutParser)
+	if !hasLayout || layout.HasHeader() {
+		// Skip the header row, a layout parser finds its columns in it
+		header, err := rows.Read()
+		if err != nil {
+			return fmt.Errorf("failed to read header: %w", err)
+		}
+		if hasLayout {
+			if err := layout.BindHeader(header); err != nil {
+				return err
+			}
+		}
+	}
 
 	for {
-		record, err := cReader.Read()
+		record, err := rows.Read()
 		if err == io.EOF {
-			break
+			return nil
 		}
-		if err != nil {
+		var csvErr *csv.ParseError
+		if errors.As(err, &csvErr) {
+			// a malformed line is skipped, reading the file failing stops the job
 			slog.ErrorContext(ctx, fmt.Sprintf("CSV parse error: %s", err.Error()))
 			continue
 		}
-		linesProcessed++
+		if err != nil {
+			return fmt.Errorf("failed to read row: %w", err)
+		}
+		w.linesProcessed++
 
 		objVal, parseErr := prsr.ParseLine(record)
 		if parseErr != nil {
-			slog.ErrorContext(ctx, fmt.Sprintf("CSV parse line: %d error: %s", linesProcessed, err.Error()))
+			slog.ErrorContext(ctx, fmt.Sprintf("CSV parse line: %d error: %s", w.linesProcessed, parseErr.Error()))
 			continue
 		}
+		if err := w.write(ctx, objVal); err != nil {
+			return err
+		}
+	}
+}
 
-		switch val := objVal.(type) {
-		case domain.Transaction:
-			sysBatch = append(sysBatch, val)
-			if len(sysBatch) >= defaultBatchSize {
-				if err := u.dataRepo.BatchInsertSystemTx(ctx, sysBatch); err != nil {
-					u.jobRepo.UpdateJobProgress(ctx, job.JobID, linesProcessed, "FAILED")
-					return err
-				}
-				sysBatch = sysBatch[:0]
-				u.jobRepo.UpdateJobProgress(ctx, job.JobID, linesProcessed, "IN_PROGRESS")
+// write adds a parsed record to its batch, storing the batch once it is full
+func (w *recordWriter) write(ctx context.Context, record interface{}) error {
+	switch val := record.(type) {
+	case domain.Transaction:
+		val.IngestionJobID, val.WorkflowID = w.job.JobID, w.job.WorkflowID
+		w.sysBatch = append(w.sysBatch, val)
+		if len(w.sysBatch) >= defaultBatchSize {
+			if err := w.u.dataRepo.BatchInsertSystemTx(ctx, w.sysBatch); err != nil {
+				return err
 			}
-		case domain.BankStatement:
-			bankBatch = append(bankBatch, val)
-			if len(bankBatch) >= defaultBatchSize {
-				if err := u.dataRepo.BatchInsertBankStmts(ctx, bankBatch); err != nil {
-					u.jobRepo.UpdateJobProgress(ctx, job.JobID, linesProcessed, "FAILED")
-					return err
-				}
-				bankBatch = bankBatch[:0]
-				u.jobRepo.UpdateJobProgress(ctx, job.JobID, linesProcessed, "IN_PROGRESS")
+			w.sysBatch = w.sysBatch[:0]
+			if err := w.u.jobRepo.UpdateJobProgress(ctx, w.job.JobID, w.job.WorkerID, w.linesProcessed, "IN_PROGRESS"); err != nil {
+				return err
 			}
-		default:
-			slog.ErrorContext(ctx, fmt.Sprintf("CSV error: Unknown object type from parser"))
-			continue
 		}
+	case domain.BankStatement:
+		val.IngestionJobID, val.WorkflowID = w.job.JobID, w.job.WorkflowID
+		w.bankBatch = append(w.bankBatch, val)
+		if len(w.bankBatch) >= defaultBatchSize {
+			if err := w.u.dataRepo.BatchInsertBankStmts(ctx, w.bankBatch); err != nil {
+				return err
+			}
+			w.bankBatch = w.bankBatch[:0]
+			if err := w.u.jobRepo.UpdateJobProgress(ctx, w.job.JobID, w.job.WorkerID, w.linesProcessed, "IN_PROGRESS"); err != nil {
+				return err
+			}
+		}
+	case domain.StatementBalance:
+		val.IngestionJobID, val.WorkflowID = w.job.JobID, w.job.WorkflowID
+		w.balances = append(w.balances, val)
+	default:
+		slog.ErrorContext(ctx, fmt.Sprintf("CSV error: Unknown object type from parser"))
 	}
+	return nil
+}
 
-	if len(sysBatch) > 0 {
-		if err := u.dataRepo.BatchInsertSystemTx(ctx, sysBatch); err != nil {
-			u.jobRepo.UpdateJobProgress(ctx, job.JobID, linesProcessed, "FAILED")
+// flush stores the records left in the batches
+func (w *recordWriter) flush(ctx context.Context) error {
+	if len(w.sysBatch) > 0 {
+		if err := w.u.dataRepo.BatchInsertSystemTx(ctx, w.sysBatch); err != nil {
 			return err
 		}
 	}
-	if len(bankBatch) > 0 {
-		if err := u.dataRepo.BatchInsertBankStmts(ctx, bankBatch); err != nil {
-			u.jobRepo.UpdateJobProgress(ctx, job.JobID, linesProcessed, "FAILED")
+	if len(w.bankBatch) > 0 {
+		if err := w.u.dataRepo.BatchInsertBankStmts(ctx, w.bankBatch); err != nil {
+			return err
+		}
+	}
+	if len(w.balances) > 0 {
+		if err := w.u.dataRepo.StoreStatementBalances(ctx, w.balances); err != nil {
 			return err
 		}
 	}
-
-	u.jobRepo.UpdateJobProgress(ctx, job.JobID, linesProcessed, "COMPLETED")
 	return nil
 }
 
@@ -2,52 +2,118 @@ package ingestion
 
 import (
 	"context"
+	"errors"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	enum_parser "github.com/ardianferdianto/reconciliation-service/internal/domain/enum/parser"
 	mock_infrastructure "github.com/ardianferdianto/reconciliation-service/internal/infrastructure/_mock"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
 	mock_repository "github.com/ardianferdianto/reconciliation-service/internal/repository/_mock"
-	"github.com/ardianferdianto/reconciliation-service/internal/usecase/parser/_mock"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/parser"
 	"github.com/golang/mock/gomock"
 	"github.com/minio/minio-go/v7"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestIngestCSVJob(t *testing.T) {
+func TestIngestFile(t *testing.T) {
+	parser.RegisterParser(enum_parser.SYSTEM_TRX, &parser.SystemTxParser{})
+
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockJobRepo := mock_repository.NewMockIngestionJobRepository(ctrl)
 	mockDataRepo := mock_repository.NewMockDataRepository(ctrl)
 	mockMinioClient := mock_infrastructure.NewMockIMinioClient(ctrl)
-	mockParser := mock_parser.NewMockCSVParser(ctrl)
+	u := &useCase{jobRepo: mockJobRepo, dataRepo: mockDataRepo, minioClient: mockMinioClient, retry: RetryPolicy{}.WithDefaults()}
 
 	ctx := context.Background()
-	bucket := "test-bucket"
 	job := &domain.IngestionJob{
-		JobID:    "job123",
-		FileName: "test.csv",
-		FileType: "csv",
+		JobID:      "job123",
+		WorkflowID: "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55",
+		FileName:   "system.csv",
+		FileType:   enum_parser.SYSTEM_TRX,
+		WorkerID:   "worker-1",
 	}
+	file := "trx_id,amount,type,transaction_time\n" +
+		"TX1001,100.00,CREDIT,2025-01-05 10:00:00\n" +
+		"TX1002,not-an-amount,DEBIT,2025-01-05 11:00:00\n" +
+		"TX1003,25.50,DEBIT,2025-01-06 09:30:00\n"
 
-	// Mock the parser response
-	mockParser.EXPECT().ParseLine(gomock.Any()).DoAndReturn(func(record []string) (interface{}, error) {
-		// Simulate parsing
-		return domain.Transaction{ID: 123, TrxID: "trx123"}, nil
-	}).AnyTimes()
+	// The line that cannot be parsed is counted and skipped, the others are stored under the job
+	mockDataRepo.EXPECT().BatchInsertSystemTx(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, txList []domain.Transaction) error {
+		require.Len(t, txList, 2)
+		assert.Equal(t, "TX1001", txList[0].TrxID)
+		assert.Equal(t, "TX1003", txList[1].TrxID)
+		for _, tx := range txList {
+			assert.Equal(t, job.JobID, tx.IngestionJobID)
+			assert.Equal(t, job.WorkflowID, tx.WorkflowID)
+		}
+		return nil
+	})
+	mockJobRepo.EXPECT().UpdateJobProgress(ctx, job.JobID, job.WorkerID, int64(3), "COMPLETED").Return(nil)
 
-	// Mock the Minio client response
-	//csvContent := "header1,header2\nvalue1,value2\n"
-	mockMinioClient.EXPECT().GetObject(ctx, job.FileName, minio.GetObjectOptions{}).Return(gomock.Any(), nil)
+	assert.NoError(t, u.ingestFile(ctx, job, strings.NewReader(file)))
+}
 
-	// Mock the repository methods
-	mockJobRepo.EXPECT().UpdateJobProgress(ctx, gomock.Any(), gomock.Any(), "IN_PROGRESS").AnyTimes()
-	mockJobRepo.EXPECT().UpdateJobProgress(ctx, gomock.Any(), gomock.Any(), "COMPLETED").AnyTimes()
-	mockDataRepo.EXPECT().BatchInsertSystemTx(ctx, gomock.Any()).Return(nil).AnyTimes()
-	mockDataRepo.EXPECT().BatchInsertBankStmts(ctx, gomock.Any()).Return(nil).AnyTimes()
+func TestProcessIngestionJob(t *testing.T) {
+	ctx := context.Background()
+	downloadErr := errors.New("connection reset")
 
-	// Execute the function
-	err := ingestCSVJob(ctx, mockJobRepo, mockDataRepo, mockMinioClient, bucket, job)
+	tests := map[string]struct {
+		attempts   int
+		setupMocks func(jobRepo *mock_repository.MockIngestionJobRepository)
+		assertErr  func(t *testing.T, err error)
+	}{
+		"retried with backoff": {
+			attempts: 1,
+			setupMocks: func(jobRepo *mock_repository.MockIngestionJobRepository) {
+				jobRepo.EXPECT().RetryJob(ctx, "job123", "worker-1", gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, jobID, workerID, lastError string, nextAttemptAt time.Time) error {
+						assert.Contains(t, lastError, downloadErr.Error())
+						assert.True(t, nextAttemptAt.After(time.Now()))
+						return nil
+					})
+			},
+			assertErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, downloadErr)
+			},
+		},
+		"dead-lettered once out of attempts": {
+			attempts: 3,
+			setupMocks: func(jobRepo *mock_repository.MockIngestionJobRepository) {
+				jobRepo.EXPECT().DeadLetterJob(ctx, "job123", "worker-1", gomock.Any()).Return(nil)
+			},
+			assertErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, downloadErr)
+			},
+		},
+		"left alone once the worker lost it": {
+			attempts: 1,
+			setupMocks: func(jobRepo *mock_repository.MockIngestionJobRepository) {
+				jobRepo.EXPECT().RetryJob(ctx, "job123", "worker-1", gomock.Any(), gomock.Any()).Return(repository.ErrJobNotHeld)
+			},
+			assertErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, repository.ErrJobNotHeld)
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
 
-	// Assert no error
-	assert.NoError(t, err)
+			mockJobRepo := mock_repository.NewMockIngestionJobRepository(ctrl)
+			mockMinioClient := mock_infrastructure.NewMockIMinioClient(ctrl)
+			u := &useCase{jobRepo: mockJobRepo, minioClient: mockMinioClient, retry: RetryPolicy{MaxAttempts: 3}.WithDefaults()}
+
+			job := &domain.IngestionJob{JobID: "job123", FileName: "system.csv", FileType: enum_parser.SYSTEM_TRX, WorkerID: "worker-1", Attempts: tc.attempts}
+			mockMinioClient.EXPECT().GetObject(ctx, job.FileName, minio.GetObjectOptions{}).Return(nil, downloadErr)
+			tc.setupMocks(mockJobRepo)
+
+			tc.assertErr(t, u.ProcessIngestionJob(ctx, job))
+		})
+	}
 }
@@ -0,0 +1,56 @@
+package ingestion
+
+import (
+	"errors"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/parser"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultRetryDelay  = 30 * time.Second
+	DefaultMaxDelay    = 30 * time.Minute
+)
+
+// ErrNotDeadLettered is returned when requeueing a job that is not dead-lettered
+var ErrNotDeadLettered = errors.New("ingestion job is not dead-lettered")
+
+// errNoParser fails a job for good, retrying it cannot help, as do an unknown or invalid parser profile
+var errNoParser = errors.New("no parser registered")
+
+// RetryPolicy decides when a failed ingestion job is tried again
+type RetryPolicy struct {
+	MaxAttempts int           // a job failing this many times is dead-lettered
+	BaseDelay   time.Duration // wait before the second attempt, doubled for every later one
+	MaxDelay    time.Duration
+}
+
+// WithDefaults fills the settings left empty
+func (p RetryPolicy) WithDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryDelay
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = max(DefaultMaxDelay, p.BaseDelay)
+	}
+	return p
+}
+
+// Backoff returns the wait after the given failed attempt, counting from 1
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// retryable reports whether a job that failed with err on the given attempt is tried again
+func (p RetryPolicy) retryable(attempt int, err error) bool {
+	return attempt < p.MaxAttempts && !errors.Is(err, errNoParser) &&
+		!errors.Is(err, parser.ErrUnknownProfile) && !errors.Is(err, parser.ErrInvalidProfile) &&
+		!errors.Is(err, parser.ErrInvalidStatementFile)
+}
@@ -0,0 +1,32 @@
+package ingestion
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/parser"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 10 * time.Second, MaxDelay: time.Minute}
+
+	assert.Equal(t, 10*time.Second, policy.Backoff(1))
+	assert.Equal(t, 20*time.Second, policy.Backoff(2))
+	assert.Equal(t, 40*time.Second, policy.Backoff(3))
+	assert.Equal(t, time.Minute, policy.Backoff(4))
+	assert.Equal(t, time.Minute, policy.Backoff(30))
+}
+
+func TestRetryable(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}.WithDefaults()
+	transient := errors.New("connection reset")
+
+	assert.True(t, policy.retryable(1, transient))
+	assert.True(t, policy.retryable(2, transient))
+	assert.False(t, policy.retryable(3, transient))
+	assert.False(t, policy.retryable(1, fmt.Errorf("%w for fileType=XLS", errNoParser)))
+	assert.False(t, policy.retryable(1, fmt.Errorf("%w bni_v2", parser.ErrUnknownProfile)))
+	assert.False(t, policy.retryable(1, fmt.Errorf("%w: missing file trailer", parser.ErrInvalidStatementFile)))
+}
@@ -5,73 +5,99 @@ import (
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
 	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/lease"
 	"time"
 )
 
-// WorkerIngestionLoop runs a manager goroutine plus a pool of worker goroutines.
+// WorkerIngestionLoop claims pending jobs for up to concurrency worker goroutines.
+// Claims are atomic so several worker processes can share the queue, each claimed job is
+// kept alive with heartbeats and a reaper returns the jobs of workers that stopped to PENDING.
 func WorkerIngestionLoop(
 	ctx context.Context,
 	uc IUseCase,
 	repo repository.IngestionJobRepository,
 	concurrency int,
+	leaseOpts lease.Options,
 ) {
-	// 1) create a channel of jobs
-	jobCh := make(chan domain.IngestionJob, concurrency*2)
+	leaseOpts = leaseOpts.WithDefaults()
 
-	// 2) spawn worker goroutines
-	for i := 0; i < concurrency; i++ {
-		go func(workerID int) {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case job, ok := <-jobCh:
-					if !ok {
-						return
-					}
-					// process the job
-					if err := uc.ProcessIngestionJob(ctx, &job); err != nil {
-						fmt.Printf("[worker %d] error ingesting job=%s: %v\n", workerID, job.JobID, err)
-					}
-				}
-			}
-		}(i)
-	}
+	go reapExpiredLeases(ctx, uc, leaseOpts.HeartbeatInterval)
+
+	// 1) one slot per worker goroutine, jobs are only claimed for free slots so no lease waits in a queue
+	slots := make(chan struct{}, concurrency)
 
-	// 3) manager loop that fetches pending jobs and feeds them to jobCh
+	// 2) manager loop that claims pending jobs and hands each one to a worker goroutine
 	for {
-		select {
-		case <-ctx.Done():
-			close(jobCh) // signal workers to exit
+		if ctx.Err() != nil {
 			return
-		default:
-			pendingJobs, err := repo.ListPendingJobs(ctx, 10)
-			if err != nil {
-				fmt.Printf("ListPendingJobs error: %v\n", err)
-				time.Sleep(5 * time.Second)
-				continue
-			}
-			if len(pendingJobs) == 0 {
-				// no jobs => sleep a bit
-				time.Sleep(5 * time.Second)
-				continue
+		}
+		free := concurrency - len(slots)
+		if free == 0 {
+			// all workers busy => wait for one to finish
+			select {
+			case <-ctx.Done():
+				return
+			case slots <- struct{}{}:
+				<-slots
 			}
+			continue
+		}
 
-			for _, job := range pendingJobs {
-				// Mark the job IN_PROGRESS
-				if err := repo.MarkJobInProgress(ctx, job.JobID); err != nil {
-					fmt.Printf("MarkJobInProgress error for job=%s: %v\n", job.JobID, err)
-					continue
-				}
-				// Send job to a worker
-				select {
-				case <-ctx.Done():
-					close(jobCh)
-					return
-				case jobCh <- job:
-					// enqueued successfully
-				}
-			}
+		claimedJobs, err := repo.ClaimPendingJobs(ctx, leaseOpts.WorkerID, free, leaseOpts.Duration)
+		if err != nil {
+			fmt.Printf("ClaimPendingJobs error: %v\n", err)
+			sleep(ctx, 5*time.Second)
+			continue
+		}
+		if len(claimedJobs) == 0 {
+			// no jobs => sleep a bit
+			sleep(ctx, 5*time.Second)
+			continue
+		}
+
+		for _, job := range claimedJobs {
+			slots <- struct{}{}
+			go func(job domain.IngestionJob) {
+				defer func() { <-slots }()
+				processClaimedJob(ctx, uc, repo, job, leaseOpts)
+			}(job)
+		}
+	}
+}
+
+// processClaimedJob ingests the job while renewing its lease, processing stops when the lease is lost
+func processClaimedJob(ctx context.Context, uc IUseCase, repo repository.IngestionJobRepository, job domain.IngestionJob, leaseOpts lease.Options) {
+	jobCtx, cancel := lease.KeepAlive(ctx, leaseOpts, func(ctx context.Context) (bool, error) {
+		return repo.HeartbeatJob(ctx, job.JobID, leaseOpts.WorkerID, leaseOpts.Duration)
+	})
+	defer cancel()
+
+	if err := uc.ProcessIngestionJob(jobCtx, &job); err != nil {
+		fmt.Printf("[worker %s] error ingesting job=%s: %v\n", leaseOpts.WorkerID, job.JobID, err)
+	}
+}
+
+// reapExpiredLeases periodically returns the jobs whose worker stopped sending heartbeats to PENDING
+func reapExpiredLeases(ctx context.Context, uc IUseCase, interval time.Duration) {
+	for {
+		reaped, err := uc.ReapExpiredJobs(ctx)
+		if err != nil {
+			fmt.Printf("ReapExpiredJobs error: %v\n", err)
+		} else if reaped > 0 {
+			fmt.Printf("reaped %d ingestion jobs with an expired lease\n", reaped)
+		}
+		if !sleep(ctx, interval) {
+			return
 		}
 	}
 }
+
+// sleep waits for d, it returns false when ctx is cancelled first
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,73 @@
+package journal
+
+import (
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"slices"
+	"strings"
+)
+
+// AccountMap resolves the GL accounts of an entry from the configured rules.
+// Each account is taken from the most specific rule setting it: bank code and reason, then bank code, then reason, then neither.
+type AccountMap struct {
+	rules []domain.GLAccountRule
+}
+
+// NewAccountMap validates the rules, a reason has to be DISCREPANCY or a write-off reason code
+// and no two rules for the same bank code and reason may set the same account
+func NewAccountMap(rules []domain.GLAccountRule) (*AccountMap, error) {
+	reasons := append([]string{domain.JournalReasonDiscrepancy}, domain.WriteOffReasonCodes...)
+	m := &AccountMap{}
+	for _, rule := range rules {
+		rule.BankCode = strings.TrimSpace(rule.BankCode)
+		rule.Reason = strings.ToUpper(strings.TrimSpace(rule.Reason))
+		rule.CashAccount = strings.TrimSpace(rule.CashAccount)
+		rule.AdjustmentAccount = strings.TrimSpace(rule.AdjustmentAccount)
+		if rule.Reason != "" && !slices.Contains(reasons, rule.Reason) {
+			return nil, fmt.Errorf("GL account rule has unknown reason %s, it must be one of %s", rule.Reason, strings.Join(reasons, ", "))
+		}
+		if rule.CashAccount == "" && rule.AdjustmentAccount == "" {
+			return nil, fmt.Errorf("GL account rule for bank %q and reason %q sets no account", rule.BankCode, rule.Reason)
+		}
+		for _, other := range m.rules {
+			if other.BankCode != rule.BankCode || other.Reason != rule.Reason {
+				continue
+			}
+			if (other.CashAccount != "" && rule.CashAccount != "") || (other.AdjustmentAccount != "" && rule.AdjustmentAccount != "") {
+				return nil, fmt.Errorf("duplicate GL account rule for bank %q and reason %q", rule.BankCode, rule.Reason)
+			}
+		}
+		m.rules = append(m.rules, rule)
+	}
+	return m, nil
+}
+
+// Resolve returns the cash and adjustment accounts of an entry, ErrUnmappedAccount when either of them has no rule
+func (m *AccountMap) Resolve(bankCode, reason string) (string, string, error) {
+	cash := m.resolve(bankCode, reason, func(r domain.GLAccountRule) string { return r.CashAccount })
+	adjustment := m.resolve(bankCode, reason, func(r domain.GLAccountRule) string { return r.AdjustmentAccount })
+	if cash == "" || adjustment == "" {
+		return "", "", fmt.Errorf("%w: bank %q and reason %s", ErrUnmappedAccount, bankCode, reason)
+	}
+	return cash, adjustment, nil
+}
+
+func (m *AccountMap) resolve(bankCode, reason string, account func(domain.GLAccountRule) string) string {
+	best, bestRank := "", -1
+	for _, rule := range m.rules {
+		if account(rule) == "" || (rule.BankCode != "" && rule.BankCode != bankCode) || (rule.Reason != "" && rule.Reason != reason) {
+			continue
+		}
+		rank := 0
+		if rule.BankCode != "" {
+			rank += 2
+		}
+		if rule.Reason != "" {
+			rank++
+		}
+		if rank > bestRank {
+			best, bestRank = account(rule), rank
+		}
+	}
+	return best
+}
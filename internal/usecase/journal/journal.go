@@ -0,0 +1,146 @@
+package journal
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ErrUnmappedAccount is returned when no GL account rule covers the bank code and reason of an entry
+var ErrUnmappedAccount = errors.New("no GL account mapped")
+
+// erpHeader is the layout of the journal import file of the ERP, one row per line
+var erpHeader = []string{"journal_no", "entry_date", "line_no", "account", "debit", "credit", "currency", "description", "reference"}
+
+type IUseCase interface {
+	GenerateEntries(ctx context.Context, jobID string) ([]domain.JournalEntry, error)
+	ListEntries(ctx context.Context, jobID string) ([]domain.JournalEntry, error)
+	ExportEntries(ctx context.Context, jobID string, w io.Writer) error
+}
+
+type useCase struct {
+	journalRepo repository.JournalRepository
+	accounts    *AccountMap
+}
+
+func NewJournalUseCase(journalRepo repository.JournalRepository, accounts *AccountMap) IUseCase {
+	return &useCase{journalRepo: journalRepo, accounts: accounts}
+}
+
+// GenerateEntries books every discrepancy and write-off of a job as a balanced entry and replaces the entries generated before,
+// so the entries follow the manual changes made to the job since. Nothing is stored when an account is not mapped.
+func (u *useCase) GenerateEntries(ctx context.Context, jobID string) ([]domain.JournalEntry, error) {
+	sources, err := u.journalRepo.ListJournalSources(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journal sources: %w", err)
+	}
+
+	entries := make([]domain.JournalEntry, 0, len(sources))
+	for _, source := range sources {
+		if source.CashEffect.IsZero() {
+			continue
+		}
+		entry, err := u.entry(source)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	entries, err = u.journalRepo.ReplaceJournalEntries(ctx, jobID, entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store journal entries: %w", err)
+	}
+	return entries, nil
+}
+
+func (u *useCase) ListEntries(ctx context.Context, jobID string) ([]domain.JournalEntry, error) {
+	entries, err := u.journalRepo.ListJournalEntries(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journal entries: %w", err)
+	}
+	return entries, nil
+}
+
+// ExportEntries writes the stored entries of a job as the CSV the ERP imports
+func (u *useCase) ExportEntries(ctx context.Context, jobID string, w io.Writer) error {
+	entries, err := u.ListEntries(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(erpHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		for _, line := range e.Lines {
+			err := cw.Write([]string{
+				strconv.Itoa(e.ID),
+				e.EntryDate.Format(time.DateOnly),
+				strconv.Itoa(line.LineNo),
+				line.Account,
+				line.Debit.String(),
+				line.Credit.String(),
+				e.Currency,
+				e.Description,
+				e.Reference,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// entry books the cash effect of a source: more on the bank than in the books debits the bank account
+// and credits the adjustment account, less does the opposite
+func (u *useCase) entry(source domain.JournalSource) (domain.JournalEntry, error) {
+	cash, adjustment, err := u.accounts.Resolve(source.BankCode, source.Reason)
+	if err != nil {
+		return domain.JournalEntry{}, err
+	}
+
+	amount := source.CashEffect.Abs()
+	zero := money.New(0, amount.Currency)
+	debit, credit := cash, adjustment
+	if source.CashEffect.IsNegative() {
+		debit, credit = adjustment, cash
+	}
+	y, m, d := source.EntryDate.Date()
+
+	return domain.JournalEntry{
+		SourceType:  source.SourceType,
+		SourceID:    source.SourceID,
+		BankCode:    source.BankCode,
+		Reason:      source.Reason,
+		Reference:   source.Reference,
+		Description: description(source),
+		EntryDate:   time.Date(y, m, d, 0, 0, 0, 0, time.UTC),
+		Currency:    amount.Currency,
+		Lines: []domain.JournalLine{
+			{LineNo: 1, Account: debit, Debit: amount, Credit: zero},
+			{LineNo: 2, Account: credit, Debit: zero, Credit: amount},
+		},
+	}, nil
+}
+
+func description(source domain.JournalSource) string {
+	switch source.SourceType {
+	case domain.JournalSourceMatch:
+		return fmt.Sprintf("Discrepancy of match %d", source.SourceID)
+	case domain.JournalSourceMatchGroup:
+		return fmt.Sprintf("Discrepancy of match group %d", source.SourceID)
+	default:
+		return fmt.Sprintf("Write-off %d (%s)", source.SourceID, source.Reason)
+	}
+}
@@ -0,0 +1,138 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	mock_repository "github.com/ardianferdianto/reconciliation-service/internal/repository/_mock"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type JournalUseCaseSuite struct {
+	suite.Suite
+	mockJournalRepo *mock_repository.MockJournalRepository
+	uc              IUseCase
+	controller      *gomock.Controller
+}
+
+const jobID = "1fa446d9-51f4-4958-bfb7-21fb5df6934b"
+
+var rules = []domain.GLAccountRule{
+	{CashAccount: "1100", AdjustmentAccount: "6900"},
+	{BankCode: "BCA", CashAccount: "1110"},
+	{Reason: domain.WriteOffBankFee, AdjustmentAccount: "6100"},
+	{BankCode: "BCA", Reason: domain.WriteOffBankFee, AdjustmentAccount: "6110"},
+}
+
+func (suite *JournalUseCaseSuite) SetupTest() {
+	suite.controller = gomock.NewController(suite.T())
+	suite.mockJournalRepo = mock_repository.NewMockJournalRepository(suite.controller)
+	accounts, err := NewAccountMap(rules)
+	suite.Require().NoError(err)
+	suite.uc = NewJournalUseCase(suite.mockJournalRepo, accounts)
+}
+
+func (suite *JournalUseCaseSuite) SetupSubTest() {
+	suite.SetupTest()
+}
+
+func (suite *JournalUseCaseSuite) TearDownTest() {
+	suite.controller.Finish()
+}
+
+func TestJournalUseCaseSuite(t *testing.T) {
+	suite.Run(t, new(JournalUseCaseSuite))
+}
+
+func idr(amount string) money.Money {
+	return money.MustParse(amount, "IDR")
+}
+
+func (suite *JournalUseCaseSuite) TestGenerateEntries() {
+	ctx := context.Background()
+	date := time.Date(2025, 1, 2, 13, 45, 0, 0, time.UTC)
+
+	suite.Run("Balanced Entries", func() {
+		suite.mockJournalRepo.EXPECT().ListJournalSources(ctx, jobID).Return([]domain.JournalSource{
+			{SourceType: domain.JournalSourceMatch, SourceID: 4, BankCode: "BNI", Reason: domain.JournalReasonDiscrepancy, Reference: "TRX-4", EntryDate: date, CashEffect: idr("12.50")},
+			{SourceType: domain.JournalSourceWriteOff, SourceID: 1, BankCode: "BCA", Reason: domain.WriteOffBankFee, Reference: "FEE-1", EntryDate: date, CashEffect: idr("-6.50")},
+			{SourceType: domain.JournalSourceMatchGroup, SourceID: 2, BankCode: "BCA", Reason: domain.JournalReasonDiscrepancy, EntryDate: date, CashEffect: idr("0")},
+		}, nil)
+		suite.mockJournalRepo.EXPECT().ReplaceJournalEntries(ctx, jobID, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, entries []domain.JournalEntry) ([]domain.JournalEntry, error) {
+				return entries, nil
+			})
+
+		entries, err := suite.uc.GenerateEntries(ctx, jobID)
+		suite.NoError(err)
+		suite.Require().Len(entries, 2)
+
+		// the bank holds more than the books: debit the bank, credit the adjustment account
+		suite.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), entries[0].EntryDate)
+		suite.Equal([]domain.JournalLine{
+			{LineNo: 1, Account: "1100", Debit: idr("12.50"), Credit: idr("0")},
+			{LineNo: 2, Account: "6900", Debit: idr("0"), Credit: idr("12.50")},
+		}, entries[0].Lines)
+		// a fee charged by the bank: debit the most specific fee account, credit the bank
+		suite.Equal([]domain.JournalLine{
+			{LineNo: 1, Account: "6110", Debit: idr("6.50"), Credit: idr("0")},
+			{LineNo: 2, Account: "1110", Debit: idr("0"), Credit: idr("6.50")},
+		}, entries[1].Lines)
+	})
+
+	suite.Run("Unmapped Account", func() {
+		accounts, err := NewAccountMap([]domain.GLAccountRule{{BankCode: "BCA", CashAccount: "1110", AdjustmentAccount: "6900"}})
+		suite.Require().NoError(err)
+		suite.uc = NewJournalUseCase(suite.mockJournalRepo, accounts)
+		suite.mockJournalRepo.EXPECT().ListJournalSources(ctx, jobID).Return([]domain.JournalSource{
+			{SourceType: domain.JournalSourceMatch, SourceID: 4, BankCode: "BNI", Reason: domain.JournalReasonDiscrepancy, EntryDate: date, CashEffect: idr("12.50")},
+		}, nil)
+
+		_, err = suite.uc.GenerateEntries(ctx, jobID)
+		suite.ErrorIs(err, ErrUnmappedAccount)
+	})
+}
+
+func (suite *JournalUseCaseSuite) TestExportEntries() {
+	ctx := context.Background()
+	suite.mockJournalRepo.EXPECT().ListJournalEntries(ctx, jobID).Return([]domain.JournalEntry{
+		{
+			ID:          9,
+			JobID:       jobID,
+			Reference:   "FEE-1",
+			Description: "Write-off 1 (BANK_FEE)",
+			EntryDate:   time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+			Currency:    "IDR",
+			Lines: []domain.JournalLine{
+				{LineNo: 1, Account: "6110", Debit: idr("6.50"), Credit: idr("0")},
+				{LineNo: 2, Account: "1110", Debit: idr("0"), Credit: idr("6.50")},
+			},
+		},
+	}, nil)
+
+	var buf bytes.Buffer
+	suite.NoError(suite.uc.ExportEntries(ctx, jobID, &buf))
+	suite.Equal("journal_no,entry_date,line_no,account,debit,credit,currency,description,reference\n"+
+		"9,2025-01-02,1,6110,6.50,0.00,IDR,Write-off 1 (BANK_FEE),FEE-1\n"+
+		"9,2025-01-02,2,1110,0.00,6.50,IDR,Write-off 1 (BANK_FEE),FEE-1\n", buf.String())
+}
+
+func TestNewAccountMap(t *testing.T) {
+	_, err := NewAccountMap([]domain.GLAccountRule{{Reason: "LOST", CashAccount: "1100"}})
+	assert.Error(t, err)
+
+	_, err = NewAccountMap([]domain.GLAccountRule{{BankCode: "BCA", CashAccount: "1110"}, {BankCode: "BCA", CashAccount: "1111"}})
+	assert.Error(t, err)
+
+	accounts, err := NewAccountMap([]domain.GLAccountRule{{BankCode: "BCA", CashAccount: "1110"}, {BankCode: "BCA", AdjustmentAccount: "6900"}})
+	assert.NoError(t, err)
+	cash, adjustment, err := accounts.Resolve("BCA", domain.WriteOffRounding)
+	assert.NoError(t, err)
+	assert.Equal(t, "1110", cash)
+	assert.Equal(t, "6900", adjustment)
+}
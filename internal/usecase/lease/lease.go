@@ -0,0 +1,74 @@
+package lease
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+const DefaultDuration = 60 * time.Second
+
+// Options identify a worker and how long the jobs and workflows it claims stay reserved for it
+type Options struct {
+	WorkerID          string
+	Duration          time.Duration // a claim the worker stops renewing expires after this long
+	HeartbeatInterval time.Duration // how often the worker renews its claims, well below Duration
+}
+
+// WithDefaults fills the options left empty, the worker id defaults to the host name and process id
+func (o Options) WithDefaults() Options {
+	if o.WorkerID == "" {
+		host, _ := os.Hostname()
+		o.WorkerID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if o.Duration <= 0 {
+		o.Duration = DefaultDuration
+	}
+	if o.HeartbeatInterval <= 0 || o.HeartbeatInterval >= o.Duration {
+		o.HeartbeatInterval = o.Duration / 3
+	}
+	return o
+}
+
+// Renew extends a claim, it returns false once the claim is no longer held by the worker
+type Renew func(ctx context.Context) (bool, error)
+
+// KeepAlive renews a claim every heartbeat interval until the returned context is cancelled.
+// The context is cancelled as well when the claim is lost so the work done under it stops.
+// A failed renewal is retried on the next tick, once the last renewal that succeeded is older than
+// the lease duration the claim may have been handed to another worker and the context is cancelled too.
+// The claim is taken to be renewed when KeepAlive is called.
+func KeepAlive(ctx context.Context, opts Options, renew Renew) (context.Context, context.CancelFunc) {
+	opts = opts.WithDefaults()
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(opts.HeartbeatInterval)
+		defer ticker.Stop()
+		renewedAt := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				held, err := renew(ctx)
+				if err != nil {
+					fmt.Printf("lease renewal error: %v\n", err)
+					if time.Since(renewedAt) >= opts.Duration {
+						fmt.Println("lease expired without renewal, stopping work")
+						cancel()
+						return
+					}
+					continue
+				}
+				renewedAt = time.Now()
+				if !held {
+					fmt.Println("lease lost, stopping work")
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,55 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeepAliveCancelsWhenLeaseIsLost(t *testing.T) {
+	var renewals atomic.Int32
+	ctx, cancel := KeepAlive(context.Background(), Options{Duration: time.Minute, HeartbeatInterval: time.Millisecond}, func(ctx context.Context) (bool, error) {
+		return renewals.Add(1) < 3, nil
+	})
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled after the lease was lost")
+	}
+	assert.Equal(t, int32(3), renewals.Load())
+}
+
+func TestKeepAliveCancelsWhenRenewalsFailForTheLease(t *testing.T) {
+	var renewals atomic.Int32
+	opts := Options{Duration: 20 * time.Millisecond, HeartbeatInterval: time.Millisecond}
+	started := time.Now()
+	ctx, cancel := KeepAlive(context.Background(), opts, func(ctx context.Context) (bool, error) {
+		renewals.Add(1)
+		return false, errors.New("connection refused")
+	})
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled after the lease expired without renewal")
+	}
+	// failed renewals are retried until the lease would have expired
+	assert.GreaterOrEqual(t, time.Since(started), opts.Duration)
+	assert.Greater(t, renewals.Load(), int32(1))
+}
+
+func TestWithDefaults(t *testing.T) {
+	opts := Options{Duration: 30 * time.Second}.WithDefaults()
+	assert.NotEmpty(t, opts.WorkerID)
+	assert.Equal(t, 10*time.Second, opts.HeartbeatInterval)
+
+	opts = Options{WorkerID: "worker-1"}.WithDefaults()
+	assert.Equal(t, "worker-1", opts.WorkerID)
+	assert.Equal(t, DefaultDuration, opts.Duration)
+}
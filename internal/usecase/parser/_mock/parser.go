@@ -5,6 +5,7 @@
 package mock_parser
 
 import (
+	io "io"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -47,3 +48,40 @@ func (mr *MockCSVParserMockRecorder) ParseLine(record interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseLine", reflect.TypeOf((*MockCSVParser)(nil).ParseLine), record)
 }
+
+// MockFileParser is a mock of FileParser interface.
+type MockFileParser struct {
+	ctrl     *gomock.Controller
+	recorder *MockFileParserMockRecorder
+}
+
+// MockFileParserMockRecorder is the mock recorder for MockFileParser.
+type MockFileParserMockRecorder struct {
+	mock *MockFileParser
+}
+
+// NewMockFileParser creates a new mock instance.
+func NewMockFileParser(ctrl *gomock.Controller) *MockFileParser {
+	mock := &MockFileParser{ctrl: ctrl}
+	mock.recorder = &MockFileParserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFileParser) EXPECT() *MockFileParserMockRecorder {
+	return m.recorder
+}
+
+// ParseFile mocks base method.
+func (m *MockFileParser) ParseFile(r io.Reader, emit func(interface{}, error) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseFile", r, emit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ParseFile indicates an expected call of ParseFile.
+func (mr *MockFileParserMockRecorder) ParseFile(r, emit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseFile", reflect.TypeOf((*MockFileParser)(nil).ParseFile), r, emit)
+}
@@ -0,0 +1,395 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BAI2 record codes
+const (
+	bai2FileHeader     = "01"
+	bai2GroupHeader    = "02"
+	bai2AccountHeader  = "03"
+	bai2Detail         = "16"
+	bai2AccountTrailer = "49"
+	bai2Continuation   = "88"
+	bai2GroupTrailer   = "98"
+	bai2FileTrailer    = "99"
+)
+
+// BAI2 summary type codes of the ledger balances, and the currency of files and groups naming none
+const (
+	bai2OpeningLedger   = "010"
+	bai2ClosingLedger   = "015"
+	bai2DefaultCurrency = "USD"
+)
+
+// BAI2Parser reads BAI2 cash management balance reports.
+// Every 16 record becomes a domain.BankStatement keeping its type code and references, the customer reference is
+// its unique id, or the bank reference when there is none. Type codes 100 to 399 are credits, 400 to 699 debits.
+// The 010 and 015 summaries of an account become its opening and closing domain.StatementBalance.
+// 88 continuation records are joined to the record they continue. The records of an account are handed over once
+// its 49 trailer checks out, an account whose control total or record count does not add up is skipped, a group or
+// file trailer that does not add up fails the file with ErrInvalidStatementFile.
+type BAI2Parser struct{}
+
+func (p *BAI2Parser) ParseFile(r io.Reader, emit func(record interface{}, err error) error) error {
+	f := &bai2File{emit: emit}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var record string
+	physical := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if code, rest, _ := strings.Cut(line, ","); code == bai2Continuation {
+			if record == "" {
+				return fmt.Errorf("%w: continuation record without a record to continue", ErrInvalidStatementFile)
+			}
+			record = continueBAI2Record(record, rest)
+			physical++
+			continue
+		}
+		if record != "" {
+			if err := f.record(record, physical); err != nil {
+				return err
+			}
+		}
+		record, physical = line, 1
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read error: %w", err)
+	}
+	if record != "" {
+		if err := f.record(record, physical); err != nil {
+			return err
+		}
+	}
+	if !f.closed {
+		return fmt.Errorf("%w: missing file trailer", ErrInvalidStatementFile)
+	}
+	return nil
+}
+
+// continueBAI2Record joins a continuation to its record, the fields of a record ending with / go on with the next
+// field, otherwise the continuation goes on with the text of the 16 record
+func continueBAI2Record(record, continuation string) string {
+	if strings.HasSuffix(record, "/") {
+		return strings.TrimSuffix(record, "/") + "," + continuation
+	}
+	return record + " " + continuation
+}
+
+type bai2File struct {
+	emit func(record interface{}, err error) error
+
+	open    bool
+	closed  bool
+	total   int64
+	groups  int
+	records int
+	group   *bai2Group
+	account *bai2Account
+}
+
+type bai2Group struct {
+	bankCode string
+	currency string
+	date     time.Time
+	total    int64
+	accounts int
+	records  int
+}
+
+type bai2Account struct {
+	number   string
+	currency string
+	total    int64
+	records  int
+	details  int
+	entries  []bai2Entry // records held until the trailer checks out
+}
+
+type bai2Entry struct {
+	record interface{}
+	err    error
+}
+
+func (f *bai2File) record(text string, physical int) error {
+	fields := strings.Split(strings.TrimSuffix(text, "/"), ",")
+	code := fields[0]
+	if f.closed {
+		return fmt.Errorf("%w: record %s after the file trailer", ErrInvalidStatementFile, code)
+	}
+	if !f.open && code != bai2FileHeader {
+		return fmt.Errorf("%w: record %s before the file header", ErrInvalidStatementFile, code)
+	}
+	f.records += physical
+	if f.group != nil {
+		f.group.records += physical
+	}
+	if f.account != nil {
+		f.account.records += physical
+	}
+
+	switch code {
+	case bai2FileHeader:
+		if f.open {
+			return fmt.Errorf("%w: second file header", ErrInvalidStatementFile)
+		}
+		f.open = true
+	case bai2GroupHeader:
+		return f.groupHeader(fields, physical)
+	case bai2AccountHeader:
+		return f.accountHeader(fields, physical)
+	case bai2Detail:
+		if f.account == nil {
+			return fmt.Errorf("%w: transaction detail outside of an account", ErrInvalidStatementFile)
+		}
+		f.detail(fields)
+	case bai2AccountTrailer:
+		return f.accountTrailer(fields)
+	case bai2GroupTrailer:
+		return f.groupTrailer(fields)
+	case bai2FileTrailer:
+		return f.fileTrailer(fields)
+	default:
+		return fmt.Errorf("%w: unknown record %s", ErrInvalidStatementFile, code)
+	}
+	return nil
+}
+
+func (f *bai2File) groupHeader(fields []string, physical int) error {
+	if f.group != nil {
+		return fmt.Errorf("%w: group header inside a group", ErrInvalidStatementFile)
+	}
+	date, err := time.Parse("060102", fieldAt(fields, 4))
+	if err != nil {
+		return fmt.Errorf("%w: group as-of date: %s", ErrInvalidStatementFile, err)
+	}
+	f.group = &bai2Group{
+		bankCode: fieldAt(fields, 2),
+		currency: firstNonEmpty(fieldAt(fields, 6), bai2DefaultCurrency),
+		date:     date,
+		records:  physical,
+	}
+	return nil
+}
+
+func (f *bai2File) accountHeader(fields []string, physical int) error {
+	if f.group == nil || f.account != nil {
+		return fmt.Errorf("%w: account header outside of a group", ErrInvalidStatementFile)
+	}
+	currency, err := money.ParseCurrency(firstNonEmpty(fieldAt(fields, 2), f.group.currency))
+	if err != nil {
+		return fmt.Errorf("%w: account %s: %s", ErrInvalidStatementFile, fieldAt(fields, 1), err)
+	}
+	a := &bai2Account{
+		number:   fieldAt(fields, 1),
+		currency: currency,
+		records:  physical,
+	}
+	f.account = a
+
+	// summaries of type code, amount, item count and funds type
+	for i := 3; i < len(fields) && strings.TrimSpace(fields[i]) != ""; {
+		typeCode, text := strings.TrimSpace(fields[i]), fieldAt(fields, i+1)
+		next, err := skipBAI2Funds(fields, i+3)
+		if err != nil {
+			return fmt.Errorf("%w: account %s summary %s: %s", ErrInvalidStatementFile, a.number, typeCode, err)
+		}
+		i = next
+		if text == "" {
+			continue
+		}
+		amount, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: account %s summary %s: invalid amount %q", ErrInvalidStatementFile, a.number, typeCode, text)
+		}
+		a.total += amount
+
+		var balanceType string
+		switch typeCode {
+		case bai2OpeningLedger:
+			balanceType = domain.BalanceOpening
+		case bai2ClosingLedger:
+			balanceType = domain.BalanceClosing
+		default:
+			continue
+		}
+		a.entries = append(a.entries, bai2Entry{record: statementBalance(balanceType, f.group.bankCode, a.number,
+			f.group.date, money.New(amount, a.currency))})
+	}
+	return nil
+}
+
+func (f *bai2File) detail(fields []string) {
+	a := f.account
+	a.details++
+	typeCode, text := fieldAt(fields, 1), fieldAt(fields, 2)
+	amount, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		// left out of the total, the trailer does not add up and skips the account
+		a.entries = append(a.entries, bai2Entry{err: fmt.Errorf("transaction detail %d of account %s: invalid amount %q", a.details, a.number, text)})
+		return
+	}
+	a.total += amount
+
+	i, err := skipBAI2Funds(fields, 3)
+	if err != nil {
+		a.entries = append(a.entries, bai2Entry{err: fmt.Errorf("transaction detail %d of account %s: %w", a.details, a.number, err)})
+		return
+	}
+	code, err := strconv.Atoi(typeCode)
+	switch {
+	case err != nil || code < 100 || code >= 700:
+		a.entries = append(a.entries, bai2Entry{err: fmt.Errorf("transaction detail %d of account %s: type code %q is neither a credit nor a debit", a.details, a.number, typeCode)})
+		return
+	case code >= 400:
+		amount = -amount
+	}
+
+	bankRef, customerRef := fieldAt(fields, i), fieldAt(fields, i+1)
+	uniqueID := firstNonEmpty(customerRef, bankRef)
+	if uniqueID == "" {
+		uniqueID = fmt.Sprintf("%s-%s-%d", a.number, f.group.date.Format("060102"), a.details)
+	}
+	description := ""
+	if i+2 < len(fields) {
+		description = strings.TrimSpace(strings.Join(fields[i+2:], ","))
+	}
+	a.entries = append(a.entries, bai2Entry{record: domain.BankStatement{
+		UniqueID:      uniqueID,
+		Amount:        money.New(amount, a.currency),
+		StatementTime: f.group.date,
+		BankCode:      f.group.bankCode,
+		AccountNumber: a.number,
+		Description:   description,
+		TypeCode:      typeCode,
+		BankReference: bankRef,
+	}})
+}
+
+func (f *bai2File) accountTrailer(fields []string) error {
+	a := f.account
+	if a == nil {
+		return fmt.Errorf("%w: account trailer outside of an account", ErrInvalidStatementFile)
+	}
+	f.account = nil
+	total, count, err := bai2Control(fields[1:])
+	if err != nil {
+		return fmt.Errorf("%w: account %s trailer: %s", ErrInvalidStatementFile, a.number, err)
+	}
+	// the group adds up the totals the accounts report, a bad account does not fail its group as well
+	f.group.total += total
+	f.group.accounts++
+	if total != a.total || count != a.records {
+		return f.emit(nil, fmt.Errorf("account %s: control total %d of %d records, the records add up to %d of %d records",
+			a.number, total, count, a.total, a.records))
+	}
+	for _, entry := range a.entries {
+		if err := f.emit(entry.record, entry.err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *bai2File) groupTrailer(fields []string) error {
+	g := f.group
+	if g == nil || f.account != nil {
+		return fmt.Errorf("%w: group trailer outside of a group", ErrInvalidStatementFile)
+	}
+	f.group = nil
+	total, accounts, count, err := bai2Trailer(fields)
+	if err != nil {
+		return fmt.Errorf("%w: group %s trailer: %s", ErrInvalidStatementFile, g.bankCode, err)
+	}
+	if total != g.total || accounts != g.accounts || count != g.records {
+		return fmt.Errorf("%w: group %s control total %d of %d accounts and %d records, the accounts add up to %d of %d accounts and %d records",
+			ErrInvalidStatementFile, g.bankCode, total, accounts, count, g.total, g.accounts, g.records)
+	}
+	f.total += total
+	f.groups++
+	return nil
+}
+
+func (f *bai2File) fileTrailer(fields []string) error {
+	if !f.open || f.group != nil {
+		return fmt.Errorf("%w: file trailer inside a group", ErrInvalidStatementFile)
+	}
+	f.closed = true
+	total, groups, count, err := bai2Trailer(fields)
+	if err != nil {
+		return fmt.Errorf("%w: file trailer: %s", ErrInvalidStatementFile, err)
+	}
+	if total != f.total || groups != f.groups || count != f.records {
+		return fmt.Errorf("%w: file control total %d of %d groups and %d records, the groups add up to %d of %d groups and %d records",
+			ErrInvalidStatementFile, total, groups, count, f.total, f.groups, f.records)
+	}
+	return nil
+}
+
+// bai2Trailer reads the control total, the number of groups or accounts and the number of records of a trailer
+func bai2Trailer(fields []string) (int64, int, int, error) {
+	if len(fields) < 4 {
+		return 0, 0, 0, fmt.Errorf("expected 4 fields, got %d", len(fields))
+	}
+	total, count, err := bai2Control([]string{fields[1], fields[3]})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	items, err := strconv.Atoi(strings.TrimSpace(fields[2]))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid count %q", fields[2])
+	}
+	return total, items, count, nil
+}
+
+// bai2Control reads a control total and the number of records following it
+func bai2Control(fields []string) (int64, int, error) {
+	if len(fields) < 2 {
+		return 0, 0, fmt.Errorf("expected a control total and a number of records")
+	}
+	total, err := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid control total %q", fields[0])
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid number of records %q", fields[1])
+	}
+	return total, count, nil
+}
+
+// skipBAI2Funds returns the index of the field after the funds type at i and the availability it details
+func skipBAI2Funds(fields []string, i int) (int, error) {
+	switch fieldAt(fields, i) {
+	case "S":
+		return i + 4, nil // immediate, one-day and two-or-more-day availability
+	case "V":
+		return i + 3, nil // value date and time
+	case "D":
+		n, err := strconv.Atoi(fieldAt(fields, i+1))
+		if err != nil {
+			return 0, fmt.Errorf("invalid distributed availability count %q", fieldAt(fields, i+1))
+		}
+		return i + 2 + 2*n, nil // days and amount of every distribution
+	}
+	return i + 1, nil
+}
+
+func fieldAt(fields []string, i int) string {
+	if i < len(fields) {
+		return strings.TrimSpace(fields[i])
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBAI2Parser(t *testing.T) {
+	file := strings.Join([]string{
+		"01,SENDR1,RECVR1,250131,0600,1,80,,2/",
+		"02,RECVR1,021000021,1,250130,2400,USD,2/",
+		"03,0012345678,USD,010,500000,,,015,612000,,/",
+		"16,195,150000,Z,BR-1,INV-9,WIRE FROM ACME",
+		"88,CORP PAYMENT",
+		"16,475,38000,V,250130,1200,BR-2,,CHECK 1001",
+		"16,999,100,Z,BR-3,,",
+		"49,1300100,6/",
+		"03,999,USD/",
+		"16,115,1000,0,BR-4,REF-4/",
+		"49,9999,3/",
+		"98,1310099,2,11/",
+		"99,1310099,1,13/",
+	}, "\r\n")
+
+	records, errs := parseFile(t, &BAI2Parser{}, file)
+	require.Len(t, errs, 2)
+	assert.ErrorContains(t, errs[0], "transaction detail 3 of account 0012345678: type code \"999\"")
+	assert.ErrorContains(t, errs[1], "account 999: control total 9999 of 3 records, the records add up to 1000 of 3 records")
+
+	date := time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []interface{}{
+		domain.StatementBalance{
+			BankCode:      "021000021",
+			AccountNumber: "0012345678",
+			BalanceType:   domain.BalanceOpening,
+			BalanceDate:   date,
+			Amount:        money.MustParse("5000", "USD"),
+		},
+		domain.StatementBalance{
+			BankCode:      "021000021",
+			AccountNumber: "0012345678",
+			BalanceType:   domain.BalanceClosing,
+			BalanceDate:   date,
+			Amount:        money.MustParse("6120", "USD"),
+		},
+		domain.BankStatement{
+			UniqueID:      "INV-9",
+			Amount:        money.MustParse("1500", "USD"),
+			StatementTime: date,
+			BankCode:      "021000021",
+			AccountNumber: "0012345678",
+			Description:   "WIRE FROM ACME CORP PAYMENT",
+			TypeCode:      "195",
+			BankReference: "BR-1",
+		},
+		domain.BankStatement{
+			UniqueID:      "BR-2",
+			Amount:        money.MustParse("-380", "USD"),
+			StatementTime: date,
+			BankCode:      "021000021",
+			AccountNumber: "0012345678",
+			Description:   "CHECK 1001",
+			TypeCode:      "475",
+			BankReference: "BR-2",
+		},
+	}, records)
+}
+
+func TestBAI2ParserInvalidFile(t *testing.T) {
+	header := "01,SENDR1,RECVR1,250131,0600,1,80,,2/\n02,RECVR1,021000021,1,250130,2400,USD,2/\n" +
+		"03,0012345678,USD/\n16,115,1000,Z,BR-1,REF-1/\n49,1000,3/\n"
+
+	tests := map[string]string{
+		"group control total": header + "98,2000,1,5/\n99,2000,1,7/",
+		"file record count":   header + "98,1000,1,5/\n99,1000,1,8/",
+		"missing trailer":     header + "98,1000,1,5/",
+		"orphan continuation": "88,TEXT/",
+	}
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := (&BAI2Parser{}).ParseFile(strings.NewReader(file), func(interface{}, error) error { return nil })
+			assert.ErrorIs(t, err, ErrInvalidStatementFile)
+		})
+	}
+}
@@ -3,18 +3,31 @@ package parser
 import (
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
-	"strconv"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
 	"strings"
 	"time"
 )
 
+// Rows of a bank statement file carrying a balance reported by the bank instead of a statement line,
+// the amount column holds the balance of the account at the date
+const (
+	openingBalanceRow = "OPENING_BALANCE"
+	closingBalanceRow = "CLOSING_BALANCE"
+)
+
+// BankStatementParser reads unique_id, amount, date, bank_code and the optional currency and account_number columns.
+// OPENING_BALANCE and CLOSING_BALANCE in the unique_id column mark the balances the bank reported for the account.
 type BankStatementParser struct{}
 
 func (b *BankStatementParser) ParseLine(fields []string) (interface{}, error) {
 	if len(fields) < 4 {
 		return nil, fmt.Errorf("not enough columns for BCA bank statement")
 	}
-	amt, err := strconv.ParseFloat(fields[1], 64)
+	currency, err := currencyAt(fields, 4)
+	if err != nil {
+		return nil, fmt.Errorf("parse currency error: %w", err)
+	}
+	amt, err := money.Parse(fields[1], currency)
 	if err != nil {
 		return nil, fmt.Errorf("parse amount error: %w", err)
 	}
@@ -22,10 +35,32 @@ func (b *BankStatementParser) ParseLine(fields []string) (interface{}, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parse date error: %w", err)
 	}
+	accountNumber := ""
+	if len(fields) > 5 {
+		accountNumber = strings.TrimSpace(fields[5])
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(fields[0])) {
+	case openingBalanceRow:
+		return statementBalance(domain.BalanceOpening, fields[3], accountNumber, dt, amt), nil
+	case closingBalanceRow:
+		return statementBalance(domain.BalanceClosing, fields[3], accountNumber, dt, amt), nil
+	}
 	return domain.BankStatement{
 		UniqueID:      fields[0],
 		Amount:        amt,
 		StatementTime: dt,
 		BankCode:      fields[3],
+		AccountNumber: accountNumber,
 	}, nil
 }
+
+func statementBalance(balanceType, bankCode, accountNumber string, date time.Time, amount money.Money) domain.StatementBalance {
+	return domain.StatementBalance{
+		BankCode:      bankCode,
+		AccountNumber: accountNumber,
+		BalanceType:   balanceType,
+		BalanceDate:   date,
+		Amount:        amount,
+	}
+}
@@ -0,0 +1,326 @@
+package parser
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ISO 20022 codes of the camt messages
+const (
+	camtCredit         = "CRDT"
+	camtDebit          = "DBIT"
+	camtBooked         = "BOOK"
+	camtOpeningBooked  = "OPBD"
+	camtPreviousClosed = "PRCD"
+	camtClosingBooked  = "CLBD"
+	camtNotProvided    = "NOTPROVIDED"
+)
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtStatus struct {
+	Text string `xml:",chardata"` // camt.053.001.02 to .07
+	Code string `xml:"Cd"`
+}
+
+type camtAccount struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+	BICFI string `xml:"Svcr>FinInstnId>BICFI"`
+	BIC   string `xml:"Svcr>FinInstnId>BIC"` // camt.053.001.02
+}
+
+type camtBalance struct {
+	Code        string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount      camtAmount `xml:"Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+	Date        camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Reference    string            `xml:"NtryRef"`
+	Amount       camtAmount        `xml:"Amt"`
+	CreditDebit  string            `xml:"CdtDbtInd"`
+	Status       camtStatus        `xml:"Sts"`
+	BookingDate  camtDate          `xml:"BookgDt"`
+	ValueDate    camtDate          `xml:"ValDt"`
+	ServicerRef  string            `xml:"AcctSvcrRef"`
+	Info         string            `xml:"AddtlNtryInf"`
+	Transactions []camtTransaction `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTransaction struct {
+	EndToEndID  string     `xml:"Refs>EndToEndId"`
+	ServicerRef string     `xml:"Refs>AcctSvcrRef"`
+	Amount      camtAmount `xml:"Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+	Remittance  []string   `xml:"RmtInf>Ustrd"`
+	Info        string     `xml:"AddtlTxInf"`
+}
+
+// CamtParser reads ISO 20022 camt.053 statements and camt.054 debit/credit notifications.
+// The document is streamed: only the entry being read is held in memory, whatever the size of the file.
+// A booked Ntry becomes a domain.BankStatement, one per TxDtls when a batch booking details the amount of each.
+// The unique id is the end-to-end id, or the reference of the bank when it is not provided, and the statement
+// time the booking date, or the value date when there is none. OPBD (or PRCD) and CLBD balances of a camt.053
+// become the opening and closing domain.StatementBalance of the account.
+type CamtParser struct{}
+
+func (p *CamtParser) ParseFile(r io.Reader, emit func(record interface{}, err error) error) error {
+	dec := xml.NewDecoder(r)
+	var path []string
+	var s *camtStatement
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read error: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			if s == nil || len(path) == 0 || path[len(path)-1] != s.element {
+				if name == "Stmt" || name == "Ntfctn" {
+					s = &camtStatement{element: name, emit: emit}
+				}
+				path = append(path, name)
+				continue
+			}
+			// direct children of the statement, each decoded as a whole
+			if err := s.decode(dec, t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+			if s != nil && t.Name.Local == s.element {
+				if err := s.flushBalances(); err != nil {
+					return err
+				}
+				s = nil
+			}
+		}
+	}
+}
+
+// camtStatement holds what is known of the Stmt or Ntfctn being read
+type camtStatement struct {
+	element string
+	emit    func(record interface{}, err error) error
+
+	id            string
+	bankCode      string
+	accountNumber string
+	entries       int
+	opening       *camtBalance
+	closing       *camtBalance
+}
+
+func (s *camtStatement) decode(dec *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "Id":
+		if err := dec.DecodeElement(&s.id, &start); err != nil {
+			return fmt.Errorf("read error: %w", err)
+		}
+	case "Acct":
+		var acct camtAccount
+		if err := dec.DecodeElement(&acct, &start); err != nil {
+			return fmt.Errorf("read error: %w", err)
+		}
+		s.accountNumber = firstNonEmpty(acct.IBAN, acct.Other)
+		s.bankCode = firstNonEmpty(acct.BICFI, acct.BIC)
+		if len(s.bankCode) > 8 {
+			s.bankCode = s.bankCode[:8]
+		}
+	case "Bal":
+		var bal camtBalance
+		if err := dec.DecodeElement(&bal, &start); err != nil {
+			return fmt.Errorf("read error: %w", err)
+		}
+		switch bal.Code {
+		case camtOpeningBooked:
+			s.opening = &bal
+		case camtPreviousClosed:
+			if s.opening == nil {
+				s.opening = &bal
+			}
+		case camtClosingBooked:
+			s.closing = &bal
+		}
+	case "Ntry":
+		var entry camtEntry
+		if err := dec.DecodeElement(&entry, &start); err != nil {
+			return fmt.Errorf("read error: %w", err)
+		}
+		// balances all come before the entries
+		if err := s.flushBalances(); err != nil {
+			return err
+		}
+		s.entries++
+		return s.entry(entry)
+	default:
+		return dec.Skip()
+	}
+	return nil
+}
+
+func (s *camtStatement) flushBalances() error {
+	for _, b := range []struct {
+		balanceType string
+		balance     *camtBalance
+	}{{domain.BalanceOpening, s.opening}, {domain.BalanceClosing, s.closing}} {
+		if b.balance == nil {
+			continue
+		}
+		date, err := camtTime(b.balance.Date)
+		if err != nil {
+			return s.emit(nil, fmt.Errorf("balance %s of %s: %w", b.balance.Code, s.id, err))
+		}
+		amount, err := camtMoney(b.balance.Amount, b.balance.CreditDebit)
+		if err != nil {
+			return s.emit(nil, fmt.Errorf("balance %s of %s: %w", b.balance.Code, s.id, err))
+		}
+		if err := s.emit(statementBalance(b.balanceType, s.bankCode, s.accountNumber, date, amount), nil); err != nil {
+			return err
+		}
+	}
+	s.opening, s.closing = nil, nil
+	return nil
+}
+
+func (s *camtStatement) entry(entry camtEntry) error {
+	// pending and informational entries are not on the account yet
+	if status := firstNonEmpty(entry.Status.Code, entry.Status.Text); status != "" && status != camtBooked {
+		return s.emit(nil, fmt.Errorf("entry %d of %s: status %s is not booked", s.entries, s.id, status))
+	}
+	date, err := camtTime(entry.BookingDate)
+	if err != nil {
+		date, err = camtTime(entry.ValueDate)
+	}
+	if err != nil {
+		return s.emit(nil, fmt.Errorf("entry %d of %s: %w", s.entries, s.id, err))
+	}
+
+	txs := entry.Transactions
+	if len(txs) < 2 || !everyAmount(txs) {
+		// the entry amount is the one booked, the details only tell what it is for
+		var tx camtTransaction
+		if len(txs) > 0 {
+			tx = txs[0]
+		}
+		tx.Amount, tx.CreditDebit = entry.Amount, entry.CreditDebit
+		txs = []camtTransaction{tx}
+	}
+	for i, tx := range txs {
+		if err := s.transaction(entry, tx, i, date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *camtStatement) transaction(entry camtEntry, tx camtTransaction, i int, date time.Time) error {
+	// a reversal carries the indicator of its own direction, not the one of the entry it reverses
+	amount, err := camtMoney(tx.Amount, firstNonEmpty(tx.CreditDebit, entry.CreditDebit))
+	if err != nil {
+		return s.emit(nil, fmt.Errorf("entry %d of %s: %w", s.entries, s.id, err))
+	}
+	endToEndID := tx.EndToEndID
+	if endToEndID == camtNotProvided {
+		endToEndID = ""
+	}
+	uniqueID := firstNonEmpty(endToEndID, tx.ServicerRef, entry.ServicerRef, entry.Reference)
+	if uniqueID == "" {
+		uniqueID = s.id + "-" + strconv.Itoa(s.entries)
+	}
+	if i > 0 && endToEndID == "" {
+		uniqueID += "-" + strconv.Itoa(i+1)
+	}
+	return s.emit(domain.BankStatement{
+		UniqueID:      uniqueID,
+		Amount:        amount,
+		StatementTime: date,
+		BankCode:      s.bankCode,
+		AccountNumber: s.accountNumber,
+		Description:   firstNonEmpty(strings.Join(tx.Remittance, " "), tx.Info, entry.Info),
+		BankReference: firstNonEmpty(tx.ServicerRef, entry.ServicerRef),
+	}, nil)
+}
+
+func everyAmount(txs []camtTransaction) bool {
+	for _, tx := range txs {
+		if strings.TrimSpace(tx.Amount.Value) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// camtMoney reads an amount in its Ccy attribute, debits are negative
+func camtMoney(amount camtAmount, creditDebit string) (money.Money, error) {
+	currency, err := money.ParseCurrency(amount.Currency)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("parse currency error: %w", err)
+	}
+	m, err := money.Parse(amount.Value, currency)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("parse amount error: %w", err)
+	}
+	switch strings.TrimSpace(creditDebit) {
+	case camtCredit:
+		return m, nil
+	case camtDebit:
+		return m.Neg(), nil
+	}
+	return money.Money{}, fmt.Errorf("invalid credit debit indicator %q", creditDebit)
+}
+
+// camtTime reads an ISODate, or an ISODateTime with or without its offset
+func camtTime(d camtDate) (time.Time, error) {
+	if date := strings.TrimSpace(d.Date); date != "" {
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parse date error: %w", err)
+		}
+		return t, nil
+	}
+	if dateTime := strings.TrimSpace(d.DateTime); dateTime != "" {
+		t, err := time.Parse(time.RFC3339, dateTime)
+		if err != nil {
+			t, err = time.Parse("2006-01-02T15:04:05", dateTime)
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parse date error: %w", err)
+		}
+		return t, nil
+	}
+	return time.Time{}, errors.New("no date")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
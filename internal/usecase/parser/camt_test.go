@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCamtParserStatement(t *testing.T) {
+	file := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG-1</MsgId><CreDtTm>2025-02-01T06:00:00+07:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>STMT-0131</Id>
+      <Acct>
+        <Id><Othr><Id>0012345678</Id></Othr></Id>
+        <Ccy>IDR</Ccy>
+        <Svcr><FinInstnId><BICFI>CENAIDJAXXX</BICFI></FinInstnId></Svcr>
+      </Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>PRCD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="IDR">1000000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2025-01-30</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="IDR">1100000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2025-01-31</Dt></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="IDR">150000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2025-01-31</Dt></BookgDt><ValDt><Dt>2025-02-01</Dt></ValDt>
+        <AcctSvcrRef>BCA-77</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>INV-001</EndToEndId></Refs>
+            <Amt Ccy="IDR">100000.00</Amt>
+            <RmtInf><Ustrd>INVOICE 001</Ustrd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <Refs><EndToEndId>NOTPROVIDED</EndToEndId><AcctSvcrRef>BCA-78</AcctSvcrRef></Refs>
+            <Amt Ccy="IDR">50000.00</Amt>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="IDR">50000.00</Amt><CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <ValDt><DtTm>2025-01-31T10:15:00+07:00</DtTm></ValDt>
+        <AddtlNtryInf>MONTHLY FEE</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="IDR">10.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><Dt>2025-01-31</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+	records, errs := parseFile(t, &CamtParser{}, file)
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "entry 3 of STMT-0131: status PDNG is not booked")
+
+	date := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []interface{}{
+		domain.StatementBalance{
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			BalanceType:   domain.BalanceOpening,
+			BalanceDate:   time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC),
+			Amount:        money.MustParse("1000000", "IDR"),
+		},
+		domain.StatementBalance{
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			BalanceType:   domain.BalanceClosing,
+			BalanceDate:   date,
+			Amount:        money.MustParse("1100000", "IDR"),
+		},
+		domain.BankStatement{
+			UniqueID:      "INV-001",
+			Amount:        money.MustParse("100000", "IDR"),
+			StatementTime: date,
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			Description:   "INVOICE 001",
+			BankReference: "BCA-77",
+		},
+		domain.BankStatement{
+			UniqueID:      "BCA-78-2",
+			Amount:        money.MustParse("50000", "IDR"),
+			StatementTime: date,
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			BankReference: "BCA-78",
+		},
+		domain.BankStatement{
+			UniqueID:      "STMT-0131-2",
+			Amount:        money.MustParse("-50000", "IDR"),
+			StatementTime: time.Date(2025, 1, 31, 10, 15, 0, 0, time.FixedZone("", 7*60*60)),
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			Description:   "MONTHLY FEE",
+		},
+	}, records)
+}
+
+func TestCamtParserNotification(t *testing.T) {
+	file := `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.02">
+  <BkToCstmrDbtCdtNtfctn>
+    <Ntfctn>
+      <Id>NTF-9</Id>
+      <Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Svcr><FinInstnId><BIC>COBADEFF</BIC></FinInstnId></Svcr></Acct>
+      <Ntry>
+        <Amt Ccy="EUR">12.50</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-01-31</Dt></BookgDt>
+        <NtryDtls><TxDtls><Refs><EndToEndId>E2E-1</EndToEndId></Refs><AddtlTxInf>CARD</AddtlTxInf></TxDtls></NtryDtls>
+      </Ntry>
+    </Ntfctn>
+  </BkToCstmrDbtCdtNtfctn>
+</Document>`
+
+	records, errs := parseFile(t, &CamtParser{}, file)
+	assert.Empty(t, errs)
+	assert.Equal(t, []interface{}{
+		domain.BankStatement{
+			UniqueID:      "E2E-1",
+			Amount:        money.MustParse("-12.50", "EUR"),
+			StatementTime: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+			BankCode:      "COBADEFF",
+			AccountNumber: "DE89370400440532013000",
+			Description:   "CARD",
+		},
+	}, records)
+}
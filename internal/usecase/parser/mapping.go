@@ -0,0 +1,355 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	enum_parser "github.com/ardianferdianto/reconciliation-service/internal/domain/enum/parser"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// mappedColumns are the columns a profile may map
+var mappedColumns = []string{
+	domain.ColumnReference, domain.ColumnAmount, domain.ColumnDebitAmount, domain.ColumnCreditAmount, domain.ColumnIndicator,
+	domain.ColumnDate, domain.ColumnTime, domain.ColumnCurrency, domain.ColumnBankCode, domain.ColumnAccountNumber,
+}
+
+var (
+	defaultDebitIndicators  = []string{"D", "DR", "DB", domain.Debit}
+	defaultCreditIndicators = []string{"C", "CR", domain.Credit}
+)
+
+// LayoutParser is a CSVParser reading a file layout of its own, the reader of the file is set up from it.
+// Columns referenced by name are looked up in the header row with BindHeader before the first line is parsed.
+type LayoutParser interface {
+	CSVParser
+	Delimiter() rune
+	Sheet() string
+	SkipRows() (preamble, footer int)
+	HasHeader() bool
+	BindHeader(header []string) error
+}
+
+// MappingParser parses the lines of a file into system transactions or bank statements
+// from the columns, formats and sign convention declared by a parser profile
+type MappingParser struct {
+	profile     domain.ParserProfile
+	fileType    string
+	delimiter   rune
+	location    *time.Location
+	dateFormats []string
+	columns     map[string]int // index of every mapped column once bound
+}
+
+// NewMappingParser checks the profile declares everything a file of the type needs
+func NewMappingParser(profile domain.ParserProfile, fileType string) (*MappingParser, error) {
+	if fileType != enum_parser.SYSTEM_TRX && fileType != enum_parser.BANK_STATEMENT {
+		return nil, fmt.Errorf("%w %s: unknown file type %s", ErrInvalidProfile, profile.Name, fileType)
+	}
+	p := &MappingParser{profile: profile, fileType: fileType, delimiter: ',', location: time.UTC, dateFormats: profile.DateFormats}
+	if err := p.configure(); err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, err)
+	}
+	if !p.HasHeader() {
+		// configure made sure every column has an index
+		_ = p.BindHeader(nil)
+	}
+	return p, nil
+}
+
+// configure checks the profile and applies its settings, the defaults of the built-in parsers fill the ones left out
+func (p *MappingParser) configure() error {
+	profile := p.profile
+	if profile.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(profile.Delimiter)
+		if size != len(profile.Delimiter) || r == '"' || r == '\r' || r == '\n' {
+			return fmt.Errorf("delimiter %q is not a single character", profile.Delimiter)
+		}
+		p.delimiter = r
+	}
+	if profile.Timezone != "" {
+		loc, err := time.LoadLocation(profile.Timezone)
+		if err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+		p.location = loc
+	}
+	if len(p.dateFormats) == 0 {
+		p.dateFormats = []string{"2006-01-02"}
+		if _, hasTime := profile.Columns[domain.ColumnTime]; p.fileType == enum_parser.SYSTEM_TRX && !hasTime {
+			p.dateFormats = []string{"2006-01-02 15:04:05"}
+		}
+	}
+	if _, ok := profile.Columns[domain.ColumnTime]; ok && profile.TimeFormat == "" {
+		return fmt.Errorf("time column without a time_format")
+	}
+	if profile.DefaultCurrency != "" {
+		if _, err := money.ParseCurrency(profile.DefaultCurrency); err != nil {
+			return fmt.Errorf("default currency: %w", err)
+		}
+	}
+	if profile.SkipRows < 0 || profile.SkipFooterRows < 0 {
+		return fmt.Errorf("negative number of rows to skip")
+	}
+	if profile.DecimalSeparator != "" && profile.DecimalSeparator == profile.ThousandSeparator {
+		return fmt.Errorf("decimal and thousand separator are both %q", profile.DecimalSeparator)
+	}
+
+	required := []string{domain.ColumnReference, domain.ColumnDate}
+	switch p.signConvention() {
+	case domain.SignSigned, domain.SignInverted:
+		required = append(required, domain.ColumnAmount)
+	case domain.SignDebitCredit:
+		required = append(required, domain.ColumnDebitAmount, domain.ColumnCreditAmount)
+	case domain.SignIndicator:
+		required = append(required, domain.ColumnAmount, domain.ColumnIndicator)
+	default:
+		return fmt.Errorf("unknown sign convention %s", profile.SignConvention)
+	}
+	if p.fileType == enum_parser.BANK_STATEMENT && profile.DefaultBankCode == "" {
+		required = append(required, domain.ColumnBankCode)
+	}
+	for _, column := range required {
+		if _, ok := profile.Columns[column]; !ok {
+			return fmt.Errorf("no %s column", column)
+		}
+	}
+	for column, ref := range profile.Columns {
+		switch {
+		case !slices.Contains(mappedColumns, column):
+			return fmt.Errorf("unknown column %s", column)
+		case ref.Name == "" && ref.Index == nil:
+			return fmt.Errorf("%s column has neither a name nor an index", column)
+		case ref.Index != nil && *ref.Index < 0:
+			return fmt.Errorf("%s column has a negative index", column)
+		case ref.Index == nil && profile.NoHeader:
+			return fmt.Errorf("%s column needs an index, the file has no header", column)
+		}
+	}
+	return nil
+}
+
+func (p *MappingParser) signConvention() string {
+	if p.profile.SignConvention == "" {
+		return domain.SignSigned
+	}
+	return strings.ToUpper(p.profile.SignConvention)
+}
+
+func (p *MappingParser) Delimiter() rune {
+	return p.delimiter
+}
+
+func (p *MappingParser) Sheet() string {
+	return p.profile.Sheet
+}
+
+func (p *MappingParser) SkipRows() (preamble, footer int) {
+	return p.profile.SkipRows, p.profile.SkipFooterRows
+}
+
+func (p *MappingParser) HasHeader() bool {
+	return !p.profile.NoHeader
+}
+
+// BindHeader resolves the columns referenced by name to their index in the header, an index set on the profile wins
+func (p *MappingParser) BindHeader(header []string) error {
+	columns := make(map[string]int, len(p.profile.Columns))
+	for column, ref := range p.profile.Columns {
+		if ref.Index != nil {
+			columns[column] = *ref.Index
+			continue
+		}
+		idx := slices.IndexFunc(header, func(name string) bool {
+			return strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")), strings.TrimSpace(ref.Name))
+		})
+		if idx < 0 {
+			return fmt.Errorf("%w %s: no column %q in the header", ErrInvalidProfile, p.profile.Name, ref.Name)
+		}
+		columns[column] = idx
+	}
+	p.columns = columns
+	return nil
+}
+
+func (p *MappingParser) ParseLine(fields []string) (interface{}, error) {
+	if p.columns == nil {
+		return nil, fmt.Errorf("header of profile %s is not bound", p.profile.Name)
+	}
+	reference, err := p.required(fields, domain.ColumnReference)
+	if err != nil {
+		return nil, err
+	}
+	currency := p.profile.DefaultCurrency
+	if value := p.value(fields, domain.ColumnCurrency); value != "" {
+		currency = value
+	}
+	currency, err = currencyAt([]string{currency}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("parse currency error: %w", err)
+	}
+	amt, err := p.signedAmount(fields, currency)
+	if err != nil {
+		return nil, fmt.Errorf("parse amount error: %w", err)
+	}
+	dt, err := p.dateTime(fields)
+	if err != nil {
+		return nil, fmt.Errorf("parse date error: %w", err)
+	}
+
+	if p.fileType == enum_parser.SYSTEM_TRX {
+		trxType := domain.Credit
+		if amt.IsNegative() {
+			trxType = domain.Debit
+		}
+		return domain.Transaction{
+			TrxID:           reference,
+			Amount:          amt.Abs(),
+			Type:            trxType,
+			TransactionTime: dt,
+		}, nil
+	}
+
+	bankCode := p.profile.DefaultBankCode
+	if value := p.value(fields, domain.ColumnBankCode); value != "" {
+		bankCode = value
+	}
+	accountNumber := p.value(fields, domain.ColumnAccountNumber)
+	switch strings.ToUpper(reference) {
+	case openingBalanceRow:
+		return statementBalance(domain.BalanceOpening, bankCode, accountNumber, dt, amt), nil
+	case closingBalanceRow:
+		return statementBalance(domain.BalanceClosing, bankCode, accountNumber, dt, amt), nil
+	}
+	return domain.BankStatement{
+		UniqueID:      reference,
+		Amount:        amt,
+		StatementTime: dt,
+		BankCode:      bankCode,
+		AccountNumber: accountNumber,
+	}, nil
+}
+
+// value returns the trimmed value of a mapped column, empty when the column is not mapped or the line is short
+func (p *MappingParser) value(fields []string, column string) string {
+	idx, ok := p.columns[column]
+	if !ok || idx >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[idx])
+}
+
+func (p *MappingParser) required(fields []string, column string) (string, error) {
+	value := p.value(fields, column)
+	if value == "" {
+		return "", fmt.Errorf("empty %s column", column)
+	}
+	return value, nil
+}
+
+// signedAmount returns the amount of the line following the sign convention, credits positive and debits negative
+func (p *MappingParser) signedAmount(fields []string, currency string) (money.Money, error) {
+	switch p.signConvention() {
+	case domain.SignDebitCredit:
+		debit, err := p.amount(p.value(fields, domain.ColumnDebitAmount), currency)
+		if err != nil {
+			return money.Money{}, err
+		}
+		credit, err := p.amount(p.value(fields, domain.ColumnCreditAmount), currency)
+		if err != nil {
+			return money.Money{}, err
+		}
+		return credit.Abs().Sub(debit.Abs()), nil
+	case domain.SignIndicator:
+		amt, err := p.requiredAmount(fields, currency)
+		if err != nil {
+			return money.Money{}, err
+		}
+		indicator := p.value(fields, domain.ColumnIndicator)
+		switch {
+		case indicatorIn(indicator, p.profile.DebitIndicators, defaultDebitIndicators):
+			return amt.Abs().Neg(), nil
+		case indicatorIn(indicator, p.profile.CreditIndicators, defaultCreditIndicators):
+			return amt.Abs(), nil
+		}
+		return money.Money{}, fmt.Errorf("unknown debit/credit indicator %q", indicator)
+	case domain.SignInverted:
+		amt, err := p.requiredAmount(fields, currency)
+		return amt.Neg(), err
+	default:
+		return p.requiredAmount(fields, currency)
+	}
+}
+
+func (p *MappingParser) requiredAmount(fields []string, currency string) (money.Money, error) {
+	text, err := p.required(fields, domain.ColumnAmount)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return p.amount(text, currency)
+}
+
+// amount parses a number written with the separators of the profile, an empty one is zero.
+// Negative numbers may be written in parentheses or with a trailing minus as well.
+func (p *MappingParser) amount(text, currency string) (money.Money, error) {
+	s := strings.TrimSpace(text)
+	if s == "" {
+		return money.New(0, currency), nil
+	}
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative, s = true, s[1:len(s)-1]
+	}
+	if strings.HasSuffix(s, "-") {
+		negative, s = !negative, strings.TrimSuffix(s, "-")
+	}
+	if p.profile.ThousandSeparator != "" {
+		s = strings.ReplaceAll(s, p.profile.ThousandSeparator, "")
+	}
+	if p.profile.DecimalSeparator != "" && p.profile.DecimalSeparator != "." {
+		s = strings.Replace(s, p.profile.DecimalSeparator, ".", 1)
+	}
+	amt, err := money.Parse(s, currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if negative {
+		amt = amt.Neg()
+	}
+	return amt, nil
+}
+
+// dateTime parses the date column with the first date format that fits, joined with the time column when one is mapped
+func (p *MappingParser) dateTime(fields []string) (time.Time, error) {
+	value, err := p.required(fields, domain.ColumnDate)
+	if err != nil {
+		return time.Time{}, err
+	}
+	timeOfDay := p.value(fields, domain.ColumnTime)
+	var lastErr error
+	for _, layout := range p.dateFormats {
+		text := value
+		if timeOfDay != "" {
+			layout, text = layout+" "+p.profile.TimeFormat, value+" "+timeOfDay
+		}
+		dt, err := time.ParseInLocation(layout, text, p.location)
+		if err == nil {
+			return dt, nil
+		}
+		lastErr = err
+	}
+	return time.Time{}, lastErr
+}
+
+// indicatorIn reports whether the indicator is one of the configured ones, or of the defaults when none are configured
+func indicatorIn(indicator string, configured, defaults []string) bool {
+	if len(configured) == 0 {
+		configured = defaults
+	}
+	return slices.ContainsFunc(configured, func(candidate string) bool {
+		return strings.EqualFold(strings.TrimSpace(candidate), indicator)
+	})
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	enum_parser "github.com/ardianferdianto/reconciliation-service/internal/domain/enum/parser"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func index(i int) *int {
+	return &i
+}
+
+func TestMappingParserBankStatement(t *testing.T) {
+	profile := domain.ParserProfile{
+		Name:      "bni_v2",
+		Delimiter: ";",
+		Columns: map[string]domain.ColumnRef{
+			domain.ColumnReference:     {Name: "Ref No"},
+			domain.ColumnDebitAmount:   {Name: "Debit"},
+			domain.ColumnCreditAmount:  {Name: "Credit"},
+			domain.ColumnDate:          {Name: "Posting Date"},
+			domain.ColumnAccountNumber: {Index: index(4)},
+		},
+		DateFormats:       []string{"02/01/2006"},
+		Timezone:          "Asia/Jakarta",
+		DecimalSeparator:  ",",
+		ThousandSeparator: ".",
+		SignConvention:    domain.SignDebitCredit,
+		DefaultBankCode:   "BNI",
+	}
+	p, err := NewMappingParser(profile, enum_parser.BANK_STATEMENT)
+	require.NoError(t, err)
+	assert.Equal(t, ';', p.Delimiter())
+	require.NoError(t, p.BindHeader([]string{"\ufeffPosting Date", "Ref No", "Debit", "Credit", "Account"}))
+
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+	line, err := p.ParseLine([]string{"05/01/2025", "BNI-001", "1.250.000,50", "", "0012345678"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.BankStatement{
+		UniqueID:      "BNI-001",
+		Amount:        money.MustParse("-1250000.50", "IDR"),
+		StatementTime: time.Date(2025, 1, 5, 0, 0, 0, 0, jakarta),
+		BankCode:      "BNI",
+		AccountNumber: "0012345678",
+	}, line)
+
+	balance, err := p.ParseLine([]string{"31/01/2025", "closing_balance", "", "9.000,00", "0012345678"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.BalanceClosing, balance.(domain.StatementBalance).BalanceType)
+	assert.Equal(t, money.MustParse("9000", "IDR"), balance.(domain.StatementBalance).Amount)
+
+	_, err = p.ParseLine([]string{"2025-01-05", "BNI-002", "10,00", "", ""})
+	assert.ErrorContains(t, err, "parse date error")
+
+	assert.ErrorIs(t, p.BindHeader([]string{"Posting Date", "Reference"}), ErrInvalidProfile)
+}
+
+func TestMappingParserSystemTransaction(t *testing.T) {
+	profile := domain.ParserProfile{
+		Name:     "erp_export",
+		NoHeader: true,
+		Columns: map[string]domain.ColumnRef{
+			domain.ColumnReference: {Index: index(0)},
+			domain.ColumnDate:      {Index: index(1)},
+			domain.ColumnTime:      {Index: index(2)},
+			domain.ColumnAmount:    {Index: index(3)},
+			domain.ColumnIndicator: {Index: index(4)},
+			domain.ColumnCurrency:  {Index: index(5)},
+		},
+		DateFormats:    []string{"20060102"},
+		TimeFormat:     "150405",
+		SignConvention: domain.SignIndicator,
+	}
+	p, err := NewMappingParser(profile, enum_parser.SYSTEM_TRX)
+	require.NoError(t, err)
+	assert.False(t, p.HasHeader())
+
+	line, err := p.ParseLine([]string{"TX9", "20250105", "134500", "(15.25)", "dr", "usd"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.Transaction{
+		TrxID:           "TX9",
+		Amount:          money.MustParse("15.25", "USD"),
+		Type:            domain.Debit,
+		TransactionTime: time.Date(2025, 1, 5, 13, 45, 0, 0, time.UTC),
+	}, line)
+
+	_, err = p.ParseLine([]string{"TX10", "20250105", "134500", "15.25", "X", "USD"})
+	assert.ErrorContains(t, err, "unknown debit/credit indicator")
+}
+
+func TestNewMappingParserInvalidProfile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		profile domain.ParserProfile
+	}{
+		{
+			name: "No Bank Code",
+			profile: domain.ParserProfile{Columns: map[string]domain.ColumnRef{
+				domain.ColumnReference: {Name: "ref"}, domain.ColumnAmount: {Name: "amount"}, domain.ColumnDate: {Name: "date"},
+			}},
+		},
+		{
+			name: "Name Without Header",
+			profile: domain.ParserProfile{NoHeader: true, DefaultBankCode: "BCA", Columns: map[string]domain.ColumnRef{
+				domain.ColumnReference: {Index: index(0)}, domain.ColumnAmount: {Name: "amount"}, domain.ColumnDate: {Index: index(2)},
+			}},
+		},
+		{
+			name: "Unknown Sign Convention",
+			profile: domain.ParserProfile{SignConvention: "REVERSED", DefaultBankCode: "BCA", Columns: map[string]domain.ColumnRef{
+				domain.ColumnReference: {Name: "ref"}, domain.ColumnAmount: {Name: "amount"}, domain.ColumnDate: {Name: "date"},
+			}},
+		},
+		{
+			name: "Unknown Column",
+			profile: domain.ParserProfile{DefaultBankCode: "BCA", Columns: map[string]domain.ColumnRef{
+				domain.ColumnReference: {Name: "ref"}, domain.ColumnAmount: {Name: "amount"}, domain.ColumnDate: {Name: "date"},
+				"narrative": {Name: "desc"},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewMappingParser(tc.profile, enum_parser.BANK_STATEMENT)
+			assert.ErrorIs(t, err, ErrInvalidProfile)
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// :TAG: at the start of a line opens a field, the lines up to the next tag continue it
+	mt940FieldRe = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):(.*)$`)
+	// sender BIC of the output application header {2:O940HHMMYYMMDD<BIC8>...}
+	mt940SenderRe = regexp.MustCompile(`\{2:O\d{3}\d{4}\d{6}([A-Z0-9]{8})`)
+	// value date, entry date, debit/credit mark, funds code, amount, transaction type, references
+	mt940LineRe = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+(?:,\d*)?)([NFS][A-Z0-9]{3})(.*)$`)
+	// debit/credit mark, date, currency, amount
+	mt940BalanceRe = regexp.MustCompile(`^(C|D)(\d{6})([A-Z]{3})(\d+(?:,\d*)?)$`)
+	mt940BICRe     = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+)
+
+// mt940NoReference is the customer reference of lines the account owner gave no reference for
+const mt940NoReference = "NONREF"
+
+// MT940Parser reads SWIFT MT940 customer statements and MT942 interim reports.
+// Every :61: line becomes a domain.BankStatement with the :86: narrative following it as description,
+// :60F: and :62F: become the opening and closing domain.StatementBalance of the account.
+// The bank code is the BIC in front of the :25: account identification, or the sender of the message when there is none.
+type MT940Parser struct{}
+
+func (p *MT940Parser) ParseFile(r io.Reader, emit func(record interface{}, err error) error) error {
+	s := &mt940Statement{emit: emit}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := s.line(strings.TrimRight(scanner.Text(), "\r")); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read error: %w", err)
+	}
+	return s.endMessage()
+}
+
+// mt940Statement holds what the fields read so far tell about the statement being parsed
+type mt940Statement struct {
+	emit func(record interface{}, err error) error
+
+	senderBIC     string
+	reference     string // :20: transaction reference number
+	bankCode      string
+	accountNumber string
+	currency      string
+	lines         int
+	pending       *domain.BankStatement // :61: line waiting for its :86: narrative
+
+	tag     string
+	content []string
+}
+
+func (s *mt940Statement) line(text string) error {
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		if m := mt940SenderRe.FindStringSubmatch(trimmed); m != nil {
+			s.senderBIC = m[1]
+		}
+		// the text block may start on the header line
+		if i := strings.Index(trimmed, "{4:"); i >= 0 && strings.TrimSpace(trimmed[i+3:]) != "" {
+			return s.line(trimmed[i+3:])
+		}
+		return nil
+	case trimmed == "-" || strings.HasPrefix(trimmed, "-}"):
+		// the block terminator, a field continued on a line starting with a dash goes on
+		return s.endMessage()
+	}
+
+	if m := mt940FieldRe.FindStringSubmatch(text); m != nil {
+		if err := s.endField(); err != nil {
+			return err
+		}
+		s.tag, s.content = m[1], []string{m[2]}
+		return nil
+	}
+	if s.tag != "" {
+		s.content = append(s.content, text)
+	}
+	return nil
+}
+
+func (s *mt940Statement) endMessage() error {
+	if err := s.endField(); err != nil {
+		return err
+	}
+	if err := s.flushLine(); err != nil {
+		return err
+	}
+	s.senderBIC = ""
+	return nil
+}
+
+func (s *mt940Statement) endField() error {
+	tag, content := s.tag, s.content
+	s.tag, s.content = "", nil
+	if tag == "" {
+		return nil
+	}
+	if tag != "86" {
+		if err := s.flushLine(); err != nil {
+			return err
+		}
+	}
+
+	switch tag {
+	case "20":
+		s.reference = strings.TrimSpace(content[0])
+		s.bankCode, s.accountNumber, s.currency, s.lines = "", "", "", 0
+	case "25", "25P":
+		s.account(tag, content)
+	case "34F":
+		// MT942 floor limit, the only field naming its currency
+		if len(content[0]) >= 3 {
+			s.currency = content[0][:3]
+		}
+	case "60F", "60M", "62F", "62M":
+		return s.balance(tag, content[0])
+	case "61":
+		s.lines++
+		stmt, err := s.statementLine(content)
+		if err != nil {
+			return s.emit(nil, fmt.Errorf("statement line %d of %s: %w", s.lines, s.reference, err))
+		}
+		s.pending = stmt
+	case "86":
+		if s.pending != nil {
+			s.pending.Description = narrative(content)
+		}
+	}
+	return nil
+}
+
+// flushLine hands the pending :61: line over once no :86: narrative may follow it anymore
+func (s *mt940Statement) flushLine() error {
+	if s.pending == nil {
+		return nil
+	}
+	stmt := *s.pending
+	s.pending = nil
+	return s.emit(stmt, nil)
+}
+
+func (s *mt940Statement) account(tag string, content []string) {
+	account := strings.TrimSpace(content[0])
+	bic := s.senderBIC
+	if tag == "25P" && len(content) > 1 {
+		bic = strings.TrimSpace(content[1])
+	}
+	if prefix, rest, found := strings.Cut(account, "/"); found && mt940BICRe.MatchString(prefix) {
+		bic, account = prefix, rest
+	}
+	if len(bic) > 8 {
+		bic = bic[:8]
+	}
+	s.bankCode, s.accountNumber = bic, account
+}
+
+func (s *mt940Statement) balance(tag, content string) error {
+	m := mt940BalanceRe.FindStringSubmatch(strings.TrimSpace(content))
+	if m == nil {
+		return s.emit(nil, fmt.Errorf("balance :%s: of %s: invalid format %q", tag, s.reference, content))
+	}
+	s.currency = m[3]
+	// only the final balances open and close the statement, the intermediate ones split it into pages
+	var balanceType string
+	switch tag {
+	case "60F":
+		balanceType = domain.BalanceOpening
+	case "62F":
+		balanceType = domain.BalanceClosing
+	default:
+		return nil
+	}
+
+	date, err := time.Parse("060102", m[2])
+	if err != nil {
+		return s.emit(nil, fmt.Errorf("balance :%s: of %s: parse date error: %w", tag, s.reference, err))
+	}
+	amount, err := swiftAmount(m[4], m[3], m[1] == "D")
+	if err != nil {
+		return s.emit(nil, fmt.Errorf("balance :%s: of %s: %w", tag, s.reference, err))
+	}
+	return s.emit(statementBalance(balanceType, s.bankCode, s.accountNumber, date, amount), nil)
+}
+
+func (s *mt940Statement) statementLine(content []string) (*domain.BankStatement, error) {
+	m := mt940LineRe.FindStringSubmatch(strings.TrimSpace(content[0]))
+	if m == nil {
+		return nil, fmt.Errorf("invalid format %q", content[0])
+	}
+	date, err := time.Parse("060102", m[1])
+	if err != nil {
+		return nil, fmt.Errorf("parse date error: %w", err)
+	}
+	currency := s.currency
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	// a reversal of a credit takes money out of the account, one of a debit puts it back
+	amount, err := swiftAmount(m[5], currency, m[3] == "D" || m[3] == "RC")
+	if err != nil {
+		return nil, err
+	}
+
+	customerRef, bankRef, _ := strings.Cut(m[7], "//")
+	customerRef, bankRef = strings.TrimSpace(customerRef), strings.TrimSpace(bankRef)
+	uniqueID := customerRef
+	if uniqueID == "" || uniqueID == mt940NoReference {
+		uniqueID = bankRef
+	}
+	if uniqueID == "" {
+		uniqueID = s.reference + "-" + strconv.Itoa(s.lines)
+	}
+	return &domain.BankStatement{
+		UniqueID:      uniqueID,
+		Amount:        amount,
+		StatementTime: date,
+		BankCode:      s.bankCode,
+		AccountNumber: s.accountNumber,
+		TypeCode:      m[6],
+		BankReference: bankRef,
+	}, nil
+}
+
+// swiftAmount reads an amount with a comma as decimal mark, debits are negative
+func swiftAmount(text, currency string, debit bool) (money.Money, error) {
+	amount, err := money.Parse(strings.Replace(text, ",", ".", 1), currency)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("parse amount error: %w", err)
+	}
+	if debit {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// narrative joins the lines of a free text field, SWIFT wraps them at 65 characters
+func narrative(content []string) string {
+	var b strings.Builder
+	for _, line := range content {
+		b.WriteString(strings.TrimRight(line, " "))
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseFile(t *testing.T, p FileParser, file string) ([]interface{}, []error) {
+	var records []interface{}
+	var errs []error
+	err := p.ParseFile(strings.NewReader(file), func(record interface{}, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		records = append(records, record)
+		return nil
+	})
+	require.NoError(t, err)
+	return records, errs
+}
+
+func TestMT940Parser(t *testing.T) {
+	file := "{1:F01CENAIDJAAXXX0000000000}{2:O9401200250131CENAIDJAXXXX00000000002501311200N}{4:\r\n" +
+		":20:STMT250131\r\n" +
+		":25:0012345678\r\n" +
+		":28C:1/1\r\n" +
+		":60F:C250130IDR1000000,00\r\n" +
+		":61:2501310131C250000,50NTRFINV-001//BCA123\r\n" +
+		":86:TRANSFER FROM PT MAJU\r\n" +
+		" JAYA INVOICE 001\r\n" +
+		"-50 IDR DISCOUNT\r\n" +
+		":61:250131D10000,NCHGNONREF//FEE-9\r\n" +
+		":61:250131RC500,NTRF\r\n" +
+		":61:250131X1,00NTRFBROKEN\r\n" +
+		":62F:C250131IDR1239500,50\r\n" +
+		"-}"
+
+	records, errs := parseFile(t, &MT940Parser{}, file)
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "statement line 4 of STMT250131")
+
+	date := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []interface{}{
+		domain.StatementBalance{
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			BalanceType:   domain.BalanceOpening,
+			BalanceDate:   time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC),
+			Amount:        money.MustParse("1000000", "IDR"),
+		},
+		domain.BankStatement{
+			UniqueID:      "INV-001",
+			Amount:        money.MustParse("250000.50", "IDR"),
+			StatementTime: date,
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			Description:   "TRANSFER FROM PT MAJU JAYA INVOICE 001-50 IDR DISCOUNT",
+			TypeCode:      "NTRF",
+			BankReference: "BCA123",
+		},
+		domain.BankStatement{
+			UniqueID:      "FEE-9",
+			Amount:        money.MustParse("-10000", "IDR"),
+			StatementTime: date,
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			TypeCode:      "NCHG",
+			BankReference: "FEE-9",
+		},
+		domain.BankStatement{
+			UniqueID:      "STMT250131-3",
+			Amount:        money.MustParse("-500", "IDR"),
+			StatementTime: date,
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			TypeCode:      "NTRF",
+		},
+		domain.StatementBalance{
+			BankCode:      "CENAIDJA",
+			AccountNumber: "0012345678",
+			BalanceType:   domain.BalanceClosing,
+			BalanceDate:   date,
+			Amount:        money.MustParse("1239500.50", "IDR"),
+		},
+	}, records)
+}
+
+func TestMT940ParserInterimReport(t *testing.T) {
+	file := ":20:INTRADAY1\n" +
+		":25:BNINIDJA/9988776655\n" +
+		":28C:5/1\n" +
+		":34F:USDD0,\n" +
+		":13D:2501311015+0700\n" +
+		":61:250131C99,95NMSCREF-1\n" +
+		":86:CARD TOPUP\n"
+
+	records, errs := parseFile(t, &MT940Parser{}, file)
+	assert.Empty(t, errs)
+	assert.Equal(t, []interface{}{
+		domain.BankStatement{
+			UniqueID:      "REF-1",
+			Amount:        money.MustParse("99.95", "USD"),
+			StatementTime: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+			BankCode:      "BNINIDJA",
+			AccountNumber: "9988776655",
+			Description:   "CARD TOPUP",
+			TypeCode:      "NMSC",
+		},
+	}, records)
+}
@@ -0,0 +1,267 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ofxAggregates are the elements holding other elements, OFX 1.x leaves the elements holding a value unclosed
+var ofxAggregates = map[string]bool{
+	"STMTRS": true, "CCSTMTRS": true, "FI": true,
+	"BANKACCTFROM": true, "CCACCTFROM": true, "BANKACCTTO": true, "CCACCTTO": true,
+	"STMTTRN": true, "CURRENCY": true, "ORIGCURRENCY": true, "PAYEE": true,
+	"LEDGERBAL": true, "AVAILBAL": true,
+}
+
+var ofxEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ", "&amp;", "&")
+
+// OFXParser reads OFX 1.x (SGML) and 2.x (XML) bank and credit card statements.
+// Every STMTTRN becomes a domain.BankStatement with the FITID as unique id, the TRNTYPE as type code and the
+// REFNUM or CHECKNUM as bank reference. TRNAMT is signed already, credits positive.
+// The LEDGERBAL becomes the closing domain.StatementBalance of the account. The bank code is the BANKID of the
+// account, or the ORG of the financial institution for credit card statements.
+type OFXParser struct{}
+
+func (p *OFXParser) ParseFile(r io.Reader, emit func(record interface{}, err error) error) error {
+	s := &ofxStatement{emit: emit}
+	br := bufio.NewReader(r)
+	var leaf string // element the text read next is the value of
+	for {
+		text, err := br.ReadString('<')
+		if value := strings.TrimSpace(strings.TrimSuffix(text, "<")); leaf != "" && value != "" {
+			s.value(leaf, ofxEntities.Replace(value))
+		}
+		leaf = ""
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read error: %w", err)
+		}
+
+		tag, err := br.ReadString('>')
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: unterminated tag <%s", ErrInvalidStatementFile, tag)
+		}
+		if err != nil {
+			return fmt.Errorf("read error: %w", err)
+		}
+		tag = strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(tag, ">")))
+		switch {
+		case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"), strings.HasSuffix(tag, "/"):
+			// processing instructions, comments and empty elements
+		case strings.HasPrefix(tag, "/"):
+			if err := s.end(tag[1:]); err != nil {
+				return err
+			}
+		case ofxAggregates[tag]:
+			s.open = append(s.open, tag)
+			if tag == "STMTTRN" {
+				s.trn = &ofxTransaction{}
+			}
+		default:
+			leaf = tag
+		}
+	}
+}
+
+// ofxStatement holds what is known of the statement being read
+type ofxStatement struct {
+	emit func(record interface{}, err error) error
+
+	open          []string // aggregates the reader is in
+	org           string
+	bankCode      string
+	accountNumber string
+	currency      string
+	trns          int
+	trn           *ofxTransaction
+	ledger        ofxBalance
+}
+
+type ofxTransaction struct {
+	trnType  string
+	posted   string
+	user     string
+	amount   string
+	fitID    string
+	checkNum string
+	refNum   string
+	name     string
+	memo     string
+	currency string
+}
+
+type ofxBalance struct {
+	amount string
+	asOf   string
+}
+
+func (s *ofxStatement) value(tag, value string) {
+	if len(s.open) == 0 {
+		return
+	}
+	switch s.open[len(s.open)-1] {
+	case "FI":
+		if tag == "ORG" {
+			s.org = value
+		}
+	case "STMTRS", "CCSTMTRS":
+		if tag == "CURDEF" {
+			s.currency = value
+		}
+	case "BANKACCTFROM", "CCACCTFROM":
+		switch tag {
+		case "BANKID":
+			s.bankCode = value
+		case "ACCTID":
+			s.accountNumber = value
+		}
+	case "LEDGERBAL":
+		switch tag {
+		case "BALAMT":
+			s.ledger.amount = value
+		case "DTASOF":
+			s.ledger.asOf = value
+		}
+	case "CURRENCY":
+		// the amount is in this currency instead of the one of the statement
+		if tag == "CURSYM" && s.trn != nil {
+			s.trn.currency = value
+		}
+	case "STMTTRN":
+		fields := map[string]*string{
+			"TRNTYPE": &s.trn.trnType, "DTPOSTED": &s.trn.posted, "DTUSER": &s.trn.user, "TRNAMT": &s.trn.amount,
+			"FITID": &s.trn.fitID, "CHECKNUM": &s.trn.checkNum, "REFNUM": &s.trn.refNum, "NAME": &s.trn.name, "MEMO": &s.trn.memo,
+		}
+		if field, ok := fields[tag]; ok {
+			*field = value
+		}
+	}
+}
+
+func (s *ofxStatement) end(tag string) error {
+	// an aggregate closes the ones left open inside it
+	i := len(s.open) - 1
+	for i >= 0 && s.open[i] != tag {
+		i--
+	}
+	if i < 0 {
+		return nil
+	}
+	s.open = s.open[:i]
+
+	switch tag {
+	case "STMTTRN":
+		trn := s.trn
+		s.trn = nil
+		s.trns++
+		record, err := s.transaction(trn)
+		if err != nil {
+			return s.emit(nil, fmt.Errorf("transaction %d of account %s: %w", s.trns, s.accountNumber, err))
+		}
+		return s.emit(record, nil)
+	case "LEDGERBAL":
+		ledger := s.ledger
+		s.ledger = ofxBalance{}
+		date, err := ofxTime(ledger.asOf)
+		if err != nil {
+			return s.emit(nil, fmt.Errorf("ledger balance of account %s: %w", s.accountNumber, err))
+		}
+		amount, err := ofxAmount(ledger.amount, s.statementCurrency())
+		if err != nil {
+			return s.emit(nil, fmt.Errorf("ledger balance of account %s: %w", s.accountNumber, err))
+		}
+		return s.emit(statementBalance(domain.BalanceClosing, s.statementBankCode(), s.accountNumber, date, amount), nil)
+	case "STMTRS", "CCSTMTRS":
+		s.bankCode, s.accountNumber, s.currency, s.trns = "", "", "", 0
+	}
+	return nil
+}
+
+func (s *ofxStatement) transaction(trn *ofxTransaction) (domain.BankStatement, error) {
+	date, err := ofxTime(firstNonEmpty(trn.posted, trn.user))
+	if err != nil {
+		return domain.BankStatement{}, err
+	}
+	amount, err := ofxAmount(trn.amount, firstNonEmpty(trn.currency, s.statementCurrency()))
+	if err != nil {
+		return domain.BankStatement{}, err
+	}
+	bankRef := firstNonEmpty(trn.refNum, trn.checkNum)
+	uniqueID := firstNonEmpty(trn.fitID, bankRef)
+	if uniqueID == "" {
+		return domain.BankStatement{}, errors.New("no FITID")
+	}
+	description := trn.name
+	if trn.memo != "" && trn.memo != trn.name {
+		description = strings.TrimSpace(description + " " + trn.memo)
+	}
+	return domain.BankStatement{
+		UniqueID:      uniqueID,
+		Amount:        amount,
+		StatementTime: date,
+		BankCode:      s.statementBankCode(),
+		AccountNumber: s.accountNumber,
+		Description:   description,
+		TypeCode:      trn.trnType,
+		BankReference: bankRef,
+	}, nil
+}
+
+func (s *ofxStatement) statementBankCode() string {
+	return firstNonEmpty(s.bankCode, s.org)
+}
+
+func (s *ofxStatement) statementCurrency() string {
+	return firstNonEmpty(s.currency, money.DefaultCurrency)
+}
+
+// ofxAmount reads a signed amount, some banks write it with a comma as decimal mark
+func ofxAmount(text, currency string) (money.Money, error) {
+	currency, err := money.ParseCurrency(currency)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("parse currency error: %w", err)
+	}
+	if !strings.Contains(text, ".") {
+		text = strings.Replace(text, ",", ".", 1)
+	}
+	amount, err := money.Parse(text, currency)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("parse amount error: %w", err)
+	}
+	return amount, nil
+}
+
+// ofxTime reads YYYYMMDD[HHMM[SS[.XXX]]] with an optional [offset:TZ] suffix, UTC when there is none
+func ofxTime(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	loc := time.UTC
+	if i := strings.Index(text, "["); i >= 0 {
+		offset, _, _ := strings.Cut(strings.TrimSuffix(text[i+1:], "]"), ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parse date error: invalid offset %q", text[i:])
+		}
+		loc = time.FixedZone("", int(hours*3600))
+		text = text[:i]
+	}
+	text, _, _ = strings.Cut(text, ".")
+	layouts := map[int]string{8: "20060102", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[len(text)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("parse date error: invalid date %q", text)
+	}
+	t, err := time.ParseInLocation(layout, text, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse date error: %w", err)
+	}
+	return t, nil
+}
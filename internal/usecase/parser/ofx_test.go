@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestOFXParserSGML(t *testing.T) {
+	file := `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><FI><ORG>CHASE<FID>10898</FI></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>1<STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><BANKID>021000021<ACCTID>123456789<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST><DTSTART>20250101<DTEND>20250131
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20250130120000.000[-5:EST]<TRNAMT>-42.10<FITID>2025013001<NAME>COFFEE &amp; CO<MEMO>POS PURCHASE</STMTTRN>
+<STMTTRN><TRNTYPE>CHECK<DTPOSTED>20250131<TRNAMT>-1,000.00<FITID>2025013102<CHECKNUM>1001</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20250131<TRNAMT>250.00<FITID>2025013103<NAME>REFUND
+<CURRENCY><CURRATE>0.9<CURSYM>EUR</CURRENCY></STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1207.90<DTASOF>20250131</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+	records, errs := parseFile(t, &OFXParser{}, file)
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "transaction 2 of account 123456789: parse amount error")
+
+	date := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []interface{}{
+		domain.BankStatement{
+			UniqueID:      "2025013001",
+			Amount:        money.MustParse("-42.10", "USD"),
+			StatementTime: time.Date(2025, 1, 30, 12, 0, 0, 0, time.FixedZone("", -5*60*60)),
+			BankCode:      "021000021",
+			AccountNumber: "123456789",
+			Description:   "COFFEE & CO POS PURCHASE",
+			TypeCode:      "DEBIT",
+		},
+		domain.BankStatement{
+			UniqueID:      "2025013103",
+			Amount:        money.MustParse("250", "EUR"),
+			StatementTime: date,
+			BankCode:      "021000021",
+			AccountNumber: "123456789",
+			Description:   "REFUND",
+			TypeCode:      "CREDIT",
+		},
+		domain.StatementBalance{
+			BankCode:      "021000021",
+			AccountNumber: "123456789",
+			BalanceType:   domain.BalanceClosing,
+			BalanceDate:   date,
+			Amount:        money.MustParse("1207.90", "USD"),
+		},
+	}, records)
+}
+
+func TestOFXParserXMLCreditCard(t *testing.T) {
+	file := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <SIGNONMSGSRSV1><SONRS><FI><ORG>AMEX</ORG><FID>3101</FID></FI></SONRS></SIGNONMSGSRSV1>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <CURDEF>USD</CURDEF>
+    <CCACCTFROM><ACCTID>371449635398431</ACCTID></CCACCTFROM>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>PAYMENT</TRNTYPE><DTPOSTED>20250115</DTPOSTED><TRNAMT>-75.00</TRNAMT>
+        <FITID>AMX-1</FITID><REFNUM>320250150001</REFNUM><NAME>AIRLINE</NAME>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>`
+
+	records, errs := parseFile(t, &OFXParser{}, file)
+	assert.Empty(t, errs)
+	assert.Equal(t, []interface{}{
+		domain.BankStatement{
+			UniqueID:      "AMX-1",
+			Amount:        money.MustParse("-75", "USD"),
+			StatementTime: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+			BankCode:      "AMEX",
+			AccountNumber: "371449635398431",
+			Description:   "AIRLINE",
+			TypeCode:      "PAYMENT",
+			BankReference: "320250150001",
+		},
+	}, records)
+}
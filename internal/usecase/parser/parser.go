@@ -1,5 +1,16 @@
 package parser
 
+import (
+	"errors"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"io"
+	"strings"
+)
+
+// ErrInvalidStatementFile is returned by a FileParser for a file that breaks the structure of its format,
+// such as a trailer whose control total does not add up
+var ErrInvalidStatementFile = errors.New("invalid statement file")
+
 //go:generate mockgen -source=parser.go -destination=_mock/parser.go
 type CSVParser interface {
 	ParseLine(record []string) (interface{}, error)
@@ -14,3 +25,28 @@ func RegisterParser(parserID string, parser CSVParser) {
 func GetParser(parserID string) CSVParser {
 	return ParserRegistry[parserID]
 }
+
+// currencyAt returns the ISO 4217 code in an optional column, files without it are in money.DefaultCurrency
+func currencyAt(fields []string, idx int) (string, error) {
+	if idx >= len(fields) || strings.TrimSpace(fields[idx]) == "" {
+		return money.DefaultCurrency, nil
+	}
+	return money.ParseCurrency(fields[idx])
+}
+
+// FileParser reads a whole file, for formats that do not hold one record per CSV line.
+// ParseFile hands every record to emit as ParseLine would return it, or the error of an entry it could not parse
+// so the caller can skip it. Reading the file failing, or emit returning an error, stops parsing.
+type FileParser interface {
+	ParseFile(r io.Reader, emit func(record interface{}, err error) error) error
+}
+
+var FileParserRegistry = map[string]FileParser{}
+
+func RegisterFileParser(parserID string, parser FileParser) {
+	FileParserRegistry[parserID] = parser
+}
+
+func GetFileParser(parserID string) FileParser {
+	return FileParserRegistry[parserID]
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	enum_parser "github.com/ardianferdianto/reconciliation-service/internal/domain/enum/parser"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrUnknownProfile is returned for a parser profile that is neither configured nor stored
+	ErrUnknownProfile = errors.New("unknown parser profile")
+	// ErrInvalidProfile is returned for a profile that does not declare a usable layout
+	ErrInvalidProfile = errors.New("invalid parser profile")
+)
+
+// Profiles finds the parser profiles set in the configuration and the ones stored in the parser_profiles table,
+// a configured profile hides a stored one of the same name
+type Profiles struct {
+	configured map[string]domain.ParserProfile
+	repo       repository.ParserProfileRepository
+}
+
+// NewProfiles checks the configured profiles, repo may be nil to only use those
+func NewProfiles(configured []domain.ParserProfile, repo repository.ParserProfileRepository) (*Profiles, error) {
+	p := &Profiles{configured: make(map[string]domain.ParserProfile, len(configured)), repo: repo}
+	for _, profile := range configured {
+		if err := CheckProfile(profile); err != nil {
+			return nil, err
+		}
+		if _, ok := p.configured[profile.Name]; ok {
+			return nil, fmt.Errorf("%w %s: configured twice", ErrInvalidProfile, profile.Name)
+		}
+		p.configured[profile.Name] = profile
+	}
+	return p, nil
+}
+
+// CheckProfile validates a profile on its own, the columns a file type needs are only checked by NewMappingParser
+func CheckProfile(profile domain.ParserProfile) error {
+	if strings.TrimSpace(profile.Name) == "" {
+		return fmt.Errorf("%w: no name", ErrInvalidProfile)
+	}
+	// a profile may be meant for either file type, it has to be usable for one of them
+	_, sysErr := NewMappingParser(profile, enum_parser.SYSTEM_TRX)
+	if sysErr == nil {
+		return nil
+	}
+	_, bankErr := NewMappingParser(profile, enum_parser.BANK_STATEMENT)
+	if bankErr == nil {
+		return nil
+	}
+	return bankErr
+}
+
+// Get returns the profile of the name
+func (p *Profiles) Get(ctx context.Context, name string) (domain.ParserProfile, error) {
+	if profile, ok := p.configured[name]; ok {
+		return profile, nil
+	}
+	if p.repo == nil {
+		return domain.ParserProfile{}, fmt.Errorf("%w %s", ErrUnknownProfile, name)
+	}
+	profile, err := p.repo.GetParserProfile(ctx, name)
+	if errors.Is(err, repository.ErrParserProfileNotFound) {
+		return domain.ParserProfile{}, fmt.Errorf("%w %s", ErrUnknownProfile, name)
+	}
+	if err != nil {
+		return domain.ParserProfile{}, fmt.Errorf("failed to get parser profile: %w", err)
+	}
+	return profile, nil
+}
+
+// List returns every profile by name, configured ones first
+func (p *Profiles) List(ctx context.Context) ([]domain.ParserProfile, error) {
+	profiles := make([]domain.ParserProfile, 0, len(p.configured))
+	for _, profile := range p.configured {
+		profiles = append(profiles, profile)
+	}
+	slices.SortFunc(profiles, func(a, b domain.ParserProfile) int { return strings.Compare(a.Name, b.Name) })
+	if p.repo == nil {
+		return profiles, nil
+	}
+	stored, err := p.repo.ListParserProfiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parser profiles: %w", err)
+	}
+	for _, profile := range stored {
+		if _, ok := p.configured[profile.Name]; !ok {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+// Save stores a profile in the parser_profiles table, configured profiles cannot be replaced
+func (p *Profiles) Save(ctx context.Context, profile domain.ParserProfile) error {
+	if err := CheckProfile(profile); err != nil {
+		return err
+	}
+	if _, ok := p.configured[profile.Name]; ok {
+		return fmt.Errorf("%w %s: set in the configuration", ErrInvalidProfile, profile.Name)
+	}
+	if p.repo == nil {
+		return fmt.Errorf("%w %s: profiles are not stored", ErrInvalidProfile, profile.Name)
+	}
+	if err := p.repo.SaveParserProfile(ctx, profile); err != nil {
+		return fmt.Errorf("failed to save parser profile: %w", err)
+	}
+	return nil
+}
+
+// Parser returns a parser for a file of the type laid out as the named profile declares
+func (p *Profiles) Parser(ctx context.Context, name, fileType string) (*MappingParser, error) {
+	profile, err := p.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewMappingParser(profile, fileType)
+}
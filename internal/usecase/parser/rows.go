@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"strings"
+)
+
+// RowReader returns the rows of a tabular file one by one and io.EOF after the last one, *csv.Reader is one.
+// The rows are handed to a CSVParser whatever the format of the file.
+type RowReader interface {
+	Read() ([]string, error)
+}
+
+// XLSXReader reads the rows of a sheet of an XLSX workbook one by one, cells read as Excel displays them.
+// The workbook itself is held in memory, excelize needs random access to the archive, only the rows of the
+// sheet are decoded as they are read. Blank rows are left out as a CSV reader leaves out blank lines.
+type XLSXReader struct {
+	file *excelize.File
+	rows *excelize.Rows
+}
+
+// NewXLSXReader opens the named sheet of the workbook, the first one when the name is empty
+func NewXLSXReader(r io.Reader, sheet string) (*XLSXReader, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: open workbook error: %v", ErrInvalidStatementFile, err)
+	}
+	if sheet == "" {
+		if sheets := file.GetSheetList(); len(sheets) > 0 {
+			sheet = sheets[0]
+		}
+	}
+	if idx, err := file.GetSheetIndex(sheet); err != nil || idx < 0 {
+		_ = file.Close()
+		return nil, fmt.Errorf("%w: no sheet %q in the workbook", ErrInvalidStatementFile, sheet)
+	}
+	rows, err := file.Rows(sheet)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("read sheet error: %w", err)
+	}
+	return &XLSXReader{file: file, rows: rows}, nil
+}
+
+func (x *XLSXReader) Read() ([]string, error) {
+	for x.rows.Next() {
+		row, err := x.rows.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("read row error: %w", err)
+		}
+		if !blankRow(row) {
+			return row, nil
+		}
+	}
+	if err := x.rows.Error(); err != nil {
+		return nil, fmt.Errorf("read row error: %w", err)
+	}
+	return nil, io.EOF
+}
+
+func (x *XLSXReader) Close() error {
+	return errors.Join(x.rows.Close(), x.file.Close())
+}
+
+func blankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// TrimRows leaves out the preamble rows above the header and the footer rows below the data, such as totals.
+// The footer rows are held back while reading, so the file is still read row by row.
+func TrimRows(rows RowReader, preamble, footer int) RowReader {
+	return &trimmedRows{rows: rows, preamble: preamble, footer: footer}
+}
+
+type trimmedRows struct {
+	rows     RowReader
+	preamble int
+	footer   int
+	held     [][]string
+}
+
+func (t *trimmedRows) Read() ([]string, error) {
+	for ; t.preamble > 0; t.preamble-- {
+		// a preamble row with another number of fields than the data is skipped all the same
+		if _, err := t.rows.Read(); err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, err
+		}
+	}
+	for len(t.held) <= t.footer {
+		row, err := t.rows.Read()
+		if err != nil {
+			// the rows still held at the end are the footer
+			return nil, err
+		}
+		t.held = append(t.held, row)
+	}
+	row := t.held[0]
+	t.held = t.held[1:]
+	return row, nil
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	enum_parser "github.com/ardianferdianto/reconciliation-service/internal/domain/enum/parser"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, rows RowReader) [][]string {
+	var all [][]string
+	for {
+		row, err := rows.Read()
+		if err == io.EOF {
+			return all
+		}
+		require.NoError(t, err)
+		all = append(all, row)
+	}
+}
+
+func workbook(t *testing.T, sheet string, rows [][]interface{}) []byte {
+	f := excelize.NewFile()
+	defer f.Close()
+	require.NoError(t, f.SetSheetName("Sheet1", "Summary"))
+	_, err := f.NewSheet(sheet)
+	require.NoError(t, err)
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, f.SetSheetRow(sheet, cell, &row))
+	}
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestXLSXReader(t *testing.T) {
+	file := workbook(t, "Mutasi", [][]interface{}{
+		{"PT BANK NEGARA INDONESIA"},
+		{"Account", "0012345678"},
+		{},
+		{"Posting Date", "Ref No", "Debit", "Credit"},
+		{"05/01/2025", "BNI-001", "1.250.000,50", ""},
+		{},
+		{"06/01/2025", "BNI-002", "", "99,00"},
+		{"Total", "", "1.250.000,50", "99,00"},
+	})
+
+	profile := domain.ParserProfile{
+		Name:           "bni_xlsx",
+		Sheet:          "Mutasi",
+		SkipRows:       2,
+		SkipFooterRows: 1,
+		Columns: map[string]domain.ColumnRef{
+			domain.ColumnReference:    {Name: "Ref No"},
+			domain.ColumnDebitAmount:  {Name: "Debit"},
+			domain.ColumnCreditAmount: {Name: "Credit"},
+			domain.ColumnDate:         {Name: "Posting Date"},
+		},
+		DateFormats:       []string{"02/01/2006"},
+		DecimalSeparator:  ",",
+		ThousandSeparator: ".",
+		SignConvention:    domain.SignDebitCredit,
+		DefaultBankCode:   "BNI",
+	}
+	p, err := NewMappingParser(profile, enum_parser.BANK_STATEMENT)
+	require.NoError(t, err)
+
+	workbookRows, err := NewXLSXReader(bytes.NewReader(file), p.Sheet())
+	require.NoError(t, err)
+	defer workbookRows.Close()
+	preamble, footer := p.SkipRows()
+	rows := readAll(t, TrimRows(workbookRows, preamble, footer))
+	require.Len(t, rows, 3)
+	require.NoError(t, p.BindHeader(rows[0]))
+
+	line, err := p.ParseLine(rows[2])
+	require.NoError(t, err)
+	assert.Equal(t, domain.BankStatement{
+		UniqueID:      "BNI-002",
+		Amount:        money.MustParse("99", "IDR"),
+		StatementTime: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC),
+		BankCode:      "BNI",
+	}, line)
+
+	_, err = NewXLSXReader(bytes.NewReader(file), "Januari")
+	assert.ErrorIs(t, err, ErrInvalidStatementFile)
+	assert.ErrorContains(t, err, `no sheet "Januari"`)
+}
+
+func TestTrimRows(t *testing.T) {
+	reader := csv.NewReader(strings.NewReader("Statement of account\nunique_id,amount\nA,1\nB,2\nTotal,3\nEnd of statement\n"))
+	reader.FieldsPerRecord = -1
+
+	assert.Equal(t, [][]string{{"unique_id", "amount"}, {"A", "1"}, {"B", "2"}}, readAll(t, TrimRows(reader, 1, 2)))
+	assert.Empty(t, readAll(t, TrimRows(csv.NewReader(strings.NewReader("a\nb\n")), 1, 3)))
+
+	_, err := TrimRows(csv.NewReader(strings.NewReader("Statement \"of\" account\nunique_id,amount\n")), 1, 0).Read()
+	var parseErr *csv.ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.ErrorIs(t, err, csv.ErrBareQuote)
+}
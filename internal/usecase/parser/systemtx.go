@@ -3,7 +3,7 @@ package parser
 import (
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
-	"strconv"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
 	"strings"
 	"time"
 )
@@ -14,7 +14,11 @@ func (p *SystemTxParser) ParseLine(fields []string) (interface{}, error) {
 	if len(fields) < 4 {
 		return nil, fmt.Errorf("not enough columns for system tx")
 	}
-	amt, err := strconv.ParseFloat(fields[1], 64)
+	currency, err := currencyAt(fields, 4)
+	if err != nil {
+		return nil, fmt.Errorf("parse currency error: %w", err)
+	}
+	amt, err := money.Parse(fields[1], currency)
 	if err != nil {
 		return nil, fmt.Errorf("parse amount error: %w", err)
 	}
@@ -0,0 +1,266 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: reconcile.go
+
+// Package mock_reconcile is a generated GoMock package.
+package mock_reconcile
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/ardianferdianto/reconciliation-service/internal/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIUseCase is a mock of IUseCase interface.
+type MockIUseCase struct {
+	ctrl     *gomock.Controller
+	recorder *MockIUseCaseMockRecorder
+}
+
+// MockIUseCaseMockRecorder is the mock recorder for MockIUseCase.
+type MockIUseCaseMockRecorder struct {
+	mock *MockIUseCase
+}
+
+// NewMockIUseCase creates a new mock instance.
+func NewMockIUseCase(ctrl *gomock.Controller) *MockIUseCase {
+	mock := &MockIUseCase{ctrl: ctrl}
+	mock.recorder = &MockIUseCaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIUseCase) EXPECT() *MockIUseCaseMockRecorder {
+	return m.recorder
+}
+
+// ApproveProposal mocks base method.
+func (m *MockIUseCase) ApproveProposal(ctx context.Context, jobID string, proposalID int, note string) (domain.ManualResolution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveProposal", ctx, jobID, proposalID, note)
+	ret0, _ := ret[0].(domain.ManualResolution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveProposal indicates an expected call of ApproveProposal.
+func (mr *MockIUseCaseMockRecorder) ApproveProposal(ctx, jobID, proposalID, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveProposal", reflect.TypeOf((*MockIUseCase)(nil).ApproveProposal), ctx, jobID, proposalID, note)
+}
+
+// GetAuditLog mocks base method.
+func (m *MockIUseCase) GetAuditLog(ctx context.Context, jobID string) ([]domain.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditLog", ctx, jobID)
+	ret0, _ := ret[0].([]domain.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditLog indicates an expected call of GetAuditLog.
+func (mr *MockIUseCaseMockRecorder) GetAuditLog(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditLog", reflect.TypeOf((*MockIUseCase)(nil).GetAuditLog), ctx, jobID)
+}
+
+// GetReconciliationSummary mocks base method.
+func (m *MockIUseCase) GetReconciliationSummary(ctx context.Context, jobID string) (domain.ReconciliationSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReconciliationSummary", ctx, jobID)
+	ret0, _ := ret[0].(domain.ReconciliationSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReconciliationSummary indicates an expected call of GetReconciliationSummary.
+func (mr *MockIUseCaseMockRecorder) GetReconciliationSummary(ctx, jobID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReconciliationSummary", reflect.TypeOf((*MockIUseCase)(nil).GetReconciliationSummary), ctx, jobID)
+}
+
+// GetWorkflowRuns mocks base method.
+func (m *MockIUseCase) GetWorkflowRuns(ctx context.Context, workflowID string) ([]domain.ReconciliationJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkflowRuns", ctx, workflowID)
+	ret0, _ := ret[0].([]domain.ReconciliationJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorkflowRuns indicates an expected call of GetWorkflowRuns.
+func (mr *MockIUseCaseMockRecorder) GetWorkflowRuns(ctx, workflowID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkflowRuns", reflect.TypeOf((*MockIUseCase)(nil).GetWorkflowRuns), ctx, workflowID)
+}
+
+// ListMatchGroups mocks base method.
+func (m *MockIUseCase) ListMatchGroups(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchGroup, *domain.ExceptionCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMatchGroups", ctx, jobID, q)
+	ret0, _ := ret[0].([]domain.MatchGroup)
+	ret1, _ := ret[1].(*domain.ExceptionCursor)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListMatchGroups indicates an expected call of ListMatchGroups.
+func (mr *MockIUseCaseMockRecorder) ListMatchGroups(ctx, jobID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMatchGroups", reflect.TypeOf((*MockIUseCase)(nil).ListMatchGroups), ctx, jobID, q)
+}
+
+// ListMatchedPairs mocks base method.
+func (m *MockIUseCase) ListMatchedPairs(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchedPair, *domain.ExceptionCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMatchedPairs", ctx, jobID, q)
+	ret0, _ := ret[0].([]domain.MatchedPair)
+	ret1, _ := ret[1].(*domain.ExceptionCursor)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListMatchedPairs indicates an expected call of ListMatchedPairs.
+func (mr *MockIUseCaseMockRecorder) ListMatchedPairs(ctx, jobID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMatchedPairs", reflect.TypeOf((*MockIUseCase)(nil).ListMatchedPairs), ctx, jobID, q)
+}
+
+// ListProposals mocks base method.
+func (m *MockIUseCase) ListProposals(ctx context.Context, jobID, status string) ([]domain.Proposal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListProposals", ctx, jobID, status)
+	ret0, _ := ret[0].([]domain.Proposal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListProposals indicates an expected call of ListProposals.
+func (mr *MockIUseCaseMockRecorder) ListProposals(ctx, jobID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListProposals", reflect.TypeOf((*MockIUseCase)(nil).ListProposals), ctx, jobID, status)
+}
+
+// ListUnmatchedBankTx mocks base method.
+func (m *MockIUseCase) ListUnmatchedBankTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedBankTx, *domain.ExceptionCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnmatchedBankTx", ctx, jobID, q)
+	ret0, _ := ret[0].([]domain.UnmatchedBankTx)
+	ret1, _ := ret[1].(*domain.ExceptionCursor)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListUnmatchedBankTx indicates an expected call of ListUnmatchedBankTx.
+func (mr *MockIUseCaseMockRecorder) ListUnmatchedBankTx(ctx, jobID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnmatchedBankTx", reflect.TypeOf((*MockIUseCase)(nil).ListUnmatchedBankTx), ctx, jobID, q)
+}
+
+// ListUnmatchedSystemTx mocks base method.
+func (m *MockIUseCase) ListUnmatchedSystemTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedSystemTx, *domain.ExceptionCursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnmatchedSystemTx", ctx, jobID, q)
+	ret0, _ := ret[0].([]domain.UnmatchedSystemTx)
+	ret1, _ := ret[1].(*domain.ExceptionCursor)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListUnmatchedSystemTx indicates an expected call of ListUnmatchedSystemTx.
+func (mr *MockIUseCaseMockRecorder) ListUnmatchedSystemTx(ctx, jobID, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnmatchedSystemTx", reflect.TypeOf((*MockIUseCase)(nil).ListUnmatchedSystemTx), ctx, jobID, q)
+}
+
+// ManualMatch mocks base method.
+func (m *MockIUseCase) ManualMatch(ctx context.Context, jobID string, systemItemID int, bankItemIDs []int, reason string) (domain.ManualResolution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ManualMatch", ctx, jobID, systemItemID, bankItemIDs, reason)
+	ret0, _ := ret[0].(domain.ManualResolution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ManualMatch indicates an expected call of ManualMatch.
+func (mr *MockIUseCaseMockRecorder) ManualMatch(ctx, jobID, systemItemID, bankItemIDs, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ManualMatch", reflect.TypeOf((*MockIUseCase)(nil).ManualMatch), ctx, jobID, systemItemID, bankItemIDs, reason)
+}
+
+// ProcessReconciliation mocks base method.
+func (m *MockIUseCase) ProcessReconciliation(ctx context.Context, workflowID string, startDate, endDate time.Time, opts domain.MatchOptions) (domain.ReconciliationResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessReconciliation", ctx, workflowID, startDate, endDate, opts)
+	ret0, _ := ret[0].(domain.ReconciliationResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProcessReconciliation indicates an expected call of ProcessReconciliation.
+func (mr *MockIUseCaseMockRecorder) ProcessReconciliation(ctx, workflowID, startDate, endDate, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessReconciliation", reflect.TypeOf((*MockIUseCase)(nil).ProcessReconciliation), ctx, workflowID, startDate, endDate, opts)
+}
+
+// RejectProposal mocks base method.
+func (m *MockIUseCase) RejectProposal(ctx context.Context, jobID string, proposalID int, note string) (domain.Proposal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectProposal", ctx, jobID, proposalID, note)
+	ret0, _ := ret[0].(domain.Proposal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectProposal indicates an expected call of RejectProposal.
+func (mr *MockIUseCaseMockRecorder) RejectProposal(ctx, jobID, proposalID, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectProposal", reflect.TypeOf((*MockIUseCase)(nil).RejectProposal), ctx, jobID, proposalID, note)
+}
+
+// Unmatch mocks base method.
+func (m *MockIUseCase) Unmatch(ctx context.Context, jobID string, matchID int, reason string) (domain.ManualResolution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unmatch", ctx, jobID, matchID, reason)
+	ret0, _ := ret[0].(domain.ManualResolution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Unmatch indicates an expected call of Unmatch.
+func (mr *MockIUseCaseMockRecorder) Unmatch(ctx, jobID, matchID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unmatch", reflect.TypeOf((*MockIUseCase)(nil).Unmatch), ctx, jobID, matchID, reason)
+}
+
+// UnmatchGroup mocks base method.
+func (m *MockIUseCase) UnmatchGroup(ctx context.Context, jobID string, groupID int, reason string) (domain.ManualResolution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnmatchGroup", ctx, jobID, groupID, reason)
+	ret0, _ := ret[0].(domain.ManualResolution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnmatchGroup indicates an expected call of UnmatchGroup.
+func (mr *MockIUseCaseMockRecorder) UnmatchGroup(ctx, jobID, groupID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmatchGroup", reflect.TypeOf((*MockIUseCase)(nil).UnmatchGroup), ctx, jobID, groupID, reason)
+}
+
+// WriteOff mocks base method.
+func (m *MockIUseCase) WriteOff(ctx context.Context, jobID, itemType string, itemID int, reasonCode, note string) (domain.ManualResolution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteOff", ctx, jobID, itemType, itemID, reasonCode, note)
+	ret0, _ := ret[0].(domain.ManualResolution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteOff indicates an expected call of WriteOff.
+func (mr *MockIUseCaseMockRecorder) WriteOff(ctx, jobID, itemType, itemID, reasonCode, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteOff", reflect.TypeOf((*MockIUseCase)(nil).WriteOff), ctx, jobID, itemType, itemID, reasonCode, note)
+}
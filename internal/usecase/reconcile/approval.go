@@ -0,0 +1,109 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contextprop"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"slices"
+	"strings"
+)
+
+// ListProposals returns the proposals of a job, oldest first, only those in the given status when it is set
+func (s *useCase) ListProposals(ctx context.Context, jobID, status string) ([]domain.Proposal, error) {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	if status != "" && !slices.Contains([]string{domain.ProposalPending, domain.ProposalApproved, domain.ProposalRejected}, status) {
+		return nil, fmt.Errorf("%w: status must be %s, %s or %s", ErrInvalidResolution, domain.ProposalPending, domain.ProposalApproved, domain.ProposalRejected)
+	}
+	proposals, err := s.recRepo.ListProposals(ctx, jobID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proposals: %w", err)
+	}
+	return proposals, nil
+}
+
+// ApproveProposal applies a pending manual match or write-off of a job on behalf of the client that proposed it.
+// The items are checked again, so a proposal whose exceptions were resolved in the meantime cannot be approved and has to be rejected.
+func (s *useCase) ApproveProposal(ctx context.Context, jobID string, proposalID int, note string) (domain.ManualResolution, error) {
+	decision, proposal, err := s.decision(ctx, jobID, proposalID, note)
+	if err != nil {
+		return domain.ManualResolution{}, err
+	}
+
+	switch proposal.Action {
+	case domain.AuditManualMatch:
+		match, err := s.prepareManualMatch(ctx, jobID, proposal.SystemExceptionID, proposal.BankExceptionIDs)
+		if err != nil {
+			return domain.ManualResolution{}, err
+		}
+		match.Actor, match.Reason, match.Approval = proposal.ProposedBy, proposal.Reason, &decision
+		return s.applyManualMatch(ctx, match)
+	case domain.AuditWriteOff:
+		writeOff, err := s.prepareWriteOff(ctx, jobID, proposal.ItemType, proposal.ExceptionID, proposal.ReasonCode, proposal.Reason)
+		if err != nil {
+			return domain.ManualResolution{}, err
+		}
+		writeOff.WrittenOffBy, writeOff.Approval = proposal.ProposedBy, &decision
+		return s.applyWriteOff(ctx, writeOff)
+	default:
+		return domain.ManualResolution{}, fmt.Errorf("%w: unknown proposal action %s", ErrInvalidResolution, proposal.Action)
+	}
+}
+
+// RejectProposal closes a pending proposal of a job without applying it, a reason is required
+func (s *useCase) RejectProposal(ctx context.Context, jobID string, proposalID int, note string) (domain.Proposal, error) {
+	decision, _, err := s.decision(ctx, jobID, proposalID, note)
+	if err != nil {
+		return domain.Proposal{}, err
+	}
+	if decision.Note == "" {
+		return domain.Proposal{}, fmt.Errorf("%w: a reason is required", ErrInvalidResolution)
+	}
+	proposal, err := s.recRepo.RejectProposal(ctx, decision)
+	if err != nil {
+		return domain.Proposal{}, fmt.Errorf("failed to reject proposal: %w", err)
+	}
+	return proposal, nil
+}
+
+// decision returns the decision of the caller on a pending proposal of a job, the caller cannot be the client that proposed it
+func (s *useCase) decision(ctx context.Context, jobID string, proposalID int, note string) (domain.ProposalDecision, domain.Proposal, error) {
+	actor := contextprop.GetValue(ctx, contextprop.ClientIDKey)
+	if actor == "" {
+		return domain.ProposalDecision{}, domain.Proposal{}, ErrNoActor
+	}
+	proposal, err := s.recRepo.GetProposal(ctx, jobID, proposalID)
+	if err != nil {
+		return domain.ProposalDecision{}, domain.Proposal{}, fmt.Errorf("failed to get proposal: %w", err)
+	}
+	if proposal.Status != domain.ProposalPending {
+		return domain.ProposalDecision{}, domain.Proposal{}, repository.ErrProposalNotPending
+	}
+	if proposal.ProposedBy == actor {
+		return domain.ProposalDecision{}, domain.Proposal{}, ErrSelfApproval
+	}
+	return domain.ProposalDecision{
+		ProposalID: proposalID,
+		JobID:      jobID,
+		DecidedBy:  actor,
+		Note:       strings.TrimSpace(note),
+	}, proposal, nil
+}
+
+// needsApproval tells whether a manual change of the given amount has to wait for a second client
+func (s *useCase) needsApproval(amount money.Money) bool {
+	if s.approval.Threshold <= 0 {
+		return false
+	}
+	return amount.Abs().Cmp(money.FromFloat(s.approval.Threshold, amount.Currency)) > 0
+}
+
+func (s *useCase) propose(ctx context.Context, proposal domain.Proposal) (domain.ManualResolution, error) {
+	proposal, err := s.recRepo.CreateProposal(ctx, proposal)
+	if err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("failed to create proposal: %w", err)
+	}
+	return domain.ManualResolution{Proposal: &proposal}, nil
+}
@@ -0,0 +1,409 @@
+package reconcile
+
+import (
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"log/slog"
+	"sort"
+)
+
+// maxOptimalComponentSize bounds the number of transactions solved together by the optimal assignment,
+// larger components are solved in slices of this size to keep the O(n^3) solver in check
+const maxOptimalComponentSize = 400
+
+// pairing links a system transaction index to a bank statement index
+type pairing struct {
+	tx   int
+	stmt int
+}
+
+// passMatcher keeps track of which records are already taken while passes run
+type passMatcher struct {
+	ruleSets   *RuleSets
+	systemTx   []domain.Transaction
+	bankStmts  []domain.BankStatement
+	opts       domain.MatchOptions
+	bankByDate map[string][]int // Statement indexes keyed by statement date for coarse candidate lookup
+	usedTx     []bool
+	usedStmt   []bool
+	// maxComponentSize is the number of transactions solved together by the optimal assignment
+	maxComponentSize int
+}
+
+func newPassMatcher(ruleSets *RuleSets, systemTx []domain.Transaction, bankStmts []domain.BankStatement, opts domain.MatchOptions) *passMatcher {
+	m := &passMatcher{
+		ruleSets:   ruleSets,
+		systemTx:   systemTx,
+		bankStmts:  bankStmts,
+		opts:       opts,
+		bankByDate: make(map[string][]int),
+		usedTx:     make([]bool, len(systemTx)),
+		usedStmt:   make([]bool, len(bankStmts)),
+
+		maxComponentSize: maxOptimalComponentSize,
+	}
+	for i, stmt := range bankStmts {
+		key := dateKey(stmt.StatementTime)
+		m.bankByDate[key] = append(m.bankByDate[key], i)
+	}
+	return m
+}
+
+// candidates returns the free statements the n-th pass accepts for the i-th transaction, in statement order
+func (m *passMatcher) candidates(i, passIdx int) []int {
+	tx := m.systemTx[i]
+	var lookup []int
+	if windowDays, bounded := m.ruleSets.passWindow(passIdx, m.opts); bounded {
+		from, to := businessDayWindow(tx.TransactionTime, windowDays)
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			lookup = append(lookup, m.bankByDate[dateKey(day)]...)
+		}
+	} else {
+		for idx := range m.bankStmts {
+			lookup = append(lookup, idx)
+		}
+	}
+
+	var eligible []int
+	for _, idx := range lookup {
+		if m.usedStmt[idx] {
+			continue
+		}
+		set := m.ruleSets.forBank(m.bankStmts[idx].BankCode)
+		if passIdx < len(set.passes) && set.passes[passIdx].matches(tx, m.bankStmts[idx], m.opts) {
+			eligible = append(eligible, idx)
+		}
+	}
+	sort.Ints(eligible)
+	return eligible
+}
+
+// greedyPairs gives each transaction, in order, the closest and then smallest-difference candidate still free
+func (m *passMatcher) greedyPairs(passIdx int) []pairing {
+	var pairs []pairing
+	for i := range m.systemTx {
+		if m.usedTx[i] {
+			continue
+		}
+		if best := m.bestCandidate(i, m.candidates(i, passIdx)); best != -1 {
+			m.take(i, best)
+			pairs = append(pairs, pairing{tx: i, stmt: best})
+		}
+	}
+	return pairs
+}
+
+func (m *passMatcher) bestCandidate(i int, candidates []int) int {
+	tx := m.systemTx[i]
+	expectedAmount := expectedBankAmount(tx)
+	best, bestDistance := -1, 0
+	var bestDiscrepancy money.Money
+	for _, idx := range candidates {
+		if m.usedStmt[idx] {
+			continue
+		}
+		stmt := m.bankStmts[idx]
+		discrepancy := calculateDiscrepancy(expectedAmount, stmt.Amount)
+		distance := dayDistance(tx.TransactionTime, stmt.StatementTime)
+		// Prefer the closest posting date, then the smallest difference
+		if best == -1 || distance < bestDistance || (distance == bestDistance && discrepancy.Cmp(bestDiscrepancy) < 0) {
+			best, bestDiscrepancy, bestDistance = idx, discrepancy, distance
+		}
+	}
+	return best
+}
+
+func (m *passMatcher) take(i, idx int) {
+	m.usedTx[i] = true
+	m.usedStmt[idx] = true
+}
+
+// optimalPairs solves the pass as a weighted bipartite assignment. Transactions and statements linked by
+// eligible pairs form independent components, each solved so that it maximises the number of matches,
+// then the total pair score, then minimises the total discrepancy and date distance.
+// A component too large to solve at once is solved in slices of its transactions in date order,
+// each slice optimally against the statements the slices before it left free.
+func (m *passMatcher) optimalPairs(passIdx int) []pairing {
+	edges := make(map[int][]int)
+	var txOrder []int
+	for i := range m.systemTx {
+		if m.usedTx[i] {
+			continue
+		}
+		if candidates := m.candidates(i, passIdx); len(candidates) > 0 {
+			edges[i] = candidates
+			txOrder = append(txOrder, i)
+		}
+	}
+
+	var pairs []pairing
+	for _, c := range connectedComponents(txOrder, edges, len(m.systemTx)) {
+		if len(c.txs) > m.maxComponentSize {
+			slog.Warn(fmt.Sprintf("optimal assignment of pass %d: %d transactions sharing candidates solved in slices of %d",
+				passIdx+1, len(c.txs), m.maxComponentSize))
+		}
+		for start := 0; start < len(c.txs); start += m.maxComponentSize {
+			slice, sliceEdges := m.freeComponent(c.txs[start:min(start+m.maxComponentSize, len(c.txs))], edges)
+			for _, pair := range m.solveComponent(slice, sliceEdges) {
+				m.take(pair.tx, pair.stmt)
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(a, b int) bool { return pairs[a].tx < pairs[b].tx })
+	return pairs
+}
+
+type component struct {
+	txs   []int
+	stmts []int
+}
+
+// connectedComponents groups transactions sharing candidate statements, both sides in ascending index order
+func connectedComponents(txOrder []int, edges map[int][]int, txCount int) []component {
+	// Union-find over transactions followed by statements, statement j is node txCount+j
+	parent := make(map[int]int)
+	var find func(n int) int
+	find = func(n int) int {
+		if p, ok := parent[n]; ok && p != n {
+			root := find(p)
+			parent[n] = root
+			return root
+		}
+		parent[n] = n
+		return n
+	}
+	union := func(a, b int) {
+		ra, rb := find(a), find(b)
+		if ra < rb {
+			parent[rb] = ra
+		} else if rb < ra {
+			parent[ra] = rb
+		}
+	}
+	for _, i := range txOrder {
+		for _, idx := range edges[i] {
+			union(i, txCount+idx)
+		}
+	}
+
+	byRoot := make(map[int]*component)
+	var roots []int
+	seenStmt := make(map[int]bool)
+	for _, i := range txOrder {
+		root := find(i)
+		c, ok := byRoot[root]
+		if !ok {
+			c = &component{}
+			byRoot[root] = c
+			roots = append(roots, root)
+		}
+		c.txs = append(c.txs, i)
+		for _, idx := range edges[i] {
+			if !seenStmt[idx] {
+				seenStmt[idx] = true
+				c.stmts = append(c.stmts, idx)
+			}
+		}
+	}
+
+	components := make([]component, 0, len(roots))
+	for _, root := range roots {
+		c := byRoot[root]
+		sort.Ints(c.stmts)
+		components = append(components, *c)
+	}
+	return components
+}
+
+// freeComponent returns the transactions with the candidates that are still free, statements in ascending order
+func (m *passMatcher) freeComponent(txs []int, edges map[int][]int) (component, map[int][]int) {
+	c := component{txs: txs}
+	free := make(map[int][]int, len(txs))
+	seenStmt := make(map[int]bool)
+	for _, i := range txs {
+		for _, idx := range edges[i] {
+			if m.usedStmt[idx] {
+				continue
+			}
+			free[i] = append(free[i], idx)
+			if !seenStmt[idx] {
+				seenStmt[idx] = true
+				c.stmts = append(c.stmts, idx)
+			}
+		}
+	}
+	sort.Ints(c.stmts)
+	return c, free
+}
+
+func (m *passMatcher) solveComponent(c component, edges map[int][]int) []pairing {
+	column := make(map[int]int, len(c.stmts))
+	for j, idx := range c.stmts {
+		column[idx] = j
+	}
+
+	// Every transaction also gets its own "stay unmatched" column so the assignment is always complete
+	rows, cols := len(c.txs), len(c.stmts)+len(c.txs)
+	costs := make([][]assignmentCost, rows)
+	for r, i := range c.txs {
+		costs[r] = make([]assignmentCost, cols)
+		for j := range costs[r] {
+			costs[r][j] = forbiddenCost
+		}
+		costs[r][len(c.stmts)+r] = assignmentCost{}
+		for _, idx := range edges[i] {
+			costs[r][column[idx]] = m.pairCost(i, idx)
+		}
+	}
+
+	var pairs []pairing
+	for r, j := range solveAssignment(costs) {
+		if j < len(c.stmts) {
+			pairs = append(pairs, pairing{tx: c.txs[r], stmt: c.stmts[j]})
+		}
+	}
+	return pairs
+}
+
+// pairCost ranks a candidate pair, lower is better
+func (m *passMatcher) pairCost(i, idx int) assignmentCost {
+	tx, stmt := m.systemTx[i], m.bankStmts[idx]
+	score := 0
+	for _, rule := range []MatchRule{sameDateRule{}, exactAmountRule{}, referenceRule{}} {
+		if rule.Matches(tx, stmt, m.opts) {
+			score++
+		}
+	}
+	discrepancy := calculateDiscrepancy(expectedBankAmount(tx), stmt.Amount)
+	return assignmentCost{0, -1, int64(-score), discrepancy.Minor, int64(dayDistance(tx.TransactionTime, stmt.StatementTime))}
+}
+
+// assignmentCost is compared lexicographically: forbidden pair, -matched, -score, discrepancy in minor units, days apart.
+// Component-wise addition keeps it an ordered group, which is all the Hungarian method needs.
+type assignmentCost [5]int64
+
+var forbiddenCost = assignmentCost{1}
+
+func (a assignmentCost) add(b assignmentCost) assignmentCost {
+	for k := range a {
+		a[k] += b[k]
+	}
+	return a
+}
+
+func (a assignmentCost) sub(b assignmentCost) assignmentCost {
+	for k := range a {
+		a[k] -= b[k]
+	}
+	return a
+}
+
+func (a assignmentCost) less(b assignmentCost) bool {
+	for k := range a {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return false
+}
+
+// solveAssignment runs the Hungarian method on a rows x cols matrix (rows <= cols) and returns the column
+// assigned to every row. Columns are scanned in order and only a strictly better cost replaces the current
+// choice, so equal-cost inputs always resolve the same way.
+func solveAssignment(costs [][]assignmentCost) []int {
+	rows := len(costs)
+	if rows == 0 {
+		return nil
+	}
+	cols := len(costs[0])
+	infinity := assignmentCost{1 << 40}
+
+	u := make([]assignmentCost, rows+1)
+	v := make([]assignmentCost, cols+1)
+	p := make([]int, cols+1) // p[j] is the 1-based row assigned to column j
+	way := make([]int, cols+1)
+
+	for i := 1; i <= rows; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]assignmentCost, cols+1)
+		used := make([]bool, cols+1)
+		for j := range minv {
+			minv[j] = infinity
+		}
+		for {
+			used[j0] = true
+			i0, delta, j1 := p[j0], infinity, 0
+			for j := 1; j <= cols; j++ {
+				if used[j] {
+					continue
+				}
+				cur := costs[i0-1][j-1].sub(u[i0]).sub(v[j])
+				if cur.less(minv[j]) {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j].less(delta) {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= cols; j++ {
+				if used[j] {
+					u[p[j]] = u[p[j]].add(delta)
+					v[j] = v[j].sub(delta)
+				} else {
+					minv[j] = minv[j].sub(delta)
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	assignment := make([]int, rows)
+	for j := 1; j <= cols; j++ {
+		if p[j] != 0 {
+			assignment[p[j]-1] = j - 1
+		}
+	}
+	return assignment
+}
+
+func sortedTransactions(txList []domain.Transaction) []domain.Transaction {
+	sorted := append([]domain.Transaction(nil), txList...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		if !sorted[a].TransactionTime.Equal(sorted[b].TransactionTime) {
+			return sorted[a].TransactionTime.Before(sorted[b].TransactionTime)
+		}
+		if sorted[a].TrxID != sorted[b].TrxID {
+			return sorted[a].TrxID < sorted[b].TrxID
+		}
+		return sorted[a].ID < sorted[b].ID
+	})
+	return sorted
+}
+
+func sortedStatements(stmts []domain.BankStatement) []domain.BankStatement {
+	sorted := append([]domain.BankStatement(nil), stmts...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		if !sorted[a].StatementTime.Equal(sorted[b].StatementTime) {
+			return sorted[a].StatementTime.Before(sorted[b].StatementTime)
+		}
+		if sorted[a].UniqueID != sorted[b].UniqueID {
+			return sorted[a].UniqueID < sorted[b].UniqueID
+		}
+		return sorted[a].ID < sorted[b].ID
+	})
+	return sorted
+}
@@ -0,0 +1,40 @@
+package reconcile
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+)
+
+// balanceRollforward rolls the opening balance of every account forward through the reconciled activity of the job
+// and compares the result with the closing balance the bank reported
+func balanceRollforward(activity []domain.AccountActivity) []domain.BalanceRollforward {
+	rollforward := make([]domain.BalanceRollforward, 0, len(activity))
+	for _, a := range activity {
+		r := domain.BalanceRollforward{
+			BankCode:               a.BankCode,
+			AccountNumber:          a.AccountNumber,
+			Currency:               a.Currency,
+			OpeningBalance:         a.OpeningBalance,
+			SystemMovements:        a.SystemMovements,
+			MatchDifferences:       a.MatchedBank.Sub(a.SystemMovements),
+			UnmatchedBankMovements: a.UnmatchedBank,
+			WrittenOff:             a.WrittenOff,
+			StatementMovements:     a.StatementMovements,
+			ReportedClosing:        a.ClosingBalance,
+			Status:                 domain.RollforwardMissingBalance,
+		}
+		if a.OpeningBalance != nil {
+			expected := a.OpeningBalance.Add(r.SystemMovements).Add(r.MatchDifferences).Add(r.UnmatchedBankMovements).Add(r.WrittenOff)
+			r.ExpectedClosing = &expected
+		}
+		if r.ExpectedClosing != nil && r.ReportedClosing != nil {
+			difference := r.ReportedClosing.Sub(*r.ExpectedClosing)
+			r.UnexplainedDifference = &difference
+			r.Status = domain.RollforwardUnexplained
+			if difference.IsZero() {
+				r.Status = domain.RollforwardBalanced
+			}
+		}
+		rollforward = append(rollforward, r)
+	}
+	return rollforward
+}
@@ -0,0 +1,125 @@
+package reconcile
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+)
+
+// ageingBuckets are the ranges of days outstanding open items are counted in, the last one is open ended
+var ageingBuckets = []struct {
+	label   string
+	maxDays int
+}{
+	{label: "0-30", maxDays: 30},
+	{label: "31-60", maxDays: 60},
+	{label: "61-90", maxDays: 90},
+	{label: "90+", maxDays: -1},
+}
+
+// withCarriedTransactions adds the outstanding transactions of earlier jobs to the ones loaded for this job.
+// It returns the IDs of every outstanding transaction, including those the job loaded itself.
+func withCarriedTransactions(txList, carried []domain.Transaction) ([]domain.Transaction, map[int]bool) {
+	loaded := make(map[int]bool, len(txList))
+	for _, tx := range txList {
+		loaded[tx.ID] = true
+	}
+	carriedIDs := make(map[int]bool, len(carried))
+	merged := txList
+	for _, tx := range carried {
+		carriedIDs[tx.ID] = true
+		if !loaded[tx.ID] {
+			merged = append(merged, tx)
+		}
+	}
+	return merged, carriedIDs
+}
+
+// withCarriedStatements is withCarriedTransactions for bank statements
+func withCarriedStatements(stmts, carried []domain.BankStatement) ([]domain.BankStatement, map[int]bool) {
+	loaded := make(map[int]bool, len(stmts))
+	for _, stmt := range stmts {
+		loaded[stmt.ID] = true
+	}
+	carriedIDs := make(map[int]bool, len(carried))
+	merged := stmts
+	for _, stmt := range carried {
+		carriedIDs[stmt.ID] = true
+		if !loaded[stmt.ID] {
+			merged = append(merged, stmt)
+		}
+	}
+	return merged, carriedIDs
+}
+
+// resolvedCarriedIDs returns the outstanding records that this job matched
+func resolvedCarriedIDs(matched []domain.MatchedRecord, groups []domain.MatchGroup, carriedTx, carriedStmts map[int]bool) ([]int, []int) {
+	var systemTxIDs, bankStatementIDs []int
+	addTx := func(id int) {
+		if carriedTx[id] {
+			systemTxIDs = append(systemTxIDs, id)
+		}
+	}
+	addStmt := func(id int) {
+		if carriedStmts[id] {
+			bankStatementIDs = append(bankStatementIDs, id)
+		}
+	}
+	for _, rec := range matched {
+		addTx(rec.SystemTxID)
+		addStmt(rec.BankStatementID)
+	}
+	for _, group := range groups {
+		for _, id := range group.SystemTxIDs {
+			addTx(id)
+		}
+		for _, id := range group.BankStatementIDs {
+			addStmt(id)
+		}
+	}
+	return systemTxIDs, bankStatementIDs
+}
+
+func newOpenItems(jobID string, txList []domain.Transaction, stmts []domain.BankStatement) []domain.OpenItem {
+	var items []domain.OpenItem
+	for _, tx := range txList {
+		id := tx.ID
+		items = append(items, domain.OpenItem{
+			ItemType:    domain.OpenItemSystemTx,
+			SystemTxID:  &id,
+			Reference:   tx.TrxID,
+			Amount:      tx.Amount,
+			ItemDate:    tx.TransactionTime,
+			Status:      domain.OpenItemOpen,
+			OriginJobID: jobID,
+		})
+	}
+	for _, stmt := range stmts {
+		id := stmt.ID
+		items = append(items, domain.OpenItem{
+			ItemType:        domain.OpenItemBankStatement,
+			BankStatementID: &id,
+			Reference:       stmt.UniqueID,
+			BankCode:        stmt.BankCode,
+			Amount:          stmt.Amount,
+			ItemDate:        stmt.StatementTime,
+			Status:          domain.OpenItemOpen,
+			OriginJobID:     jobID,
+		})
+	}
+	return items
+}
+
+func openItemsAgeing(items []domain.OpenItem) []domain.AgeingBucket {
+	buckets := make([]domain.AgeingBucket, len(ageingBuckets))
+	for i, bucket := range ageingBuckets {
+		buckets[i].Label = bucket.label
+	}
+	for _, item := range items {
+		for i, bucket := range ageingBuckets {
+			if bucket.maxDays < 0 || item.DaysOutstanding <= bucket.maxDays {
+				buckets[i].Count++
+				break
+			}
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,91 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultExceptionPageSize = 50
+	MaxExceptionPageSize     = 500
+)
+
+// ErrInvalidCursor is returned for a cursor that was not handed out for the same list and sort key
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (s *useCase) ListUnmatchedSystemTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedSystemTx, *domain.ExceptionCursor, error) {
+	items, next, err := s.recRepo.ListUnmatchedSystemTx(ctx, jobID, withPageDefaults(q))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list unmatched system transactions: %w", err)
+	}
+	return items, next, nil
+}
+
+func (s *useCase) ListUnmatchedBankTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedBankTx, *domain.ExceptionCursor, error) {
+	items, next, err := s.recRepo.ListUnmatchedBankTx(ctx, jobID, withPageDefaults(q))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list unmatched bank statements: %w", err)
+	}
+	return items, next, nil
+}
+
+func (s *useCase) ListMatchedPairs(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchedPair, *domain.ExceptionCursor, error) {
+	items, next, err := s.recRepo.ListMatchedPairs(ctx, jobID, withPageDefaults(q))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list matched pairs: %w", err)
+	}
+	return items, next, nil
+}
+
+func (s *useCase) ListMatchGroups(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchGroup, *domain.ExceptionCursor, error) {
+	items, next, err := s.recRepo.ListMatchGroups(ctx, jobID, withPageDefaults(q))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list match groups: %w", err)
+	}
+	return items, next, nil
+}
+
+// withPageDefaults sorts by date and bounds the page size when the caller did not
+func withPageDefaults(q domain.ExceptionQuery) domain.ExceptionQuery {
+	if q.SortBy == "" {
+		q.SortBy = domain.SortByDate
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultExceptionPageSize
+	}
+	if q.Limit > MaxExceptionPageSize {
+		q.Limit = MaxExceptionPageSize
+	}
+	return q
+}
+
+// EncodeExceptionCursor turns the position of the last row of a page into an opaque token
+func EncodeExceptionCursor(cursor domain.ExceptionCursor) string {
+	raw := cursor.SortBy + "|" + strconv.Itoa(cursor.ID) + "|" + cursor.Value
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeExceptionCursor reads a token made by EncodeExceptionCursor for a list sorted by sortBy
+func DecodeExceptionCursor(token, sortBy string) (domain.ExceptionCursor, error) {
+	if sortBy == "" {
+		sortBy = domain.SortByDate
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return domain.ExceptionCursor{}, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 || parts[0] != sortBy {
+		return domain.ExceptionCursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return domain.ExceptionCursor{}, ErrInvalidCursor
+	}
+	return domain.ExceptionCursor{SortBy: sortBy, Value: parts[2], ID: id}, nil
+}
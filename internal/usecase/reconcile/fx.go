@@ -0,0 +1,159 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+// maxFXRateAgeDays is how far back the latest rate of a currency pair is looked up,
+// so records dated on weekends and holidays use the last published rate
+const maxFXRateAgeDays = 7
+
+// conversion keeps the original amount of a record next to the amount matching works with
+type conversion struct {
+	original  money.Money
+	rate      money.Rate
+	converted money.Money
+}
+
+// fxTable holds daily rates per currency pair, oldest first
+type fxTable struct {
+	byPair map[string][]domain.FXRate
+}
+
+func newFXTable(rates []domain.FXRate) *fxTable {
+	t := &fxTable{byPair: make(map[string][]domain.FXRate)}
+	for _, rate := range rates {
+		key := pairKey(rate.BaseCurrency, rate.QuoteCurrency)
+		t.byPair[key] = append(t.byPair[key], rate)
+	}
+	return t
+}
+
+// rate returns the rate converting from into to on day, falling back to the inverse of the opposite pair
+func (t *fxTable) rate(from, to string, day time.Time) (money.Rate, bool) {
+	if rate, ok := t.latest(pairKey(from, to), day); ok {
+		return rate, true
+	}
+	if rate, ok := t.latest(pairKey(to, from), day); ok {
+		return rate.Inverse(), true
+	}
+	return money.Rate{}, false
+}
+
+func (t *fxTable) latest(key string, day time.Time) (money.Rate, bool) {
+	day = truncateDay(day)
+	oldest := day.AddDate(0, 0, -maxFXRateAgeDays)
+	rates := t.byPair[key]
+	for i := len(rates) - 1; i >= 0; i-- {
+		rateDay := time.Date(rates[i].RateDate.Year(), rates[i].RateDate.Month(), rates[i].RateDate.Day(), 0, 0, 0, 0, day.Location())
+		if rateDay.After(day) {
+			continue
+		}
+		if rateDay.Before(oldest) {
+			break
+		}
+		return rates[i].Rate, true
+	}
+	return money.Rate{}, false
+}
+
+func pairKey(base, quote string) string {
+	return base + "/" + quote
+}
+
+// currencyConverter brings every amount into the reconciliation currency before matching.
+// Without a rate table only records already in that currency can be matched.
+type currencyConverter struct {
+	currency string
+	rates    *fxTable
+}
+
+func (s *useCase) newCurrencyConverter(ctx context.Context, startDate, endDate time.Time, opts domain.MatchOptions) (*currencyConverter, error) {
+	c := &currencyConverter{currency: opts.ReconciliationCurrency()}
+	if !opts.ConvertCurrency {
+		return c, nil
+	}
+	rates, err := s.fxRepo.FindRatesByDateRange(ctx, truncateDay(startDate).AddDate(0, 0, -maxFXRateAgeDays), endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fx rates: %w", err)
+	}
+	c.rates = newFXTable(rates)
+	return c, nil
+}
+
+func (c *currencyConverter) convert(amount money.Money, day time.Time) (conversion, bool) {
+	if amount.Currency == c.currency {
+		return conversion{original: amount, rate: money.OneRate(), converted: amount}, true
+	}
+	if c.rates == nil {
+		return conversion{}, false
+	}
+	rate, ok := c.rates.rate(amount.Currency, c.currency, day)
+	if !ok {
+		return conversion{}, false
+	}
+	return conversion{original: amount, rate: rate, converted: amount.Convert(rate, c.currency)}, true
+}
+
+// transactions converts system transactions by ID, the ones that cannot be converted are returned untouched
+func (c *currencyConverter) transactions(txList []domain.Transaction) ([]domain.Transaction, map[int]conversion, []domain.Transaction) {
+	var converted, unconverted []domain.Transaction
+	conversions := make(map[int]conversion, len(txList))
+	for _, tx := range txList {
+		conv, ok := c.convert(tx.Amount, tx.TransactionTime)
+		if !ok {
+			unconverted = append(unconverted, tx)
+			continue
+		}
+		conversions[tx.ID] = conv
+		tx.Amount = conv.converted
+		converted = append(converted, tx)
+	}
+	return converted, conversions, unconverted
+}
+
+// statements converts bank statements by ID, the ones that cannot be converted are returned untouched
+func (c *currencyConverter) statements(stmts []domain.BankStatement) ([]domain.BankStatement, map[int]conversion, []domain.BankStatement) {
+	var converted, unconverted []domain.BankStatement
+	conversions := make(map[int]conversion, len(stmts))
+	for _, stmt := range stmts {
+		conv, ok := c.convert(stmt.Amount, stmt.StatementTime)
+		if !ok {
+			unconverted = append(unconverted, stmt)
+			continue
+		}
+		conversions[stmt.ID] = conv
+		stmt.Amount = conv.converted
+		converted = append(converted, stmt)
+	}
+	return converted, conversions, unconverted
+}
+
+func withConversions(rec domain.MatchedRecord, tx, stmt conversion) domain.MatchedRecord {
+	rec.SystemAmount, rec.SystemFXRate, rec.SystemConvertedAmount = tx.original, tx.rate, tx.converted
+	rec.BankAmount, rec.BankFXRate, rec.BankConvertedAmount = stmt.original, stmt.rate, stmt.converted
+	return rec
+}
+
+// originalTransactions puts the original amounts back on records that were not matched
+func originalTransactions(txList []domain.Transaction, conversions map[int]conversion) []domain.Transaction {
+	restored := make([]domain.Transaction, 0, len(txList))
+	for _, tx := range txList {
+		tx.Amount = conversions[tx.ID].original
+		restored = append(restored, tx)
+	}
+	return restored
+}
+
+func originalStatements(stmts []domain.BankStatement, conversions map[int]conversion) []domain.BankStatement {
+	restored := make([]domain.BankStatement, 0, len(stmts))
+	for _, stmt := range stmts {
+		stmt.Amount = conversions[stmt.ID].original
+		restored = append(restored, stmt)
+	}
+	return restored
+}
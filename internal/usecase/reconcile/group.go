@@ -0,0 +1,175 @@
+package reconcile
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"sort"
+)
+
+const (
+	defaultMaxGroupSize = 5
+	// maxGroupSearchSteps bounds the subset search per target so large same-day buckets
+	// end up unmatched instead of stalling the whole reconciliation
+	maxGroupSearchSteps = 100000
+)
+
+// matchGroupRecords looks for split and aggregate settlements among the records left over by the 1:1 pass.
+// Bank lines are first matched against subsets of same-day system transactions (MANY_TO_ONE),
+// then the remaining system transactions against subsets of same-day bank lines (ONE_TO_MANY).
+func matchGroupRecords(jobID string, systemTx []domain.Transaction, bankStmts []domain.BankStatement, opts domain.MatchOptions) ([]domain.MatchGroup, []domain.Transaction, []domain.BankStatement) {
+	maxSize := opts.MaxGroupSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxGroupSize
+	}
+
+	var groups []domain.MatchGroup
+	usedTx := make([]bool, len(systemTx))
+	usedStmt := make([]bool, len(bankStmts))
+
+	txByDate := make(map[string][]int)
+	for i, tx := range systemTx {
+		key := dateKey(tx.TransactionTime)
+		txByDate[key] = append(txByDate[key], i)
+	}
+	for i, stmt := range bankStmts {
+		var candidates []int
+		var amounts []money.Money
+		for _, idx := range txByDate[dateKey(stmt.StatementTime)] {
+			if amount := expectedBankAmount(systemTx[idx]); !usedTx[idx] && sameSign(amount, stmt.Amount) {
+				candidates = append(candidates, idx)
+				amounts = append(amounts, amount)
+			}
+		}
+		subset := findSubset(amounts, stmt.Amount, opts.AllowedDiscrepancy(stmt.Amount), maxSize)
+		if subset == nil {
+			continue
+		}
+
+		group := domain.MatchGroup{JobID: jobID, GroupType: domain.ManyToOne, BankStatementIDs: []int{stmt.ID}}
+		var sum money.Money
+		for _, pos := range subset {
+			idx := candidates[pos]
+			usedTx[idx] = true
+			sum = sum.Add(amounts[pos])
+			group.SystemTxIDs = append(group.SystemTxIDs, systemTx[idx].ID)
+		}
+		group.Discrepancy = calculateDiscrepancy(sum, stmt.Amount)
+		usedStmt[i] = true
+		groups = append(groups, group)
+	}
+
+	stmtByDate := make(map[string][]int)
+	for i, stmt := range bankStmts {
+		if !usedStmt[i] {
+			key := dateKey(stmt.StatementTime)
+			stmtByDate[key] = append(stmtByDate[key], i)
+		}
+	}
+	for i, tx := range systemTx {
+		if usedTx[i] {
+			continue
+		}
+		expectedAmount := expectedBankAmount(tx)
+		var candidates []int
+		var amounts []money.Money
+		for _, idx := range stmtByDate[dateKey(tx.TransactionTime)] {
+			if !usedStmt[idx] && sameSign(bankStmts[idx].Amount, expectedAmount) {
+				candidates = append(candidates, idx)
+				amounts = append(amounts, bankStmts[idx].Amount)
+			}
+		}
+		subset := findSubset(amounts, expectedAmount, opts.AllowedDiscrepancy(expectedAmount), maxSize)
+		if subset == nil {
+			continue
+		}
+
+		group := domain.MatchGroup{JobID: jobID, GroupType: domain.OneToMany, SystemTxIDs: []int{tx.ID}}
+		var sum money.Money
+		for _, pos := range subset {
+			idx := candidates[pos]
+			usedStmt[idx] = true
+			sum = sum.Add(amounts[pos])
+			group.BankStatementIDs = append(group.BankStatementIDs, bankStmts[idx].ID)
+		}
+		group.Discrepancy = calculateDiscrepancy(expectedAmount, sum)
+		usedTx[i] = true
+		groups = append(groups, group)
+	}
+
+	var leftoverTx []domain.Transaction
+	for i, tx := range systemTx {
+		if !usedTx[i] {
+			leftoverTx = append(leftoverTx, tx)
+		}
+	}
+	var leftoverStmts []domain.BankStatement
+	for i, stmt := range bankStmts {
+		if !usedStmt[i] {
+			leftoverStmts = append(leftoverStmts, stmt)
+		}
+	}
+	return groups, leftoverTx, leftoverStmts
+}
+
+// findSubset returns the positions of 2 to maxSize amounts whose sum is within allowed of target, or nil.
+// All amounts are expected to share the sign of target. Larger amounts are tried first which keeps groups small.
+func findSubset(amounts []money.Money, target, allowed money.Money, maxSize int) []int {
+	if len(amounts) < 2 {
+		return nil
+	}
+
+	// Only absolute minor units matter once all amounts share a sign
+	targetMinor, allowedMinor := target.Abs().Minor, allowed.Abs().Minor
+
+	order := make([]int, len(amounts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return amounts[order[a]].Abs().Minor > amounts[order[b]].Abs().Minor
+	})
+	minors := make([]int64, len(order))
+	remaining := make([]int64, len(order)+1) // remaining[i] = sum of minors[i:]
+	for i, pos := range order {
+		minors[i] = amounts[pos].Abs().Minor
+	}
+	for i := len(minors) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + minors[i]
+	}
+
+	steps := 0
+	var picked []int
+	var search func(start int, sum int64) bool
+	search = func(start int, sum int64) bool {
+		steps++
+		if steps > maxGroupSearchSteps {
+			return false
+		}
+		if len(picked) >= 2 && sum >= targetMinor-allowedMinor && sum <= targetMinor+allowedMinor {
+			return true
+		}
+		if len(picked) == maxSize || sum+remaining[start] < targetMinor-allowedMinor {
+			return false
+		}
+		for i := start; i < len(minors); i++ {
+			if sum+minors[i] > targetMinor+allowedMinor {
+				continue
+			}
+			picked = append(picked, order[i])
+			if search(i+1, sum+minors[i]) {
+				return true
+			}
+			picked = picked[:len(picked)-1]
+		}
+		return false
+	}
+	if !search(0, 0) {
+		return nil
+	}
+	sort.Ints(picked)
+	return picked
+}
+
+func sameSign(a, b money.Money) bool {
+	return a.IsNegative() == b.IsNegative()
+}
@@ -0,0 +1,235 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contextprop"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrNoActor is returned for a manual change without an authenticated client to record in the audit log
+	ErrNoActor = errors.New("manual changes need an authenticated client")
+	// ErrInvalidResolution is returned for a manual change that cannot be applied as requested
+	ErrInvalidResolution = errors.New("invalid manual resolution")
+	// ErrSelfApproval is returned when a client decides on a proposal it made itself
+	ErrSelfApproval = errors.New("a proposal has to be decided by another client")
+)
+
+// ManualMatch links an unmatched system transaction of a job to one or more of its unmatched bank lines.
+// Every item has to be in the currency of the job, the discrepancy is the difference between the expected and the total bank amount.
+// A match whose discrepancy is above the approval threshold is only proposed, it is applied once another client approves it.
+func (s *useCase) ManualMatch(ctx context.Context, jobID string, systemItemID int, bankItemIDs []int, reason string) (domain.ManualResolution, error) {
+	actor, reason, err := manualChange(ctx, reason)
+	if err != nil {
+		return domain.ManualResolution{}, err
+	}
+	match, err := s.prepareManualMatch(ctx, jobID, systemItemID, bankItemIDs)
+	if err != nil {
+		return domain.ManualResolution{}, err
+	}
+	if s.needsApproval(match.Discrepancy) {
+		return s.propose(ctx, domain.Proposal{
+			JobID:             jobID,
+			Action:            domain.AuditManualMatch,
+			SystemExceptionID: systemItemID,
+			BankExceptionIDs:  bankItemIDs,
+			Reason:            reason,
+			Amount:            match.Discrepancy,
+			ProposedBy:        actor,
+		})
+	}
+
+	match.Actor, match.Reason = actor, reason
+	return s.applyManualMatch(ctx, match)
+}
+
+func (s *useCase) prepareManualMatch(ctx context.Context, jobID string, systemItemID int, bankItemIDs []int) (domain.ManualMatch, error) {
+	if len(bankItemIDs) == 0 {
+		return domain.ManualMatch{}, fmt.Errorf("%w: at least one bank line is required", ErrInvalidResolution)
+	}
+	for i, id := range bankItemIDs {
+		if slices.Contains(bankItemIDs[:i], id) {
+			return domain.ManualMatch{}, fmt.Errorf("%w: bank line %d is listed twice", ErrInvalidResolution, id)
+		}
+	}
+
+	result, err := s.recRepo.GetReconciliationResult(ctx, jobID)
+	if err != nil {
+		return domain.ManualMatch{}, fmt.Errorf("failed to get reconciliation result: %w", err)
+	}
+	currency := result.TotalDiscrepancies.Currency
+
+	systemItems, bankItems, err := s.recRepo.GetUnmatchedItems(ctx, jobID, []int{systemItemID}, bankItemIDs)
+	if err != nil {
+		return domain.ManualMatch{}, fmt.Errorf("failed to get unmatched items: %w", err)
+	}
+	if len(systemItems) != 1 || len(bankItems) != len(bankItemIDs) {
+		return domain.ManualMatch{}, repository.ErrExceptionNotFound
+	}
+	systemItem := systemItems[0]
+	if systemItem.SystemTxID == 0 {
+		return domain.ManualMatch{}, fmt.Errorf("%w: system exception %d has no transaction to match", ErrInvalidResolution, systemItem.ID)
+	}
+	if systemItem.Amount.Currency != currency {
+		return domain.ManualMatch{}, fmt.Errorf("%w: system exception %d is in %s, not %s", ErrInvalidResolution, systemItem.ID, systemItem.Amount.Currency, currency)
+	}
+
+	bankTotal := money.New(0, currency)
+	for _, item := range bankItems {
+		if item.BankStatementID == 0 {
+			return domain.ManualMatch{}, fmt.Errorf("%w: bank exception %d has no statement to match", ErrInvalidResolution, item.ID)
+		}
+		if item.Amount.Currency != currency {
+			return domain.ManualMatch{}, fmt.Errorf("%w: bank exception %d is in %s, not %s", ErrInvalidResolution, item.ID, item.Amount.Currency, currency)
+		}
+		bankTotal = bankTotal.Add(item.Amount)
+	}
+	expected := expectedBankAmount(domain.Transaction{Amount: systemItem.Amount, Type: systemItem.Type})
+
+	return domain.ManualMatch{
+		JobID:       jobID,
+		SystemItem:  systemItem,
+		BankItems:   bankItems,
+		Discrepancy: calculateDiscrepancy(expected, bankTotal),
+	}, nil
+}
+
+func (s *useCase) applyManualMatch(ctx context.Context, match domain.ManualMatch) (domain.ManualResolution, error) {
+	resolution, err := s.recRepo.ApplyManualMatch(ctx, match)
+	if err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("failed to apply manual match: %w", err)
+	}
+	return resolution, nil
+}
+
+// Unmatch breaks a matched record of a job, its records become exceptions again
+func (s *useCase) Unmatch(ctx context.Context, jobID string, matchID int, reason string) (domain.ManualResolution, error) {
+	return s.unmatch(ctx, domain.Unmatch{JobID: jobID, MatchID: matchID}, reason)
+}
+
+// UnmatchGroup breaks a match group of a job, its records become exceptions again
+func (s *useCase) UnmatchGroup(ctx context.Context, jobID string, groupID int, reason string) (domain.ManualResolution, error) {
+	return s.unmatch(ctx, domain.Unmatch{JobID: jobID, GroupID: groupID}, reason)
+}
+
+func (s *useCase) unmatch(ctx context.Context, u domain.Unmatch, reason string) (domain.ManualResolution, error) {
+	var err error
+	if u.Actor, u.Reason, err = manualChange(ctx, reason); err != nil {
+		return domain.ManualResolution{}, err
+	}
+	resolution, err := s.recRepo.ApplyUnmatch(ctx, u)
+	if err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("failed to apply unmatch: %w", err)
+	}
+	return resolution, nil
+}
+
+// WriteOff closes an unmatched item of a job without a counterpart, itemType tells which exception list itemID belongs to.
+// A write-off of an amount above the approval threshold is only proposed, it is applied once another client approves it.
+func (s *useCase) WriteOff(ctx context.Context, jobID, itemType string, itemID int, reasonCode, note string) (domain.ManualResolution, error) {
+	actor := contextprop.GetValue(ctx, contextprop.ClientIDKey)
+	if actor == "" {
+		return domain.ManualResolution{}, ErrNoActor
+	}
+	writeOff, err := s.prepareWriteOff(ctx, jobID, itemType, itemID, reasonCode, note)
+	if err != nil {
+		return domain.ManualResolution{}, err
+	}
+	if s.needsApproval(writeOff.Amount) {
+		return s.propose(ctx, domain.Proposal{
+			JobID:       jobID,
+			Action:      domain.AuditWriteOff,
+			ItemType:    itemType,
+			ExceptionID: itemID,
+			ReasonCode:  writeOff.ReasonCode,
+			Reason:      writeOff.Note,
+			Amount:      writeOff.Amount,
+			ProposedBy:  actor,
+		})
+	}
+
+	writeOff.WrittenOffBy = actor
+	return s.applyWriteOff(ctx, writeOff)
+}
+
+func (s *useCase) prepareWriteOff(ctx context.Context, jobID, itemType string, itemID int, reasonCode, note string) (domain.WriteOff, error) {
+	reasonCode = strings.ToUpper(strings.TrimSpace(reasonCode))
+	if !slices.Contains(domain.WriteOffReasonCodes, reasonCode) {
+		return domain.WriteOff{}, fmt.Errorf("%w: reason code must be one of %s", ErrInvalidResolution, strings.Join(domain.WriteOffReasonCodes, ", "))
+	}
+
+	writeOff := domain.WriteOff{
+		JobID:       jobID,
+		ItemType:    itemType,
+		ExceptionID: itemID,
+		ReasonCode:  reasonCode,
+		Note:        strings.TrimSpace(note),
+	}
+	switch itemType {
+	case domain.OpenItemSystemTx:
+		items, _, err := s.recRepo.GetUnmatchedItems(ctx, jobID, []int{itemID}, nil)
+		if err != nil {
+			return domain.WriteOff{}, fmt.Errorf("failed to get unmatched items: %w", err)
+		}
+		if len(items) != 1 {
+			return domain.WriteOff{}, repository.ErrExceptionNotFound
+		}
+		if items[0].SystemTxID == 0 {
+			return domain.WriteOff{}, fmt.Errorf("%w: system exception %d has no transaction to write off", ErrInvalidResolution, itemID)
+		}
+		writeOff.SystemTxID = &items[0].SystemTxID
+		writeOff.Reference, writeOff.Amount, writeOff.ItemDate = items[0].TrxID, items[0].Amount, items[0].TransactionTime
+	case domain.OpenItemBankStatement:
+		_, items, err := s.recRepo.GetUnmatchedItems(ctx, jobID, nil, []int{itemID})
+		if err != nil {
+			return domain.WriteOff{}, fmt.Errorf("failed to get unmatched items: %w", err)
+		}
+		if len(items) != 1 {
+			return domain.WriteOff{}, repository.ErrExceptionNotFound
+		}
+		if items[0].BankStatementID == 0 {
+			return domain.WriteOff{}, fmt.Errorf("%w: bank exception %d has no statement to write off", ErrInvalidResolution, itemID)
+		}
+		writeOff.BankStatementID = &items[0].BankStatementID
+		writeOff.Reference, writeOff.Amount, writeOff.ItemDate = items[0].UniqueID, items[0].Amount, items[0].StatementDate
+	default:
+		return domain.WriteOff{}, fmt.Errorf("%w: item type must be %s or %s", ErrInvalidResolution, domain.OpenItemSystemTx, domain.OpenItemBankStatement)
+	}
+	return writeOff, nil
+}
+
+func (s *useCase) applyWriteOff(ctx context.Context, writeOff domain.WriteOff) (domain.ManualResolution, error) {
+	resolution, err := s.recRepo.ApplyWriteOff(ctx, writeOff)
+	if err != nil {
+		return domain.ManualResolution{}, fmt.Errorf("failed to apply write-off: %w", err)
+	}
+	return resolution, nil
+}
+
+// GetAuditLog returns the manual changes made to a job, oldest first
+func (s *useCase) GetAuditLog(ctx context.Context, jobID string) ([]domain.AuditEntry, error) {
+	entries, err := s.recRepo.ListAuditLog(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// manualChange returns the caller recorded as the actor of a manual change and its trimmed reason, both are required
+func manualChange(ctx context.Context, reason string) (string, string, error) {
+	actor := contextprop.GetValue(ctx, contextprop.ClientIDKey)
+	if actor == "" {
+		return "", "", ErrNoActor
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", "", fmt.Errorf("%w: a reason is required", ErrInvalidResolution)
+	}
+	return actor, reason, nil
+}
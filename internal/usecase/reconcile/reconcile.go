@@ -5,33 +5,64 @@ import (
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
 	"github.com/ardianferdianto/reconciliation-service/internal/repository"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
 	"github.com/google/uuid"
-	"strings"
 	"time"
 )
 
-const (
-	MinMatchScore = 2
-)
-
+//go:generate mockgen -source=reconcile.go -destination=_mock/reconcile.go
 type IUseCase interface {
-	ProcessReconciliation(ctx context.Context, startDate time.Time, endDate time.Time) (domain.ReconciliationResult, error)
+	ProcessReconciliation(ctx context.Context, workflowID string, startDate time.Time, endDate time.Time, opts domain.MatchOptions) (domain.ReconciliationResult, error)
 	GetReconciliationSummary(ctx context.Context, jobID string) (domain.ReconciliationSummary, error)
+	GetWorkflowRuns(ctx context.Context, workflowID string) ([]domain.ReconciliationJob, error)
+	ListUnmatchedSystemTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedSystemTx, *domain.ExceptionCursor, error)
+	ListUnmatchedBankTx(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.UnmatchedBankTx, *domain.ExceptionCursor, error)
+	ListMatchedPairs(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchedPair, *domain.ExceptionCursor, error)
+	ListMatchGroups(ctx context.Context, jobID string, q domain.ExceptionQuery) ([]domain.MatchGroup, *domain.ExceptionCursor, error)
+	ManualMatch(ctx context.Context, jobID string, systemItemID int, bankItemIDs []int, reason string) (domain.ManualResolution, error)
+	Unmatch(ctx context.Context, jobID string, matchID int, reason string) (domain.ManualResolution, error)
+	UnmatchGroup(ctx context.Context, jobID string, groupID int, reason string) (domain.ManualResolution, error)
+	WriteOff(ctx context.Context, jobID, itemType string, itemID int, reasonCode, note string) (domain.ManualResolution, error)
+	GetAuditLog(ctx context.Context, jobID string) ([]domain.AuditEntry, error)
+	ListProposals(ctx context.Context, jobID, status string) ([]domain.Proposal, error)
+	ApproveProposal(ctx context.Context, jobID string, proposalID int, note string) (domain.ManualResolution, error)
+	RejectProposal(ctx context.Context, jobID string, proposalID int, note string) (domain.Proposal, error)
 }
 
 type useCase struct {
-	recRepo  repository.ReconciliationRepository
-	dataRepo repository.DataRepository
+	recRepo      repository.ReconciliationRepository
+	dataRepo     repository.DataRepository
+	fxRepo       repository.FXRateRepository
+	openItemRepo repository.OpenItemRepository
+	ruleSets     *RuleSets
+	approval     domain.ApprovalPolicy
 }
 
 func NewReconciliationUseCase(
 	recRepo repository.ReconciliationRepository,
 	dataRepo repository.DataRepository,
+	fxRepo repository.FXRateRepository,
+	openItemRepo repository.OpenItemRepository,
+	ruleSets *RuleSets,
+	approval domain.ApprovalPolicy,
 ) IUseCase {
 	return &useCase{
-		recRepo:  recRepo,
-		dataRepo: dataRepo,
+		recRepo:      recRepo,
+		dataRepo:     dataRepo,
+		fxRepo:       fxRepo,
+		openItemRepo: openItemRepo,
+		ruleSets:     ruleSets,
+		approval:     approval,
+	}
+}
+
+// GetWorkflowRuns returns the reconciliation jobs of every run of a workflow, oldest first
+func (s *useCase) GetWorkflowRuns(ctx context.Context, workflowID string) ([]domain.ReconciliationJob, error) {
+	jobs, err := s.recRepo.ListJobsByWorkflow(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reconciliation jobs: %w", err)
 	}
+	return jobs, nil
 }
 
 func (s *useCase) GetReconciliationSummary(ctx context.Context, jobID string) (domain.ReconciliationSummary, error) {
@@ -40,53 +71,167 @@ func (s *useCase) GetReconciliationSummary(ctx context.Context, jobID string) (d
 		return domain.ReconciliationSummary{}, fmt.Errorf("failed to get reconciliation result: %w", err)
 	}
 
-	unmatchedSystemTx, err := s.recRepo.GetUnmatchedSystemTx(ctx, jobID)
+	unmatchedByBank, err := s.recRepo.CountUnmatchedBankByBank(ctx, jobID)
 	if err != nil {
-		return domain.ReconciliationSummary{}, fmt.Errorf("failed to get unmatched system transactions: %w", err)
+		return domain.ReconciliationSummary{}, fmt.Errorf("failed to count unmatched bank transactions by bank: %w", err)
 	}
 
-	unmatchedByBank, err := s.recRepo.GetUnmatchedBankTxGroupedByBank(ctx, jobID)
+	openItems, err := s.openItemRepo.GetOpenItems(ctx, jobID)
 	if err != nil {
-		return domain.ReconciliationSummary{}, fmt.Errorf("failed to get unmatched bank transactions grouped by bank: %w", err)
+		return domain.ReconciliationSummary{}, fmt.Errorf("failed to get open items: %w", err)
+	}
+
+	resolvedOpenItems, err := s.openItemRepo.CountResolvedOpenItems(ctx, jobID)
+	if err != nil {
+		return domain.ReconciliationSummary{}, fmt.Errorf("failed to count resolved open items: %w", err)
+	}
+
+	activity, err := s.recRepo.ListAccountActivity(ctx, jobID)
+	if err != nil {
+		return domain.ReconciliationSummary{}, fmt.Errorf("failed to list account activity: %w", err)
 	}
 
 	return domain.ReconciliationSummary{
-		TotalTransactionsProcessed: result.TotalSystemTxCount,
-		TotalMatchedTransactions:   result.MatchedCount,
-		TotalUnmatchedTransactions: result.UnmatchedSystemCount,
-		UnmatchedSystemTx:          unmatchedSystemTx,
-		UnmatchedBankTxByBank:      unmatchedByBank,
-		TotalDiscrepancies:         result.TotalDiscrepancies,
+		TotalTransactionsProcessed:     result.TotalSystemTxCount,
+		TotalBankTransactions:          result.TotalBankTxCount,
+		TotalMatchedTransactions:       result.MatchedCount,
+		TotalUnmatchedTransactions:     result.UnmatchedSystemCount,
+		TotalUnmatchedBankTransactions: result.UnmatchedBankCount,
+		UnmatchedBankByBank:            unmatchedByBank,
+		TotalMatchedGroups:             result.MatchedGroupCount,
+		TotalDiscrepancies:             result.TotalDiscrepancies,
+		ResolvedOpenItems:              resolvedOpenItems,
+		TotalOpenItems:                 len(openItems),
+		OpenItemsAgeing:                openItemsAgeing(openItems),
+		BalanceRollforward:             balanceRollforward(activity),
 	}, nil
 }
 
-func (s *useCase) ProcessReconciliation(ctx context.Context, startDate, endDate time.Time) (domain.ReconciliationResult, error) {
-	jobID := uuid.New().String()
+// ProcessReconciliation reconciles the rows uploaded for a workflow, or every row in the period when workflowID is empty
+func (s *useCase) ProcessReconciliation(ctx context.Context, workflowID string, startDate, endDate time.Time, opts domain.MatchOptions) (domain.ReconciliationResult, error) {
 	job := domain.ReconciliationJob{
-		JobID:     jobID,
-		StartDate: startDate,
-		EndDate:   endDate,
+		JobID:      uuid.New().String(),
+		WorkflowID: workflowID,
+		StartDate:  startDate,
+		EndDate:    endDate,
 	}
+	// The job is stored with its outcome and its changes to the open-items ledger in one transaction,
+	// nothing of a run that fails or is cancelled is kept
+	var result domain.ReconciliationResult
+	err := s.recRepo.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.reconcile(ctx, job, opts)
+		return err
+	})
+	if err != nil {
+		return domain.ReconciliationResult{}, err
+	}
+	return result, nil
+}
+
+// reconcile matches the records of the job's period and stores the outcome under the job
+func (s *useCase) reconcile(ctx context.Context, job domain.ReconciliationJob, opts domain.MatchOptions) (domain.ReconciliationResult, error) {
+	jobID, workflowID, startDate, endDate := job.JobID, job.WorkflowID, job.StartDate, job.EndDate
 	if err := s.recRepo.CreateJob(ctx, job); err != nil {
 		return domain.ReconciliationResult{}, err
 	}
 
-	systemTx, err := s.dataRepo.FindSystemTxByDateRange(ctx, startDate, endDate)
+	scope := domain.DataScope{WorkflowID: workflowID, IncludeHistorical: opts.IncludeHistorical}
+	// The period runs up to the end of its last day, records later that day than midnight belong to it
+	periodEnd := endOfDay(endDate)
+	systemTx, err := s.dataRepo.FindSystemTxByDateRange(ctx, scope, startDate, periodEnd)
+	if err != nil {
+		return domain.ReconciliationResult{}, err
+	}
+	// Widen the bank side by the date window so postings that land just outside the period can still match
+	// and up to the end of the last day of the window, a posting later that day is still in it
+	bankStartDate, _ := businessDayWindow(startDate, opts.DateWindowDays)
+	_, windowEnd := businessDayWindow(endDate, opts.DateWindowDays)
+	bankEndDate := endOfDay(windowEnd)
+	bankStmts, err := s.dataRepo.FindBankStmtsByDateRange(ctx, scope, bankStartDate, bankEndDate)
+	if err != nil {
+		return domain.ReconciliationResult{}, err
+	}
+
+	// A re-run starts from the ledger as it was before the workflow's earlier runs
+	if workflowID != "" {
+		if err := s.openItemRepo.ReopenWorkflowItems(ctx, workflowID, jobID); err != nil {
+			return domain.ReconciliationResult{}, err
+		}
+	}
+
+	// Records earlier jobs left unmatched stay eligible until a job matches them
+	openTx, err := s.openItemRepo.FindOpenSystemTx(ctx, scope, periodEnd)
 	if err != nil {
 		return domain.ReconciliationResult{}, err
 	}
-	bankStmts, err := s.dataRepo.FindBankStmtsByDateRange(ctx, startDate, endDate)
+	openStmts, err := s.openItemRepo.FindOpenBankStmts(ctx, scope, bankEndDate)
 	if err != nil {
 		return domain.ReconciliationResult{}, err
 	}
+	candidateTx, carriedTx := withCarriedTransactions(systemTx, openTx)
+	candidateStmts, carriedStmts := withCarriedStatements(bankStmts, openStmts)
 
-	matchedRecords, unmatchedSystemTx, unmatchedBankStmts, totalDiscrepancies := s.matchRecords(jobID, systemTx, bankStmts)
+	// Amounts are compared in the reconciliation currency, records that cannot be converted stay unmatched
+	converter, err := s.newCurrencyConverter(ctx, bankStartDate, bankEndDate, opts)
+	if err != nil {
+		return domain.ReconciliationResult{}, err
+	}
+	convertedTx, txConversions, unconvertedTx := converter.transactions(candidateTx)
+	convertedStmts, stmtConversions, unconvertedStmts := converter.statements(candidateStmts)
+
+	matchedRecords, leftoverTx, leftoverStmts, totalDiscrepancies := s.matchRecords(jobID, convertedTx, convertedStmts, opts)
+	for i, rec := range matchedRecords {
+		matchedRecords[i] = withConversions(rec, txConversions[rec.SystemTxID], stmtConversions[rec.BankStatementID])
+	}
+
+	var matchGroups []domain.MatchGroup
+	if opts.GroupMatching {
+		matchGroups, leftoverTx, leftoverStmts = matchGroupRecords(jobID, leftoverTx, leftoverStmts, opts)
+		for _, group := range matchGroups {
+			totalDiscrepancies = totalDiscrepancies.Add(group.Discrepancy)
+		}
+	}
+	// A cancelled run stores nothing more, the transaction it runs in is rolled back
+	if err := ctx.Err(); err != nil {
+		return domain.ReconciliationResult{}, err
+	}
+	leftoverTx = append(originalTransactions(leftoverTx, txConversions), unconvertedTx...)
+	leftoverStmts = append(originalStatements(leftoverStmts, stmtConversions), unconvertedStmts...)
+
+	// Statements outside the period were only loaded as candidates, they are not exceptions of this job
+	totalBankTxCount := 0
+	for _, stmt := range bankStmts {
+		if withinPeriod(stmt.StatementTime, startDate, endDate) {
+			totalBankTxCount++
+		}
+	}
+	// Outstanding items of earlier jobs that are still unmatched remain exceptions of those jobs
+	var newOpenTx []domain.Transaction
+	for _, tx := range leftoverTx {
+		if !carriedTx[tx.ID] {
+			newOpenTx = append(newOpenTx, tx)
+		}
+	}
+	var newOpenStmts []domain.BankStatement
+	for _, stmt := range leftoverStmts {
+		if !carriedStmts[stmt.ID] && withinPeriod(stmt.StatementTime, startDate, endDate) {
+			newOpenStmts = append(newOpenStmts, stmt)
+		}
+	}
+	unmatchedSystemTx := toUnmatchedSystemTx(jobID, newOpenTx)
+	unmatchedBankStmts := toUnmatchedBankTx(jobID, newOpenStmts)
 
 	for _, matched := range matchedRecords {
 		if _, err := s.recRepo.StoreMatchedRecord(ctx, matched); err != nil {
 			return domain.ReconciliationResult{}, err
 		}
 	}
+	for _, group := range matchGroups {
+		if _, err := s.recRepo.StoreMatchGroup(ctx, group); err != nil {
+			return domain.ReconciliationResult{}, err
+		}
+	}
 
 	if err := s.recRepo.StoreUnmatchedSystemTx(ctx, unmatchedSystemTx); err != nil {
 		return domain.ReconciliationResult{}, err
@@ -95,13 +240,25 @@ func (s *useCase) ProcessReconciliation(ctx context.Context, startDate, endDate
 		return domain.ReconciliationResult{}, err
 	}
 
+	if resolvedTxIDs, resolvedStmtIDs := resolvedCarriedIDs(matchedRecords, matchGroups, carriedTx, carriedStmts); len(resolvedTxIDs) > 0 || len(resolvedStmtIDs) > 0 {
+		if _, err := s.openItemRepo.ResolveOpenItems(ctx, jobID, resolvedTxIDs, resolvedStmtIDs); err != nil {
+			return domain.ReconciliationResult{}, err
+		}
+	}
+	if openItems := newOpenItems(jobID, newOpenTx, newOpenStmts); len(openItems) > 0 {
+		if err := s.openItemRepo.StoreOpenItems(ctx, openItems); err != nil {
+			return domain.ReconciliationResult{}, err
+		}
+	}
+
 	result := domain.ReconciliationResult{
 		JobID:                jobID,
 		TotalSystemTxCount:   len(systemTx),
-		TotalBankTxCount:     len(bankStmts),
+		TotalBankTxCount:     totalBankTxCount,
 		MatchedCount:         len(matchedRecords),
 		UnmatchedSystemCount: len(unmatchedSystemTx),
 		UnmatchedBankCount:   len(unmatchedBankStmts),
+		MatchedGroupCount:    len(matchGroups),
 		TotalDiscrepancies:   totalDiscrepancies,
 	}
 	_, err = s.recRepo.StoreResult(ctx, result)
@@ -112,96 +269,143 @@ func (s *useCase) ProcessReconciliation(ctx context.Context, startDate, endDate
 	return result, nil
 }
 
-func (s *useCase) matchRecords(jobID string, systemTx []domain.Transaction, bankStmts []domain.BankStatement) ([]domain.MatchedRecord, []domain.UnmatchedSystemTx, []domain.UnmatchedBankTx, float64) {
+// matchRecords pairs system transactions with bank statements one to one and returns what is left on both sides.
+// Passes run in order, each one only sees the records left over by the previous passes.
+func (s *useCase) matchRecords(jobID string, systemTx []domain.Transaction, bankStmts []domain.BankStatement, opts domain.MatchOptions) ([]domain.MatchedRecord, []domain.Transaction, []domain.BankStatement, money.Money) {
 	var matched []domain.MatchedRecord
-	var unmatchedSystem []domain.UnmatchedSystemTx
-	var unmatchedBank []domain.UnmatchedBankTx
-	var totalDiscrepancies float64
+	var leftoverTx []domain.Transaction
+	var leftoverStmts []domain.BankStatement
+	totalDiscrepancies := money.New(0, opts.ReconciliationCurrency())
 
-	bankMap := make(map[string][]domain.BankStatement) // Keyed by date and amount for initial coarse matching
-	for _, stmt := range bankStmts {
-		key := stmt.StatementTime.Format("20060102") + "_" + formatAmount(stmt.Amount)
-		bankMap[key] = append(bankMap[key], stmt)
-	}
+	// Work on a stable order so the outcome does not depend on how the rows came out of the database
+	systemTx, bankStmts = sortedTransactions(systemTx), sortedStatements(bankStmts)
+	m := newPassMatcher(s.ruleSets, systemTx, bankStmts, opts)
 
-	// Attempt to match transactions
-	for _, tx := range systemTx {
-		expectedAmount := tx.Amount
-		if tx.Type == domain.Debit {
-			expectedAmount = -tx.Amount
-		}
-		txKey := fmt.Sprintf("%s_%.2f", tx.TransactionTime.Format("20060102"), expectedAmount)
-		if stmts, exists := bankMap[txKey]; exists {
-			for i, stmt := range stmts {
-				score := calculateMatchScore(tx, stmt)
-				if score >= MinMatchScore {
-					discrepancy := calculateDiscrepancy(tx.Amount, stmt.Amount)
-					totalDiscrepancies += discrepancy
-					matchedRecord := domain.MatchedRecord{
-						JobID:           jobID,
-						SystemTxID:      tx.ID,
-						BankStatementID: stmt.ID,
-						Discrepancy:     discrepancy,
-					}
-					matched = append(matched, matchedRecord)
-					// Remove the matched statement
-					stmts = append(stmts[:i], stmts[i+1:]...)
-					bankMap[txKey] = stmts
-					break
-				}
-			}
-			if len(stmts) == 0 {
-				delete(bankMap, txKey)
-			}
+	for passIdx := 0; passIdx < s.ruleSets.maxPasses(bankStmts); passIdx++ {
+		var pairs []pairing
+		if opts.AssignmentMode == domain.AssignmentOptimal {
+			pairs = m.optimalPairs(passIdx)
 		} else {
-			unmatchedSystem = append(unmatchedSystem, domain.UnmatchedSystemTx{
+			pairs = m.greedyPairs(passIdx)
+		}
+
+		for _, pair := range pairs {
+			tx, stmt := systemTx[pair.tx], bankStmts[pair.stmt]
+			set := s.ruleSets.forBank(stmt.BankCode)
+			discrepancy := calculateDiscrepancy(expectedBankAmount(tx), stmt.Amount)
+			totalDiscrepancies = totalDiscrepancies.Add(discrepancy)
+			matched = append(matched, domain.MatchedRecord{
 				JobID:           jobID,
-				TrxID:           tx.TrxID,
-				Amount:          tx.Amount,
-				Type:            tx.Type,
-				TransactionTime: tx.TransactionTime,
+				SystemTxID:      tx.ID,
+				BankStatementID: stmt.ID,
+				Discrepancy:     discrepancy,
+				RuleSet:         set.name,
+				MatchPass:       set.passes[passIdx].name,
+				PassNumber:      passIdx + 1,
 			})
 		}
 	}
 
-	// Collect any remaining unmatched bank statements
-	for _, stmts := range bankMap {
-		for _, stmt := range stmts {
-			unmatchedBank = append(unmatchedBank, domain.UnmatchedBankTx{
-				JobID:         jobID,
-				UniqueID:      stmt.UniqueID,
-				Amount:        stmt.Amount,
-				StatementDate: stmt.StatementTime,
-				BankCode:      stmt.BankCode,
-			})
+	for i, tx := range systemTx {
+		if !m.usedTx[i] {
+			leftoverTx = append(leftoverTx, tx)
+		}
+	}
+	for i, stmt := range bankStmts {
+		if !m.usedStmt[i] {
+			leftoverStmts = append(leftoverStmts, stmt)
 		}
 	}
 
-	return matched, unmatchedSystem, unmatchedBank, totalDiscrepancies
+	return matched, leftoverTx, leftoverStmts, totalDiscrepancies
 }
 
-func calculateMatchScore(tx domain.Transaction, stmt domain.BankStatement) int {
-	score := 0
-	if tx.TransactionTime.Format("20060102") == stmt.StatementTime.Format("20060102") {
-		score++
+func toUnmatchedSystemTx(jobID string, txList []domain.Transaction) []domain.UnmatchedSystemTx {
+	var unmatched []domain.UnmatchedSystemTx
+	for _, tx := range txList {
+		unmatched = append(unmatched, domain.UnmatchedSystemTx{
+			JobID:           jobID,
+			SystemTxID:      tx.ID,
+			TrxID:           tx.TrxID,
+			Amount:          tx.Amount,
+			Type:            tx.Type,
+			TransactionTime: tx.TransactionTime,
+		})
 	}
-	if formatAmount(tx.Amount) == formatAmount(stmt.Amount) {
-		score++
+	return unmatched
+}
+
+func toUnmatchedBankTx(jobID string, stmts []domain.BankStatement) []domain.UnmatchedBankTx {
+	var unmatched []domain.UnmatchedBankTx
+	for _, stmt := range stmts {
+		unmatched = append(unmatched, domain.UnmatchedBankTx{
+			JobID:           jobID,
+			BankStatementID: stmt.ID,
+			UniqueID:        stmt.UniqueID,
+			Amount:          stmt.Amount,
+			StatementDate:   stmt.StatementTime,
+			BankCode:        stmt.BankCode,
+		})
 	}
-	if strings.Contains(stmt.UniqueID, tx.TrxID) || strings.Contains(tx.TrxID, stmt.UniqueID) {
-		score++
+	return unmatched
+}
+
+// expectedBankAmount is the signed amount the bank should report for a system transaction
+func expectedBankAmount(tx domain.Transaction) money.Money {
+	if tx.Type == domain.Debit {
+		return tx.Amount.Neg()
 	}
-	return score
+	return tx.Amount
 }
 
-func calculateDiscrepancy(txAmount, stmtAmount float64) float64 {
-	discrepancy := txAmount - stmtAmount
-	if discrepancy < 0 {
-		return -discrepancy
+func calculateDiscrepancy(expectedAmount, stmtAmount money.Money) money.Money {
+	return expectedAmount.Sub(stmtAmount).Abs()
+}
+
+func dateKey(t time.Time) string {
+	return t.Format("20060102")
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// endOfDay returns the last instant of the day of t
+func endOfDay(t time.Time) time.Time {
+	return truncateDay(t).AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+func dayDistance(a, b time.Time) int {
+	days := int(truncateDay(a).Sub(truncateDay(b)).Hours() / 24)
+	if days < 0 {
+		return -days
+	}
+	return days
+}
+
+// businessDayWindow returns the first and last calendar day reachable by moving n business days
+// backward and forward from t. Weekends are skipped but do not count towards n.
+func businessDayWindow(t time.Time, n int) (time.Time, time.Time) {
+	day := truncateDay(t)
+	from, to := day, day
+	for i := 0; i < n; i++ {
+		from = from.AddDate(0, 0, -1)
+		for isWeekend(from) {
+			from = from.AddDate(0, 0, -1)
+		}
+		to = to.AddDate(0, 0, 1)
+		for isWeekend(to) {
+			to = to.AddDate(0, 0, 1)
+		}
 	}
-	return discrepancy
+	return from, to
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
 }
 
-func formatAmount(amount float64) string {
-	return fmt.Sprintf("%.2f", amount)
+func withinPeriod(t, startDate, endDate time.Time) bool {
+	day := truncateDay(t)
+	return !day.Before(truncateDay(startDate)) && !day.After(truncateDay(endDate))
 }
@@ -3,17 +3,27 @@ package reconcile
 import (
 	"context"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/internal/repository"
 	mock_repository "github.com/ardianferdianto/reconciliation-service/internal/repository/_mock"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contextprop"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"testing"
 	"time"
 )
 
+func idr(amount string) money.Money {
+	return money.MustParse(amount, money.DefaultCurrency)
+}
+
 type ReconcileUseCaseSuite struct {
 	suite.Suite
 	mockDataRepo *mock_repository.MockDataRepository
 	mockRecRepo  *mock_repository.MockReconciliationRepository
+	mockFXRepo   *mock_repository.MockFXRateRepository
+	mockOpenRepo *mock_repository.MockOpenItemRepository
 	uc           IUseCase
 	controller   *gomock.Controller
 }
@@ -22,19 +32,46 @@ func (suite *ReconcileUseCaseSuite) SetupTest() {
 	suite.controller = gomock.NewController(suite.T())
 	suite.mockDataRepo = mock_repository.NewMockDataRepository(suite.controller)
 	suite.mockRecRepo = mock_repository.NewMockReconciliationRepository(suite.controller)
-	suite.uc = NewReconciliationUseCase(suite.mockRecRepo, suite.mockDataRepo)
+	suite.mockFXRepo = mock_repository.NewMockFXRateRepository(suite.controller)
+	suite.mockOpenRepo = mock_repository.NewMockOpenItemRepository(suite.controller)
+	ruleSets, err := NewRuleSets(nil)
+	suite.Require().NoError(err)
+	suite.uc = NewReconciliationUseCase(suite.mockRecRepo, suite.mockDataRepo, suite.mockFXRepo, suite.mockOpenRepo, ruleSets, domain.ApprovalPolicy{})
+}
+
+// SetupSubTest gives every case its own mocks so expectations of one case never satisfy calls of another
+func (suite *ReconcileUseCaseSuite) SetupSubTest() {
+	suite.SetupTest()
 }
 
 func (suite *ReconcileUseCaseSuite) TearDownTest() {
 	suite.controller.Finish()
 }
 
+// expectWithinTx runs the work handed to the transaction as the repository does, committed reports whether it succeeded
+func (suite *ReconcileUseCaseSuite) expectWithinTx(ctx context.Context) (committed *bool) {
+	committed = new(bool)
+	suite.mockRecRepo.EXPECT().WithinTx(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+		err := fn(ctx)
+		*committed = err == nil
+		return err
+	})
+	return committed
+}
+
 func (suite *ReconcileUseCaseSuite) TestProcessReconciliation() {
 	ctx := context.Background()
 	startDate, endDate := time.Date(2021, 01, 01, 0, 0, 0, 0, time.UTC), time.Date(2021, 01, 31, 0, 0, 0, 0, time.UTC)
+	workflowID := "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55"
+	// without a date window the bank side ends with the period, at the end of its last day
+	periodEnd := endOfDay(endDate)
+	bankEndDate := periodEnd
+	scope := domain.DataScope{WorkflowID: workflowID}
 
 	testCases := []struct {
 		name          string
+		opts          domain.MatchOptions
+		ruleSets      []domain.MatchRuleSet
 		setupMocks    func()
 		expectedError bool
 		verifyResult  func(result domain.ReconciliationResult)
@@ -42,15 +79,20 @@ func (suite *ReconcileUseCaseSuite) TestProcessReconciliation() {
 		{
 			name: "Perfect Match",
 			setupMocks: func() {
-				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: 100.0, TransactionTime: startDate}}
-				statements := []domain.BankStatement{{ID: 1, UniqueID: "TX1001", Amount: 100.0, StatementTime: startDate}}
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("100.0"), TransactionTime: startDate}}
+				statements := []domain.BankStatement{{ID: 1, UniqueID: "TX1001", Amount: idr("100.0"), StatementTime: startDate}}
 
-				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, startDate, endDate).Return(transactions, nil)
-				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, startDate, endDate).Return(statements, nil)
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
 				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, job domain.ReconciliationJob) error {
+					suite.Equal(workflowID, job.WorkflowID)
 					return nil
 				})
-				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).Return(1, nil)
+				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, rec domain.MatchedRecord) (int, error) {
+					suite.Equal(DefaultRuleSetName, rec.RuleSet)
+					suite.Equal(1, rec.PassNumber)
+					return 1, nil
+				})
 				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Any()).Return(nil)
 				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Any()).Return(nil)
 				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
@@ -61,12 +103,410 @@ func (suite *ReconcileUseCaseSuite) TestProcessReconciliation() {
 				suite.Equal(1, result.MatchedCount)
 			},
 		},
+		{
+			name: "Amount Within Tolerance",
+			opts: domain.MatchOptions{AmountTolerance: 0.5},
+			setupMocks: func() {
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("100.0"), Type: domain.Credit, TransactionTime: startDate}}
+				statements := []domain.BankStatement{{ID: 1, UniqueID: "BNK-1", Amount: idr("99.75"), StatementTime: startDate}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, rec domain.MatchedRecord) (int, error) {
+					suite.Equal(idr("0.25"), rec.Discrepancy)
+					return 1, nil
+				})
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(1, result.MatchedCount)
+				suite.Equal(idr("0.25"), result.TotalDiscrepancies)
+			},
+		},
+		{
+			name: "Amount Outside Tolerance",
+			opts: domain.MatchOptions{AmountTolerancePercent: 0.1},
+			setupMocks: func() {
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("100.0"), Type: domain.Credit, TransactionTime: startDate}}
+				statements := []domain.BankStatement{{ID: 1, UniqueID: "BNK-1", Amount: idr("99.75"), StatementTime: startDate}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(1)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(1)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(0, result.MatchedCount)
+				suite.Equal(1, result.UnmatchedSystemCount)
+				suite.Equal(1, result.UnmatchedBankCount)
+			},
+		},
+		{
+			name: "Posted Next Business Day",
+			opts: domain.MatchOptions{DateWindowDays: 1},
+			setupMocks: func() {
+				// 2021-01-29 is a Friday, the bank posts on Monday 2021-02-01 which is after the period
+				friday := time.Date(2021, 01, 29, 10, 0, 0, 0, time.UTC)
+				monday := time.Date(2021, 02, 01, 0, 0, 0, 0, time.UTC)
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("50.0"), Type: domain.Debit, TransactionTime: friday}}
+				statements := []domain.BankStatement{{ID: 1, UniqueID: "BNK-1", Amount: idr("-50.0"), StatementTime: monday}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), endOfDay(monday)).Return(statements, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).Return(1, nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(1, result.MatchedCount)
+				suite.Equal(0, result.TotalBankTxCount)
+				suite.True(result.TotalDiscrepancies.IsZero())
+			},
+		},
+		{
+			name: "Bank Posts Later On The Last Day",
+			setupMocks: func() {
+				// the period ends at midnight of 2021-01-31, the bank still posts later that day
+				afternoon := time.Date(2021, 01, 31, 16, 30, 0, 0, time.UTC)
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("75.0"), Type: domain.Credit, TransactionTime: endDate}}
+				statements := []domain.BankStatement{{ID: 1, UniqueID: "BNK-1", Amount: idr("75.0"), StatementTime: afternoon}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, scope domain.DataScope, from, to time.Time) ([]domain.BankStatement, error) {
+						suite.False(afternoon.After(to))
+						return statements, nil
+					})
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).Return(1, nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(1, result.MatchedCount)
+				suite.Equal(1, result.TotalBankTxCount)
+			},
+		},
+		{
+			name: "Posted Later On The Last Day",
+			setupMocks: func() {
+				// the system books the transaction on the afternoon of the last day, the bank posts it the same day
+				afternoon := time.Date(2021, 01, 31, 15, 45, 0, 0, time.UTC)
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("75.0"), Type: domain.Credit, TransactionTime: afternoon}}
+				statements := []domain.BankStatement{{ID: 1, UniqueID: "BNK-1", Amount: idr("75.0"), StatementTime: endDate}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, scope domain.DataScope, from, to time.Time) ([]domain.Transaction, error) {
+						suite.False(afternoon.After(to))
+						return transactions, nil
+					})
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockOpenRepo.EXPECT().FindOpenSystemTx(ctx, scope, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, scope domain.DataScope, until time.Time) ([]domain.Transaction, error) {
+						suite.False(afternoon.After(until))
+						return nil, nil
+					})
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).Return(1, nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(1, result.MatchedCount)
+				suite.Equal(1, result.TotalSystemTxCount)
+				suite.Equal(1, result.TotalBankTxCount)
+			},
+		},
+		{
+			name: "Lump Credit Settles Several Transactions",
+			opts: domain.MatchOptions{GroupMatching: true},
+			setupMocks: func() {
+				transactions := []domain.Transaction{
+					{ID: 1, TrxID: "TX1001", Amount: idr("40.0"), Type: domain.Credit, TransactionTime: startDate},
+					{ID: 2, TrxID: "TX1002", Amount: idr("60.0"), Type: domain.Credit, TransactionTime: startDate},
+					{ID: 3, TrxID: "TX1003", Amount: idr("25.0"), Type: domain.Credit, TransactionTime: startDate},
+				}
+				statements := []domain.BankStatement{{ID: 7, UniqueID: "BNK-LUMP", Amount: idr("100.0"), StatementTime: startDate}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchGroup(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, group domain.MatchGroup) (int, error) {
+					suite.Equal(domain.ManyToOne, group.GroupType)
+					suite.Equal([]int{1, 2}, group.SystemTxIDs)
+					suite.Equal([]int{7}, group.BankStatementIDs)
+					return 1, nil
+				})
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(1)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(0, result.MatchedCount)
+				suite.Equal(1, result.MatchedGroupCount)
+				suite.Equal(1, result.UnmatchedSystemCount)
+			},
+		},
+		{
+			name: "Payout Split Over Several Lines",
+			opts: domain.MatchOptions{GroupMatching: true, AmountTolerance: 1},
+			setupMocks: func() {
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("300.0"), Type: domain.Debit, TransactionTime: startDate}}
+				statements := []domain.BankStatement{
+					{ID: 7, UniqueID: "BNK-1", Amount: idr("-150.0"), StatementTime: startDate},
+					{ID: 8, UniqueID: "BNK-2", Amount: idr("-149.5"), StatementTime: startDate},
+				}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchGroup(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, group domain.MatchGroup) (int, error) {
+					suite.Equal(domain.OneToMany, group.GroupType)
+					suite.Equal([]int{7, 8}, group.BankStatementIDs)
+					return 1, nil
+				})
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(1, result.MatchedGroupCount)
+				suite.Equal(idr("0.50"), result.TotalDiscrepancies)
+			},
+		},
+		{
+			name: "Bank Rule Set Passes In Order",
+			ruleSets: []domain.MatchRuleSet{{
+				Name:     "bca",
+				BankCode: "BCA",
+				Passes: []domain.MatchPassDefinition{
+					{Name: "reference", Rules: []domain.MatchRuleDefinition{{Type: RuleReference}}},
+					{Name: "amount_date", Rules: []domain.MatchRuleDefinition{{Type: RuleExactAmount}, {Type: RuleSameDate}}},
+				},
+			}},
+			setupMocks: func() {
+				// TX1002 would take BNK-2 on amount and date, but the reference pass pairs it with TX1001 first
+				transactions := []domain.Transaction{
+					{ID: 1, TrxID: "TX1001", Amount: idr("100.0"), Type: domain.Credit, TransactionTime: startDate},
+					{ID: 2, TrxID: "TX1002", Amount: idr("100.0"), Type: domain.Credit, TransactionTime: startDate},
+				}
+				statements := []domain.BankStatement{
+					{ID: 7, UniqueID: "TX1001", Amount: idr("98.0"), StatementTime: startDate.AddDate(0, 0, 3), BankCode: "BCA"},
+					{ID: 8, UniqueID: "BNK-2", Amount: idr("100.0"), StatementTime: startDate, BankCode: "BCA"},
+				}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				gomock.InOrder(
+					suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, rec domain.MatchedRecord) (int, error) {
+						suite.Equal(7, rec.BankStatementID)
+						suite.Equal("reference", rec.MatchPass)
+						suite.Equal(idr("2.00"), rec.Discrepancy)
+						return 1, nil
+					}),
+					suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, rec domain.MatchedRecord) (int, error) {
+						suite.Equal(8, rec.BankStatementID)
+						suite.Equal("bca", rec.RuleSet)
+						suite.Equal(2, rec.PassNumber)
+						return 2, nil
+					}),
+				)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(2, result.MatchedCount)
+			},
+		},
+		{
+			name: "Optimal Assignment Does Not Steal Candidates",
+			opts: domain.MatchOptions{DateWindowDays: 1, AssignmentMode: domain.AssignmentOptimal},
+			setupMocks: func() {
+				// Greedy would give BNK-1 to TX1001 (same day) and leave TX1002 without a candidate
+				transactions := []domain.Transaction{
+					{ID: 1, TrxID: "TX1001", Amount: idr("100.0"), Type: domain.Credit, TransactionTime: time.Date(2021, 01, 05, 9, 0, 0, 0, time.UTC)},
+					{ID: 2, TrxID: "TX1002", Amount: idr("100.0"), Type: domain.Credit, TransactionTime: time.Date(2021, 01, 06, 9, 0, 0, 0, time.UTC)},
+				}
+				statements := []domain.BankStatement{
+					{ID: 7, UniqueID: "BNK-1", Amount: idr("100.0"), StatementTime: time.Date(2021, 01, 05, 0, 0, 0, 0, time.UTC)},
+					{ID: 8, UniqueID: "BNK-2", Amount: idr("100.0"), StatementTime: time.Date(2021, 01, 04, 0, 0, 0, 0, time.UTC)},
+				}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, gomock.Any(), gomock.Any()).Return(statements, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				gomock.InOrder(
+					suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, rec domain.MatchedRecord) (int, error) {
+						suite.Equal(1, rec.SystemTxID)
+						suite.Equal(8, rec.BankStatementID)
+						return 1, nil
+					}),
+					suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, rec domain.MatchedRecord) (int, error) {
+						suite.Equal(2, rec.SystemTxID)
+						suite.Equal(7, rec.BankStatementID)
+						return 2, nil
+					}),
+				)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(2, result.MatchedCount)
+			},
+		},
+		{
+			name: "Foreign Currency Converted Before Matching",
+			opts: domain.MatchOptions{AmountTolerance: 100, ConvertCurrency: true},
+			setupMocks: func() {
+				usdIdr, _ := money.ParseRate("15850.25")
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("1585000.00"), Type: domain.Credit, TransactionTime: startDate}}
+				statements := []domain.BankStatement{{ID: 7, UniqueID: "TX1001", Amount: money.MustParse("100.00", "USD"), StatementTime: startDate}}
+				rates := []domain.FXRate{
+					// The latest rate published on or before the record date applies
+					{RateDate: time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), BaseCurrency: "USD", QuoteCurrency: money.DefaultCurrency, Rate: usdIdr},
+				}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockFXRepo.EXPECT().FindRatesByDateRange(ctx, startDate.AddDate(0, 0, -maxFXRateAgeDays), bankEndDate).Return(rates, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, rec domain.MatchedRecord) (int, error) {
+					suite.Equal(idr("25.00"), rec.Discrepancy)
+					suite.Equal(idr("1585000.00"), rec.SystemAmount)
+					suite.Equal("1", rec.SystemFXRate.String())
+					suite.Equal(money.MustParse("100.00", "USD"), rec.BankAmount)
+					suite.Equal("15850.25", rec.BankFXRate.String())
+					suite.Equal(idr("1585025.00"), rec.BankConvertedAmount)
+					return 1, nil
+				})
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(1, result.MatchedCount)
+				suite.Equal(idr("25.00"), result.TotalDiscrepancies)
+			},
+		},
+		{
+			name: "Foreign Currency Without Conversion Stays Unmatched",
+			opts: domain.MatchOptions{AmountTolerance: 100},
+			setupMocks: func() {
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("100.00"), Type: domain.Credit, TransactionTime: startDate}}
+				statements := []domain.BankStatement{{ID: 7, UniqueID: "TX1001", Amount: money.MustParse("100.00", "USD"), StatementTime: startDate}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(1)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, txList []domain.UnmatchedBankTx) error {
+					suite.Require().Len(txList, 1)
+					suite.Equal(money.MustParse("100.00", "USD"), txList[0].Amount)
+					return nil
+				})
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(0, result.MatchedCount)
+				suite.Equal(idr("0"), result.TotalDiscrepancies)
+			},
+		},
+		{
+			name: "Historical Rows Are Opt In",
+			opts: domain.MatchOptions{IncludeHistorical: true},
+			setupMocks: func() {
+				historicalScope := domain.DataScope{WorkflowID: workflowID, IncludeHistorical: true}
+				transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("100.00"), Type: domain.Credit, TransactionTime: startDate}}
+				statements := []domain.BankStatement{{ID: 7, UniqueID: "TX1001", Amount: idr("100.00"), StatementTime: startDate}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, historicalScope, startDate, periodEnd).Return(transactions, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, historicalScope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).Return(1, nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(1, result.MatchedCount)
+			},
+		},
+		{
+			name: "Carried Forward Item Resolved",
+			setupMocks: func() {
+				carried := []domain.Transaction{{ID: 3, TrxID: "TX0999", Amount: idr("75.00"), Type: domain.Credit, TransactionTime: startDate}}
+				statements := []domain.BankStatement{{ID: 9, UniqueID: "TX0999", Amount: idr("75.00"), StatementTime: startDate}}
+				unmatched := []domain.BankStatement{{ID: 10, UniqueID: "BANK1", Amount: idr("20.00"), StatementTime: startDate}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(nil, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(append(statements, unmatched...), nil)
+				suite.mockOpenRepo.EXPECT().FindOpenSystemTx(ctx, scope, periodEnd).Return(carried, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).Return(1, nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(1)).Return(nil)
+				suite.mockOpenRepo.EXPECT().ResolveOpenItems(ctx, gomock.Any(), []int{3}, nil).Return(1, nil)
+				suite.mockOpenRepo.EXPECT().StoreOpenItems(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, items []domain.OpenItem) error {
+					suite.Require().Len(items, 1)
+					suite.Equal(domain.OpenItemBankStatement, items[0].ItemType)
+					suite.Equal(10, *items[0].BankStatementID)
+					return nil
+				})
+				suite.mockRecRepo.EXPECT().StoreResult(ctx, gomock.Any()).Return(1, nil)
+			},
+			verifyResult: func(result domain.ReconciliationResult) {
+				suite.Equal(1, result.MatchedCount)
+				suite.Equal(0, result.TotalSystemTxCount)
+				suite.Equal(1, result.UnmatchedBankCount)
+			},
+		},
+		{
+			name: "Carried Forward Item Resolved By Another Job",
+			setupMocks: func() {
+				carried := []domain.Transaction{{ID: 3, TrxID: "TX0999", Amount: idr("75.00"), Type: domain.Credit, TransactionTime: startDate}}
+				statements := []domain.BankStatement{{ID: 9, UniqueID: "TX0999", Amount: idr("75.00"), StatementTime: startDate}}
+
+				suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, scope, startDate, periodEnd).Return(nil, nil)
+				suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, scope, startDate, bankEndDate).Return(statements, nil)
+				suite.mockOpenRepo.EXPECT().FindOpenSystemTx(ctx, scope, periodEnd).Return(carried, nil)
+				suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreMatchedRecord(ctx, gomock.Any()).Return(1, nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedSystemTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockRecRepo.EXPECT().StoreUnmatchedBankTx(ctx, gomock.Len(0)).Return(nil)
+				suite.mockOpenRepo.EXPECT().ResolveOpenItems(ctx, gomock.Any(), []int{3}, nil).Return(0, repository.ErrOpenItemResolved)
+			},
+			expectedError: true,
+		},
 	}
 
 	for _, tc := range testCases {
 		suite.Run(tc.name, func() {
+			if tc.ruleSets != nil {
+				ruleSets, err := NewRuleSets(tc.ruleSets)
+				suite.Require().NoError(err)
+				suite.uc = NewReconciliationUseCase(suite.mockRecRepo, suite.mockDataRepo, suite.mockFXRepo, suite.mockOpenRepo, ruleSets, domain.ApprovalPolicy{})
+			}
 			tc.setupMocks()
-			result, err := suite.uc.ProcessReconciliation(ctx, startDate, endDate)
+			committed := suite.expectWithinTx(ctx)
+			// Without its own expectations a case starts from an empty open-items ledger
+			suite.mockOpenRepo.EXPECT().ReopenWorkflowItems(ctx, workflowID, gomock.Any()).Return(nil).AnyTimes()
+			suite.mockOpenRepo.EXPECT().FindOpenSystemTx(ctx, gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+			suite.mockOpenRepo.EXPECT().FindOpenBankStmts(ctx, gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+			suite.mockOpenRepo.EXPECT().StoreOpenItems(ctx, gomock.Any()).Return(nil).AnyTimes()
+			result, err := suite.uc.ProcessReconciliation(ctx, workflowID, startDate, endDate, tc.opts)
+			suite.Equal(!tc.expectedError, *committed)
 			if tc.expectedError {
 				suite.NotNil(err)
 			} else {
@@ -77,6 +517,394 @@ func (suite *ReconcileUseCaseSuite) TestProcessReconciliation() {
 	}
 }
 
+func (suite *ReconcileUseCaseSuite) TestProcessReconciliationCancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startDate, endDate := time.Date(2021, 01, 01, 0, 0, 0, 0, time.UTC), time.Date(2021, 01, 31, 0, 0, 0, 0, time.UTC)
+	workflowID := "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55"
+	transactions := []domain.Transaction{{ID: 1, TrxID: "TX1001", Amount: idr("100.0"), TransactionTime: startDate}}
+	statements := []domain.BankStatement{{ID: 1, UniqueID: "TX1001", Amount: idr("100.0"), StatementTime: startDate}}
+
+	// The writes made before the run is cancelled must be part of the transaction that is rolled back
+	inTx := false
+	var txErr error
+	suite.mockRecRepo.EXPECT().WithinTx(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+		inTx = true
+		txErr = fn(ctx)
+		inTx = false
+		return txErr
+	})
+	suite.mockRecRepo.EXPECT().CreateJob(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, job domain.ReconciliationJob) error {
+		suite.True(inTx)
+		return nil
+	})
+	suite.mockOpenRepo.EXPECT().ReopenWorkflowItems(ctx, workflowID, gomock.Any()).DoAndReturn(func(ctx context.Context, workflowID, jobID string) error {
+		suite.True(inTx)
+		return nil
+	})
+	suite.mockDataRepo.EXPECT().FindSystemTxByDateRange(ctx, gomock.Any(), startDate, endOfDay(endDate)).Return(transactions, nil)
+	suite.mockDataRepo.EXPECT().FindBankStmtsByDateRange(ctx, gomock.Any(), startDate, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, scope domain.DataScope, from, to time.Time) ([]domain.BankStatement, error) {
+			cancel()
+			return statements, nil
+		})
+	suite.mockOpenRepo.EXPECT().FindOpenSystemTx(ctx, gomock.Any(), gomock.Any()).Return(nil, nil)
+	suite.mockOpenRepo.EXPECT().FindOpenBankStmts(ctx, gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	// no matches, exceptions, open items or result are stored
+	_, err := suite.uc.ProcessReconciliation(ctx, workflowID, startDate, endDate, domain.MatchOptions{})
+	suite.ErrorIs(err, context.Canceled)
+	suite.ErrorIs(txErr, context.Canceled)
+}
+
+func (suite *ReconcileUseCaseSuite) TestGetReconciliationSummary() {
+	ctx := context.Background()
+	jobID := "1fa446d9-51f4-4958-bfb7-21fb5df6934b"
+
+	suite.mockRecRepo.EXPECT().GetReconciliationResult(ctx, jobID).Return(&domain.ReconciliationResult{
+		JobID: jobID, TotalSystemTxCount: 10, TotalBankTxCount: 9, MatchedCount: 7, UnmatchedSystemCount: 3, UnmatchedBankCount: 2,
+		TotalDiscrepancies: idr("1.50"),
+	}, nil)
+	suite.mockRecRepo.EXPECT().CountUnmatchedBankByBank(ctx, jobID).Return([]domain.BankExceptionCount{{BankCode: "BCA", Count: 2, Amount: idr("300.00")}}, nil)
+	suite.mockOpenRepo.EXPECT().GetOpenItems(ctx, jobID).Return([]domain.OpenItem{{DaysOutstanding: 3}, {DaysOutstanding: 45}}, nil)
+	suite.mockOpenRepo.EXPECT().CountResolvedOpenItems(ctx, jobID).Return(1, nil)
+	opening, closing, reported := idr("1000.00"), idr("1448.50"), idr("1450.00")
+	suite.mockRecRepo.EXPECT().ListAccountActivity(ctx, jobID).Return([]domain.AccountActivity{
+		{
+			BankCode: "BCA", AccountNumber: "0012345678", Currency: "IDR",
+			StatementMovements: idr("448.50"), MatchedBank: idr("148.50"), SystemMovements: idr("150.00"),
+			UnmatchedBank: idr("200.00"), WrittenOff: idr("100.00"),
+			OpeningBalance: &opening, ClosingBalance: &closing,
+		},
+		{
+			BankCode: "BNI", AccountNumber: "998877", Currency: "IDR",
+			StatementMovements: idr("448.50"), MatchedBank: idr("148.50"), SystemMovements: idr("150.00"),
+			UnmatchedBank: idr("200.00"), WrittenOff: idr("100.00"),
+			OpeningBalance: &opening, ClosingBalance: &reported,
+		},
+		{BankCode: "MANDIRI", Currency: "IDR", StatementMovements: idr("10.00"), UnmatchedBank: idr("10.00"), OpeningBalance: &opening},
+	}, nil)
+
+	summary, err := suite.uc.GetReconciliationSummary(ctx, jobID)
+	suite.NoError(err)
+	suite.Equal(10, summary.TotalTransactionsProcessed)
+	suite.Equal(2, summary.TotalUnmatchedBankTransactions)
+	suite.Equal([]domain.BankExceptionCount{{BankCode: "BCA", Count: 2, Amount: idr("300.00")}}, summary.UnmatchedBankByBank)
+	suite.Equal(2, summary.TotalOpenItems)
+	suite.Equal(1, summary.ResolvedOpenItems)
+
+	suite.Require().Len(summary.BalanceRollforward, 3)
+	balanced, unexplained, missing := summary.BalanceRollforward[0], summary.BalanceRollforward[1], summary.BalanceRollforward[2]
+	suite.Equal(idr("-1.50"), balanced.MatchDifferences)
+	suite.Equal(idr("1448.50"), *balanced.ExpectedClosing)
+	suite.True(balanced.UnexplainedDifference.IsZero())
+	suite.Equal(domain.RollforwardBalanced, balanced.Status)
+	suite.Equal(idr("1.50"), *unexplained.UnexplainedDifference)
+	suite.Equal(domain.RollforwardUnexplained, unexplained.Status)
+	suite.Equal(idr("1010.00"), *missing.ExpectedClosing)
+	suite.Nil(missing.UnexplainedDifference)
+	suite.Equal(domain.RollforwardMissingBalance, missing.Status)
+}
+
+func (suite *ReconcileUseCaseSuite) TestListUnmatchedSystemTx() {
+	ctx := context.Background()
+	jobID := "1fa446d9-51f4-4958-bfb7-21fb5df6934b"
+
+	suite.Run("Defaults Applied", func() {
+		suite.mockRecRepo.EXPECT().ListUnmatchedSystemTx(ctx, jobID, domain.ExceptionQuery{SortBy: domain.SortByDate, Limit: DefaultExceptionPageSize}).Return(nil, nil, nil)
+
+		_, _, err := suite.uc.ListUnmatchedSystemTx(ctx, jobID, domain.ExceptionQuery{})
+		suite.NoError(err)
+	})
+
+	suite.Run("Page Size Capped", func() {
+		suite.mockRecRepo.EXPECT().ListUnmatchedSystemTx(ctx, jobID, domain.ExceptionQuery{SortBy: domain.SortByAmount, Desc: true, Limit: MaxExceptionPageSize}).Return(nil, nil, nil)
+
+		_, _, err := suite.uc.ListUnmatchedSystemTx(ctx, jobID, domain.ExceptionQuery{SortBy: domain.SortByAmount, Desc: true, Limit: 100000})
+		suite.NoError(err)
+	})
+}
+
+func (suite *ReconcileUseCaseSuite) TestManualMatch() {
+	ctx := contextprop.SetValue(context.Background(), contextprop.ClientIDKey, "ops-user")
+	jobID := "1fa446d9-51f4-4958-bfb7-21fb5df6934b"
+	result := domain.ReconciliationResult{JobID: jobID, TotalDiscrepancies: idr("0")}
+	systemItem := domain.UnmatchedSystemTx{ID: 1, JobID: jobID, SystemTxID: 11, TrxID: "TX1", Amount: idr("150.00"), Type: domain.Debit}
+	bankItems := []domain.UnmatchedBankTx{
+		{ID: 2, JobID: jobID, BankStatementID: 21, UniqueID: "B1", Amount: idr("-100.00")},
+		{ID: 3, JobID: jobID, BankStatementID: 22, UniqueID: "B2", Amount: idr("-49.00")},
+	}
+
+	suite.Run("Split Across Bank Lines", func() {
+		suite.mockRecRepo.EXPECT().GetReconciliationResult(ctx, jobID).Return(&result, nil)
+		suite.mockRecRepo.EXPECT().GetUnmatchedItems(ctx, jobID, []int{1}, []int{2, 3}).Return([]domain.UnmatchedSystemTx{systemItem}, bankItems, nil)
+		suite.mockRecRepo.EXPECT().ApplyManualMatch(ctx, domain.ManualMatch{
+			JobID:       jobID,
+			SystemItem:  systemItem,
+			BankItems:   bankItems,
+			Discrepancy: idr("1.00"),
+			Actor:       "ops-user",
+			Reason:      "split payment",
+		}).Return(domain.ManualResolution{AuditID: 7, GroupID: 5}, nil)
+
+		resolution, err := suite.uc.ManualMatch(ctx, jobID, 1, []int{2, 3}, "  split payment ")
+		suite.NoError(err)
+		suite.Equal(int64(7), resolution.AuditID)
+	})
+
+	suite.Run("No Actor", func() {
+		_, err := suite.uc.ManualMatch(context.Background(), jobID, 1, []int{2}, "split payment")
+		suite.ErrorIs(err, ErrNoActor)
+	})
+
+	suite.Run("Reason Required", func() {
+		_, err := suite.uc.ManualMatch(ctx, jobID, 1, []int{2}, " ")
+		suite.ErrorIs(err, ErrInvalidResolution)
+	})
+
+	suite.Run("Bank Line Listed Twice", func() {
+		_, err := suite.uc.ManualMatch(ctx, jobID, 1, []int{2, 2}, "split payment")
+		suite.ErrorIs(err, ErrInvalidResolution)
+	})
+
+	suite.Run("Exception Not Found", func() {
+		suite.mockRecRepo.EXPECT().GetReconciliationResult(ctx, jobID).Return(&result, nil)
+		suite.mockRecRepo.EXPECT().GetUnmatchedItems(ctx, jobID, []int{1}, []int{2, 4}).Return([]domain.UnmatchedSystemTx{systemItem}, bankItems[:1], nil)
+
+		_, err := suite.uc.ManualMatch(ctx, jobID, 1, []int{2, 4}, "split payment")
+		suite.ErrorIs(err, repository.ErrExceptionNotFound)
+	})
+
+	suite.Run("Other Currency", func() {
+		usd := domain.UnmatchedBankTx{ID: 2, JobID: jobID, BankStatementID: 21, Amount: money.MustParse("-150.00", "USD")}
+		suite.mockRecRepo.EXPECT().GetReconciliationResult(ctx, jobID).Return(&result, nil)
+		suite.mockRecRepo.EXPECT().GetUnmatchedItems(ctx, jobID, []int{1}, []int{2}).Return([]domain.UnmatchedSystemTx{systemItem}, []domain.UnmatchedBankTx{usd}, nil)
+
+		_, err := suite.uc.ManualMatch(ctx, jobID, 1, []int{2}, "split payment")
+		suite.ErrorIs(err, ErrInvalidResolution)
+	})
+}
+
+func (suite *ReconcileUseCaseSuite) TestUnmatch() {
+	ctx := contextprop.SetValue(context.Background(), contextprop.ClientIDKey, "ops-user")
+	jobID := "1fa446d9-51f4-4958-bfb7-21fb5df6934b"
+
+	suite.Run("Matched Record", func() {
+		suite.mockRecRepo.EXPECT().ApplyUnmatch(ctx, domain.Unmatch{JobID: jobID, MatchID: 9, Actor: "ops-user", Reason: "wrong pair"}).
+			Return(domain.ManualResolution{AuditID: 3}, nil)
+
+		_, err := suite.uc.Unmatch(ctx, jobID, 9, "wrong pair")
+		suite.NoError(err)
+	})
+
+	suite.Run("Match Not Found", func() {
+		suite.mockRecRepo.EXPECT().ApplyUnmatch(ctx, domain.Unmatch{JobID: jobID, GroupID: 4, Actor: "ops-user", Reason: "wrong group"}).
+			Return(domain.ManualResolution{}, repository.ErrMatchNotFound)
+
+		_, err := suite.uc.UnmatchGroup(ctx, jobID, 4, "wrong group")
+		suite.ErrorIs(err, repository.ErrMatchNotFound)
+	})
+}
+
+func (suite *ReconcileUseCaseSuite) TestWriteOff() {
+	ctx := contextprop.SetValue(context.Background(), contextprop.ClientIDKey, "ops-user")
+	jobID := "1fa446d9-51f4-4958-bfb7-21fb5df6934b"
+	statementDate := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	suite.Run("Bank Fee", func() {
+		stmtID := 21
+		suite.mockRecRepo.EXPECT().GetUnmatchedItems(ctx, jobID, nil, []int{2}).Return(nil, []domain.UnmatchedBankTx{
+			{ID: 2, JobID: jobID, BankStatementID: stmtID, UniqueID: "FEE-1", Amount: idr("-6.50"), StatementDate: statementDate},
+		}, nil)
+		suite.mockRecRepo.EXPECT().ApplyWriteOff(ctx, domain.WriteOff{
+			JobID:           jobID,
+			ItemType:        domain.OpenItemBankStatement,
+			ExceptionID:     2,
+			BankStatementID: &stmtID,
+			Reference:       "FEE-1",
+			Amount:          idr("-6.50"),
+			ItemDate:        statementDate,
+			ReasonCode:      domain.WriteOffBankFee,
+			Note:            "monthly fee",
+			WrittenOffBy:    "ops-user",
+		}).Return(domain.ManualResolution{AuditID: 4, WriteOffID: 1}, nil)
+
+		resolution, err := suite.uc.WriteOff(ctx, jobID, domain.OpenItemBankStatement, 2, "bank_fee", "monthly fee")
+		suite.NoError(err)
+		suite.Equal(1, resolution.WriteOffID)
+	})
+
+	suite.Run("Unknown Reason Code", func() {
+		_, err := suite.uc.WriteOff(ctx, jobID, domain.OpenItemBankStatement, 2, "LOST", "")
+		suite.ErrorIs(err, ErrInvalidResolution)
+	})
+
+	suite.Run("Unknown Item Type", func() {
+		_, err := suite.uc.WriteOff(ctx, jobID, "JOURNAL", 2, domain.WriteOffOther, "")
+		suite.ErrorIs(err, ErrInvalidResolution)
+	})
+}
+
+func (suite *ReconcileUseCaseSuite) TestApproval() {
+	maker := contextprop.SetValue(context.Background(), contextprop.ClientIDKey, "ops-user")
+	checker := contextprop.SetValue(context.Background(), contextprop.ClientIDKey, "supervisor")
+	jobID := "1fa446d9-51f4-4958-bfb7-21fb5df6934b"
+	statementDate := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	withThreshold := func() {
+		ruleSets, err := NewRuleSets(nil)
+		suite.Require().NoError(err)
+		suite.uc = NewReconciliationUseCase(suite.mockRecRepo, suite.mockDataRepo, suite.mockFXRepo, suite.mockOpenRepo, ruleSets,
+			domain.ApprovalPolicy{Threshold: 1000})
+	}
+	bankItem := domain.UnmatchedBankTx{ID: 2, JobID: jobID, BankStatementID: 21, UniqueID: "LOSS-1", Amount: idr("-5000"), StatementDate: statementDate}
+	pending := domain.Proposal{
+		ID:          7,
+		JobID:       jobID,
+		Action:      domain.AuditWriteOff,
+		ItemType:    domain.OpenItemBankStatement,
+		ExceptionID: 2,
+		ReasonCode:  domain.WriteOffOther,
+		Reason:      "unrecoverable",
+		Amount:      idr("-5000"),
+		Status:      domain.ProposalPending,
+		ProposedBy:  "ops-user",
+	}
+
+	suite.Run("Write-off Over Threshold Is Proposed", func() {
+		withThreshold()
+		suite.mockRecRepo.EXPECT().GetUnmatchedItems(maker, jobID, nil, []int{2}).Return(nil, []domain.UnmatchedBankTx{bankItem}, nil)
+		proposal := pending
+		proposal.ID, proposal.Status = 0, ""
+		suite.mockRecRepo.EXPECT().CreateProposal(maker, proposal).Return(pending, nil)
+
+		resolution, err := suite.uc.WriteOff(maker, jobID, domain.OpenItemBankStatement, 2, domain.WriteOffOther, "unrecoverable")
+		suite.NoError(err)
+		suite.Require().NotNil(resolution.Proposal)
+		suite.Equal(7, resolution.Proposal.ID)
+		suite.Zero(resolution.WriteOffID)
+	})
+
+	suite.Run("Write-off Under Threshold Is Applied", func() {
+		withThreshold()
+		small := bankItem
+		small.Amount = idr("-6.50")
+		suite.mockRecRepo.EXPECT().GetUnmatchedItems(maker, jobID, nil, []int{2}).Return(nil, []domain.UnmatchedBankTx{small}, nil)
+		suite.mockRecRepo.EXPECT().ApplyWriteOff(maker, gomock.Any()).Return(domain.ManualResolution{AuditID: 4, WriteOffID: 1}, nil)
+
+		resolution, err := suite.uc.WriteOff(maker, jobID, domain.OpenItemBankStatement, 2, domain.WriteOffBankFee, "")
+		suite.NoError(err)
+		suite.Nil(resolution.Proposal)
+		suite.Equal(1, resolution.WriteOffID)
+	})
+
+	suite.Run("Approve Applies Proposal", func() {
+		withThreshold()
+		stmtID := 21
+		suite.mockRecRepo.EXPECT().GetProposal(checker, jobID, 7).Return(pending, nil)
+		suite.mockRecRepo.EXPECT().GetUnmatchedItems(checker, jobID, nil, []int{2}).Return(nil, []domain.UnmatchedBankTx{bankItem}, nil)
+		suite.mockRecRepo.EXPECT().ApplyWriteOff(checker, domain.WriteOff{
+			JobID:           jobID,
+			ItemType:        domain.OpenItemBankStatement,
+			ExceptionID:     2,
+			BankStatementID: &stmtID,
+			Reference:       "LOSS-1",
+			Amount:          idr("-5000"),
+			ItemDate:        statementDate,
+			ReasonCode:      domain.WriteOffOther,
+			Note:            "unrecoverable",
+			WrittenOffBy:    "ops-user",
+			Approval:        &domain.ProposalDecision{ProposalID: 7, JobID: jobID, DecidedBy: "supervisor", Note: "checked"},
+		}).Return(domain.ManualResolution{AuditID: 9, WriteOffID: 3}, nil)
+
+		resolution, err := suite.uc.ApproveProposal(checker, jobID, 7, " checked ")
+		suite.NoError(err)
+		suite.Equal(3, resolution.WriteOffID)
+	})
+
+	suite.Run("Proposer Cannot Approve", func() {
+		suite.mockRecRepo.EXPECT().GetProposal(maker, jobID, 7).Return(pending, nil)
+
+		_, err := suite.uc.ApproveProposal(maker, jobID, 7, "")
+		suite.ErrorIs(err, ErrSelfApproval)
+	})
+
+	suite.Run("Decided Proposal", func() {
+		rejected := pending
+		rejected.Status = domain.ProposalRejected
+		suite.mockRecRepo.EXPECT().GetProposal(checker, jobID, 7).Return(rejected, nil)
+
+		_, err := suite.uc.ApproveProposal(checker, jobID, 7, "")
+		suite.ErrorIs(err, repository.ErrProposalNotPending)
+	})
+
+	suite.Run("Reject", func() {
+		decision := domain.ProposalDecision{ProposalID: 7, JobID: jobID, DecidedBy: "supervisor", Note: "recover it from the bank"}
+		rejected := pending
+		rejected.Status, rejected.DecidedBy, rejected.DecisionNote = domain.ProposalRejected, "supervisor", decision.Note
+		suite.mockRecRepo.EXPECT().GetProposal(checker, jobID, 7).Return(pending, nil)
+		suite.mockRecRepo.EXPECT().RejectProposal(checker, decision).Return(rejected, nil)
+
+		proposal, err := suite.uc.RejectProposal(checker, jobID, 7, decision.Note)
+		suite.NoError(err)
+		suite.Equal(domain.ProposalRejected, proposal.Status)
+	})
+
+	suite.Run("Reject Without Reason", func() {
+		suite.mockRecRepo.EXPECT().GetProposal(checker, jobID, 7).Return(pending, nil)
+
+		_, err := suite.uc.RejectProposal(checker, jobID, 7, " ")
+		suite.ErrorIs(err, ErrInvalidResolution)
+	})
+}
+
+func TestExceptionCursor(t *testing.T) {
+	cursor := domain.ExceptionCursor{SortBy: domain.SortByReference, Value: "TRX|001", ID: 42}
+
+	decoded, err := DecodeExceptionCursor(EncodeExceptionCursor(cursor), domain.SortByReference)
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+
+	// a cursor only continues the list in the order it was handed out for
+	_, err = DecodeExceptionCursor(EncodeExceptionCursor(cursor), domain.SortByAmount)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
 func TestReconcileUseCaseSuite(t *testing.T) {
 	suite.Run(t, new(ReconcileUseCaseSuite))
 }
+
+func TestOptimalPairsSolvesLargeComponentsInSlices(t *testing.T) {
+	ruleSets, err := NewRuleSets(nil)
+	assert.NoError(t, err)
+	// The three transactions share BNK-1 and form one component, too large for one solve at a size of two.
+	// Greedy first-fit gives BNK-1 to TX1001 and leaves TX1002 without a candidate.
+	transactions := sortedTransactions([]domain.Transaction{
+		{ID: 1, TrxID: "TX1001", Amount: idr("100.0"), Type: domain.Credit, TransactionTime: time.Date(2021, 01, 05, 9, 0, 0, 0, time.UTC)},
+		{ID: 2, TrxID: "TX1002", Amount: idr("100.0"), Type: domain.Credit, TransactionTime: time.Date(2021, 01, 06, 9, 0, 0, 0, time.UTC)},
+		{ID: 3, TrxID: "TX1003", Amount: idr("140.0"), Type: domain.Credit, TransactionTime: time.Date(2021, 01, 06, 10, 0, 0, 0, time.UTC)},
+	})
+	statements := sortedStatements([]domain.BankStatement{
+		{ID: 7, UniqueID: "BNK-1", Amount: idr("100.0"), StatementTime: time.Date(2021, 01, 05, 0, 0, 0, 0, time.UTC)},
+		{ID: 8, UniqueID: "BNK-2", Amount: idr("100.0"), StatementTime: time.Date(2021, 01, 04, 0, 0, 0, 0, time.UTC)},
+		{ID: 9, UniqueID: "BNK-3", Amount: idr("180.0"), StatementTime: time.Date(2021, 01, 06, 0, 0, 0, 0, time.UTC)},
+	})
+	m := newPassMatcher(ruleSets, transactions, statements,
+		domain.MatchOptions{DateWindowDays: 1, AmountTolerance: 50, AssignmentMode: domain.AssignmentOptimal})
+	m.maxComponentSize = 2
+
+	matched := make(map[int]int)
+	for _, pair := range m.optimalPairs(1) { // the amount_date pass of the default rule set
+		matched[transactions[pair.tx].ID] = statements[pair.stmt].ID
+	}
+	assert.Equal(t, map[int]int{1: 8, 2: 7, 3: 9}, matched)
+}
+
+func TestOpenItemsAgeing(t *testing.T) {
+	items := []domain.OpenItem{{DaysOutstanding: 0}, {DaysOutstanding: 30}, {DaysOutstanding: 31}, {DaysOutstanding: 91}, {DaysOutstanding: 400}}
+	assert.Equal(t, []domain.AgeingBucket{
+		{Label: "0-30", Count: 2},
+		{Label: "31-60", Count: 1},
+		{Label: "61-90", Count: 0},
+		{Label: "90+", Count: 2},
+	}, openItemsAgeing(items))
+}
@@ -0,0 +1,274 @@
+package reconcile
+
+import (
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"strings"
+)
+
+const (
+	RuleReference       = "exact_reference"
+	RuleExactAmount     = "exact_amount"
+	RuleAmountTolerance = "amount_tolerance"
+	RuleSameDate        = "same_date"
+	RuleDateWindow      = "date_window"
+
+	DefaultRuleSetName = "default"
+)
+
+// MatchRule is a single criterion a bank statement has to meet to be paired with a system transaction
+type MatchRule interface {
+	Name() string
+	Matches(tx domain.Transaction, stmt domain.BankStatement, opts domain.MatchOptions) bool
+}
+
+// windowedRule is implemented by rules that only accept statements within a number of business days,
+// which lets a pass look candidates up by date instead of scanning every statement
+type windowedRule interface {
+	WindowDays(opts domain.MatchOptions) int
+}
+
+type RuleFactory func(def domain.MatchRuleDefinition) (MatchRule, error)
+
+var ruleRegistry = map[string]RuleFactory{}
+
+func RegisterRule(ruleType string, factory RuleFactory) {
+	ruleRegistry[ruleType] = factory
+}
+
+func init() {
+	RegisterRule(RuleReference, func(def domain.MatchRuleDefinition) (MatchRule, error) {
+		return referenceRule{}, nil
+	})
+	RegisterRule(RuleExactAmount, func(def domain.MatchRuleDefinition) (MatchRule, error) {
+		return exactAmountRule{}, nil
+	})
+	RegisterRule(RuleAmountTolerance, func(def domain.MatchRuleDefinition) (MatchRule, error) {
+		if (def.AmountTolerance != nil && *def.AmountTolerance < 0) || (def.AmountTolerancePercent != nil && *def.AmountTolerancePercent < 0) {
+			return nil, fmt.Errorf("amount tolerance must not be negative")
+		}
+		return amountToleranceRule{absolute: def.AmountTolerance, percent: def.AmountTolerancePercent}, nil
+	})
+	RegisterRule(RuleSameDate, func(def domain.MatchRuleDefinition) (MatchRule, error) {
+		return sameDateRule{}, nil
+	})
+	RegisterRule(RuleDateWindow, func(def domain.MatchRuleDefinition) (MatchRule, error) {
+		if def.DateWindowDays != nil && *def.DateWindowDays < 0 {
+			return nil, fmt.Errorf("date window must not be negative")
+		}
+		return dateWindowRule{days: def.DateWindowDays}, nil
+	})
+}
+
+// referenceRule matches when one side's reference contains the other's
+type referenceRule struct{}
+
+func (referenceRule) Name() string { return RuleReference }
+
+func (referenceRule) Matches(tx domain.Transaction, stmt domain.BankStatement, _ domain.MatchOptions) bool {
+	if tx.TrxID == "" || stmt.UniqueID == "" {
+		return false
+	}
+	return strings.Contains(stmt.UniqueID, tx.TrxID) || strings.Contains(tx.TrxID, stmt.UniqueID)
+}
+
+type exactAmountRule struct{}
+
+func (exactAmountRule) Name() string { return RuleExactAmount }
+
+func (exactAmountRule) Matches(tx domain.Transaction, stmt domain.BankStatement, _ domain.MatchOptions) bool {
+	return calculateDiscrepancy(expectedBankAmount(tx), stmt.Amount).IsZero()
+}
+
+// amountToleranceRule uses the workflow tolerances unless the rule definition overrides them
+type amountToleranceRule struct {
+	absolute *float64
+	percent  *float64
+}
+
+func (amountToleranceRule) Name() string { return RuleAmountTolerance }
+
+func (r amountToleranceRule) Matches(tx domain.Transaction, stmt domain.BankStatement, opts domain.MatchOptions) bool {
+	if r.absolute != nil {
+		opts.AmountTolerance = *r.absolute
+	}
+	if r.percent != nil {
+		opts.AmountTolerancePercent = *r.percent
+	}
+	expectedAmount := expectedBankAmount(tx)
+	return calculateDiscrepancy(expectedAmount, stmt.Amount).Cmp(opts.AllowedDiscrepancy(expectedAmount)) <= 0
+}
+
+type sameDateRule struct{}
+
+func (sameDateRule) Name() string { return RuleSameDate }
+
+func (sameDateRule) Matches(tx domain.Transaction, stmt domain.BankStatement, _ domain.MatchOptions) bool {
+	return dateKey(tx.TransactionTime) == dateKey(stmt.StatementTime)
+}
+
+func (sameDateRule) WindowDays(domain.MatchOptions) int { return 0 }
+
+// dateWindowRule uses the workflow date window unless the rule definition overrides it
+type dateWindowRule struct {
+	days *int
+}
+
+func (dateWindowRule) Name() string { return RuleDateWindow }
+
+func (r dateWindowRule) Matches(tx domain.Transaction, stmt domain.BankStatement, opts domain.MatchOptions) bool {
+	from, to := businessDayWindow(tx.TransactionTime, r.WindowDays(opts))
+	day := truncateDay(stmt.StatementTime)
+	return !day.Before(from) && !day.After(to)
+}
+
+func (r dateWindowRule) WindowDays(opts domain.MatchOptions) int {
+	if r.days != nil {
+		return *r.days
+	}
+	return opts.DateWindowDays
+}
+
+// matchPass accepts a pair only when every one of its rules does
+type matchPass struct {
+	name  string
+	rules []MatchRule
+}
+
+func (p matchPass) matches(tx domain.Transaction, stmt domain.BankStatement, opts domain.MatchOptions) bool {
+	for _, rule := range p.rules {
+		if !rule.Matches(tx, stmt, opts) {
+			return false
+		}
+	}
+	return true
+}
+
+// windowDays returns the narrowest date window enforced by the pass, bounded is false when no rule limits the date
+func (p matchPass) windowDays(opts domain.MatchOptions) (days int, bounded bool) {
+	for _, rule := range p.rules {
+		if w, ok := rule.(windowedRule); ok {
+			if d := w.WindowDays(opts); !bounded || d < days {
+				days, bounded = d, true
+			}
+		}
+	}
+	return days, bounded
+}
+
+type ruleSet struct {
+	name   string
+	passes []matchPass
+}
+
+// RuleSets resolves the ordered matching passes to apply to the statements of a bank
+type RuleSets struct {
+	byBankCode map[string]ruleSet
+	fallback   ruleSet
+}
+
+// DefaultRuleSet first pairs on reference, then on amount and date alone, both within the workflow tolerances
+func DefaultRuleSet() domain.MatchRuleSet {
+	return domain.MatchRuleSet{
+		Name: DefaultRuleSetName,
+		Passes: []domain.MatchPassDefinition{
+			{Name: "reference", Rules: []domain.MatchRuleDefinition{{Type: RuleReference}, {Type: RuleDateWindow}, {Type: RuleAmountTolerance}}},
+			{Name: "amount_date", Rules: []domain.MatchRuleDefinition{{Type: RuleDateWindow}, {Type: RuleAmountTolerance}}},
+		},
+	}
+}
+
+// NewRuleSets compiles rule set definitions. A definition without a bank code replaces the default
+// rule set used for banks that have none of their own.
+func NewRuleSets(defs []domain.MatchRuleSet) (*RuleSets, error) {
+	fallback, err := compileRuleSet(DefaultRuleSet())
+	if err != nil {
+		return nil, err
+	}
+	sets := &RuleSets{byBankCode: make(map[string]ruleSet), fallback: fallback}
+	for _, def := range defs {
+		compiled, err := compileRuleSet(def)
+		if err != nil {
+			return nil, err
+		}
+		if def.BankCode == "" {
+			sets.fallback = compiled
+			continue
+		}
+		if _, exists := sets.byBankCode[def.BankCode]; exists {
+			return nil, fmt.Errorf("duplicate rule set for bank code %s", def.BankCode)
+		}
+		sets.byBankCode[def.BankCode] = compiled
+	}
+	return sets, nil
+}
+
+func compileRuleSet(def domain.MatchRuleSet) (ruleSet, error) {
+	if len(def.Passes) == 0 {
+		return ruleSet{}, fmt.Errorf("rule set %s has no passes", def.Name)
+	}
+	compiled := ruleSet{name: def.Name}
+	for i, passDef := range def.Passes {
+		if len(passDef.Rules) == 0 {
+			return ruleSet{}, fmt.Errorf("rule set %s pass %d has no rules", def.Name, i+1)
+		}
+		pass := matchPass{name: passDef.Name}
+		if pass.name == "" {
+			pass.name = fmt.Sprintf("pass_%d", i+1)
+		}
+		for _, ruleDef := range passDef.Rules {
+			factory, ok := ruleRegistry[ruleDef.Type]
+			if !ok {
+				return ruleSet{}, fmt.Errorf("rule set %s: unknown rule type %q", def.Name, ruleDef.Type)
+			}
+			rule, err := factory(ruleDef)
+			if err != nil {
+				return ruleSet{}, fmt.Errorf("rule set %s: rule %s: %w", def.Name, ruleDef.Type, err)
+			}
+			pass.rules = append(pass.rules, rule)
+		}
+		compiled.passes = append(compiled.passes, pass)
+	}
+	return compiled, nil
+}
+
+func (r *RuleSets) forBank(bankCode string) ruleSet {
+	if set, ok := r.byBankCode[bankCode]; ok {
+		return set
+	}
+	return r.fallback
+}
+
+// passWindow returns the date window used to look candidates up for the n-th pass (zero based)
+// across all rule sets, bounded is false when any of those passes does not restrict the date
+func (r *RuleSets) passWindow(n int, opts domain.MatchOptions) (days int, bounded bool) {
+	sets := []ruleSet{r.fallback}
+	for _, set := range r.byBankCode {
+		sets = append(sets, set)
+	}
+	bounded = true
+	for _, set := range sets {
+		if n >= len(set.passes) {
+			continue
+		}
+		d, ok := set.passes[n].windowDays(opts)
+		if !ok {
+			return 0, false
+		}
+		if d > days {
+			days = d
+		}
+	}
+	return days, bounded
+}
+
+// maxPasses returns the number of passes of the longest rule set in use for the given statements
+func (r *RuleSets) maxPasses(stmts []domain.BankStatement) int {
+	maxPasses := 0
+	for _, stmt := range stmts {
+		if n := len(r.forBank(stmt.BankCode).passes); n > maxPasses {
+			maxPasses = n
+		}
+	}
+	return maxPasses
+}
@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidCursor is returned for a cursor that was not handed out by ListWorkflows
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListWorkflows returns one page of the workflows matching the filter, newest first.
+// One workflow more than the page size is read to know whether another page follows.
+func (uc *workflowUseCase) ListWorkflows(ctx context.Context, filter domain.WorkflowFilter) (domain.WorkflowPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	pageSize := filter.Limit
+	filter.Limit++
+
+	items, err := uc.workflowRepo.ListWorkflows(ctx, filter)
+	if err != nil {
+		return domain.WorkflowPage{}, fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	page := domain.WorkflowPage{Items: items}
+	if len(items) > pageSize {
+		page.Items = items[:pageSize]
+		last := page.Items[pageSize-1]
+		page.Next = &domain.WorkflowCursor{CreatedAt: last.CreatedAt, WorkflowID: last.WorkflowID}
+	}
+	return page, nil
+}
+
+// EncodeCursor turns the position of the last workflow of a page into an opaque token
+func EncodeCursor(cursor domain.WorkflowCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.WorkflowID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor reads a token made by EncodeCursor
+func DecodeCursor(token string) (domain.WorkflowCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return domain.WorkflowCursor{}, ErrInvalidCursor
+	}
+	createdAt, workflowID, ok := strings.Cut(string(raw), "|")
+	if !ok || workflowID == "" {
+		return domain.WorkflowCursor{}, ErrInvalidCursor
+	}
+	at, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return domain.WorkflowCursor{}, ErrInvalidCursor
+	}
+	return domain.WorkflowCursor{CreatedAt: at, WorkflowID: workflowID}, nil
+}
@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/lease"
+	"time"
+)
+
+// WorkerWorkflowLoop advances the active workflows one step at a time until ctx is cancelled.
+// A workflow is leased to this worker while it is advanced so concurrent workers never run the same step,
+// workflows left PENDING, INGESTING or RECONCILING by a stopped worker are picked up once their lease expires.
+func WorkerWorkflowLoop(ctx context.Context, uc IUseCase, interval time.Duration, leaseOpts lease.Options) {
+	leaseOpts = leaseOpts.WithDefaults()
+	for {
+		advanceActiveWorkflows(ctx, uc, leaseOpts)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// advanceActiveWorkflows gives every active workflow one step. Workflows are claimed one at a time,
+// a long reconciliation must not let the lease of a workflow waiting in line expire. Released workflows
+// are claimed last, so the pass is over once a workflow advanced in it is claimed again.
+func advanceActiveWorkflows(ctx context.Context, uc IUseCase, leaseOpts lease.Options) {
+	advanced := make(map[string]bool)
+	for ctx.Err() == nil {
+		workflows, err := uc.ClaimActiveWorkflows(ctx, leaseOpts.WorkerID, 1, leaseOpts.Duration)
+		if err != nil {
+			fmt.Printf("ClaimActiveWorkflows error: %v\n", err)
+			return
+		}
+		if len(workflows) == 0 {
+			return
+		}
+
+		wf := workflows[0]
+		if advanced[wf.WorkflowID] {
+			releaseWorkflow(ctx, uc, wf.WorkflowID, leaseOpts.WorkerID)
+			return
+		}
+		advanced[wf.WorkflowID] = true
+
+		leaseCtx, cancel := lease.KeepAlive(ctx, leaseOpts, func(ctx context.Context) (bool, error) {
+			return uc.HeartbeatWorkflow(ctx, wf.WorkflowID, leaseOpts.WorkerID, leaseOpts.Duration)
+		})
+		if err := uc.AdvanceWorkflow(leaseCtx, wf); err != nil {
+			fmt.Printf("[workflow] error advancing workflow=%s status=%s: %v\n", wf.WorkflowID, wf.Status, err)
+		}
+		cancel()
+		releaseWorkflow(ctx, uc, wf.WorkflowID, leaseOpts.WorkerID)
+	}
+}
+
+func releaseWorkflow(ctx context.Context, uc IUseCase, workflowID, workerID string) {
+	if err := uc.ReleaseWorkflow(ctx, workflowID, workerID); err != nil {
+		fmt.Printf("ReleaseWorkflow error for workflow=%s: %v\n", workflowID, err)
+	}
+}
@@ -2,6 +2,7 @@ package workflow
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
 	enum_parser "github.com/ardianferdianto/reconciliation-service/internal/domain/enum/parser"
@@ -9,25 +10,38 @@ import (
 	"github.com/ardianferdianto/reconciliation-service/internal/repository"
 	"github.com/ardianferdianto/reconciliation-service/internal/usecase/ingestion"
 	"github.com/ardianferdianto/reconciliation-service/internal/usecase/reconcile"
+	"github.com/ardianferdianto/reconciliation-service/pkg/contextprop"
 	"github.com/google/uuid"
-	"sync"
+	"log/slog"
 	"time"
 )
 
 type IUseCase interface {
-	StartWorkflow(ctx context.Context, sysFile string, bankFiles []string, startDate, endDate time.Time) (string, error)
-	OnSystemIngestionComplete(ctx context.Context, workflowID, jobID string, success bool) error
-	OnBankIngestionComplete(ctx context.Context, workflowID, jobID string, success bool) error
-	OnReconciliationComplete(ctx context.Context, workflowID, jobID string, success bool) error
+	StartWorkflow(ctx context.Context, sysFile string, bankFiles []string, fileProfiles map[string]string, startDate, endDate time.Time, opts domain.MatchOptions) (string, error)
+	ClaimActiveWorkflows(ctx context.Context, workerID string, limit int, lease time.Duration) ([]domain.Workflow, error)
+	HeartbeatWorkflow(ctx context.Context, workflowID, workerID string, lease time.Duration) (bool, error)
+	ReleaseWorkflow(ctx context.Context, workflowID, workerID string) error
+	AdvanceWorkflow(ctx context.Context, wf domain.Workflow) error
 	GetWorkflowSummary(ctx context.Context, workflowID string) (*domain.Workflow, error)
+	ListWorkflows(ctx context.Context, filter domain.WorkflowFilter) (domain.WorkflowPage, error)
+	GetWorkflowTransitions(ctx context.Context, workflowID string) ([]domain.WorkflowTransition, error)
+	RequeueIngestionJob(ctx context.Context, jobID string) (domain.IngestionJob, error)
+	CancelWorkflow(ctx context.Context, workflowID string) (domain.Workflow, error)
+	RerunWorkflow(ctx context.Context, workflowID string, opts domain.MatchOptions) (domain.Workflow, error)
+	CloneWorkflow(ctx context.Context, sourceID, sysFile string, bankFiles []string, fileProfiles map[string]string, startDate, endDate time.Time, opts domain.MatchOptions) (string, error)
 }
 
+var (
+	// ErrWorkflowNotActive is returned when cancelling a workflow that already finished
+	ErrWorkflowNotActive = errors.New("workflow is not active")
+	// ErrWorkflowNotRerunnable is returned when re-running a workflow that is still active or whose files are not all ingested
+	ErrWorkflowNotRerunnable = errors.New("workflow cannot be re-run")
+)
+
 type workflowUseCase struct {
 	workflowRepo repository.WorkflowRepository
 	ingestionUC  ingestion.IUseCase
 	reconcileUC  reconcile.IUseCase
-	mu           sync.Mutex // Mutex to handle concurrent updates
-	wg           sync.WaitGroup
 }
 
 func NewWorkflowUseCase(
@@ -50,164 +64,313 @@ func (uc *workflowUseCase) GetWorkflowSummary(ctx context.Context, workflowID st
 	return &wf, nil
 }
 
+func (uc *workflowUseCase) GetWorkflowTransitions(ctx context.Context, workflowID string) ([]domain.WorkflowTransition, error) {
+	transitions, err := uc.workflowRepo.GetTransitions(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving workflow transitions: %w", err)
+	}
+	return transitions, nil
+}
+
+// StartWorkflow checks the uploaded files exist and stores the workflow as PENDING,
+// the worker creates the ingestion jobs and runs the reconciliation.
+// fileProfiles selects the parser profile of a file by its path, files left out use the built-in parser.
 func (uc *workflowUseCase) StartWorkflow(
 	ctx context.Context,
 	sysFile string,
 	bankFiles []string,
+	fileProfiles map[string]string,
 	startDate, endDate time.Time,
+	opts domain.MatchOptions,
 ) (string, error) {
+	return uc.createWorkflow(ctx, "", sysFile, bankFiles, fileProfiles, startDate, endDate, opts)
+}
 
-	workflowID := uuid.New().String()
-
-	wf := domain.Workflow{
-		WorkflowID: workflowID,
-		Status:     enum_status.IN_PROGRESS.String(),
-		StartDate:  startDate,
-		EndDate:    endDate,
-	}
+// CloneWorkflow starts a new workflow from the files, period and options given for a copy of sourceID
+func (uc *workflowUseCase) CloneWorkflow(
+	ctx context.Context,
+	sourceID, sysFile string,
+	bankFiles []string,
+	fileProfiles map[string]string,
+	startDate, endDate time.Time,
+	opts domain.MatchOptions,
+) (string, error) {
+	return uc.createWorkflow(ctx, sourceID, sysFile, bankFiles, fileProfiles, startDate, endDate, opts)
+}
 
-	if err := uc.workflowRepo.CreateWorkflow(ctx, wf); err != nil {
-		return "", fmt.Errorf("failed to create workflow: %w", err)
+func (uc *workflowUseCase) createWorkflow(
+	ctx context.Context,
+	sourceID, sysFile string,
+	bankFiles []string,
+	fileProfiles map[string]string,
+	startDate, endDate time.Time,
+	opts domain.MatchOptions,
+) (string, error) {
+	// profiles are stored by the object key of their file, the key ingestion reads the file with
+	profiles := make(map[string]string, len(fileProfiles))
+	selectProfile := func(file, key, fileType string) error {
+		profile := fileProfiles[file]
+		if profile == "" {
+			return nil
+		}
+		if err := uc.ingestionUC.CheckParserProfile(ctx, profile, fileType); err != nil {
+			return fmt.Errorf("file %s: %w", file, err)
+		}
+		profiles[key] = profile
+		return nil
 	}
 
 	sysObjInfo, err := uc.ingestionUC.FetchFileMetadata(ctx, sysFile)
 	if err != nil {
-		wf.Status = enum_status.FAILED.String()
-		uc.workflowRepo.UpdateWorkflow(ctx, wf)
 		return "", fmt.Errorf("failed to fetch system transaction file metadata: %w", err)
 	}
-
-	sysJob := &domain.IngestionJob{
-		JobID:    uuid.New().String(),
-		FileType: enum_parser.SYSTEM_TRX,
-		FileName: sysObjInfo.Key,
-		Status:   enum_status.IN_PROGRESS.String(),
-	}
-	if err := uc.ingestionUC.CreateIngestionJob(ctx, sysJob); err != nil {
-		wf.Status = enum_status.FAILED.String()
-		uc.workflowRepo.UpdateWorkflow(ctx, wf)
-		return "", fmt.Errorf("failed to create system ingestion job: %w", err)
+	if err := selectProfile(sysFile, sysObjInfo.Key, enum_parser.SYSTEM_TRX); err != nil {
+		return "", err
 	}
 
-	uc.wg.Add(1)
-	go func() {
-		defer uc.wg.Done()
-		err := uc.ingestionUC.ProcessIngestionJob(ctx, sysJob)
-		success := err == nil
-		uc.OnSystemIngestionComplete(ctx, workflowID, sysJob.JobID, success)
-	}()
-
+	bankKeys := make([]string, 0, len(bankFiles))
 	for _, bankFile := range bankFiles {
 		bankObjInfo, err := uc.ingestionUC.FetchFileMetadata(ctx, bankFile)
 		if err != nil {
-			wf.Status = enum_status.FAILED.String()
-			uc.workflowRepo.UpdateWorkflow(ctx, wf)
 			return "", fmt.Errorf("failed to fetch bank statement file metadata: %w", err)
 		}
-
-		bankJob := &domain.IngestionJob{
-			JobID:    uuid.New().String(),
-			FileType: enum_parser.BANK_STATEMENT,
-			FileName: bankObjInfo.Key,
-			Status:   enum_status.IN_PROGRESS.String(),
+		if err := selectProfile(bankFile, bankObjInfo.Key, enum_parser.BANK_STATEMENT); err != nil {
+			return "", err
 		}
-		if err := uc.ingestionUC.CreateIngestionJob(ctx, bankJob); err != nil {
-			wf.Status = enum_status.FAILED.String()
-			uc.workflowRepo.UpdateWorkflow(ctx, wf)
-			return "", fmt.Errorf("failed to create bank ingestion job: %w", err)
+		bankKeys = append(bankKeys, bankObjInfo.Key)
+	}
+
+	wf := domain.Workflow{
+		WorkflowID:       uuid.New().String(),
+		Status:           enum_status.PENDING.String(),
+		SystemFile:       sysObjInfo.Key,
+		BankFiles:        bankKeys,
+		FileProfiles:     profiles,
+		StartDate:        startDate,
+		EndDate:          endDate,
+		MatchOptions:     opts,
+		SourceWorkflowID: sourceID,
+		CreatedBy:        contextprop.GetValue(ctx, contextprop.ClientIDKey),
+	}
+	if err := uc.workflowRepo.CreateWorkflow(ctx, wf); err != nil {
+		return "", fmt.Errorf("failed to create workflow: %w", err)
+	}
+
+	return wf.WorkflowID, nil
+}
+
+func (uc *workflowUseCase) ClaimActiveWorkflows(ctx context.Context, workerID string, limit int, lease time.Duration) ([]domain.Workflow, error) {
+	workflows, err := uc.workflowRepo.ClaimActiveWorkflows(ctx, workerID, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim active workflows: %w", err)
+	}
+	return workflows, nil
+}
+
+func (uc *workflowUseCase) HeartbeatWorkflow(ctx context.Context, workflowID, workerID string, lease time.Duration) (bool, error) {
+	return uc.workflowRepo.HeartbeatWorkflow(ctx, workflowID, workerID, lease)
+}
+
+func (uc *workflowUseCase) ReleaseWorkflow(ctx context.Context, workflowID, workerID string) error {
+	return uc.workflowRepo.ReleaseWorkflow(ctx, workflowID, workerID)
+}
+
+// RequeueIngestionJob gives a dead-lettered ingestion job a fresh set of attempts,
+// the workflow the job failed goes back to INGESTING to wait for it.
+func (uc *workflowUseCase) RequeueIngestionJob(ctx context.Context, jobID string) (domain.IngestionJob, error) {
+	job, err := uc.ingestionUC.RequeueDeadLetterJob(ctx, jobID)
+	if err != nil {
+		return domain.IngestionJob{}, err
+	}
+	if job.WorkflowID == "" {
+		return job, nil
+	}
+
+	wf, err := uc.workflowRepo.GetWorkflow(ctx, job.WorkflowID)
+	if err != nil {
+		return domain.IngestionJob{}, fmt.Errorf("failed to get workflow: %w", err)
+	}
+	if wf.Status == enum_status.FAILED.String() {
+		wf.ErrorMessage = ""
+		if err := uc.transition(ctx, wf, enum_status.INGESTING, fmt.Sprintf("ingestion job %s requeued", jobID)); err != nil {
+			return domain.IngestionJob{}, err
 		}
-		uc.wg.Add(1)
-		go func(job *domain.IngestionJob) {
-			defer uc.wg.Done()
-			err := uc.ingestionUC.ProcessIngestionJob(ctx, job)
-			success := err == nil
-			uc.OnBankIngestionComplete(ctx, workflowID, job.JobID, success)
-		}(bankJob)
 	}
-	uc.wg.Wait()
-	return workflowID, nil
+	return job, nil
 }
 
-func (uc *workflowUseCase) OnSystemIngestionComplete(ctx context.Context, workflowID, jobID string, success bool) error {
-	uc.mu.Lock()
-	defer uc.mu.Unlock()
+// CancelWorkflow stops an active workflow. Its unfinished ingestion jobs are cancelled with it,
+// the workers processing the workflow or its jobs stop at their next heartbeat.
+func (uc *workflowUseCase) CancelWorkflow(ctx context.Context, workflowID string) (domain.Workflow, error) {
+	wf, cancelled, err := uc.workflowRepo.CancelWorkflow(ctx, workflowID, "cancelled on request")
+	if err != nil {
+		return domain.Workflow{}, fmt.Errorf("failed to cancel workflow: %w", err)
+	}
+	if !cancelled {
+		return wf, ErrWorkflowNotActive
+	}
+	return wf, nil
+}
 
+// RerunWorkflow reconciles the already ingested files of a finished workflow again with opts.
+// The run gets a new reconciliation job, the jobs of the earlier runs are kept.
+func (uc *workflowUseCase) RerunWorkflow(ctx context.Context, workflowID string, opts domain.MatchOptions) (domain.Workflow, error) {
 	wf, err := uc.workflowRepo.GetWorkflow(ctx, workflowID)
 	if err != nil {
-		return fmt.Errorf("failed to get workflow: %w", err)
+		return domain.Workflow{}, fmt.Errorf("failed to get workflow: %w", err)
 	}
-
-	if success {
-		jobIDCopy := jobID
-		wf.SystemIngestionJobID = &jobIDCopy
-	} else {
-		wf.Status = enum_status.FAILED.String()
-		uc.workflowRepo.UpdateWorkflow(ctx, wf)
-		return fmt.Errorf("system ingestion failed")
+	switch enum_status.FromString(wf.Status) {
+	case enum_status.COMPLETED, enum_status.FAILED, enum_status.CANCELLED:
+	default:
+		return wf, fmt.Errorf("%w: workflow is %s", ErrWorkflowNotRerunnable, wf.Status)
 	}
 
-	if wf.SystemIngestionJobID != nil && wf.BankIngestionJobID != nil {
-		return uc.startReconciliation(ctx, workflowID, wf.StartDate, wf.EndDate)
+	jobs, err := uc.ingestionUC.GetWorkflowJobs(ctx, workflowID)
+	if err != nil {
+		return domain.Workflow{}, fmt.Errorf("failed to get ingestion jobs: %w", err)
+	}
+	if len(jobs) == 0 {
+		return wf, fmt.Errorf("%w: workflow has no ingested files", ErrWorkflowNotRerunnable)
+	}
+	for _, job := range jobs {
+		if job.Status != enum_status.COMPLETED.String() {
+			return wf, fmt.Errorf("%w: ingestion of %s is %s", ErrWorkflowNotRerunnable, job.FileName, job.Status)
+		}
 	}
 
-	uc.workflowRepo.UpdateWorkflow(ctx, wf)
-	return nil
+	from := wf.Status
+	wf.Status = enum_status.RECONCILING.String()
+	wf.ErrorMessage = ""
+	wf.MatchOptions = opts
+	moved, err := uc.workflowRepo.TransitionWorkflow(ctx, wf, from, "re-run requested")
+	if err != nil {
+		return domain.Workflow{}, fmt.Errorf("failed to move workflow from %s to %s: %w", from, wf.Status, err)
+	}
+	if !moved {
+		return wf, fmt.Errorf("%w: workflow is no longer %s", ErrWorkflowNotRerunnable, from)
+	}
+	return wf, nil
 }
 
-func (uc *workflowUseCase) OnBankIngestionComplete(ctx context.Context, workflowID, jobID string, success bool) error {
-	uc.mu.Lock()
-	defer uc.mu.Unlock()
+// AdvanceWorkflow moves the workflow at most one step forward. Every step can be repeated,
+// so a workflow interrupted by a restart is resumed from the status it was left in.
+func (uc *workflowUseCase) AdvanceWorkflow(ctx context.Context, wf domain.Workflow) error {
+	switch enum_status.FromString(wf.Status) {
+	case enum_status.PENDING:
+		return uc.startIngestion(ctx, wf)
+	case enum_status.INGESTING:
+		return uc.checkIngestion(ctx, wf)
+	case enum_status.RECONCILING:
+		return uc.startReconciliation(ctx, wf)
+	default:
+		return nil
+	}
+}
 
-	wf, err := uc.workflowRepo.GetWorkflow(ctx, workflowID)
+// startIngestion creates the ingestion jobs the workflow does not have yet and moves it to INGESTING
+func (uc *workflowUseCase) startIngestion(ctx context.Context, wf domain.Workflow) error {
+	jobs, err := uc.ingestionUC.GetWorkflowJobs(ctx, wf.WorkflowID)
 	if err != nil {
-		return fmt.Errorf("failed to get workflow: %w", err)
+		return fmt.Errorf("failed to get ingestion jobs: %w", err)
+	}
+	created := make(map[string]string, len(jobs))
+	for _, job := range jobs {
+		created[job.FileType+"|"+job.FileName] = job.JobID
 	}
 
-	if success {
-		jobIDCopy := jobID
-		wf.BankIngestionJobID = &jobIDCopy
-	} else {
-		wf.Status = enum_status.FAILED.String()
-		uc.workflowRepo.UpdateWorkflow(ctx, wf)
-		return fmt.Errorf("bank ingestion failed")
+	jobID := func(fileType, fileName string) (string, error) {
+		if id, ok := created[fileType+"|"+fileName]; ok {
+			return id, nil
+		}
+		job := &domain.IngestionJob{
+			JobID:         uuid.New().String(),
+			WorkflowID:    wf.WorkflowID,
+			FileType:      fileType,
+			FileName:      fileName,
+			ParserProfile: wf.FileProfiles[fileName],
+			Status:        enum_status.PENDING.String(),
+		}
+		if err := uc.ingestionUC.CreateIngestionJob(ctx, job); err != nil {
+			return "", err
+		}
+		created[fileType+"|"+fileName] = job.JobID
+		return job.JobID, nil
 	}
 
-	if wf.SystemIngestionJobID != nil && wf.BankIngestionJobID != nil {
-		return uc.startReconciliation(ctx, workflowID, wf.StartDate, wf.EndDate)
+	sysJobID, err := jobID(enum_parser.SYSTEM_TRX, wf.SystemFile)
+	if err != nil {
+		return fmt.Errorf("failed to create system ingestion job: %w", err)
+	}
+	wf.SystemIngestionJobID = &sysJobID
+	for i, bankFile := range wf.BankFiles {
+		bankJobID, err := jobID(enum_parser.BANK_STATEMENT, bankFile)
+		if err != nil {
+			return fmt.Errorf("failed to create bank ingestion job: %w", err)
+		}
+		if i == 0 {
+			wf.BankIngestionJobID = &bankJobID
+		}
 	}
 
-	uc.workflowRepo.UpdateWorkflow(ctx, wf)
-	return nil
+	return uc.transition(ctx, wf, enum_status.INGESTING, fmt.Sprintf("%d ingestion jobs queued", len(wf.BankFiles)+1))
 }
 
-func (uc *workflowUseCase) OnReconciliationComplete(ctx context.Context, workflowID, jobID string, success bool) error {
-	uc.mu.Lock()
-	defer uc.mu.Unlock()
-
-	wf, err := uc.workflowRepo.GetWorkflow(ctx, workflowID)
+// checkIngestion moves the workflow to RECONCILING once all of its files are ingested
+func (uc *workflowUseCase) checkIngestion(ctx context.Context, wf domain.Workflow) error {
+	jobs, err := uc.ingestionUC.GetWorkflowJobs(ctx, wf.WorkflowID)
 	if err != nil {
-		return fmt.Errorf("failed to get workflow: %w", err)
+		return fmt.Errorf("failed to get ingestion jobs: %w", err)
+	}
+	if len(jobs) == 0 {
+		return uc.fail(ctx, wf, fmt.Errorf("workflow has no ingestion jobs"))
 	}
 
-	if success {
-		wf.ReconciliationJobID = &jobID
-		wf.Status = enum_status.COMPLETED.String()
-	} else {
-		wf.Status = enum_status.FAILED.String()
+	for _, job := range jobs {
+		switch enum_status.FromString(job.Status) {
+		case enum_status.FAILED, enum_status.DEAD_LETTER:
+			return uc.fail(ctx, wf, fmt.Errorf("ingestion of %s failed after %d attempts: %s", job.FileName, job.Attempts, job.LastError))
+		case enum_status.COMPLETED:
+		default:
+			return nil
+		}
 	}
 
-	return uc.workflowRepo.UpdateWorkflow(ctx, wf)
+	return uc.transition(ctx, wf, enum_status.RECONCILING, "all files ingested")
 }
 
-func (uc *workflowUseCase) startReconciliation(ctx context.Context, workflowID string, startDate, endDate time.Time) error {
-	result, err := uc.reconcileUC.ProcessReconciliation(ctx, startDate, endDate)
-	if err != nil {
+// startReconciliation runs the reconciliation of the workflow, a run interrupted by a restart is started again
+func (uc *workflowUseCase) startReconciliation(ctx context.Context, wf domain.Workflow) error {
+	result, err := uc.reconcileUC.ProcessReconciliation(ctx, wf.WorkflowID, wf.StartDate, wf.EndDate, wf.MatchOptions)
+	// The run was cancelled or this worker lost the workflow, it is not a failure of the reconciliation
+	if err != nil && ctx.Err() != nil {
 		return err
 	}
+	if err != nil {
+		return uc.fail(ctx, wf, fmt.Errorf("reconciliation failed: %w", err))
+	}
 
-	go func() {
-		uc.OnReconciliationComplete(ctx, workflowID, result.JobID, true)
-	}()
+	wf.ReconciliationJobID = &result.JobID
+	return uc.transition(ctx, wf, enum_status.COMPLETED, "reconciliation completed")
+}
 
+func (uc *workflowUseCase) fail(ctx context.Context, wf domain.Workflow, cause error) error {
+	wf.ErrorMessage = cause.Error()
+	if err := uc.transition(ctx, wf, enum_status.FAILED, cause.Error()); err != nil {
+		return err
+	}
+	return cause
+}
+
+// transition stores the workflow under its new status, unless another worker already moved it on
+func (uc *workflowUseCase) transition(ctx context.Context, wf domain.Workflow, to enum_status.Type, reason string) error {
+	from := wf.Status
+	wf.Status = to.String()
+	moved, err := uc.workflowRepo.TransitionWorkflow(ctx, wf, from, reason)
+	if err != nil {
+		return fmt.Errorf("failed to move workflow from %s to %s: %w", from, wf.Status, err)
+	}
+	if !moved {
+		slog.InfoContext(ctx, fmt.Sprintf("workflow=%s is no longer %s, skipping move to %s", wf.WorkflowID, from, wf.Status))
+	}
 	return nil
 }
@@ -0,0 +1,426 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	enum_parser "github.com/ardianferdianto/reconciliation-service/internal/domain/enum/parser"
+	mock_repository "github.com/ardianferdianto/reconciliation-service/internal/repository/_mock"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/ingestion"
+	mock_ingestion "github.com/ardianferdianto/reconciliation-service/internal/usecase/ingestion/_mock"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/lease"
+	"github.com/ardianferdianto/reconciliation-service/internal/usecase/parser"
+	mock_reconcile "github.com/ardianferdianto/reconciliation-service/internal/usecase/reconcile/_mock"
+	"github.com/golang/mock/gomock"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type WorkflowUseCaseSuite struct {
+	suite.Suite
+	mockWorkflowRepo *mock_repository.MockWorkflowRepository
+	mockIngestionUC  *mock_ingestion.MockIUseCase
+	mockReconcileUC  *mock_reconcile.MockIUseCase
+	uc               IUseCase
+	controller       *gomock.Controller
+}
+
+func (suite *WorkflowUseCaseSuite) SetupTest() {
+	suite.controller = gomock.NewController(suite.T())
+	suite.mockWorkflowRepo = mock_repository.NewMockWorkflowRepository(suite.controller)
+	suite.mockIngestionUC = mock_ingestion.NewMockIUseCase(suite.controller)
+	suite.mockReconcileUC = mock_reconcile.NewMockIUseCase(suite.controller)
+	suite.uc = NewWorkflowUseCase(suite.mockWorkflowRepo, suite.mockIngestionUC, suite.mockReconcileUC)
+}
+
+func (suite *WorkflowUseCaseSuite) SetupSubTest() {
+	suite.SetupTest()
+}
+
+func (suite *WorkflowUseCaseSuite) TearDownTest() {
+	suite.controller.Finish()
+}
+
+func (suite *WorkflowUseCaseSuite) TestStartWorkflow() {
+	ctx := context.Background()
+	startDate, endDate := time.Date(2021, 01, 01, 0, 0, 0, 0, time.UTC), time.Date(2021, 01, 31, 0, 0, 0, 0, time.UTC)
+
+	suite.Run("Stored As Pending", func() {
+		suite.mockIngestionUC.EXPECT().FetchFileMetadata(ctx, "system.csv").Return(&minio.ObjectInfo{Key: "system.csv"}, nil)
+		suite.mockIngestionUC.EXPECT().FetchFileMetadata(ctx, "bca.csv").Return(&minio.ObjectInfo{Key: "bca.csv"}, nil)
+		suite.mockWorkflowRepo.EXPECT().CreateWorkflow(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, wf domain.Workflow) error {
+			suite.Equal("PENDING", wf.Status)
+			suite.Equal("system.csv", wf.SystemFile)
+			suite.Equal([]string{"bca.csv"}, wf.BankFiles)
+			return nil
+		})
+
+		workflowID, err := suite.uc.StartWorkflow(ctx, "system.csv", []string{"bca.csv"}, nil, startDate, endDate, domain.MatchOptions{})
+		suite.NoError(err)
+		suite.NotEmpty(workflowID)
+	})
+
+	suite.Run("Parser Profile Per File", func() {
+		suite.mockIngestionUC.EXPECT().FetchFileMetadata(ctx, "system.csv").Return(&minio.ObjectInfo{Key: "system.csv"}, nil)
+		suite.mockIngestionUC.EXPECT().FetchFileMetadata(ctx, "/bni.csv").Return(&minio.ObjectInfo{Key: "bni.csv"}, nil)
+		suite.mockIngestionUC.EXPECT().CheckParserProfile(ctx, "bni_v2", enum_parser.BANK_STATEMENT).Return(nil)
+		suite.mockWorkflowRepo.EXPECT().CreateWorkflow(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, wf domain.Workflow) error {
+			suite.Equal(map[string]string{"bni.csv": "bni_v2"}, wf.FileProfiles)
+			return nil
+		})
+
+		_, err := suite.uc.StartWorkflow(ctx, "system.csv", []string{"/bni.csv"}, map[string]string{"/bni.csv": "bni_v2"}, startDate, endDate, domain.MatchOptions{})
+		suite.NoError(err)
+	})
+
+	suite.Run("Unknown Parser Profile", func() {
+		suite.mockIngestionUC.EXPECT().FetchFileMetadata(ctx, "system.csv").Return(&minio.ObjectInfo{Key: "system.csv"}, nil)
+		suite.mockIngestionUC.EXPECT().CheckParserProfile(ctx, "erp_export", enum_parser.SYSTEM_TRX).Return(parser.ErrUnknownProfile)
+
+		_, err := suite.uc.StartWorkflow(ctx, "system.csv", []string{"bca.csv"}, map[string]string{"system.csv": "erp_export"}, startDate, endDate, domain.MatchOptions{})
+		suite.ErrorIs(err, parser.ErrUnknownProfile)
+	})
+
+	suite.Run("Missing File Creates Nothing", func() {
+		suite.mockIngestionUC.EXPECT().FetchFileMetadata(ctx, "system.csv").Return(&minio.ObjectInfo{Key: "system.csv"}, nil)
+		suite.mockIngestionUC.EXPECT().FetchFileMetadata(ctx, "bca.csv").Return(nil, errors.New("not found"))
+
+		_, err := suite.uc.StartWorkflow(ctx, "system.csv", []string{"bca.csv"}, nil, startDate, endDate, domain.MatchOptions{})
+		suite.Error(err)
+	})
+}
+
+func (suite *WorkflowUseCaseSuite) TestAdvanceWorkflow() {
+	ctx := context.Background()
+	startDate, endDate := time.Date(2021, 01, 01, 0, 0, 0, 0, time.UTC), time.Date(2021, 01, 31, 0, 0, 0, 0, time.UTC)
+	workflowID := "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55"
+	workflowIn := func(status string) domain.Workflow {
+		return domain.Workflow{
+			WorkflowID:   workflowID,
+			Status:       status,
+			SystemFile:   "system.csv",
+			BankFiles:    []string{"bca.csv", "bni.csv"},
+			FileProfiles: map[string]string{"bni.csv": "bni_v2"},
+			StartDate:    startDate,
+			EndDate:      endDate,
+		}
+	}
+	expectTransition := func(from, to string) {
+		suite.mockWorkflowRepo.EXPECT().TransitionWorkflow(ctx, gomock.Any(), from, gomock.Any()).DoAndReturn(func(ctx context.Context, wf domain.Workflow, from, reason string) (bool, error) {
+			suite.Equal(to, wf.Status)
+			return true, nil
+		})
+	}
+
+	testCases := []struct {
+		name          string
+		status        string
+		setupMocks    func()
+		expectedError bool
+	}{
+		{
+			name:   "Pending Queues Missing Ingestion Jobs",
+			status: "PENDING",
+			setupMocks: func() {
+				// the system job was created before the worker stopped
+				existing := []domain.IngestionJob{{JobID: "job-sys", FileType: enum_parser.SYSTEM_TRX, FileName: "system.csv", Status: "PENDING"}}
+				suite.mockIngestionUC.EXPECT().GetWorkflowJobs(ctx, workflowID).Return(existing, nil)
+				suite.mockIngestionUC.EXPECT().CreateIngestionJob(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, job *domain.IngestionJob) error {
+					suite.Equal(enum_parser.BANK_STATEMENT, job.FileType)
+					suite.Equal(map[string]string{"bni.csv": "bni_v2"}[job.FileName], job.ParserProfile)
+					suite.Equal(workflowID, job.WorkflowID)
+					suite.Equal("PENDING", job.Status)
+					return nil
+				}).Times(2)
+				suite.mockWorkflowRepo.EXPECT().TransitionWorkflow(ctx, gomock.Any(), "PENDING", gomock.Any()).DoAndReturn(func(ctx context.Context, wf domain.Workflow, from, reason string) (bool, error) {
+					suite.Equal("INGESTING", wf.Status)
+					suite.Equal("job-sys", *wf.SystemIngestionJobID)
+					suite.NotNil(wf.BankIngestionJobID)
+					return true, nil
+				})
+			},
+		},
+		{
+			name:   "Ingesting Waits For Running Jobs",
+			status: "INGESTING",
+			setupMocks: func() {
+				jobs := []domain.IngestionJob{{JobID: "job-sys", Status: "COMPLETED"}, {JobID: "job-bca", Status: "IN_PROGRESS"}}
+				suite.mockIngestionUC.EXPECT().GetWorkflowJobs(ctx, workflowID).Return(jobs, nil)
+			},
+		},
+		{
+			name:   "Ingesting Moves On When All Jobs Completed",
+			status: "INGESTING",
+			setupMocks: func() {
+				jobs := []domain.IngestionJob{{JobID: "job-sys", Status: "COMPLETED"}, {JobID: "job-bca", Status: "COMPLETED"}}
+				suite.mockIngestionUC.EXPECT().GetWorkflowJobs(ctx, workflowID).Return(jobs, nil)
+				expectTransition("INGESTING", "RECONCILING")
+			},
+		},
+		{
+			name:   "Failed Ingestion Fails Workflow",
+			status: "INGESTING",
+			setupMocks: func() {
+				jobs := []domain.IngestionJob{{JobID: "job-sys", Status: "DEAD_LETTER", FileName: "system.csv", Attempts: 5, LastError: "connection reset"}, {JobID: "job-bca", Status: "IN_PROGRESS"}}
+				suite.mockIngestionUC.EXPECT().GetWorkflowJobs(ctx, workflowID).Return(jobs, nil)
+				suite.mockWorkflowRepo.EXPECT().TransitionWorkflow(ctx, gomock.Any(), "INGESTING", gomock.Any()).DoAndReturn(func(ctx context.Context, wf domain.Workflow, from, reason string) (bool, error) {
+					suite.Equal("FAILED", wf.Status)
+					suite.Equal("ingestion of system.csv failed after 5 attempts: connection reset", wf.ErrorMessage)
+					return true, nil
+				})
+			},
+			expectedError: true,
+		},
+		{
+			name:   "Reconciling Completes Workflow",
+			status: "RECONCILING",
+			setupMocks: func() {
+				suite.mockReconcileUC.EXPECT().ProcessReconciliation(ctx, workflowID, startDate, endDate, gomock.Any()).Return(domain.ReconciliationResult{JobID: "rec-1"}, nil)
+				suite.mockWorkflowRepo.EXPECT().TransitionWorkflow(ctx, gomock.Any(), "RECONCILING", gomock.Any()).DoAndReturn(func(ctx context.Context, wf domain.Workflow, from, reason string) (bool, error) {
+					suite.Equal("COMPLETED", wf.Status)
+					suite.Equal("rec-1", *wf.ReconciliationJobID)
+					return true, nil
+				})
+			},
+		},
+		{
+			name:   "Reconciliation Error Fails Workflow",
+			status: "RECONCILING",
+			setupMocks: func() {
+				suite.mockReconcileUC.EXPECT().ProcessReconciliation(ctx, workflowID, startDate, endDate, gomock.Any()).Return(domain.ReconciliationResult{}, errors.New("db down"))
+				expectTransition("RECONCILING", "FAILED")
+			},
+			expectedError: true,
+		},
+		{
+			name:       "Completed Is Left Alone",
+			status:     "COMPLETED",
+			setupMocks: func() {},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			tc.setupMocks()
+			err := suite.uc.AdvanceWorkflow(ctx, workflowIn(tc.status))
+			if tc.expectedError {
+				suite.Error(err)
+			} else {
+				suite.NoError(err)
+			}
+		})
+	}
+}
+
+func (suite *WorkflowUseCaseSuite) TestRequeueIngestionJob() {
+	ctx := context.Background()
+	workflowID := "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55"
+
+	suite.Run("Failed Workflow Waits For Requeued Job", func() {
+		suite.mockIngestionUC.EXPECT().RequeueDeadLetterJob(ctx, "job-sys").Return(domain.IngestionJob{JobID: "job-sys", WorkflowID: workflowID, Status: "PENDING"}, nil)
+		suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, workflowID).Return(domain.Workflow{WorkflowID: workflowID, Status: "FAILED", ErrorMessage: "ingestion failed"}, nil)
+		suite.mockWorkflowRepo.EXPECT().TransitionWorkflow(ctx, gomock.Any(), "FAILED", gomock.Any()).DoAndReturn(func(ctx context.Context, wf domain.Workflow, from, reason string) (bool, error) {
+			suite.Equal("INGESTING", wf.Status)
+			suite.Empty(wf.ErrorMessage)
+			return true, nil
+		})
+
+		job, err := suite.uc.RequeueIngestionJob(ctx, "job-sys")
+		suite.NoError(err)
+		suite.Equal("PENDING", job.Status)
+	})
+
+	suite.Run("Job Not Dead Lettered", func() {
+		suite.mockIngestionUC.EXPECT().RequeueDeadLetterJob(ctx, "job-sys").Return(domain.IngestionJob{}, ingestion.ErrNotDeadLettered)
+
+		_, err := suite.uc.RequeueIngestionJob(ctx, "job-sys")
+		suite.ErrorIs(err, ingestion.ErrNotDeadLettered)
+	})
+}
+
+func (suite *WorkflowUseCaseSuite) TestCancelWorkflow() {
+	ctx := context.Background()
+	workflowID := "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55"
+
+	suite.Run("Active Workflow Cancelled", func() {
+		suite.mockWorkflowRepo.EXPECT().CancelWorkflow(ctx, workflowID, gomock.Any()).Return(domain.Workflow{WorkflowID: workflowID, Status: "CANCELLED"}, true, nil)
+
+		wf, err := suite.uc.CancelWorkflow(ctx, workflowID)
+		suite.NoError(err)
+		suite.Equal("CANCELLED", wf.Status)
+	})
+
+	suite.Run("Finished Workflow Not Cancelled", func() {
+		suite.mockWorkflowRepo.EXPECT().CancelWorkflow(ctx, workflowID, gomock.Any()).Return(domain.Workflow{WorkflowID: workflowID, Status: "COMPLETED"}, false, nil)
+
+		wf, err := suite.uc.CancelWorkflow(ctx, workflowID)
+		suite.ErrorIs(err, ErrWorkflowNotActive)
+		suite.Equal("COMPLETED", wf.Status)
+	})
+}
+
+func (suite *WorkflowUseCaseSuite) TestRerunWorkflow() {
+	ctx := context.Background()
+	workflowID := "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55"
+	recJobID := "rec-1"
+	opts := domain.MatchOptions{AmountTolerance: 5, DateWindowDays: 2}
+	ingested := []domain.IngestionJob{{JobID: "job-sys", Status: "COMPLETED"}, {JobID: "job-bank", Status: "COMPLETED"}}
+
+	suite.Run("Completed Workflow Reconciled Again", func() {
+		suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, workflowID).Return(domain.Workflow{WorkflowID: workflowID, Status: "COMPLETED", ReconciliationJobID: &recJobID}, nil)
+		suite.mockIngestionUC.EXPECT().GetWorkflowJobs(ctx, workflowID).Return(ingested, nil)
+		suite.mockWorkflowRepo.EXPECT().TransitionWorkflow(ctx, gomock.Any(), "COMPLETED", gomock.Any()).DoAndReturn(func(ctx context.Context, wf domain.Workflow, from, reason string) (bool, error) {
+			suite.Equal("RECONCILING", wf.Status)
+			suite.Equal(opts, wf.MatchOptions)
+			// the result of the earlier run stays visible until the new run completes
+			suite.Equal(&recJobID, wf.ReconciliationJobID)
+			return true, nil
+		})
+
+		wf, err := suite.uc.RerunWorkflow(ctx, workflowID, opts)
+		suite.NoError(err)
+		suite.Equal("RECONCILING", wf.Status)
+	})
+
+	suite.Run("Active Workflow Not Rerun", func() {
+		suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, workflowID).Return(domain.Workflow{WorkflowID: workflowID, Status: "INGESTING"}, nil)
+
+		_, err := suite.uc.RerunWorkflow(ctx, workflowID, opts)
+		suite.ErrorIs(err, ErrWorkflowNotRerunnable)
+	})
+
+	suite.Run("Cancelled Ingestion Not Rerun", func() {
+		suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, workflowID).Return(domain.Workflow{WorkflowID: workflowID, Status: "CANCELLED"}, nil)
+		suite.mockIngestionUC.EXPECT().GetWorkflowJobs(ctx, workflowID).Return([]domain.IngestionJob{{JobID: "job-sys", Status: "COMPLETED"}, {JobID: "job-bank", Status: "CANCELLED"}}, nil)
+
+		_, err := suite.uc.RerunWorkflow(ctx, workflowID, opts)
+		suite.ErrorIs(err, ErrWorkflowNotRerunnable)
+	})
+
+	suite.Run("Concurrent Rerun Loses", func() {
+		suite.mockWorkflowRepo.EXPECT().GetWorkflow(ctx, workflowID).Return(domain.Workflow{WorkflowID: workflowID, Status: "FAILED"}, nil)
+		suite.mockIngestionUC.EXPECT().GetWorkflowJobs(ctx, workflowID).Return(ingested, nil)
+		suite.mockWorkflowRepo.EXPECT().TransitionWorkflow(ctx, gomock.Any(), "FAILED", gomock.Any()).Return(false, nil)
+
+		_, err := suite.uc.RerunWorkflow(ctx, workflowID, opts)
+		suite.ErrorIs(err, ErrWorkflowNotRerunnable)
+	})
+}
+
+func (suite *WorkflowUseCaseSuite) TestCloneWorkflow() {
+	ctx := context.Background()
+	sourceID := "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55"
+	startDate, endDate := time.Date(2021, 02, 01, 0, 0, 0, 0, time.UTC), time.Date(2021, 02, 28, 0, 0, 0, 0, time.UTC)
+
+	suite.mockIngestionUC.EXPECT().FetchFileMetadata(ctx, "system.csv").Return(&minio.ObjectInfo{Key: "system.csv"}, nil)
+	suite.mockIngestionUC.EXPECT().FetchFileMetadata(ctx, "bca-fixed.csv").Return(&minio.ObjectInfo{Key: "bca-fixed.csv"}, nil)
+	suite.mockWorkflowRepo.EXPECT().CreateWorkflow(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, wf domain.Workflow) error {
+		suite.Equal("PENDING", wf.Status)
+		suite.Equal(sourceID, wf.SourceWorkflowID)
+		suite.Equal([]string{"bca-fixed.csv"}, wf.BankFiles)
+		suite.Equal(startDate, wf.StartDate)
+		return nil
+	})
+
+	cloneID, err := suite.uc.CloneWorkflow(ctx, sourceID, "system.csv", []string{"bca-fixed.csv"}, nil, startDate, endDate, domain.MatchOptions{})
+	suite.NoError(err)
+	suite.NotEqual(sourceID, cloneID)
+}
+
+func (suite *WorkflowUseCaseSuite) TestCancelledReconciliationIsNotFailed() {
+	ctx, cancel := context.WithCancel(context.Background())
+	workflowID := "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55"
+
+	// the lease heartbeat cancels the context once the workflow is cancelled
+	suite.mockReconcileUC.EXPECT().ProcessReconciliation(ctx, workflowID, gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, workflowID string, startDate, endDate time.Time, opts domain.MatchOptions) (domain.ReconciliationResult, error) {
+			cancel()
+			return domain.ReconciliationResult{}, ctx.Err()
+		})
+
+	err := suite.uc.AdvanceWorkflow(ctx, domain.Workflow{WorkflowID: workflowID, Status: "RECONCILING"})
+	suite.ErrorIs(err, context.Canceled)
+}
+
+func (suite *WorkflowUseCaseSuite) TestAdvanceActiveWorkflows() {
+	ctx := context.Background()
+	leaseOpts := lease.Options{WorkerID: "worker-1", Duration: time.Minute, HeartbeatInterval: time.Minute}
+	first := domain.Workflow{WorkflowID: "wf-1", Status: "COMPLETED"}
+	second := domain.Workflow{WorkflowID: "wf-2", Status: "COMPLETED"}
+
+	// each workflow is advanced once, the pass ends when the first one comes around again
+	gomock.InOrder(
+		suite.mockWorkflowRepo.EXPECT().ClaimActiveWorkflows(ctx, "worker-1", 1, time.Minute).Return([]domain.Workflow{first}, nil),
+		suite.mockWorkflowRepo.EXPECT().ReleaseWorkflow(ctx, "wf-1", "worker-1").Return(nil),
+		suite.mockWorkflowRepo.EXPECT().ClaimActiveWorkflows(ctx, "worker-1", 1, time.Minute).Return([]domain.Workflow{second}, nil),
+		suite.mockWorkflowRepo.EXPECT().ReleaseWorkflow(ctx, "wf-2", "worker-1").Return(nil),
+		suite.mockWorkflowRepo.EXPECT().ClaimActiveWorkflows(ctx, "worker-1", 1, time.Minute).Return([]domain.Workflow{first}, nil),
+		suite.mockWorkflowRepo.EXPECT().ReleaseWorkflow(ctx, "wf-1", "worker-1").Return(nil),
+	)
+
+	advanceActiveWorkflows(ctx, suite.uc, leaseOpts)
+}
+
+func (suite *WorkflowUseCaseSuite) TestListWorkflows() {
+	ctx := context.Background()
+	createdAt := time.Date(2025, 02, 01, 10, 0, 0, 0, time.UTC)
+	items := []domain.WorkflowListItem{
+		{Workflow: domain.Workflow{WorkflowID: "wf-3", CreatedAt: createdAt.Add(2 * time.Hour)}},
+		{Workflow: domain.Workflow{WorkflowID: "wf-2", CreatedAt: createdAt.Add(time.Hour)}},
+		{Workflow: domain.Workflow{WorkflowID: "wf-1", CreatedAt: createdAt}},
+	}
+
+	suite.Run("Next Page Follows", func() {
+		suite.mockWorkflowRepo.EXPECT().ListWorkflows(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, filter domain.WorkflowFilter) ([]domain.WorkflowListItem, error) {
+			// one more than the page size tells whether another page follows
+			suite.Equal(3, filter.Limit)
+			suite.Equal([]string{"COMPLETED"}, filter.Statuses)
+			return items, nil
+		})
+
+		page, err := suite.uc.ListWorkflows(ctx, domain.WorkflowFilter{Statuses: []string{"COMPLETED"}, Limit: 2})
+		suite.NoError(err)
+		suite.Len(page.Items, 2)
+		suite.Equal(&domain.WorkflowCursor{CreatedAt: createdAt.Add(time.Hour), WorkflowID: "wf-2"}, page.Next)
+	})
+
+	suite.Run("Last Page", func() {
+		suite.mockWorkflowRepo.EXPECT().ListWorkflows(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, filter domain.WorkflowFilter) ([]domain.WorkflowListItem, error) {
+			suite.Equal(DefaultPageSize+1, filter.Limit)
+			return items, nil
+		})
+
+		page, err := suite.uc.ListWorkflows(ctx, domain.WorkflowFilter{})
+		suite.NoError(err)
+		suite.Len(page.Items, 3)
+		suite.Nil(page.Next)
+	})
+
+	suite.Run("Page Size Capped", func() {
+		suite.mockWorkflowRepo.EXPECT().ListWorkflows(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, filter domain.WorkflowFilter) ([]domain.WorkflowListItem, error) {
+			suite.Equal(MaxPageSize+1, filter.Limit)
+			return nil, nil
+		})
+
+		_, err := suite.uc.ListWorkflows(ctx, domain.WorkflowFilter{Limit: 10000})
+		suite.NoError(err)
+	})
+}
+
+func TestWorkflowCursor(t *testing.T) {
+	cursor := domain.WorkflowCursor{CreatedAt: time.Date(2025, 02, 01, 10, 0, 0, 123456000, time.UTC), WorkflowID: "0b8a1f4e-2f61-4d6c-9c43-9d1f3c2a7e55"}
+
+	decoded, err := DecodeCursor(EncodeCursor(cursor))
+	if err != nil || !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.WorkflowID != cursor.WorkflowID {
+		t.Fatalf("cursor did not survive a round trip: %+v, %v", decoded, err)
+	}
+	if _, err := DecodeCursor("not-a-cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestWorkflowUseCaseSuite(t *testing.T) {
+	suite.Run(t, new(WorkflowUseCaseSuite))
+}
@@ -0,0 +1,62 @@
+package contract
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+type ExceptionCaseItem struct {
+	ID          int         `json:"id"`
+	ItemType    string      `json:"item_type"`
+	ExceptionID int         `json:"exception_id,omitempty"` // id in the exception lists while the record is unmatched
+	Reference   string      `json:"reference"`
+	BankCode    string      `json:"bank_code,omitempty"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"`
+	ItemDate    time.Time   `json:"item_date"`
+	Status      string      `json:"status"`
+	Assignee    string      `json:"assignee,omitempty"`
+	Priority    string      `json:"priority"`
+	AgeDays     int         `json:"age_days"`
+	ClosedAt    *time.Time  `json:"closed_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+type ExceptionCasePage struct {
+	Items      []ExceptionCaseItem `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// ExceptionCaseDetail is a case with its comments and attachments
+type ExceptionCaseDetail struct {
+	ExceptionCaseItem
+	Comments    []CaseComment    `json:"comments"`
+	Attachments []CaseAttachment `json:"attachments"`
+}
+
+// UpdateCaseRequest changes the status (OPEN or INVESTIGATING) or the assignee of a case, an empty assignee unassigns it
+type UpdateCaseRequest struct {
+	Status   *string `json:"status,omitempty"`
+	Assignee *string `json:"assignee,omitempty"`
+}
+
+type AddCommentRequest struct {
+	Body string `json:"body"`
+}
+
+type CaseComment struct {
+	ID        int       `json:"id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CaseAttachment struct {
+	ID          int       `json:"id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
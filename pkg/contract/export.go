@@ -0,0 +1,7 @@
+package contract
+
+type StoreExportResponse struct {
+	WorkflowID string `json:"workflow_id"`
+	Format     string `json:"format"`
+	ObjectName string `json:"object_name"` // object key of the export in the bucket
+}
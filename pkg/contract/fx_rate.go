@@ -0,0 +1,5 @@
+package contract
+
+type LoadFXRatesResponse struct {
+	Loaded int `json:"loaded"`
+}
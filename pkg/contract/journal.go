@@ -0,0 +1,32 @@
+package contract
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+// JournalEntry is a balanced adjusting entry for a discrepancy (source MATCH or MATCH_GROUP) or a WRITE_OFF
+type JournalEntry struct {
+	ID          int           `json:"id"`
+	SourceType  string        `json:"source_type"`
+	SourceID    int           `json:"source_id"`
+	BankCode    string        `json:"bank_code,omitempty"`
+	Reason      string        `json:"reason"`
+	Reference   string        `json:"reference"`
+	Description string        `json:"description"`
+	EntryDate   string        `json:"entry_date"`
+	Currency    string        `json:"currency"`
+	Lines       []JournalLine `json:"lines"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type JournalLine struct {
+	LineNo  int         `json:"line_no"`
+	Account string      `json:"account"`
+	Debit   money.Money `json:"debit"`
+	Credit  money.Money `json:"credit"`
+}
+
+type JournalEntriesResponse struct {
+	Entries []JournalEntry `json:"entries"`
+}
@@ -0,0 +1,79 @@
+package contract
+
+import (
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"time"
+)
+
+// ManualMatchRequest links an unmatched system transaction to one or more unmatched bank lines,
+// the ids are the ones listed by the exception endpoints
+type ManualMatchRequest struct {
+	SystemExceptionID int    `json:"system_exception_id"`
+	BankExceptionIDs  []int  `json:"bank_exception_ids"`
+	Reason            string `json:"reason"`
+}
+
+type UnmatchRequest struct {
+	Reason string `json:"reason"`
+}
+
+// WriteOffRequest closes an unmatched item, item_type is SYSTEM_TX or BANK_STATEMENT
+type WriteOffRequest struct {
+	ItemType    string `json:"item_type"`
+	ExceptionID int    `json:"exception_id"`
+	ReasonCode  string `json:"reason_code"`
+	Note        string `json:"note,omitempty"`
+}
+
+// ManualResolutionResponse tells what a manual change created and the counts of the reconciliation afterwards
+type ManualResolutionResponse struct {
+	AuditID    int64          `json:"audit_id"`
+	MatchID    int            `json:"match_id,omitempty"`
+	GroupID    int            `json:"group_id,omitempty"`
+	WriteOffID int            `json:"write_off_id,omitempty"`
+	Counts     WorkflowCounts `json:"counts"`
+}
+
+type AuditLogResponse struct {
+	Entries []AuditLogEntry `json:"entries"`
+}
+
+type AuditLogEntry struct {
+	ID        int64          `json:"id"`
+	JobID     string         `json:"job_id"`
+	Action    string         `json:"action"`
+	Actor     string         `json:"actor"`
+	Reason    string         `json:"reason"`
+	Details   map[string]any `json:"details"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// DecisionRequest approves or rejects a proposal, the note is required to reject one
+type DecisionRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
+// Proposal is a manual match or write-off over the approval threshold, action is MANUAL_MATCH or WRITE_OFF.
+// Amount is the discrepancy of the match or the amount written off.
+type Proposal struct {
+	ID                int         `json:"id"`
+	Action            string      `json:"action"`
+	SystemExceptionID int         `json:"system_exception_id,omitempty"`
+	BankExceptionIDs  []int       `json:"bank_exception_ids,omitempty"`
+	ItemType          string      `json:"item_type,omitempty"`
+	ExceptionID       int         `json:"exception_id,omitempty"`
+	ReasonCode        string      `json:"reason_code,omitempty"`
+	Reason            string      `json:"reason,omitempty"`
+	Amount            money.Money `json:"amount"`
+	Currency          string      `json:"currency"`
+	Status            string      `json:"status"`
+	ProposedBy        string      `json:"proposed_by"`
+	DecidedBy         string      `json:"decided_by,omitempty"`
+	DecisionNote      string      `json:"decision_note,omitempty"`
+	CreatedAt         time.Time   `json:"created_at"`
+	DecidedAt         *time.Time  `json:"decided_at,omitempty"`
+}
+
+type ProposalListResponse struct {
+	Proposals []Proposal `json:"proposals"`
+}
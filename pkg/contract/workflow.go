@@ -2,14 +2,78 @@ package contract
 
 import (
 	"github.com/ardianferdianto/reconciliation-service/internal/domain"
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
 	"time"
 )
 
 type StartWorkflowRequest struct {
-	SystemTransactionFilePath string    `json:"system_transaction_file_path"`
-	BankStatementFilePaths    []string  `json:"bank_statement_file_paths"`
-	StartDate                 time.Time `json:"start_date"`
-	EndDate                   time.Time `json:"end_date"`
+	SystemTransactionFilePath string            `json:"system_transaction_file_path"`
+	BankStatementFilePaths    []string          `json:"bank_statement_file_paths"`
+	FileProfiles              map[string]string `json:"file_profiles,omitempty"` // parser profile by file path, others use the built-in parser
+	StartDate                 time.Time         `json:"start_date"`
+	EndDate                   time.Time         `json:"end_date"`
+	MatchOptionsRequest
+}
+
+// MatchOptionsRequest holds the matching tolerances a request may set, the ones left out keep their default
+type MatchOptionsRequest struct {
+	AmountTolerance        *float64 `json:"amount_tolerance,omitempty"`
+	AmountTolerancePercent *float64 `json:"amount_tolerance_percent,omitempty"`
+	DateWindowDays         *int     `json:"date_window_days,omitempty"`
+	GroupMatching          *bool    `json:"group_matching,omitempty"`
+	MaxGroupSize           *int     `json:"max_group_size,omitempty"`
+	AssignmentMode         *string  `json:"assignment_mode,omitempty"`
+	Currency               *string  `json:"currency,omitempty"`
+	ConvertCurrency        *bool    `json:"convert_currency,omitempty"`
+	IncludeHistorical      *bool    `json:"include_historical,omitempty"`
+}
+
+// RerunWorkflowRequest overrides the match options of the workflow for the new run
+type RerunWorkflowRequest struct {
+	MatchOptionsRequest
+}
+
+// CloneWorkflowRequest overrides what the clone copies from the workflow, fields left out are copied as they are
+type CloneWorkflowRequest struct {
+	SystemTransactionFilePath string            `json:"system_transaction_file_path,omitempty"`
+	BankStatementFilePaths    []string          `json:"bank_statement_file_paths,omitempty"`
+	FileProfiles              map[string]string `json:"file_profiles,omitempty"`
+	StartDate                 *time.Time        `json:"start_date,omitempty"`
+	EndDate                   *time.Time        `json:"end_date,omitempty"`
+	MatchOptionsRequest
+}
+
+// MatchOptions overrides the given defaults with the tolerances set on the request
+func (r MatchOptionsRequest) MatchOptions(defaults domain.MatchOptions) domain.MatchOptions {
+	opts := defaults
+	if r.AmountTolerance != nil {
+		opts.AmountTolerance = *r.AmountTolerance
+	}
+	if r.AmountTolerancePercent != nil {
+		opts.AmountTolerancePercent = *r.AmountTolerancePercent
+	}
+	if r.DateWindowDays != nil {
+		opts.DateWindowDays = *r.DateWindowDays
+	}
+	if r.GroupMatching != nil {
+		opts.GroupMatching = *r.GroupMatching
+	}
+	if r.MaxGroupSize != nil {
+		opts.MaxGroupSize = *r.MaxGroupSize
+	}
+	if r.AssignmentMode != nil {
+		opts.AssignmentMode = *r.AssignmentMode
+	}
+	if r.Currency != nil {
+		opts.Currency = *r.Currency
+	}
+	if r.ConvertCurrency != nil {
+		opts.ConvertCurrency = *r.ConvertCurrency
+	}
+	if r.IncludeHistorical != nil {
+		opts.IncludeHistorical = *r.IncludeHistorical
+	}
+	return opts
 }
 
 type WorkflowSummaryResponse struct {
@@ -17,5 +81,155 @@ type WorkflowSummaryResponse struct {
 	Status           string                        `json:"status"`
 	StartDate        time.Time                     `json:"start_date"`
 	EndDate          time.Time                     `json:"end_date"`
+	ErrorMessage     string                        `json:"error_message,omitempty"`
+	SourceWorkflowID string                        `json:"source_workflow_id,omitempty"`
+	Transitions      []domain.WorkflowTransition   `json:"transitions"`
+	Runs             []ReconciliationRun           `json:"reconciliation_runs"`
 	ReconcileSummary *domain.ReconciliationSummary `json:"reconciliation_summary"`
 }
+
+// ReconciliationRun is one reconciliation job of a workflow, the summary is the one of the latest completed run
+type ReconciliationRun struct {
+	JobID     string    `json:"job_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RequeueIngestionJobResponse is the dead-lettered job put back in the queue
+type RequeueIngestionJobResponse struct {
+	JobID      string `json:"job_id"`
+	WorkflowID string `json:"workflow_id,omitempty"`
+	Status     string `json:"status"`
+}
+
+// ListParserProfilesResponse holds the parser profiles by name, configured ones first
+type ListParserProfilesResponse struct {
+	Profiles []domain.ParserProfile `json:"profiles"`
+}
+
+// StartWorkflowResponse is returned as soon as the workflow is stored, the worker carries it out
+type StartWorkflowResponse struct {
+	WorkflowID string `json:"workflow_id"`
+	Status     string `json:"status"`
+}
+
+// WorkflowStatusResponse is returned once a workflow is cancelled or queued to be re-run
+type WorkflowStatusResponse struct {
+	WorkflowID string `json:"workflow_id"`
+	Status     string `json:"status"`
+}
+
+// CloneWorkflowResponse is the new workflow started as a copy of another one
+type CloneWorkflowResponse struct {
+	WorkflowID       string `json:"workflow_id"`
+	SourceWorkflowID string `json:"source_workflow_id"`
+	Status           string `json:"status"`
+}
+
+// ListWorkflowsResponse is one page of workflows, next_cursor is left out on the last page
+type ListWorkflowsResponse struct {
+	Workflows  []WorkflowListEntry `json:"workflows"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// WorkflowListEntry is the lightweight summary of a listed workflow
+type WorkflowListEntry struct {
+	WorkflowID          string          `json:"workflow_id"`
+	Status              string          `json:"status"`
+	StartDate           time.Time       `json:"start_date"`
+	EndDate             time.Time       `json:"end_date"`
+	SystemFile          string          `json:"system_file"`
+	BankFiles           []string        `json:"bank_files"`
+	CreatedBy           string          `json:"created_by,omitempty"`
+	SourceWorkflowID    string          `json:"source_workflow_id,omitempty"`
+	ErrorMessage        string          `json:"error_message,omitempty"`
+	ReconciliationJobID *string         `json:"reconciliation_job_id,omitempty"`
+	Counts              *WorkflowCounts `json:"counts,omitempty"` // left out until a reconciliation completed
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// WorkflowCounts are the totals of the latest reconciliation of a workflow
+type WorkflowCounts struct {
+	TotalSystemTransactions int         `json:"total_system_transactions"`
+	TotalBankTransactions   int         `json:"total_bank_transactions"`
+	Matched                 int         `json:"matched"`
+	MatchedGroups           int         `json:"matched_groups"`
+	UnmatchedSystem         int         `json:"unmatched_system"`
+	UnmatchedBank           int         `json:"unmatched_bank"`
+	TotalDiscrepancies      money.Money `json:"total_discrepancies"`
+}
+
+// UnmatchedSystemPage is one page of the system transactions a workflow left unmatched
+type UnmatchedSystemPage struct {
+	Items      []UnmatchedSystemItem `json:"items"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+type UnmatchedSystemItem struct {
+	ID              int         `json:"id"`
+	TrxID           string      `json:"trx_id"`
+	Type            string      `json:"type"`
+	Amount          money.Money `json:"amount"`
+	Currency        string      `json:"currency"`
+	TransactionTime time.Time   `json:"transaction_time"`
+}
+
+// UnmatchedBankPage is one page of the bank statements a workflow left unmatched
+type UnmatchedBankPage struct {
+	Items      []UnmatchedBankItem `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+type UnmatchedBankItem struct {
+	ID            int         `json:"id"`
+	UniqueID      string      `json:"unique_id"`
+	BankCode      string      `json:"bank_code"`
+	Amount        money.Money `json:"amount"`
+	Currency      string      `json:"currency"`
+	StatementTime time.Time   `json:"statement_time"`
+}
+
+// MatchedPairPage is one page of the 1-to-1 matches of a workflow
+type MatchedPairPage struct {
+	Items      []MatchedPairItem `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type MatchedPairItem struct {
+	ID              int         `json:"id"`
+	TrxID           string      `json:"trx_id"`
+	TrxType         string      `json:"trx_type"`
+	TransactionTime time.Time   `json:"transaction_time"`
+	SystemAmount    money.Money `json:"system_amount"`
+	SystemCurrency  string      `json:"system_currency"`
+	SystemFXRate    money.Rate  `json:"system_fx_rate"`
+	UniqueID        string      `json:"unique_id"`
+	BankCode        string      `json:"bank_code"`
+	StatementTime   time.Time   `json:"statement_time"`
+	BankAmount      money.Money `json:"bank_amount"`
+	BankCurrency    string      `json:"bank_currency"`
+	BankFXRate      money.Rate  `json:"bank_fx_rate"`
+	Discrepancy     money.Money `json:"discrepancy"`
+	Currency        string      `json:"currency"`
+	RuleSet         string      `json:"rule_set,omitempty"`
+	MatchPass       string      `json:"match_pass,omitempty"`
+	MatchedBy       string      `json:"matched_by,omitempty"` // set on manual matches
+	MatchedAt       time.Time   `json:"matched_at"`
+}
+
+// MatchGroupPage is one page of the split and aggregate matches of a workflow
+type MatchGroupPage struct {
+	Items      []MatchGroupItem `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+type MatchGroupItem struct {
+	ID               int         `json:"id"`
+	GroupType        string      `json:"group_type"`
+	SystemTxIDs      []int       `json:"system_tx_ids"`
+	BankStatementIDs []int       `json:"bank_statement_ids"`
+	Discrepancy      money.Money `json:"discrepancy"`
+	Currency         string      `json:"currency"`
+	MatchedBy        string      `json:"matched_by,omitempty"` // set on manual matches
+	MatchedAt        time.Time   `json:"matched_at"`
+}
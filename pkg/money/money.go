@@ -0,0 +1,250 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used when neither the source file nor the caller names a currency
+const DefaultCurrency = "IDR"
+
+// currencyScales lists ISO 4217 currencies whose minor unit is not 2 decimals
+var currencyScales = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+var pow10 = [...]int64{1, 10, 100, 1000}
+
+// Money is an exact amount in minor units of its currency, e.g. Money{Minor: 1050, Currency: "IDR"} is 10.50 IDR.
+// The zero value is a currency-less zero that takes the currency of whatever it is added to.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// Scale returns the number of decimals of the minor unit of a currency
+func Scale(currency string) int {
+	if scale, ok := currencyScales[strings.ToUpper(currency)]; ok {
+		return scale
+	}
+	return 2
+}
+
+// ParseCurrency normalises a three letter ISO 4217 code such as "usd" to "USD"
+func ParseCurrency(s string) (string, error) {
+	currency := strings.ToUpper(strings.TrimSpace(s))
+	if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("invalid currency %q", s)
+	}
+	return currency, nil
+}
+
+// Parse reads a plain decimal such as "-1234.50" into the minor units of currency.
+// Extra decimals are only accepted when they are zeros, any other precision loss is an error.
+func Parse(amount, currency string) (Money, error) {
+	s := strings.TrimSpace(amount)
+	if s == "" {
+		return Money{}, errors.New("empty amount")
+	}
+	negative := false
+	switch s[0] {
+	case '-':
+		negative, s = true, s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	scale := Scale(currency)
+	if len(fracPart) > scale {
+		if strings.Trim(fracPart[scale:], "0") != "" {
+			return Money{}, fmt.Errorf("amount %q has more than %d decimals for %s", amount, scale, currency)
+		}
+		fracPart = fracPart[:scale]
+	}
+	fracPart += strings.Repeat("0", scale-len(fracPart))
+
+	digits := intPart + fracPart
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("invalid amount %q", amount)
+		}
+	}
+	if digits = strings.TrimLeft(digits, "0"); digits == "" {
+		digits = "0"
+	}
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("amount %q out of range: %w", amount, err)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// MustParse is Parse for literals known to be valid, it panics otherwise
+func MustParse(amount, currency string) Money {
+	m, err := Parse(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// FromFloat rounds a floating point amount half away from zero to the minor unit of currency.
+// Only meant for configuration values such as tolerances, never for ledger amounts.
+func FromFloat(amount float64, currency string) Money {
+	return Money{Minor: int64(math.Round(amount * float64(pow10[Scale(currency)]))), Currency: currency}
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{Minor: m.Minor + o.Minor, Currency: m.combine(o)}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{Minor: m.Minor - o.Minor, Currency: m.combine(o)}
+}
+
+// combine returns the currency of an operation, mixing two different currencies is a programming error
+func (m Money) combine(o Money) string {
+	switch {
+	case m.Currency == o.Currency || o.Currency == "":
+		return m.Currency
+	case m.Currency == "":
+		return o.Currency
+	}
+	panic(fmt.Sprintf("money: mixing %s and %s", m.Currency, o.Currency))
+}
+
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+func (m Money) Abs() Money {
+	if m.Minor < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// Cmp compares two amounts of the same currency and returns -1, 0 or 1
+func (m Money) Cmp(o Money) int {
+	m.combine(o)
+	switch {
+	case m.Minor < o.Minor:
+		return -1
+	case m.Minor > o.Minor:
+		return 1
+	}
+	return 0
+}
+
+// Percent returns pct percent of the amount, rounded half away from zero to the minor unit
+func (m Money) Percent(pct float64) Money {
+	return Money{Minor: int64(math.Round(float64(m.Minor) * pct / 100)), Currency: m.Currency}
+}
+
+// String renders the amount as a plain decimal with the currency scale, e.g. "-1234.50"
+func (m Money) String() string {
+	scale := Scale(m.Currency)
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+	}
+	abs := uint64(minor)
+	if minor < 0 {
+		abs = uint64(-minor)
+	}
+	if scale == 0 {
+		return sign + strconv.FormatUint(abs, 10)
+	}
+	unit := uint64(pow10[scale])
+	return fmt.Sprintf("%s%d.%0*d", sign, abs/unit, scale, abs%unit)
+}
+
+type jsonMoney struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON renders the amount as a decimal string so no client has to go through a float
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{Amount: m.String(), Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var v jsonMoney
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Currency == "" {
+		v.Currency = DefaultCurrency
+	}
+	parsed, err := Parse(v.Amount.String(), v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value writes the amount as a decimal string, which Postgres stores exactly in a NUMERIC column
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a NUMERIC column. The currency already set on m is kept, DefaultCurrency is used otherwise.
+func (m *Money) Scan(src any) error {
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*m = Money{Currency: currency}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case int64:
+		*m = Money{Minor: v * pow10[Scale(currency)], Currency: currency}
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	parsed, err := Parse(s, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,106 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ardianferdianto/reconciliation-service/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     money.Money
+		wantErr  bool
+	}{
+		{amount: "100", currency: "IDR", want: money.New(10000, "IDR")},
+		{amount: "-1234.5", currency: "IDR", want: money.New(-123450, "IDR")},
+		{amount: "0.10", currency: "USD", want: money.New(10, "USD")},
+		{amount: "99.750", currency: "IDR", want: money.New(9975, "IDR")},
+		{amount: "1500", currency: "JPY", want: money.New(1500, "JPY")},
+		{amount: "1.234", currency: "KWD", want: money.New(1234, "KWD")},
+		{amount: "0.001", currency: "IDR", wantErr: true},
+		{amount: "1.5", currency: "JPY", wantErr: true},
+		{amount: "12a", currency: "IDR", wantErr: true},
+		{amount: "", currency: "IDR", wantErr: true},
+		{amount: "99999999999999999999", currency: "IDR", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			got, err := money.Parse(tt.amount, tt.currency)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestArithmeticIsExact(t *testing.T) {
+	sum := money.Money{}
+	for i := 0; i < 10; i++ {
+		sum = sum.Add(money.MustParse("0.10", "IDR"))
+	}
+	assert.Equal(t, money.MustParse("1.00", "IDR"), sum)
+	assert.Equal(t, "-0.05", money.MustParse("0.10", "IDR").Sub(money.MustParse("0.15", "IDR")).String())
+	assert.Equal(t, "1.235", money.MustParse("1.235", "KWD").String())
+	assert.Panics(t, func() { money.MustParse("1", "IDR").Add(money.MustParse("1", "USD")) })
+}
+
+func TestJSON(t *testing.T) {
+	b, err := json.Marshal(money.MustParse("-12.30", "IDR"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"-12.30","currency":"IDR"}`, string(b))
+
+	var m money.Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":12.3}`), &m))
+	assert.Equal(t, money.MustParse("12.30", money.DefaultCurrency), m)
+}
+
+func TestScan(t *testing.T) {
+	var m money.Money
+	assert.NoError(t, m.Scan("1050.25"))
+	assert.Equal(t, money.New(105025, money.DefaultCurrency), m)
+
+	usd := money.Money{Currency: "USD"}
+	assert.NoError(t, usd.Scan([]byte("3.10")))
+	assert.Equal(t, money.New(310, "USD"), usd)
+
+	v, err := usd.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "3.10", v)
+}
+
+func TestConvert(t *testing.T) {
+	usdIdr, err := money.ParseRate("15850.25")
+	assert.NoError(t, err)
+
+	assert.Equal(t, money.MustParse("1585025.00", "IDR"), money.MustParse("100", "USD").Convert(usdIdr, "IDR"))
+	assert.Equal(t, money.MustParse("-158.50", "IDR"), money.MustParse("-0.01", "USD").Convert(usdIdr, "IDR"))
+	assert.Equal(t, money.MustParse("100.00", "USD"), money.MustParse("1585025", "IDR").Convert(usdIdr.Inverse(), "USD"))
+	assert.Equal(t, money.MustParse("0.01", "USD"), money.MustParse("100", "IDR").Convert(usdIdr.Inverse(), "USD"))
+	assert.Equal(t, money.MustParse("0.00", "USD"), money.MustParse("79", "IDR").Convert(usdIdr.Inverse(), "USD"))
+
+	jpyIdr, _ := money.ParseRate("105.5")
+	assert.Equal(t, money.MustParse("105.50", "IDR"), money.MustParse("1", "JPY").Convert(jpyIdr, "IDR"))
+	assert.Equal(t, money.MustParse("1", "JPY"), money.MustParse("105.00", "IDR").Convert(jpyIdr.Inverse(), "JPY"))
+}
+
+func TestParseRate(t *testing.T) {
+	for _, invalid := range []string{"", "0", "-1.5", "1e3", "1/3", "abc", "0.00000000001"} {
+		_, err := money.ParseRate(invalid)
+		assert.Error(t, err, invalid)
+	}
+	r, err := money.ParseRate("0.0000630900")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.00006309", r.String())
+	assert.Equal(t, "15850.3724837534", r.Inverse().String())
+
+	var decoded money.Rate
+	assert.NoError(t, json.Unmarshal([]byte(`"15850.25"`), &decoded))
+	assert.Equal(t, "15850.25", decoded.String())
+}
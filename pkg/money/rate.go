@@ -0,0 +1,146 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RateDecimals is the precision rates are written with, it matches the fx_rates.rate column
+const RateDecimals = 10
+
+// Rate is an exact exchange rate, the amount of quote currency one unit of base currency buys.
+// The zero value is not a valid rate.
+type Rate struct {
+	r *big.Rat
+}
+
+// OneRate converts a currency to itself
+func OneRate() Rate {
+	return Rate{r: big.NewRat(1, 1)}
+}
+
+// ParseRate reads a positive plain decimal such as "15850.25"
+func ParseRate(rate string) (Rate, error) {
+	s := strings.TrimSpace(rate)
+	if s == "" {
+		return Rate{}, errors.New("empty rate")
+	}
+	if strings.ContainsAny(s, "eE/") {
+		return Rate{}, fmt.Errorf("invalid rate %q", rate)
+	}
+	if _, frac, ok := strings.Cut(s, "."); ok && len(strings.TrimRight(frac, "0")) > RateDecimals {
+		return Rate{}, fmt.Errorf("rate %q has more than %d decimals", rate, RateDecimals)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q", rate)
+	}
+	if r.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("rate %q must be positive", rate)
+	}
+	return Rate{r: r}, nil
+}
+
+func (r Rate) IsZero() bool {
+	return r.r == nil || r.r.Sign() == 0
+}
+
+// Inverse returns the rate of the opposite direction, e.g. USD/IDR from IDR/USD
+func (r Rate) Inverse() Rate {
+	if r.IsZero() {
+		return Rate{}
+	}
+	return Rate{r: new(big.Rat).Inv(r.r)}
+}
+
+// String renders the rate rounded to RateDecimals without trailing zeros
+func (r Rate) String() string {
+	if r.r == nil {
+		return "0"
+	}
+	s := r.r.FloatString(RateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Convert multiplies the amount by rate into currency, rounding half away from zero to its minor unit
+func (m Money) Convert(rate Rate, currency string) Money {
+	if rate.IsZero() {
+		panic("money: converting with a zero rate")
+	}
+	// minor(to) = minor(from) * rate * 10^scale(to) / 10^scale(from)
+	num := new(big.Int).Mul(big.NewInt(m.Minor), rate.r.Num())
+	num.Mul(num, big.NewInt(pow10[Scale(currency)]))
+	den := new(big.Int).Mul(rate.r.Denom(), big.NewInt(pow10[Scale(m.Currency)]))
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	if !quo.IsInt64() {
+		panic(fmt.Sprintf("money: converting %s %s to %s overflows", m, m.Currency, currency))
+	}
+	return Money{Minor: quo.Int64(), Currency: currency}
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	if r.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(r.String())
+}
+
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*r = Rate{}
+		return nil
+	}
+	// json.Number takes both 15850.25 and "15850.25"
+	var s json.Number
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseRate(s.String())
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value writes the rate as a decimal string, a zero rate is stored as NULL
+func (r Rate) Value() (driver.Value, error) {
+	if r.IsZero() {
+		return nil, nil
+	}
+	return r.String(), nil
+}
+
+func (r *Rate) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*r = Rate{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("money: cannot scan rate from %T", src)
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}